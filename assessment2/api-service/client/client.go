@@ -0,0 +1,317 @@
+// Package client is a typed Go client of the posts API, it unwraps response envelopes,
+// retries failed requests and reports error responses as typed errors
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// userAgent identifies requests of the client
+const userAgent = "api-service-client/1.0"
+
+// Default client configuration
+const (
+	DefaultMaxRetries  = 3
+	DefaultBaseBackoff = 200 * time.Millisecond
+	DefaultMaxBackoff  = 5 * time.Second
+	DefaultPageSize    = 50
+)
+
+// API versions of post routes
+const (
+	APIVersionV1 = "v1"
+	APIVersionV2 = "v2"
+)
+
+// maxResponseSize limits size of decoded response bodies
+const maxResponseSize = 16 << 20
+
+// Authenticator adds credentials to requests of the client
+type Authenticator interface {
+	Authenticate(r *http.Request) error
+}
+
+// AuthenticatorFunc is a function used as an authenticator
+type AuthenticatorFunc func(r *http.Request) error
+
+// Authenticate calls the function
+func (f AuthenticatorFunc) Authenticate(r *http.Request) error {
+	return f(r)
+}
+
+// BearerToken authenticates requests with user token in Authorization header
+func BearerToken(token string) Authenticator {
+	return AuthenticatorFunc(func(r *http.Request) error {
+		r.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	})
+}
+
+// APIKey identifies requests with X-API-Key header, it selects rate limit bucket of the client
+func APIKey(key string) Authenticator {
+	return AuthenticatorFunc(func(r *http.Request) error {
+		r.Header.Set("X-API-Key", key)
+		return nil
+	})
+}
+
+// Config defines authentication and retry rules, zero values are replaced by defaults
+type Config struct {
+	Auth        Authenticator // credentials of requests, requests are anonymous when nil
+	MaxRetries  int           // retries of failed requests, negative disables retries
+	BaseBackoff time.Duration // delay after the first failure, doubled after every next one
+	MaxBackoff  time.Duration // limits delays, including ones requested by Retry-After header
+	UserAgent   string
+	APIVersion  string // version of post routes, APIVersionV1 by default; transfer routes are served by v1 only
+}
+
+// Client sends requests to the API at base URL
+type Client struct {
+	baseURL *url.URL
+	client  *http.Client
+	config  Config
+}
+
+// New creates client of the API at base URL such as http://localhost:8080, default HTTP client
+// is used when client is nil
+func New(baseURL string, client *http.Client, config Config) (*Client, error) {
+	parsed, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil {
+		return nil, err
+	}
+	if parsed.Scheme == "" || parsed.Host == "" {
+		return nil, fmt.Errorf("base url %q must be absolute", baseURL)
+	}
+	if client == nil {
+		client = &http.Client{}
+	}
+	if config.MaxRetries == 0 {
+		config.MaxRetries = DefaultMaxRetries
+	}
+	if config.BaseBackoff <= 0 {
+		config.BaseBackoff = DefaultBaseBackoff
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = DefaultMaxBackoff
+	}
+	if config.UserAgent == "" {
+		config.UserAgent = userAgent
+	}
+	switch config.APIVersion {
+	case "":
+		config.APIVersion = APIVersionV1
+	case APIVersionV1, APIVersionV2:
+	default:
+		return nil, fmt.Errorf("unsupported api version %q", config.APIVersion)
+	}
+	return &Client{baseURL: parsed, client: client, config: config}, nil
+}
+
+// envelope is a response of the API, data is decoded by caller
+type envelope struct {
+	Error   bool            `json:"error"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+	TraceID string          `json:"trace_id"`
+}
+
+// errorEnvelopeV2 is an error response of API v2
+type errorEnvelopeV2 struct {
+	Error struct {
+		Message string `json:"message"`
+		TraceID string `json:"trace_id"`
+	} `json:"error"`
+}
+
+// request describes API call, it is sent again on retries
+type request struct {
+	method         string
+	path           string
+	query          url.Values
+	body           any    // encoded as JSON
+	rawBody        []byte // sent as it is with content type
+	contentType    string
+	idempotencyKey string
+	notFound       error // matched by not found responses instead of ErrorNotFound
+}
+
+// do sends request with retries and decodes data of successful response into result,
+// result is ignored when it is nil
+func (c *Client) do(ctx context.Context, req request, result any) error {
+	body, contentType := req.rawBody, req.contentType
+	if req.body != nil {
+		var err error
+		if body, err = json.Marshal(req.body); err != nil {
+			return err
+		}
+		contentType = "application/json"
+	}
+
+	// only reads and creation replayed by idempotency key are retried, other changes could be applied twice
+	retryable := req.method == http.MethodGet || req.method == http.MethodHead || req.idempotencyKey != ""
+	for tries := 1; ; tries++ {
+		err := c.send(ctx, req, body, contentType, result)
+		if err == nil {
+			return nil
+		}
+		retryAfter, ok := retryDelay(err)
+		if !ok || !retryable || c.config.MaxRetries < 0 || tries > c.config.MaxRetries {
+			return err
+		}
+
+		timer := time.NewTimer(min(max(c.backoff(tries), retryAfter), c.config.MaxBackoff))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// send sends request once and decodes its response
+func (c *Client) send(ctx context.Context, req request, body []byte, contentType string, result any) error {
+	resp, err := c.open(ctx, req, body, contentType, "application/json")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return &transportError{err: err}
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		apiErr := newAPIError(resp, data)
+		apiErr.notFound = req.notFound
+		return apiErr
+	}
+	// changes of API v2 respond without body
+	if len(data) == 0 {
+		return nil
+	}
+
+	var response envelope
+	if decodeErr := json.Unmarshal(data, &response); decodeErr != nil {
+		return fmt.Errorf("failed to decode response: %w", decodeErr)
+	}
+	if result == nil || len(response.Data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(response.Data, result); err != nil {
+		return fmt.Errorf("failed to decode response data: %w", err)
+	}
+	return nil
+}
+
+// open sends request and returns response whose body must be closed by caller
+func (c *Client) open(ctx context.Context, req request, body []byte, contentType string, accept string) (*http.Response, error) {
+	target := c.baseURL.JoinPath(req.path)
+	target.RawQuery = req.query.Encode()
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	r, err := http.NewRequestWithContext(ctx, req.method, target.String(), reader)
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Set("Accept", accept)
+	r.Header.Set("User-Agent", c.config.UserAgent)
+	if contentType != "" {
+		r.Header.Set("Content-Type", contentType)
+	}
+	if req.idempotencyKey != "" {
+		r.Header.Set("Idempotency-Key", req.idempotencyKey)
+	}
+	if c.config.Auth != nil {
+		if err := c.config.Auth.Authenticate(r); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := c.client.Do(r)
+	if err != nil {
+		return nil, &transportError{err: err}
+	}
+	return resp, nil
+}
+
+// newAPIError creates error of response, error responses carry message of the envelope of any API version,
+// other bodies are reported as they are
+func newAPIError(resp *http.Response, data []byte) *APIError {
+	var response envelope
+	decodeErr := json.Unmarshal(data, &response)
+	apiErr := &APIError{StatusCode: resp.StatusCode, Message: response.Message, TraceID: response.TraceID, Data: response.Data}
+	var responseV2 errorEnvelopeV2
+	if decodeErr != nil && json.Unmarshal(data, &responseV2) == nil {
+		decodeErr = nil
+		apiErr.Message, apiErr.TraceID = responseV2.Error.Message, responseV2.Error.TraceID
+	}
+	if decodeErr != nil || apiErr.Message == "" {
+		apiErr.Message = strings.TrimSpace(string(data))
+	}
+	if apiErr.TraceID == "" {
+		apiErr.TraceID = resp.Header.Get("X-Trace-ID")
+	}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		apiErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	return apiErr
+}
+
+// backoff returns delay before the next try, it is doubled after every failure
+func (c *Client) backoff(tries int) time.Duration {
+	delay := c.config.BaseBackoff
+	for i := 1; i < tries && delay < c.config.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, c.config.MaxBackoff)
+}
+
+// transportError is a failure to send request or receive response
+type transportError struct {
+	err error
+}
+
+func (e *transportError) Error() string {
+	return e.err.Error()
+}
+
+func (e *transportError) Unwrap() error {
+	return e.err
+}
+
+// retryDelay reports whether failed request should be tried again and delay requested by the server
+func retryDelay(err error) (time.Duration, bool) {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		retry := apiErr.StatusCode == http.StatusTooManyRequests ||
+			(apiErr.StatusCode >= 500 && apiErr.StatusCode != http.StatusNotImplemented)
+		return apiErr.RetryAfter, retry
+	}
+	var transportErr *transportError
+	if errors.As(err, &transportErr) {
+		// requests cancelled by caller are not retried
+		return 0, !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	return 0, false
+}
+
+// newIdempotencyKey returns random key, so that retries of creation are replayed by the server
+func newIdempotencyKey() string {
+	key := make([]byte, 16)
+	_, _ = rand.Read(key)
+	return hex.EncodeToString(key)
+}
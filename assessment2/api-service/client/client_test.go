@@ -0,0 +1,231 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testServer replies with statuses in order, the last status is repeated, requests are recorded
+type testServer struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+}
+
+func (s *testServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests = append(s.requests, r)
+	status := s.statuses[min(len(s.requests), len(s.statuses))-1]
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case status == http.StatusTooManyRequests:
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"error":true,"message":"rate limit exceeded"}`))
+	case status >= 400:
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"error":true,"message":"failed","trace_id":"trace-1"}`))
+	case r.Method == http.MethodGet:
+		_, _ = w.Write([]byte(`{"error":false,"message":"","data":{"ID":7,"Title":"Title"}}`))
+	default:
+		_, _ = w.Write([]byte(`{"error":false,"message":"post added","data":7}`))
+	}
+}
+
+// newTestClient creates client of test server with short backoff
+func newTestClient(t *testing.T, statuses ...int) (*Client, *testServer) {
+	t.Helper()
+
+	handler := &testServer{statuses: statuses}
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	c, err := New(srv.URL, srv.Client(), Config{Auth: BearerToken("token"), BaseBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, handler
+}
+
+// TestClient_Retries tests retries of failed requests
+func TestClient_Retries(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name             string
+		statuses         []int
+		method           string
+		expectedRequests int
+		expectedError    error
+	}{
+		{"success", []int{200}, http.MethodGet, 1, nil},
+		{"server errors are retried", []int{503, 500, 200}, http.MethodGet, 3, nil},
+		{"rate limited requests are retried", []int{429, 200}, http.MethodGet, 2, nil},
+		{"creation is retried with idempotency key", []int{502, 200}, http.MethodPost, 2, nil},
+		{"client errors are not retried", []int{404}, http.MethodGet, 1, ErrorPostNotFound},
+		{"retries are limited", []int{503}, http.MethodGet, 4, ErrorServer},
+		{"updates are not retried", []int{503, 200}, http.MethodPut, 1, ErrorServer},
+		{"deletions are not retried", []int{429, 200}, http.MethodDelete, 1, ErrorRateLimited},
+		{"not implemented is not retried", []int{501}, http.MethodGet, 1, ErrorServer},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			c, srv := newTestClient(t, tt.statuses...)
+
+			var err error
+			switch tt.method {
+			case http.MethodGet:
+				_, err = c.Get(context.Background(), 7)
+			case http.MethodPost:
+				_, err = c.Create(context.Background(), PostInput{Title: "Title"})
+			case http.MethodPut:
+				err = c.Update(context.Background(), 7, PostInput{Title: "Title"})
+			case http.MethodDelete:
+				err = c.Delete(context.Background(), 7)
+			}
+			if !errors.Is(err, tt.expectedError) || (err != nil && tt.expectedError == nil) {
+				t.Errorf("expected %v error, but got %v", tt.expectedError, err)
+			}
+			if len(srv.requests) != tt.expectedRequests {
+				t.Fatalf("expected %d requests, but got %d", tt.expectedRequests, len(srv.requests))
+			}
+			for _, r := range srv.requests {
+				if r.Header.Get("Authorization") != "Bearer token" {
+					t.Errorf("expected bearer token, but got %q", r.Header.Get("Authorization"))
+				}
+				if r.Header.Get("Idempotency-Key") != srv.requests[0].Header.Get("Idempotency-Key") {
+					t.Errorf("expected the same idempotency key in all tries")
+				}
+			}
+			if key := srv.requests[0].Header.Get("Idempotency-Key"); (key != "") != (tt.method == http.MethodPost) {
+				t.Errorf("expected idempotency key of creation only, but got %q for %s", key, tt.method)
+			}
+		})
+	}
+}
+
+// TestClient_Errors tests decoding of error responses
+func TestClient_Errors(t *testing.T) {
+	t.Parallel()
+
+	t.Run("error envelope", func(t *testing.T) {
+		t.Parallel()
+		c, _ := newTestClient(t, http.StatusForbidden)
+
+		err := c.Delete(context.Background(), 7)
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || !errors.Is(err, ErrorForbidden) {
+			t.Fatalf("expected forbidden API error, but got %v", err)
+		}
+		if apiErr.StatusCode != http.StatusForbidden || apiErr.Message != "failed" || apiErr.TraceID != "trace-1" {
+			t.Errorf("expected status, message and trace id of response, but got %+v", apiErr)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		t.Parallel()
+		c, _ := newTestClient(t, http.StatusNotFound)
+
+		// only requests of post by id report missing post
+		if err := c.Delete(context.Background(), 7); !errors.Is(err, ErrorPostNotFound) {
+			t.Errorf("expected post not found error, but got %v", err)
+		}
+		_, err := c.List(context.Background(), ListOptions{})
+		if !errors.Is(err, ErrorNotFound) || errors.Is(err, ErrorPostNotFound) {
+			t.Errorf("expected not found error, but got %v", err)
+		}
+	})
+
+	t.Run("retry after is limited by context", func(t *testing.T) {
+		t.Parallel()
+		c, srv := newTestClient(t, http.StatusTooManyRequests)
+		c.config.MaxBackoff = time.Minute
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, err := c.List(ctx, ListOptions{})
+		if !errors.Is(err, ErrorRateLimited) || !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected rate limit and deadline errors, but got %v", err)
+		}
+		if len(srv.requests) != 1 {
+			t.Errorf("expected 1 request, but got %d", len(srv.requests))
+		}
+	})
+
+	t.Run("disabled retries", func(t *testing.T) {
+		t.Parallel()
+		c, srv := newTestClient(t, http.StatusServiceUnavailable, http.StatusOK)
+		c.config.MaxRetries = -1
+
+		if _, err := c.Get(context.Background(), 7); !errors.Is(err, ErrorServer) {
+			t.Errorf("expected server error, but got %v", err)
+		}
+		if len(srv.requests) != 1 {
+			t.Errorf("expected 1 request, but got %d", len(srv.requests))
+		}
+	})
+}
+
+// TestClient_APIVersion tests requests and responses of API v2
+func TestClient_APIVersion(t *testing.T) {
+	t.Parallel()
+
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/v2/posts/8":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":{"status":404,"message":"post not found","trace_id":"trace-2"}}`))
+		case r.Method == http.MethodGet:
+			_, _ = w.Write([]byte(`{"data":{"id":7,"title":"Title","version":2,"format":"markdown"}}`))
+		case r.Method == http.MethodPost:
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"data":{"id":7}}`))
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	if _, err := New(srv.URL, srv.Client(), Config{APIVersion: "v3"}); err == nil {
+		t.Error("expected error of unsupported api version")
+	}
+	c, err := New(srv.URL, srv.Client(), Config{APIVersion: APIVersionV2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	post, err := c.Get(ctx, 7)
+	if err != nil || post.ID != 7 || post.Title != "Title" || post.Version != 2 || post.Format != FormatMarkdown {
+		t.Errorf("expected decoded post, but got %+v, %v", post, err)
+	}
+	if id, err := c.Create(ctx, PostInput{Title: "Title"}); err != nil || id != 7 {
+		t.Errorf("expected id of created post, but got %d, %v", id, err)
+	}
+	if err := c.Update(ctx, 7, PostInput{Title: "Title"}); err != nil {
+		t.Errorf("expected update without response body, but got %v", err)
+	}
+	_, err = c.Get(ctx, 8)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || !errors.Is(err, ErrorPostNotFound) || apiErr.Message != "post not found" || apiErr.TraceID != "trace-2" {
+		t.Errorf("expected decoded v2 error, but got %v", err)
+	}
+
+	expected := []string{"GET /v2/posts/7", "POST /v2/posts", "PUT /v2/posts/7", "GET /v2/posts/8"}
+	if strings.Join(paths, ", ") != strings.Join(expected, ", ") {
+		t.Errorf("expected requests %v, but got %v", expected, paths)
+	}
+}
@@ -0,0 +1,63 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Errors matched by errors.Is against errors of the client
+var (
+	ErrorInvalidRequest  = errors.New("invalid request")
+	ErrorUnauthenticated = errors.New("authentication required")
+	ErrorForbidden       = errors.New("action is forbidden")
+	ErrorNotFound        = errors.New("resource not found")
+	ErrorPostNotFound    = errors.New("post not found")
+	ErrorConflict        = errors.New("request conflicts with current state")
+	ErrorRateLimited     = errors.New("rate limit exceeded")
+	ErrorServer          = errors.New("server error")
+)
+
+// APIError is an error response of the API
+type APIError struct {
+	StatusCode int
+	Message    string          // message of the error envelope
+	TraceID    string          // id of request trace, it helps to find failed request in logs
+	RetryAfter time.Duration   // delay requested by Retry-After header
+	Data       json.RawMessage // data of the error envelope, for example report of failed import
+
+	notFound error // error of missing resource addressed by request
+}
+
+func (e *APIError) Error() string {
+	if e.TraceID != "" {
+		return fmt.Sprintf("api responded with status %d: %s (trace %s)", e.StatusCode, e.Message, e.TraceID)
+	}
+	return fmt.Sprintf("api responded with status %d: %s", e.StatusCode, e.Message)
+}
+
+// Unwrap returns error matching status of the response
+func (e *APIError) Unwrap() error {
+	switch {
+	case e.StatusCode == http.StatusUnauthorized:
+		return ErrorUnauthenticated
+	case e.StatusCode == http.StatusForbidden:
+		return ErrorForbidden
+	case e.StatusCode == http.StatusNotFound && e.notFound != nil:
+		return e.notFound
+	case e.StatusCode == http.StatusNotFound:
+		return ErrorNotFound
+	case e.StatusCode == http.StatusConflict, e.StatusCode == http.StatusUnprocessableEntity:
+		return ErrorConflict
+	case e.StatusCode == http.StatusTooManyRequests:
+		return ErrorRateLimited
+	case e.StatusCode >= 500:
+		return ErrorServer
+	case e.StatusCode >= 400:
+		return ErrorInvalidRequest
+	default:
+		return nil
+	}
+}
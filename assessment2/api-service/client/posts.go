@@ -0,0 +1,146 @@
+package client
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Post is a post of the API, fields of all API versions are decoded as field names are matched case-insensitively
+type Post struct {
+	ID      int    `json:"ID"`
+	Title   string `json:"Title"`
+	Content string `json:"Content"`
+	Author  string `json:"Author"`
+	Owner   string `json:"Owner"`   // id of user who created the post
+	Version int    `json:"Version"` // incremented by every change of the post
+	Format  string `json:"Format"`  // format of content, FormatPlain or FormatMarkdown
+}
+
+// Formats of post content
+const (
+	FormatPlain    = "plain"
+	FormatMarkdown = "markdown"
+)
+
+// PostInput is data of created or updated post, posts are created as plain text
+// and keep their format on update when format is empty
+type PostInput struct {
+	Title   string `json:"title"`
+	Content string `json:"content"`
+	Author  string `json:"author"`
+	Format  string `json:"format,omitempty"`
+}
+
+// ListOptions filters and paginates list of posts, zero values are replaced by defaults of the API
+type ListOptions struct {
+	Title string // posts with titles containing the text, case insensitive
+	Page  int    // page number starting from 1
+	Limit int    // posts per page
+}
+
+func (o ListOptions) query() url.Values {
+	query := url.Values{}
+	if o.Title != "" {
+		query.Set("title", o.Title)
+	}
+	if o.Page > 0 {
+		query.Set("page", strconv.Itoa(o.Page))
+	}
+	if o.Limit > 0 {
+		query.Set("limit", strconv.Itoa(o.Limit))
+	}
+	return query
+}
+
+// List returns one page of posts ordered by id
+func (c *Client) List(ctx context.Context, options ListOptions) ([]Post, error) {
+	var posts []Post
+	err := c.do(ctx, request{method: http.MethodGet, path: c.postsPath(), query: options.query()}, &posts)
+	if err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
+
+// All iterates over posts of all pages starting from page of options, pages of DefaultPageSize
+// posts are fetched when limit is not set, iteration stops after the first error
+func (c *Client) All(ctx context.Context, options ListOptions) iter.Seq2[Post, error] {
+	return func(yield func(Post, error) bool) {
+		options.Page = max(options.Page, 1)
+		if options.Limit <= 0 {
+			options.Limit = DefaultPageSize
+		}
+		for {
+			posts, err := c.List(ctx, options)
+			if err != nil {
+				yield(Post{}, err)
+				return
+			}
+			for _, post := range posts {
+				if !yield(post, nil) {
+					return
+				}
+			}
+			// a short page is the last one
+			if len(posts) < options.Limit {
+				return
+			}
+			options.Page++
+		}
+	}
+}
+
+// Get returns post by id
+func (c *Client) Get(ctx context.Context, id int) (*Post, error) {
+	var post Post
+	err := c.do(ctx, c.postRequest(http.MethodGet, id, nil), &post)
+	if err != nil {
+		return nil, err
+	}
+	return &post, nil
+}
+
+// Create adds a new post and returns its id, the request is sent with idempotency key,
+// so that retries do not create duplicates
+func (c *Client) Create(ctx context.Context, input PostInput) (int, error) {
+	req := request{method: http.MethodPost, path: c.postsPath(), body: input, idempotencyKey: newIdempotencyKey()}
+	// API v2 responds with object of created post id
+	if c.config.APIVersion == APIVersionV2 {
+		var created struct {
+			ID int `json:"id"`
+		}
+		if err := c.do(ctx, req, &created); err != nil {
+			return 0, err
+		}
+		return created.ID, nil
+	}
+
+	var id int
+	if err := c.do(ctx, req, &id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// Update replaces data of post
+func (c *Client) Update(ctx context.Context, id int, input PostInput) error {
+	return c.do(ctx, c.postRequest(http.MethodPut, id, input), nil)
+}
+
+// Delete deletes post
+func (c *Client) Delete(ctx context.Context, id int) error {
+	return c.do(ctx, c.postRequest(http.MethodDelete, id, nil), nil)
+}
+
+// postsPath returns path of posts resource of configured API version
+func (c *Client) postsPath() string {
+	return "/" + c.config.APIVersion + "/posts"
+}
+
+// postRequest creates request of post by id, missing post is reported as ErrorPostNotFound
+func (c *Client) postRequest(method string, id int, body any) request {
+	return request{method: method, path: c.postsPath() + "/" + strconv.Itoa(id), body: body, notFound: ErrorPostNotFound}
+}
@@ -0,0 +1,92 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// transferPath is path of posts transfer routes, they are served by API v1 only
+const transferPath = "/v1/posts"
+
+// Media types of imported data
+const (
+	ContentTypeNDJSON = "application/x-ndjson"
+	ContentTypeJSON   = "application/json"
+)
+
+// Import conflict modes define handling of imported posts whose id already exists
+const (
+	ImportModeUpsert = "upsert"
+	ImportModeSkip   = "skip"
+	ImportModeFail   = "fail"
+)
+
+// ImportOptions defines import rules, zero values are replaced by defaults of the API
+type ImportOptions struct {
+	Mode   string // one of import modes, fail by default
+	Atomic bool   // either all posts are imported or none of them
+}
+
+// ImportError is a failed import of a post, line is a line of NDJSON input or position in list of posts
+type ImportError struct {
+	Line  int    `json:"line"`
+	ID    int    `json:"id,omitempty"`
+	Error string `json:"error"`
+}
+
+// ImportReport is a result of posts import
+type ImportReport struct {
+	Created   int           `json:"created"`
+	Updated   int           `json:"updated"`
+	Skipped   int           `json:"skipped"`
+	Failed    int           `json:"failed"`
+	Committed bool          `json:"committed"`
+	Errors    []ImportError `json:"errors,omitempty"`
+}
+
+// Export writes all posts to w as NDJSON records in format of store seed data, export is streamed
+// and it is not retried
+func (c *Client) Export(ctx context.Context, w io.Writer) error {
+	req := request{method: http.MethodGet, path: transferPath + "/export", query: url.Values{"format": {"ndjson"}}}
+	resp, err := c.open(ctx, req, nil, "", ContentTypeNDJSON)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+		return newAPIError(resp, data)
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// Import imports posts from data of content type, NDJSON records or JSON list of posts or seed data,
+// report is returned together with error when atomic import failed
+func (c *Client) Import(ctx context.Context, data []byte, contentType string, options ImportOptions) (*ImportReport, error) {
+	query := url.Values{}
+	if options.Mode != "" {
+		query.Set("mode", options.Mode)
+	}
+	if options.Atomic {
+		query.Set("atomic", strconv.FormatBool(options.Atomic))
+	}
+
+	var report ImportReport
+	err := c.do(ctx, request{method: http.MethodPost, path: transferPath + "/import", query: query, rawBody: data, contentType: contentType}, &report)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && len(apiErr.Data) > 0 {
+		if json.Unmarshal(apiErr.Data, &report) == nil {
+			return &report, err
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"api-service/internal/health"
+	"api-service/internal/logging"
+	"api-service/internal/server"
+	"api-service/internal/store"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"reflect"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// Build information, provided with linker flags
+var (
+	version   = "dev"
+	commit    = ""
+	buildTime = ""
+)
+
+// startTime is used to report service uptime
+var startTime = time.Now()
+
+// secretName matches names of configuration values which should not be exposed
+var secretName = regexp.MustCompile(`(?i)(token|secret|password|key|headers)`)
+
+// redacted replaces values of secret configuration entries
+const redacted = "[REDACTED]"
+
+// ErrorNotSupported is returned when store does not support admin operation
+var ErrorNotSupported = errors.New("operation is not supported by the store")
+
+// JsonBuildInfo represent build and runtime information of the service
+type JsonBuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	BuildTime string `json:"build_time,omitempty"`
+	GoVersion string `json:"go_version"`
+	Module    string `json:"module,omitempty"`
+	Uptime    string `json:"uptime"`
+	Goroutine int    `json:"goroutines"`
+	CPUs      int    `json:"cpus"`
+}
+
+// AdminBuildHandler is an endpoint handler for build and runtime information
+func (app *App) AdminBuildHandler(w http.ResponseWriter, r *http.Request) {
+	info := JsonBuildInfo{
+		Version:   version,
+		Commit:    commit,
+		BuildTime: buildTime,
+		GoVersion: runtime.Version(),
+		Uptime:    time.Since(startTime).Round(time.Second).String(),
+		Goroutine: runtime.NumGoroutine(),
+		CPUs:      runtime.NumCPU(),
+	}
+	if buildInfo, ok := debug.ReadBuildInfo(); ok {
+		info.Module = buildInfo.Main.Path
+		for _, setting := range buildInfo.Settings {
+			if setting.Key == "vcs.revision" && info.Commit == "" {
+				info.Commit = setting.Value
+			}
+		}
+	}
+
+	response := server.JsonResponse{
+		Error:   false,
+		Message: "",
+		Data:    info,
+	}
+	_ = app.WebServer.WriteJSON(w, http.StatusOK, response)
+}
+
+// AdminConfigHandler is an endpoint handler for effective service configuration, secrets are redacted
+func (app *App) AdminConfigHandler(w http.ResponseWriter, r *http.Request) {
+	config := make(map[string]any)
+	if app.Config != nil {
+		value := reflect.ValueOf(*app.Config)
+		for i := 0; i < value.NumField(); i++ {
+			name := value.Type().Field(i).Name
+			config[name] = redact(name, value.Field(i).Interface())
+		}
+	}
+
+	// OpenTelemetry SDK is configured directly from environment
+	for _, env := range os.Environ() {
+		name, val, _ := strings.Cut(env, "=")
+		if strings.HasPrefix(name, "OTEL_") {
+			config[name] = redact(name, val)
+		}
+	}
+
+	response := server.JsonResponse{
+		Error:   false,
+		Message: "",
+		Data:    config,
+	}
+	_ = app.WebServer.WriteJSON(w, http.StatusOK, response)
+}
+
+// AdminStoreStatsHandler is an endpoint handler for store statistics
+func (app *App) AdminStoreStatsHandler(w http.ResponseWriter, r *http.Request) {
+	provider, ok := app.AdminStore.(store.StatsProvider)
+	if !ok {
+		app.WebServer.ErrorJSON(w, r, ErrorNotSupported, http.StatusNotImplemented)
+		return
+	}
+	stats, err := provider.Stats(r.Context())
+	if err != nil {
+		app.WebServer.ErrorJSON(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	response := server.JsonResponse{
+		Error:   false,
+		Message: "",
+		Data:    stats,
+	}
+	_ = app.WebServer.WriteJSON(w, http.StatusOK, response)
+}
+
+// AdminStoreSnapshotHandler is an endpoint handler for saving store snapshot
+func (app *App) AdminStoreSnapshotHandler(w http.ResponseWriter, r *http.Request) {
+	snapshotter, ok := app.AdminStore.(store.Snapshotter)
+	if !ok || app.Config == nil || app.Config.SnapshotFile == "" {
+		app.WebServer.ErrorJSON(w, r, ErrorNotSupported, http.StatusNotImplemented)
+		return
+	}
+	err := snapshotter.Snapshot(r.Context(), app.Config.SnapshotFile)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("failed to save snapshot", slog.Any("error", err))
+		app.WebServer.ErrorJSON(w, r, err, http.StatusInternalServerError)
+		return
+	}
+	logging.FromContext(r.Context()).Info("snapshot saved", slog.String("file", app.Config.SnapshotFile))
+
+	response := server.JsonResponse{
+		Error:   false,
+		Message: "snapshot saved",
+		Data:    app.Config.SnapshotFile,
+	}
+	_ = app.WebServer.WriteJSON(w, http.StatusOK, response)
+}
+
+// AdminStoreReloadHandler is an endpoint handler for reloading seed data,
+// ready service is reported as not ready while data is loaded; reload replaces posts without change events,
+// so clients of change stream and webhooks receivers should reload posts, posts dropped by reload are published as deleted
+func (app *App) AdminStoreReloadHandler(w http.ResponseWriter, r *http.Request) {
+	loader, ok := app.AdminStore.(store.Loader)
+	if !ok || app.Config == nil || app.Config.StoreInit == "" {
+		app.WebServer.ErrorJSON(w, r, ErrorNotSupported, http.StatusNotImplemented)
+		return
+	}
+
+	// draining service is not made ready again when reload is finished
+	if app.Health != nil && app.Health.CompareAndSetState(health.StateReady, health.StateStarting) {
+		defer app.Health.CompareAndSetState(health.StateStarting, health.StateReady)
+	}
+	err := loader.Load(app.Config.StoreInit)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("failed to reload seed data", slog.Any("error", err))
+		app.WebServer.ErrorJSON(w, r, err, http.StatusInternalServerError)
+		return
+	}
+	// cached data is stale after store is modified directly
+	if app.Cache != nil {
+		app.Cache.Purge()
+	}
+	logging.FromContext(r.Context()).Info("seed data reloaded", slog.String("file", app.Config.StoreInit))
+
+	response := server.JsonResponse{
+		Error:   false,
+		Message: "seed data reloaded",
+		Data:    nil,
+	}
+	_ = app.WebServer.WriteJSON(w, http.StatusOK, response)
+}
+
+// AdminStoreCompactHandler is an endpoint handler for store compaction
+func (app *App) AdminStoreCompactHandler(w http.ResponseWriter, r *http.Request) {
+	compactor, ok := app.AdminStore.(store.Compactor)
+	if !ok {
+		app.WebServer.ErrorJSON(w, r, ErrorNotSupported, http.StatusNotImplemented)
+		return
+	}
+	err := compactor.Compact(r.Context())
+	if err != nil {
+		app.WebServer.ErrorJSON(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	response := server.JsonResponse{
+		Error:   false,
+		Message: "store compacted",
+		Data:    nil,
+	}
+	_ = app.WebServer.WriteJSON(w, http.StatusOK, response)
+}
+
+// redact hides values of secret configuration entries
+func redact(name string, value any) any {
+	if secretName.MatchString(name) && !reflect.ValueOf(value).IsZero() {
+		return redacted
+	}
+	return value
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"api-service/internal/health"
+	"api-service/internal/store"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestAdminApp(t *testing.T) *App {
+	t.Helper()
+
+	dir := t.TempDir()
+	seedFile := filepath.Join(dir, "seed.json")
+	seed := `{"posts": [{"id": 1, "title": "Title 1"}, {"id": 2, "title": "Title 2"}]}`
+	if err := os.WriteFile(seedFile, []byte(seed), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	memoryStore, err := store.NewMemoryPostStore(seedFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := newTestAuthApp(t, newHandlersFixture(t))
+	app.Config = &Config{
+		StoreInit:    seedFile,
+		SnapshotFile: filepath.Join(dir, "snapshot.json"),
+		UsersFile:    "users.json",
+	}
+	app.AdminStore = memoryStore
+	return app
+}
+
+func sendAdmin(app *App, method string, path string, token string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rr := httptest.NewRecorder()
+	app.adminRoutes().ServeHTTP(rr, req)
+	return rr
+}
+
+// TestAdmin_Access tests that admin server is available to administrators only
+func TestAdmin_Access(t *testing.T) {
+	t.Parallel()
+	app := newTestAdminApp(t)
+
+	tests := []struct {
+		token    string
+		expected int
+	}{
+		{"", http.StatusUnauthorized},
+		{"editor-token", http.StatusForbidden},
+		{"admin-token", http.StatusOK},
+	}
+	for _, tt := range tests {
+		for _, path := range []string{"/admin/build", "/debug/pprof/"} {
+			if rr := sendAdmin(app, "GET", path, tt.token); rr.Code != tt.expected {
+				t.Errorf("%s with token %q: expected %d, but got %d", path, tt.token, tt.expected, rr.Code)
+			}
+		}
+	}
+}
+
+// TestAdmin_StoreOperations tests store statistics, snapshot and reload
+func TestAdmin_StoreOperations(t *testing.T) {
+	t.Parallel()
+	app := newTestAdminApp(t)
+
+	rr := sendAdmin(app, "GET", "/admin/store/stats", "admin-token")
+	expectedBody := "{\"error\":false,\"message\":\"\",\"data\":{\"posts\":2,\"autoincrement\":2}}"
+	if rr.Body.String() != expectedBody {
+		t.Errorf("incorrect stats response body, got %s", rr.Body.String())
+	}
+
+	// snapshot is saved in seed data format
+	rr = sendAdmin(app, "POST", "/admin/store/snapshot", "admin-token")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected http.StatusOK, but got %d: %s", rr.Code, rr.Body.String())
+	}
+	var data store.FileData
+	bytes, _ := os.ReadFile(app.Config.SnapshotFile)
+	if err := json.Unmarshal(bytes, &data); err != nil || len(data.Posts) != 2 {
+		t.Errorf("expected snapshot with 2 posts, got %s", string(bytes))
+	}
+
+	// reload restores seed data
+	_ = app.AdminStore.Delete(context.Background(), 1)
+	rr = sendAdmin(app, "POST", "/admin/store/reload", "admin-token")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected http.StatusOK, but got %d: %s", rr.Code, rr.Body.String())
+	}
+	if _, err := app.AdminStore.GetOne(context.Background(), 1); err != nil {
+		t.Errorf("expected reloaded post, got %v", err)
+	}
+
+	rr = sendAdmin(app, "POST", "/admin/store/compact", "admin-token")
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected http.StatusOK, but got %d", rr.Code)
+	}
+}
+
+// TestAdmin_StoreReloadHealth tests that reload does not make draining service ready
+func TestAdmin_StoreReloadHealth(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		state    health.State
+		expected health.State
+	}{
+		{"ready", health.StateReady, health.StateReady},
+		{"draining", health.StateDraining, health.StateDraining},
+		{"starting", health.StateStarting, health.StateStarting},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			app := newTestAdminApp(t)
+			app.Health = health.NewRegistry(0, 0)
+			app.Health.SetState(tt.state)
+
+			rr := sendAdmin(app, "POST", "/admin/store/reload", "admin-token")
+			if rr.Code != http.StatusOK {
+				t.Fatalf("expected http.StatusOK, but got %d: %s", rr.Code, rr.Body.String())
+			}
+			if state := app.Health.State(); state != tt.expected {
+				t.Errorf("expected state %s, but got %s", tt.expected, state)
+			}
+		})
+	}
+}
+
+// TestAdmin_Config tests redaction of secret configuration values
+func TestAdmin_Config(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "api-key=secret")
+	app := newTestAdminApp(t)
+
+	rr := sendAdmin(app, "GET", "/admin/config", "admin-token")
+	body := rr.Body.String()
+	if strings.Contains(body, "api-key=secret") || !strings.Contains(body, "\"OTEL_EXPORTER_OTLP_HEADERS\":\"[REDACTED]\"") {
+		t.Errorf("expected redacted OTLP headers, got %s", body)
+	}
+	if !strings.Contains(body, "\"UsersFile\":\"users.json\"") {
+		t.Errorf("expected configuration values, got %s", body)
+	}
+}
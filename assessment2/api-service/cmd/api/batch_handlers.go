@@ -0,0 +1,199 @@
+package main
+
+import (
+	"api-service/internal/auth"
+	"api-service/internal/domain"
+	"api-service/internal/logging"
+	"api-service/internal/server"
+	"api-service/internal/store"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// batchMaxOperations limits number of operations in a batch
+const batchMaxOperations = 100
+
+// Batch operation types
+const (
+	BatchOpCreate = "create"
+	BatchOpUpdate = "update"
+	BatchOpDelete = "delete"
+)
+
+var (
+	ErrorBatchEmpty     = errors.New("batch has no operations")
+	ErrorBatchTooLarge  = fmt.Errorf("batch exceeds %d operations", batchMaxOperations)
+	ErrorBatchOperation = errors.New("operation must be one of create, update or delete")
+	ErrorBatchPost      = errors.New("post is required")
+	ErrorBatchID        = errors.New("post id is required")
+	ErrorBatchFailed    = errors.New("batch failed, no changes were applied")
+	ErrorBatchAborted   = errors.New("operation was not applied because batch failed")
+)
+
+// JsonBatchPayload represent batch of post operations
+type JsonBatchPayload struct {
+	Operations []JsonBatchOperation `json:"operations"`
+}
+
+// JsonBatchOperation represent create, update or delete post operation,
+// id is required by update and delete, post is required by create and update
+type JsonBatchOperation struct {
+	Op   string           `json:"op"`
+	ID   int              `json:"id,omitempty"`
+	Post *JsonPostPayload `json:"post,omitempty"`
+}
+
+// JsonBatchResult represent result of batch operation, status has meaning of HTTP status code
+type JsonBatchResult struct {
+	Status int    `json:"status"`
+	ID     int    `json:"id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// JsonBatchReport represent result of batch, atomic batches are either committed entirely or not at all
+type JsonBatchReport struct {
+	Atomic    bool              `json:"atomic"`
+	Committed bool              `json:"committed"`
+	Results   []JsonBatchResult `json:"results"`
+}
+
+// PostsBatchHandler is an endpoint handler for batch of post operations, operations are executed
+// atomically when store supports transactions and one by one otherwise
+func (app *App) PostsBatchHandler(w http.ResponseWriter, r *http.Request) {
+	// anonymous users could not change posts
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		app.WebServer.ErrorJSON(w, r, auth.ErrorUnauthenticated, http.StatusUnauthorized)
+		return
+	}
+
+	// read json input
+	var jsonPayload JsonBatchPayload
+	err := app.WebServer.Read(w, r, &jsonPayload)
+	if err != nil {
+		app.WebServer.Error(w, r, err, server.ReadErrorStatus(err))
+		return
+	}
+	if len(jsonPayload.Operations) == 0 {
+		app.WebServer.Error(w, r, ErrorBatchEmpty, http.StatusBadRequest)
+		return
+	}
+	if len(jsonPayload.Operations) > batchMaxOperations {
+		app.WebServer.Error(w, r, ErrorBatchTooLarge, http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	// create context with timeout, it is shared by all operations
+	ctx, cancel := context.WithTimeout(r.Context(), app.WebServer.Timeout*time.Second)
+	defer cancel()
+
+	report := JsonBatchReport{
+		Atomic:  app.PostStore.Capabilities().Transactions,
+		Results: make([]JsonBatchResult, len(jsonPayload.Operations)),
+	}
+	execute := func(st store.PostStore) error {
+		for i, op := range jsonPayload.Operations {
+			report.Results[i] = app.batchOperation(ctx, st, principal, op)
+			if report.Atomic && report.Results[i].Error != "" {
+				// operations of failed atomic batch are rolled back
+				for j := range report.Results {
+					if j != i {
+						report.Results[j] = JsonBatchResult{Status: http.StatusFailedDependency, Error: ErrorBatchAborted.Error()}
+					}
+				}
+				return ErrorBatchFailed
+			}
+		}
+		return nil
+	}
+
+	if report.Atomic {
+		err = app.PostStore.WithTx(ctx, execute)
+	} else {
+		err = execute(app.PostStore)
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Warn("failed to execute batch", slog.Any("error", err))
+		status := http.StatusUnprocessableEntity
+		if !errors.Is(err, ErrorBatchFailed) {
+			status = http.StatusInternalServerError
+		}
+		response := server.JsonResponse{
+			Error:   true,
+			Message: err.Error(),
+			Data:    report,
+			TraceID: w.Header().Get(server.TraceIDHeader),
+		}
+		_ = app.WebServer.Write(w, r, status, response)
+		return
+	}
+	report.Committed = true
+
+	// return successful response with operation results
+	response := server.JsonResponse{
+		Error:   false,
+		Message: "batch executed",
+		Data:    report,
+	}
+	_ = app.WebServer.Write(w, r, http.StatusOK, response)
+}
+
+// batchOperation authorizes and executes single operation of a batch
+func (app *App) batchOperation(ctx context.Context, st store.PostStore, principal *auth.Principal, op JsonBatchOperation) JsonBatchResult {
+	fail := func(err error) JsonBatchResult {
+		return JsonBatchResult{Status: authorizationStatus(err), ID: op.ID, Error: err.Error()}
+	}
+
+	var post domain.Post
+	switch op.Op {
+	case BatchOpCreate, BatchOpUpdate:
+		if op.Post == nil {
+			return fail(ErrorBatchPost)
+		}
+		post = domain.Post{
+			Title:   op.Post.Title,
+			Content: op.Post.Content,
+			Author:  op.Post.Author,
+			Format:  op.Post.Format,
+		}
+	case BatchOpDelete:
+	default:
+		return fail(ErrorBatchOperation)
+	}
+	if op.Op != BatchOpCreate && op.ID == 0 {
+		return fail(ErrorBatchID)
+	}
+
+	var err error
+	switch op.Op {
+	case BatchOpCreate:
+		// post is owned by its creator
+		if err = app.Policy.Authorize(principal, auth.PermissionPostsCreate, ""); err != nil {
+			return fail(err)
+		}
+		post.Owner = principal.ID
+		op.ID, err = st.Insert(ctx, post)
+	case BatchOpUpdate:
+		if err = app.authorizePostAccess(ctx, st, principal, auth.PermissionPostsUpdate, op.ID); err != nil {
+			return fail(err)
+		}
+		err = st.Update(ctx, op.ID, post)
+	case BatchOpDelete:
+		if err = app.authorizePostAccess(ctx, st, principal, auth.PermissionPostsDelete, op.ID); err != nil {
+			return fail(err)
+		}
+		err = st.Delete(ctx, op.ID)
+	}
+	if err != nil {
+		return fail(err)
+	}
+	// created posts are reported as by post routes of v2
+	if op.Op == BatchOpCreate {
+		return JsonBatchResult{Status: http.StatusCreated, ID: op.ID}
+	}
+	return JsonBatchResult{Status: http.StatusOK, ID: op.ID}
+}
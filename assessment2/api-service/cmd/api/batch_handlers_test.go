@@ -0,0 +1,110 @@
+package main
+
+import (
+	"api-service/internal/domain"
+	"api-service/internal/store"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// bestEffortStore is a store without transactions support
+type bestEffortStore struct {
+	*store.MemoryPostStore
+}
+
+func (s bestEffortStore) Capabilities() store.Capabilities {
+	return store.Capabilities{}
+}
+
+func (s bestEffortStore) WithTx(ctx context.Context, fn func(tx store.PostStore) error) error {
+	return fn(s)
+}
+
+// TestBatch_PostsBatch tests batch of post operations with and without transactions
+func TestBatch_PostsBatch(t *testing.T) {
+	t.Parallel()
+
+	valid := `{"operations": [
+		{"op": "create", "post": {"title": "New post"}},
+		{"op": "update", "id": 1, "post": {"title": "Updated post"}},
+		{"op": "delete", "id": 2}
+	]}`
+	// update of foreign post is forbidden for author
+	invalid := `{"operations": [
+		{"op": "create", "post": {"title": "New post"}},
+		{"op": "update", "id": 3, "post": {"title": "Updated post"}},
+		{"op": "delete", "id": 42},
+		{"op": "archive", "id": 1}
+	]}`
+
+	tests := []struct {
+		name          string
+		transactions  bool
+		body          string
+		expected      int
+		expectedBody  string
+		expectedPosts int
+	}{
+		{"atomic success", true, valid, http.StatusOK,
+			`{"error":false,"message":"batch executed","data":{"atomic":true,"committed":true,"results":[{"status":201,"id":4},{"status":200,"id":1},{"status":200,"id":2}]}}`, 3},
+		{"atomic failure", true, invalid, http.StatusUnprocessableEntity,
+			`{"error":true,"message":"batch failed, no changes were applied","data":{"atomic":true,"committed":false,"results":[{"status":424,"error":"operation was not applied because batch failed"},{"status":403,"id":3,"error":"access forbidden"},{"status":424,"error":"operation was not applied because batch failed"},{"status":424,"error":"operation was not applied because batch failed"}]}}`, 3},
+		{"best effort", false, invalid, http.StatusOK,
+			`{"error":false,"message":"batch executed","data":{"atomic":false,"committed":true,"results":[{"status":201,"id":4},{"status":403,"id":3,"error":"access forbidden"},{"status":404,"id":42,"error":"post not found"},{"status":400,"id":1,"error":"operation must be one of create, update or delete"}]}}`, 4},
+		{"empty batch", true, `{"operations": []}`, http.StatusBadRequest,
+			`{"error":true,"message":"batch has no operations"}`, 3},
+		{"too large batch", true, `{"operations": [` + strings.Repeat(`{"op": "delete", "id": 1},`, batchMaxOperations) + `{"op": "delete", "id": 1}]}`, http.StatusRequestEntityTooLarge,
+			`{"error":true,"message":"batch exceeds 100 operations"}`, 3},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			app, memoryStore := newTestTransferApp(t, 3)
+			// the third post is owned by another author
+			_, _ = memoryStore.Put(context.Background(), domain.Post{ID: 3, Title: "Title 3", Owner: "author-2"})
+			if !tt.transactions {
+				app.PostStore = bestEffortStore{memoryStore}
+			}
+
+			req, _ := http.NewRequest("POST", "/v1/posts/batch", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer author-token")
+			rr := httptest.NewRecorder()
+			app.routes().ServeHTTP(rr, req)
+
+			if rr.Code != tt.expected {
+				t.Errorf("expected status %d, but got %d", tt.expected, rr.Code)
+			}
+			if rr.Body.String() != tt.expectedBody {
+				t.Errorf("incorrect response body, got %s", rr.Body.String())
+			}
+			stats, _ := memoryStore.Stats(context.Background())
+			if stats.Posts != tt.expectedPosts {
+				t.Errorf("expected %d posts, but got %d", tt.expectedPosts, stats.Posts)
+			}
+		})
+	}
+}
+
+// TestBatch_Anonymous tests that anonymous users could not execute batches
+func TestBatch_Anonymous(t *testing.T) {
+	t.Parallel()
+	app, _ := newTestTransferApp(t, 1)
+
+	req, _ := http.NewRequest("POST", "/v1/posts/batch", strings.NewReader(`{"operations": [{"op": "delete", "id": 1}]}`))
+	rr := httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, req)
+
+	var response struct {
+		Error bool `json:"error"`
+	}
+	_ = json.Unmarshal(rr.Body.Bytes(), &response)
+	if rr.Code != http.StatusUnauthorized || !response.Error {
+		t.Errorf("expected http.StatusUnauthorized, but got %d", rr.Code)
+	}
+}
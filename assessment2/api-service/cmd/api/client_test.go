@@ -0,0 +1,158 @@
+package main
+
+import (
+	"api-service/client"
+	"api-service/internal/domain"
+	"bytes"
+	"context"
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// newTestClient creates client of the API version served with test server
+func newTestClient(t *testing.T, app *App, token string, version string) *client.Client {
+	t.Helper()
+
+	srv := httptest.NewServer(app.routes())
+	t.Cleanup(srv.Close)
+	config := client.Config{MaxRetries: -1, APIVersion: version}
+	if token != "" {
+		config.Auth = client.BearerToken(token)
+	}
+	c, err := client.New(srv.URL, srv.Client(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// TestClient_Posts tests typed client against routes of both API versions
+func TestClient_Posts(t *testing.T) {
+	t.Parallel()
+
+	for _, version := range []string{client.APIVersionV1, client.APIVersionV2} {
+		version := version
+		t.Run(version, func(t *testing.T) {
+			t.Parallel()
+			testClientPosts(t, version)
+		})
+	}
+}
+
+func testClientPosts(t *testing.T, version string) {
+	app, _ := newTestTransferApp(t, 12)
+	ctx := context.Background()
+	author := newTestClient(t, app, "author-token", version)
+
+	// create, read, update and delete post
+	id, err := author.Create(ctx, client.PostInput{Title: "New", Content: "Content", Author: "Author"})
+	if err != nil || id != 13 {
+		t.Fatalf("expected post 13 to be created, but got %d, %v", id, err)
+	}
+	if err := author.Update(ctx, id, client.PostInput{Title: "Updated", Format: client.FormatMarkdown}); err != nil {
+		t.Fatal(err)
+	}
+	post, err := author.Get(ctx, id)
+	expected := &client.Post{ID: 13, Title: "Updated", Owner: "author-1", Version: 2, Format: client.FormatMarkdown}
+	if err != nil || !reflect.DeepEqual(post, expected) {
+		t.Errorf("expected %+v, but got %+v, %v", expected, post, err)
+	}
+	if err := author.Delete(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := author.Get(ctx, id); !errors.Is(err, client.ErrorPostNotFound) {
+		t.Errorf("expected post not found error, but got %v", err)
+	}
+
+	// list page and iterate over all pages
+	posts, err := author.List(ctx, client.ListOptions{Page: 2, Limit: 5})
+	if err != nil || len(posts) != 5 || posts[0].Title != "Title 6" {
+		t.Errorf("expected the second page of 5 posts, but got %+v, %v", posts, err)
+	}
+	var ids []int
+	for post, err := range author.All(ctx, client.ListOptions{Limit: 5}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, post.ID)
+	}
+	if len(ids) != 12 || ids[0] != 1 || ids[11] != 12 {
+		t.Errorf("expected posts 1 to 12, but got %v", ids)
+	}
+}
+
+// TestClient_Errors tests typed errors of error responses of the API
+func TestClient_Errors(t *testing.T) {
+	t.Parallel()
+	app, memoryStore := newTestTransferApp(t, 0)
+	ctx := context.Background()
+	_, _ = memoryStore.Insert(ctx, domain.Post{Title: "Title", Owner: "editor-1"})
+
+	tests := []struct {
+		name          string
+		token         string
+		call          func(c *client.Client) error
+		expectedError error
+	}{
+		{"anonymous creation", "", func(c *client.Client) error {
+			_, err := c.Create(ctx, client.PostInput{Title: "Title"})
+			return err
+		}, client.ErrorUnauthenticated},
+		{"invalid token", "unknown-token", func(c *client.Client) error {
+			_, err := c.List(ctx, client.ListOptions{})
+			return err
+		}, client.ErrorUnauthenticated},
+		{"deletion of other user post", "author-token", func(c *client.Client) error {
+			return c.Delete(ctx, 1)
+		}, client.ErrorForbidden},
+		{"missing post", "author-token", func(c *client.Client) error {
+			return c.Update(ctx, 100, client.PostInput{Title: "Title"})
+		}, client.ErrorPostNotFound},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := tt.call(newTestClient(t, app, tt.token, ""))
+			var apiErr *client.APIError
+			if !errors.Is(err, tt.expectedError) || !errors.As(err, &apiErr) || apiErr.Message == "" {
+				t.Errorf("expected %v error with message, but got %v", tt.expectedError, err)
+			}
+		})
+	}
+}
+
+// TestClient_Transfer tests import and export of posts with typed client
+func TestClient_Transfer(t *testing.T) {
+	t.Parallel()
+	app, _ := newTestTransferApp(t, 2)
+	ctx := context.Background()
+	admin := newTestClient(t, app, "admin-token", "")
+
+	// failed atomic import is reported together with error
+	data := []byte("{\"id\":1,\"title\":\"Title\"}\n{\"title\":\"Imported\"}\n")
+	report, err := admin.Import(ctx, data, client.ContentTypeNDJSON, client.ImportOptions{Atomic: true})
+	if !errors.Is(err, client.ErrorConflict) || report == nil || report.Failed != 1 || report.Committed {
+		t.Errorf("expected failed import report, but got %+v, %v", report, err)
+	}
+	report, err = admin.Import(ctx, data, client.ContentTypeNDJSON, client.ImportOptions{Mode: client.ImportModeSkip})
+	expectedReport := &client.ImportReport{Created: 1, Skipped: 1, Committed: true}
+	if err != nil || !reflect.DeepEqual(report, expectedReport) {
+		t.Errorf("expected %+v, but got %+v, %v", expectedReport, report, err)
+	}
+
+	var out bytes.Buffer
+	if err := admin.Export(ctx, &out); err != nil {
+		t.Fatal(err)
+	}
+	expectedExport := `{"id":1,"title":"Title 1","content":"","author":"","owner":"author-1"}
+{"id":2,"title":"Title 2","content":"","author":"","owner":"author-1"}
+{"id":3,"title":"Imported","content":"","author":"","owner":"admin-1"}
+`
+	if out.String() != expectedExport {
+		t.Errorf("expected export %q, but got %q", expectedExport, out.String())
+	}
+}
@@ -0,0 +1,376 @@
+package main
+
+import (
+	"api-service/internal/auth"
+	"api-service/internal/domain"
+	"api-service/internal/gql"
+	"api-service/internal/logging"
+	"api-service/internal/store"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/graphql-go/graphql"
+)
+
+// graphqlScanLimit is a page size of posts scanned by author loader
+const graphqlScanLimit = 100
+
+var (
+	ErrorGraphQLContentType = errors.New("content type must be application/json or application/graphql")
+	ErrorGraphQLVariables   = errors.New("variables and extensions must be JSON objects")
+)
+
+type graphqlLoadersKey struct{}
+
+// graphqlLoaders batch and cache post lookups of a single GraphQL request
+type graphqlLoaders struct {
+	posts         *gql.Loader[int, *domain.Post]
+	postsByAuthor *gql.Loader[string, []domain.Post]
+}
+
+// GraphQLHandler is an endpoint handler for GraphQL queries and mutations, mutations are accepted with POST only
+func (app *App) GraphQLHandler(w http.ResponseWriter, r *http.Request) {
+	// read request from URL query or body
+	var req gql.Request
+	var err error
+	if r.Method == http.MethodGet {
+		err = readGraphQLQuery(r, &req)
+	} else {
+		err = app.readGraphQLBody(w, r, &req)
+	}
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, ErrorGraphQLContentType) {
+			status = http.StatusUnsupportedMediaType
+		}
+		app.WebServer.ErrorJSON(w, r, err, status)
+		return
+	}
+
+	// every request gets its own loaders, so that cached posts are never shared between principals
+	ctx, cancel := context.WithTimeout(r.Context(), app.WebServer.Timeout*time.Second)
+	defer cancel()
+	ctx = context.WithValue(ctx, graphqlLoadersKey{}, app.newGraphQLLoaders(ctx))
+	result := app.GraphQL.Execute(ctx, req, r.Method == http.MethodGet)
+
+	// errors of resolvers are reported with 200 status as GraphQL clients expect
+	status := http.StatusOK
+	if gql.ResultCode(result) == gql.CodeMethodNotAllowed {
+		w.Header().Set("Allow", http.MethodPost)
+		status = http.StatusMethodNotAllowed
+	}
+	for _, resultErr := range result.Errors {
+		logging.FromContext(r.Context()).Debug("graphql error", slog.String("error", resultErr.Message))
+	}
+	_ = app.WebServer.WriteJSON(w, status, result)
+}
+
+// readGraphQLBody reads request sent as JSON or as plain query with application/graphql content type
+func (app *App) readGraphQLBody(w http.ResponseWriter, r *http.Request, req *gql.Request) error {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || (mediaType != "application/json" && mediaType != "application/graphql") {
+		return ErrorGraphQLContentType
+	}
+	body, err := app.WebServer.Body(w, r, 1048576)
+	if err != nil {
+		return err
+	}
+	if mediaType == "application/graphql" {
+		query, err := io.ReadAll(body)
+		req.Query = string(query)
+		return err
+	}
+	return json.NewDecoder(body).Decode(req)
+}
+
+// readGraphQLQuery reads request from URL query, variables and extensions are JSON encoded
+func readGraphQLQuery(r *http.Request, req *gql.Request) error {
+	query := r.URL.Query()
+	req.Query = query.Get("query")
+	req.OperationName = query.Get("operationName")
+	if variables := query.Get("variables"); variables != "" {
+		if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+			return ErrorGraphQLVariables
+		}
+	}
+	if extensions := query.Get("extensions"); extensions != "" {
+		if err := json.Unmarshal([]byte(extensions), &req.Extensions); err != nil {
+			return ErrorGraphQLVariables
+		}
+	}
+	return nil
+}
+
+// newGraphQLLoaders creates loaders of request, missing posts are resolved to nil
+func (app *App) newGraphQLLoaders(ctx context.Context) *graphqlLoaders {
+	return &graphqlLoaders{
+		posts: gql.NewLoader(ctx, func(ctx context.Context, ids []int) ([]*domain.Post, []error) {
+			posts := make([]*domain.Post, len(ids))
+			errs := make([]error, len(ids))
+			for i, id := range ids {
+				post, err := app.PostStore.GetOne(ctx, id)
+				switch {
+				case err == nil:
+					posts[i] = post
+				case !errors.Is(err, domain.ErrorPostNotFound):
+					errs[i] = err
+				}
+			}
+			return posts, errs
+		}),
+		// store could not filter by author, so posts of all requested authors are collected by a single scan
+		// with id cursor, pages are not filtered and sorted again as pages of lists are
+		postsByAuthor: gql.NewLoader(ctx, func(ctx context.Context, authors []string) ([][]domain.Post, []error) {
+			index := make(map[string]int, len(authors))
+			for i, author := range authors {
+				index[author] = i
+			}
+			results := make([][]domain.Post, len(authors))
+			for afterID := 0; ; {
+				posts, err := store.Scan(ctx, app.PostStore, afterID, graphqlScanLimit)
+				if err != nil {
+					errs := make([]error, len(authors))
+					for i := range errs {
+						errs[i] = err
+					}
+					return nil, errs
+				}
+				for _, post := range posts {
+					if i, ok := index[post.Author]; ok {
+						results[i] = append(results[i], post)
+					}
+				}
+				if len(posts) < graphqlScanLimit {
+					return results, nil
+				}
+				afterID = posts[len(posts)-1].ID
+			}
+		}),
+	}
+}
+
+func graphqlLoadersFromContext(ctx context.Context) *graphqlLoaders {
+	return ctx.Value(graphqlLoadersKey{}).(*graphqlLoaders)
+}
+
+// graphqlError reports authorization and lookup errors with codes
+func graphqlError(err error) error {
+	switch authorizationStatus(err) {
+	case http.StatusUnauthorized:
+		return &gql.Error{Err: err, Code: "UNAUTHENTICATED"}
+	case http.StatusForbidden:
+		return &gql.Error{Err: err, Code: "FORBIDDEN"}
+	case http.StatusNotFound:
+		return &gql.Error{Err: err, Code: "NOT_FOUND"}
+	default:
+		return &gql.Error{Err: err, Code: gql.CodeBadRequest}
+	}
+}
+
+// newGraphQLSchema creates schema of posts resolved through the post store
+func (app *App) newGraphQLSchema() (graphql.Schema, error) {
+	authorType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Author",
+		Fields: graphql.Fields{
+			"name": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.String),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source, nil
+				},
+			},
+		},
+	})
+	postType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Post",
+		Fields: graphql.Fields{
+			"id":      &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"title":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"content": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"owner":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"version": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"format":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"author": &graphql.Field{
+				Type: graphql.NewNonNull(authorType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return sourcePost(p).Author, nil
+				},
+			},
+		},
+	})
+	// posts of author refer back to post type
+	authorType.AddFieldConfig("posts", &graphql.Field{
+		Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(postType))),
+		Args: graphql.FieldConfigArgument{
+			"limit": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: DefaultLimit},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			limit, _ := p.Args["limit"].(int)
+			if limit < 1 {
+				limit = DefaultLimit
+			}
+			load := graphqlLoadersFromContext(p.Context).postsByAuthor.Load(p.Source.(string))
+			return func() (interface{}, error) {
+				posts, err := load()
+				if err != nil {
+					return nil, err
+				}
+				list := posts.([]domain.Post)
+				if len(list) > limit {
+					list = list[:limit]
+				}
+				return list, nil
+			}, nil
+		},
+	})
+
+	// posts are created as plain text and keep their format on update when format is omitted
+	postInputType := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "PostInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"title":   &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"content": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"author":  &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"format":  &graphql.InputObjectFieldConfig{Type: graphql.String},
+		},
+	})
+
+	queryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"post": &graphql.Field{
+				Type: postType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return graphqlLoadersFromContext(p.Context).posts.Load(p.Args["id"].(int)), nil
+				},
+			},
+			"posts": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(postType))),
+				Args: graphql.FieldConfigArgument{
+					"title": &graphql.ArgumentConfig{Type: graphql.String, DefaultValue: ""},
+					"page":  &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: DefaultPage},
+					"limit": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: DefaultLimit},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					title, _ := p.Args["title"].(string)
+					page, _ := p.Args["page"].(int)
+					limit, _ := p.Args["limit"].(int)
+					if page < 1 {
+						page = DefaultPage
+					}
+					if limit < 1 {
+						limit = DefaultLimit
+					}
+					posts, err := app.PostStore.Get(p.Context, title, page, limit)
+					if err != nil {
+						return nil, graphqlError(err)
+					}
+					return *posts, nil
+				},
+			},
+			"author": &graphql.Field{
+				Type: authorType,
+				Args: graphql.FieldConfigArgument{
+					"name": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Args["name"], nil
+				},
+			},
+		},
+	})
+
+	mutationType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createPost": &graphql.Field{
+				Type: graphql.NewNonNull(postType),
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(postInputType)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					// post is owned by its creator
+					principal, _ := auth.PrincipalFromContext(p.Context)
+					if err := app.Policy.Authorize(principal, auth.PermissionPostsCreate, ""); err != nil {
+						return nil, graphqlError(err)
+					}
+					post := inputPost(p.Args["input"])
+					post.Owner = principal.ID
+					id, err := app.PostStore.Insert(p.Context, post)
+					if err != nil {
+						return nil, graphqlError(err)
+					}
+					return app.PostStore.GetOne(p.Context, id)
+				},
+			},
+			"updatePost": &graphql.Field{
+				Type: graphql.NewNonNull(postType),
+				Args: graphql.FieldConfigArgument{
+					"id":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(postInputType)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					id := p.Args["id"].(int)
+					principal, _ := auth.PrincipalFromContext(p.Context)
+					if err := app.authorizePostAccess(p.Context, app.PostStore, principal, auth.PermissionPostsUpdate, id); err != nil {
+						return nil, graphqlError(err)
+					}
+					post := inputPost(p.Args["input"])
+					err := app.updatePost(p.Context, id, JsonPostPayload{Title: post.Title, Content: post.Content, Author: post.Author, Format: post.Format})
+					if err != nil {
+						return nil, graphqlError(err)
+					}
+					return app.PostStore.GetOne(p.Context, id)
+				},
+			},
+			"deletePost": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Boolean),
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					id := p.Args["id"].(int)
+					principal, _ := auth.PrincipalFromContext(p.Context)
+					if err := app.authorizePostAccess(p.Context, app.PostStore, principal, auth.PermissionPostsDelete, id); err != nil {
+						return nil, graphqlError(err)
+					}
+					if err := app.PostStore.Delete(p.Context, id); err != nil {
+						return nil, graphqlError(err)
+					}
+					return true, nil
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: queryType, Mutation: mutationType})
+}
+
+// sourcePost returns post resolved by parent field
+func sourcePost(p graphql.ResolveParams) domain.Post {
+	switch post := p.Source.(type) {
+	case *domain.Post:
+		return *post
+	case domain.Post:
+		return post
+	}
+	return domain.Post{}
+}
+
+// inputPost constructs domain object from PostInput argument
+func inputPost(input interface{}) domain.Post {
+	fields, _ := input.(map[string]interface{})
+	title, _ := fields["title"].(string)
+	content, _ := fields["content"].(string)
+	author, _ := fields["author"].(string)
+	format, _ := fields["format"].(string)
+	return domain.Post{Title: title, Content: content, Author: author, Format: format}
+}
@@ -0,0 +1,348 @@
+package main
+
+import (
+	"api-service/internal/domain"
+	"api-service/internal/gql"
+	"api-service/internal/store"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// countingPostStore counts store lookups made by resolvers
+type countingPostStore struct {
+	store.PostStore
+	gets    atomic.Int32
+	getOnes atomic.Int32
+	scans   atomic.Int32
+}
+
+func (s *countingPostStore) Get(ctx context.Context, title string, page int, limit int) (*[]domain.Post, error) {
+	s.gets.Add(1)
+	return s.PostStore.Get(ctx, title, page, limit)
+}
+
+func (s *countingPostStore) Scan(ctx context.Context, afterID int, limit int) ([]domain.Post, error) {
+	s.scans.Add(1)
+	return store.Scan(ctx, s.PostStore, afterID, limit)
+}
+
+func (s *countingPostStore) GetOne(ctx context.Context, id int) (*domain.Post, error) {
+	s.getOnes.Add(1)
+	return s.PostStore.GetOne(ctx, id)
+}
+
+// newTestGraphQLApp creates application with GraphQL executor and posts of two authors
+func newTestGraphQLApp(t *testing.T, limits gql.Limits) (*App, *countingPostStore) {
+	t.Helper()
+
+	app, memoryStore := newTestTransferApp(t, 0)
+	for i := 1; i <= 4; i++ {
+		_, _ = memoryStore.Insert(context.Background(), domain.Post{Title: fmt.Sprintf("Title %d", i), Author: fmt.Sprintf("Author %d", i%2), Owner: "author-1"})
+	}
+	counting := &countingPostStore{PostStore: memoryStore}
+	app.PostStore = counting
+	schema, err := app.newGraphQLSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.GraphQL = &gql.Executor{Schema: schema, Limits: limits, Persisted: gql.NewPersistedQueries(10, nil, false)}
+	return app, counting
+}
+
+// serveGraphQLRequest sends GraphQL request with POST, or with GET when method is specified
+func serveGraphQLRequest(t *testing.T, app *App, method string, token string, request gql.Request) (int, string) {
+	t.Helper()
+
+	var req *http.Request
+	if method == http.MethodGet {
+		query := url.Values{"query": {request.Query}}
+		if request.Extensions != nil {
+			extensions, _ := json.Marshal(request.Extensions)
+			query.Set("extensions", string(extensions))
+		}
+		req, _ = http.NewRequest(http.MethodGet, "/v1/graphql?"+query.Encode(), nil)
+	} else {
+		body, _ := json.Marshal(request)
+		req, _ = http.NewRequest(http.MethodPost, "/v1/graphql", strings.NewReader(string(body)))
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rr := httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, req)
+	return rr.Code, rr.Body.String()
+}
+
+// TestGraphQL_Query tests queries of posts and authors
+func TestGraphQL_Query(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		method       string
+		query        string
+		expectedBody string
+	}{
+		{
+			"post by id",
+			http.MethodPost,
+			`{ post(id: 2) { id title author { name } version } }`,
+			`{"data":{"post":{"author":{"name":"Author 0"},"id":2,"title":"Title 2","version":1}}}`,
+		},
+		{
+			"missing post",
+			http.MethodGet,
+			`{ post(id: 10) { id } }`,
+			`{"data":{"post":null}}`,
+		},
+		{
+			"filtered and paginated posts",
+			http.MethodGet,
+			`{ posts(title: "title", page: 2, limit: 3) { id } }`,
+			`{"data":{"posts":[{"id":4}]}}`,
+		},
+		{
+			"posts of author",
+			http.MethodPost,
+			`{ author(name: "Author 1") { posts(limit: 1) { title } } }`,
+			`{"data":{"author":{"posts":[{"title":"Title 1"}]}}}`,
+		},
+		{
+			"posts of author with negative limit",
+			http.MethodPost,
+			`{ author(name: "Author 1") { posts(limit: -1) { title } } }`,
+			`{"data":{"author":{"posts":[{"title":"Title 1"},{"title":"Title 3"}]}}}`,
+		},
+		{
+			"validation error",
+			http.MethodPost,
+			`{ post(id: 1) { missing } }`,
+			`{"data":null,"errors":[{"message":"Cannot query field \"missing\" on type \"Post\".","locations":[{"line":1,"column":17}],"extensions":{"code":"GRAPHQL_VALIDATION_FAILED"}}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			app, _ := newTestGraphQLApp(t, gql.Limits{})
+			status, body := serveGraphQLRequest(t, app, tt.method, "", gql.Request{Query: tt.query})
+			if status != http.StatusOK || body != tt.expectedBody {
+				t.Errorf("expected %s, but got %d %s", tt.expectedBody, status, body)
+			}
+		})
+	}
+}
+
+// TestGraphQL_Mutation tests authorized mutations of posts
+func TestGraphQL_Mutation(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		method         string
+		token          string
+		query          string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			"create post",
+			http.MethodPost,
+			"author-token",
+			`mutation { createPost(input: {title: "New", content: "Text", author: "Me"}) { id owner } }`,
+			http.StatusOK,
+			`{"data":{"createPost":{"id":5,"owner":"author-1"}}}`,
+		},
+		{
+			"update own post",
+			http.MethodPost,
+			"author-token",
+			`mutation { updatePost(id: 1, input: {title: "Updated", content: "Text", author: "Me"}) { title version } }`,
+			http.StatusOK,
+			`{"data":{"updatePost":{"title":"Updated","version":2}}}`,
+		},
+		{
+			"update format",
+			http.MethodPost,
+			"author-token",
+			`mutation { updatePost(id: 1, input: {title: "Updated", content: "*Text*", author: "Me", format: "markdown"}) { format version } }`,
+			http.StatusOK,
+			`{"data":{"updatePost":{"format":"markdown","version":2}}}`,
+		},
+		{
+			"invalid format",
+			http.MethodPost,
+			"author-token",
+			`mutation { createPost(input: {title: "New", content: "Text", author: "Me", format: "html"}) { id } }`,
+			http.StatusOK,
+			`{"data":null,"errors":[{"message":"format must be one of plain or markdown","locations":[{"line":1,"column":12}],"path":["createPost"],"extensions":{"code":"BAD_REQUEST"}}]}`,
+		},
+		{
+			"delete post",
+			http.MethodPost,
+			"editor-token",
+			`mutation { deletePost(id: 1) }`,
+			http.StatusOK,
+			`{"data":{"deletePost":true}}`,
+		},
+		{
+			"anonymous mutation",
+			http.MethodPost,
+			"",
+			`mutation { deletePost(id: 1) }`,
+			http.StatusOK,
+			`{"data":null,"errors":[{"message":"authentication required","locations":[{"line":1,"column":12}],"path":["deletePost"],"extensions":{"code":"UNAUTHENTICATED"}}]}`,
+		},
+		{
+			"missing post",
+			http.MethodPost,
+			"editor-token",
+			`mutation { deletePost(id: 10) }`,
+			http.StatusOK,
+			`{"data":null,"errors":[{"message":"post not found","locations":[{"line":1,"column":12}],"path":["deletePost"],"extensions":{"code":"NOT_FOUND"}}]}`,
+		},
+		{
+			"mutation with GET",
+			http.MethodGet,
+			"editor-token",
+			`mutation { deletePost(id: 1) }`,
+			http.StatusMethodNotAllowed,
+			`{"data":null,"errors":[{"message":"mutations are allowed with POST requests only","locations":[],"extensions":{"code":"METHOD_NOT_ALLOWED"}}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			app, _ := newTestGraphQLApp(t, gql.Limits{})
+			status, body := serveGraphQLRequest(t, app, tt.method, tt.token, gql.Request{Query: tt.query})
+			if status != tt.expectedStatus || body != tt.expectedBody {
+				t.Errorf("expected %d %s, but got %d %s", tt.expectedStatus, tt.expectedBody, status, body)
+			}
+		})
+	}
+}
+
+// TestGraphQL_Limits tests rejection of too deep and too complex queries before execution
+func TestGraphQL_Limits(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		query        string
+		expectedBody string
+	}{
+		{
+			"too deep",
+			`{ post(id: 1) { author { posts { author { posts { id } } } } } }`,
+			`{"data":null,"errors":[{"message":"query depth 6 exceeds maximum 5","locations":[],"extensions":{"code":"QUERY_TOO_COMPLEX"}}]}`,
+		},
+		{
+			"too complex",
+			`{ posts(limit: 100) { id title } }`,
+			`{"data":null,"errors":[{"message":"query complexity 201 exceeds maximum 100","locations":[],"extensions":{"code":"QUERY_TOO_COMPLEX"}}]}`,
+		},
+		{
+			"overflowing complexity",
+			`{ posts(limit: 2147483647) { author { posts(limit: 2147483647) { id title content owner version author { name } } } } }`,
+			`{"data":null,"errors":[{"message":"query complexity 9223372036854775807 exceeds maximum 100","locations":[],"extensions":{"code":"QUERY_TOO_COMPLEX"}}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			app, counting := newTestGraphQLApp(t, gql.Limits{MaxDepth: 5, MaxComplexity: 100})
+			status, body := serveGraphQLRequest(t, app, http.MethodPost, "", gql.Request{Query: tt.query})
+			if status != http.StatusOK || body != tt.expectedBody {
+				t.Errorf("expected %s, but got %d %s", tt.expectedBody, status, body)
+			}
+			if counting.gets.Load() != 0 || counting.getOnes.Load() != 0 {
+				t.Error("expected query rejected before execution")
+			}
+		})
+	}
+}
+
+// TestGraphQL_Batching tests that lookups of the same level are batched and cached per request
+func TestGraphQL_Batching(t *testing.T) {
+	t.Parallel()
+	app, counting := newTestGraphQLApp(t, gql.Limits{})
+
+	query := `{
+		a: post(id: 1) { id } b: post(id: 1) { id } c: post(id: 2) { id }
+		posts(limit: 4) { author { posts { id } } }
+	}`
+	status, body := serveGraphQLRequest(t, app, http.MethodPost, "", gql.Request{Query: query})
+	if status != http.StatusOK || strings.Contains(body, "errors") {
+		t.Fatalf("unexpected response %d %s", status, body)
+	}
+	// posts of both authors are collected by a single scan after the list query
+	if gets, getOnes, scans := counting.gets.Load(), counting.getOnes.Load(), counting.scans.Load(); gets != 1 || getOnes != 2 || scans != 1 {
+		t.Errorf("expected 1 list, 2 post lookups and 1 scan, but got %d, %d and %d", gets, getOnes, scans)
+	}
+}
+
+// TestGraphQL_PostsByAuthorPages tests that posts of authors are scanned by pages following id cursor
+func TestGraphQL_PostsByAuthorPages(t *testing.T) {
+	t.Parallel()
+	app, counting := newTestGraphQLApp(t, gql.Limits{})
+	memoryStore := counting.PostStore
+	for i := 5; i <= 250; i++ {
+		_, _ = memoryStore.Insert(context.Background(), domain.Post{Title: fmt.Sprintf("Title %d", i), Author: fmt.Sprintf("Author %d", i%2)})
+	}
+
+	query := `{ author(name: "Author 1") { posts(limit: 200) { id } } }`
+	status, body := serveGraphQLRequest(t, app, http.MethodPost, "", gql.Request{Query: query})
+	var response struct {
+		Data struct {
+			Author struct {
+				Posts []struct{ ID int }
+			}
+		}
+	}
+	if err := json.Unmarshal([]byte(body), &response); status != http.StatusOK || err != nil {
+		t.Fatalf("unexpected response %d %s", status, body)
+	}
+	posts := response.Data.Author.Posts
+	if len(posts) != 125 || posts[0].ID != 1 || posts[124].ID != 249 {
+		t.Errorf("expected 125 posts from 1 to 249, but got %d", len(posts))
+	}
+	if gets, scans := counting.gets.Load(), counting.scans.Load(); gets != 0 || scans != 3 {
+		t.Errorf("expected 3 scans without lists, but got %d lists and %d scans", gets, scans)
+	}
+}
+
+// TestGraphQL_PersistedQuery tests automatic persisted queries protocol
+func TestGraphQL_PersistedQuery(t *testing.T) {
+	t.Parallel()
+	app, _ := newTestGraphQLApp(t, gql.Limits{})
+	query := `{ post(id: 1) { title } }`
+	extensions := &gql.RequestExtensions{PersistedQuery: &gql.PersistedQuery{Version: 1, Sha256Hash: gql.Hash(query)}}
+
+	// unknown hash is reported, so client sends the query with its hash
+	_, body := serveGraphQLRequest(t, app, http.MethodGet, "", gql.Request{Extensions: extensions})
+	if !strings.Contains(body, `"code":"PERSISTED_QUERY_NOT_FOUND"`) {
+		t.Errorf("expected not found persisted query, but got %s", body)
+	}
+	_, body = serveGraphQLRequest(t, app, http.MethodPost, "", gql.Request{Query: query, Extensions: extensions})
+	expectedBody := `{"data":{"post":{"title":"Title 1"}}}`
+	if body != expectedBody {
+		t.Errorf("expected %s, but got %s", expectedBody, body)
+	}
+	_, body = serveGraphQLRequest(t, app, http.MethodGet, "", gql.Request{Extensions: extensions})
+	if body != expectedBody {
+		t.Errorf("expected %s, but got %s", expectedBody, body)
+	}
+}
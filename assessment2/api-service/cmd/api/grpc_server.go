@@ -0,0 +1,492 @@
+package main
+
+import (
+	"api-service/internal/auth"
+	"api-service/internal/domain"
+	"api-service/internal/events"
+	"api-service/internal/logging"
+	"api-service/internal/ratelimit"
+	"api-service/internal/rpc/postsv1"
+	"api-service/internal/tracing"
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpchealth "google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// missedEventsHeader is set in header metadata of watch calls which could not replay all missed events
+const missedEventsHeader = "missed-events"
+
+var (
+	ErrorRPCPostRequired = errors.New("post is required")
+	ErrorRPCEventType    = errors.New("event type must be one of created, updated or deleted")
+	ErrorRPCWatchClosed  = errors.New("watch is closed, resume it with the last received event id")
+)
+
+// postService implements PostService API with the same store and authorization policy as REST routes
+type postService struct {
+	postsv1.UnimplementedPostServiceServer
+	app *App
+}
+
+// newGRPCServer creates server of PostService and health services, reflection service is registered when enabled,
+// health of services is NOT_SERVING until it is changed by the caller
+func (app *App) newGRPCServer() (*grpc.Server, *grpchealth.Server) {
+	srv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(app.grpcUnaryInterceptors()...),
+		grpc.ChainStreamInterceptor(app.grpcStreamInterceptors()...),
+	)
+	postsv1.RegisterPostServiceServer(srv, &postService{app: app})
+
+	healthServer := grpchealth.NewServer()
+	healthServer.SetServingStatus("", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	healthServer.SetServingStatus(postsv1.PostService_ServiceDesc.ServiceName, grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	grpc_health_v1.RegisterHealthServer(srv, healthServer)
+
+	// clients like grpcurl discover services by reflection, it exposes the whole API description
+	if app.GRPCReflection {
+		reflection.Register(srv)
+	}
+	return srv, healthServer
+}
+
+// setGRPCServing changes health status of all services
+func setGRPCServing(healthServer *grpchealth.Server, serving bool) {
+	status := grpc_health_v1.HealthCheckResponse_NOT_SERVING
+	if serving {
+		status = grpc_health_v1.HealthCheckResponse_SERVING
+	}
+	healthServer.SetServingStatus("", status)
+	healthServer.SetServingStatus(postsv1.PostService_ServiceDesc.ServiceName, status)
+}
+
+// GetPost returns post by id
+func (s *postService) GetPost(ctx context.Context, req *postsv1.GetPostRequest) (*postsv1.Post, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.app.WebServer.Timeout*time.Second)
+	defer cancel()
+	post, err := s.app.PostStore.GetOne(ctx, int(req.GetId()))
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	return postToProto(post), nil
+}
+
+// ListPosts returns page of posts filtered by title
+func (s *postService) ListPosts(ctx context.Context, req *postsv1.ListPostsRequest) (*postsv1.ListPostsResponse, error) {
+	page, limit := int(req.GetPage()), int(req.GetLimit())
+	if page < 1 {
+		page = DefaultPage
+	}
+	if limit < 1 {
+		limit = DefaultLimit
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.app.WebServer.Timeout*time.Second)
+	defer cancel()
+	posts, err := s.app.PostStore.Get(ctx, req.GetTitle(), page, limit)
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	response := &postsv1.ListPostsResponse{Posts: make([]*postsv1.Post, 0, len(*posts))}
+	for i := range *posts {
+		response.Posts = append(response.Posts, postToProto(&(*posts)[i]))
+	}
+	return response, nil
+}
+
+// CreatePost adds a new post owned by the caller
+func (s *postService) CreatePost(ctx context.Context, req *postsv1.CreatePostRequest) (*postsv1.Post, error) {
+	principal, _ := auth.PrincipalFromContext(ctx)
+	if err := s.app.Policy.Authorize(principal, auth.PermissionPostsCreate, ""); err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	if req.GetPost() == nil {
+		return nil, status.Error(codes.InvalidArgument, ErrorRPCPostRequired.Error())
+	}
+
+	// construct domain object from input data, post is owned by its creator
+	post := domain.Post{
+		Title:   req.GetPost().GetTitle(),
+		Content: req.GetPost().GetContent(),
+		Author:  req.GetPost().GetAuthor(),
+		Format:  req.GetPost().GetFormat(),
+		Owner:   principal.ID,
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.app.WebServer.Timeout*time.Second)
+	defer cancel()
+	id, err := s.app.PostStore.Insert(ctx, post)
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	created, err := s.app.PostStore.GetOne(ctx, id)
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	return postToProto(created), nil
+}
+
+// UpdatePost replaces data of the post
+func (s *postService) UpdatePost(ctx context.Context, req *postsv1.UpdatePostRequest) (*postsv1.Post, error) {
+	if err := s.authorizePost(ctx, auth.PermissionPostsUpdate, req.GetId()); err != nil {
+		return nil, err
+	}
+	if req.GetPost() == nil {
+		return nil, status.Error(codes.InvalidArgument, ErrorRPCPostRequired.Error())
+	}
+
+	jsonPayload := JsonPostPayload{
+		Title:   req.GetPost().GetTitle(),
+		Content: req.GetPost().GetContent(),
+		Author:  req.GetPost().GetAuthor(),
+		Format:  req.GetPost().GetFormat(),
+	}
+	if err := s.app.updatePost(ctx, int(req.GetId()), jsonPayload); err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.app.WebServer.Timeout*time.Second)
+	defer cancel()
+	updated, err := s.app.PostStore.GetOne(ctx, int(req.GetId()))
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	return postToProto(updated), nil
+}
+
+// DeletePost deletes the post
+func (s *postService) DeletePost(ctx context.Context, req *postsv1.DeletePostRequest) (*emptypb.Empty, error) {
+	if err := s.authorizePost(ctx, auth.PermissionPostsDelete, req.GetId()); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.app.WebServer.Timeout*time.Second)
+	defer cancel()
+	if err := s.app.PostStore.Delete(ctx, int(req.GetId())); err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// WatchPosts streams changes of posts, missed events are replayed after last event id
+func (s *postService) WatchPosts(req *postsv1.WatchPostsRequest, stream grpc.ServerStreamingServer[postsv1.PostEvent]) error {
+	if s.app.Events == nil {
+		return status.Error(codes.Unimplemented, "change stream is not enabled")
+	}
+	filter, err := watchFilter(req)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	sub, replay, complete := s.app.Events.Subscribe(req.GetEpoch(), req.GetLastEventId(), filter)
+	defer sub.Close()
+
+	// clients should reload posts when some of missed events are no longer available
+	if !complete {
+		if err := stream.SendHeader(metadata.Pairs(missedEventsHeader, "true")); err != nil {
+			return err
+		}
+	}
+	for _, event := range replay {
+		if err := stream.Send(eventToProto(event)); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event, ok := <-sub.Events():
+			if !ok {
+				// slow clients and clients of stopped service resume from replay buffer
+				logging.FromContext(stream.Context()).Info("watch closed", slog.Any("reason", sub.Err()))
+				return status.Error(codes.Unavailable, ErrorRPCWatchClosed.Error())
+			}
+			if err := stream.Send(eventToProto(event)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// authorizePost checks that caller has permission over the post
+func (s *postService) authorizePost(ctx context.Context, permission auth.Permission, id int64) error {
+	ctx, cancel := context.WithTimeout(ctx, s.app.WebServer.Timeout*time.Second)
+	defer cancel()
+	principal, _ := auth.PrincipalFromContext(ctx)
+	if err := s.app.authorizePostAccess(ctx, s.app.PostStore, principal, permission, int(id)); err != nil {
+		return grpcError(ctx, err)
+	}
+	return nil
+}
+
+// watchFilter creates events filter from event types and post ids of request
+func watchFilter(req *postsv1.WatchPostsRequest) (events.Filter, error) {
+	types := make(map[events.Type]bool)
+	for _, eventType := range req.GetTypes() {
+		switch eventType {
+		case postsv1.EventType_EVENT_TYPE_CREATED:
+			types[events.TypeCreated] = true
+		case postsv1.EventType_EVENT_TYPE_UPDATED:
+			types[events.TypeUpdated] = true
+		case postsv1.EventType_EVENT_TYPE_DELETED:
+			types[events.TypeDeleted] = true
+		default:
+			return nil, ErrorRPCEventType
+		}
+	}
+	ids := make(map[int]bool)
+	for _, id := range req.GetIds() {
+		ids[int(id)] = true
+	}
+	return func(event events.Event) bool {
+		return (len(types) == 0 || types[event.Type]) && (len(ids) == 0 || ids[event.PostID])
+	}, nil
+}
+
+// grpcError maps domain and authorization errors to status codes
+func grpcError(ctx context.Context, err error) error {
+	code := codes.Internal
+	switch {
+	case errors.Is(err, domain.ErrorPostNotFound):
+		code = codes.NotFound
+	case errors.Is(err, domain.ErrorInvalidPostID), errors.Is(err, domain.ErrorInvalidFormat):
+		code = codes.InvalidArgument
+	case errors.Is(err, auth.ErrorUnauthenticated):
+		code = codes.Unauthenticated
+	case errors.Is(err, auth.ErrorForbidden):
+		code = codes.PermissionDenied
+	case errors.Is(err, context.DeadlineExceeded):
+		code = codes.DeadlineExceeded
+	case errors.Is(err, context.Canceled):
+		code = codes.Canceled
+	default:
+		logging.FromContext(ctx).Warn("rpc failed", slog.Any("error", err))
+	}
+	return status.Error(code, err.Error())
+}
+
+// grpcUnaryInterceptors returns interceptors of unary calls in the order of HTTP middlewares:
+// request id, tracing, metrics, then access log, rate limit and authentication
+func (app *App) grpcUnaryInterceptors() []grpc.UnaryServerInterceptor {
+	interceptors := []grpc.UnaryServerInterceptor{
+		func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			return handler(app.grpcContext(ctx), req)
+		},
+	}
+	if app.Tracer != nil {
+		interceptors = append(interceptors, tracing.UnaryServerInterceptor(app.Tracer))
+	}
+	if app.Metrics != nil {
+		interceptors = append(interceptors, app.Metrics.UnaryServerInterceptor)
+	}
+	return append(interceptors, app.grpcUnaryInterceptor)
+}
+
+// grpcStreamInterceptors returns interceptors of streaming calls in the same order as interceptors of unary calls
+func (app *App) grpcStreamInterceptors() []grpc.StreamServerInterceptor {
+	interceptors := []grpc.StreamServerInterceptor{
+		func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			return handler(srv, &grpcServerStream{ServerStream: ss, ctx: app.grpcContext(ss.Context())})
+		},
+	}
+	if app.Tracer != nil {
+		interceptors = append(interceptors, tracing.StreamServerInterceptor(app.Tracer))
+	}
+	if app.Metrics != nil {
+		interceptors = append(interceptors, app.Metrics.StreamServerInterceptor)
+	}
+	return append(interceptors, app.grpcStreamInterceptor)
+}
+
+// grpcUnaryInterceptor limits rate, authenticates and logs unary calls
+func (app *App) grpcUnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	err := app.grpcRateLimit(ctx, info.FullMethod)
+	if err == nil {
+		ctx, err = app.grpcAuthenticate(ctx)
+	}
+	var resp any
+	if err == nil {
+		resp, err = handler(ctx, req)
+	}
+	grpcAccessLog(ctx, info.FullMethod, start, err)
+	return resp, err
+}
+
+// grpcStreamInterceptor limits rate, authenticates and logs streaming calls
+func (app *App) grpcStreamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	ctx := ss.Context()
+	err := app.grpcRateLimit(ctx, info.FullMethod)
+	if err == nil {
+		ctx, err = app.grpcAuthenticate(ctx)
+	}
+	if err == nil {
+		err = handler(srv, &grpcServerStream{ServerStream: ss, ctx: ctx})
+	}
+	grpcAccessLog(ctx, info.FullMethod, start, err)
+	return err
+}
+
+// grpcWriteMethods change posts and share limit with write requests of REST routes
+var grpcWriteMethods = []string{
+	postsv1.PostService_CreatePost_FullMethodName,
+	postsv1.PostService_UpdatePost_FullMethodName,
+	postsv1.PostService_DeletePost_FullMethodName,
+}
+
+// grpcRateLimit limits rate of calls per client with the same buckets as REST routes, clients are identified
+// by user of a valid token or by peer address; limited calls get ResourceExhausted with retry-after header
+func (app *App) grpcRateLimit(ctx context.Context, method string) error {
+	if app.Limiter == nil {
+		return nil
+	}
+	// select bucket and limit according to call kind
+	key, limit := app.grpcRateLimitKey(ctx), app.RateLimits.Read
+	if slices.Contains(grpcWriteMethods, method) {
+		key, limit = key+":write", app.RateLimits.Write
+	} else {
+		key += ":read"
+	}
+
+	// limiter backend failures should not make API unavailable
+	result, err := app.Limiter.Allow(ctx, key, limit)
+	if err != nil {
+		logging.FromContext(ctx).Error("rate limiter failed", slog.Any("error", err))
+		return nil
+	}
+	if !result.Allowed {
+		_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(ceilSeconds(result.RetryAfter))))
+		return status.Error(codes.ResourceExhausted, ratelimit.ErrorRateLimited.Error())
+	}
+	return nil
+}
+
+// grpcRateLimitKey identifies client of the call like rateLimitKey does for HTTP requests
+func (app *App) grpcRateLimitKey(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get("authorization"); len(values) > 0 && app.Users != nil {
+		if token, ok := strings.CutPrefix(values[0], "Bearer "); ok {
+			if principal, err := app.Users.Authenticate(strings.TrimSpace(token)); err == nil {
+				return "user:" + principal.ID
+			}
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+			return "ip:" + host
+		}
+		return "ip:" + p.Addr.String()
+	}
+	return "ip:"
+}
+
+// grpcContext stores request id and logger annotated with it into context, request id is sent back in header
+func (app *App) grpcContext(ctx context.Context) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var id string
+	if values := md.Get(strings.ToLower(logging.RequestIDHeader)); len(values) > 0 {
+		id = values[0]
+	}
+	logger := app.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	ctx, id = logging.WithRequestID(ctx, logger, id)
+	_ = grpc.SetHeader(ctx, metadata.Pairs(logging.RequestIDHeader, id))
+	return ctx
+}
+
+// grpcAuthenticate resolves bearer token of authorization metadata into principal, calls without token are anonymous
+func (app *App) grpcAuthenticate(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 || app.Users == nil {
+		return ctx, nil
+	}
+	token, ok := strings.CutPrefix(values[0], "Bearer ")
+	if !ok {
+		return ctx, status.Error(codes.Unauthenticated, auth.ErrorUnauthenticated.Error())
+	}
+	principal, err := app.Users.Authenticate(strings.TrimSpace(token))
+	if err != nil {
+		logging.FromContext(ctx).Warn("authentication failed", slog.Any("error", err))
+		return ctx, status.Error(codes.Unauthenticated, err.Error())
+	}
+	ctx = auth.WithPrincipal(ctx, principal)
+	return logging.WithLogger(ctx, logging.FromContext(ctx).With(slog.String("user_id", principal.ID))), nil
+}
+
+// grpcAccessLog writes a log record for each processed call
+func grpcAccessLog(ctx context.Context, method string, start time.Time, err error) {
+	code := status.Code(err)
+	level := slog.LevelInfo
+	switch code {
+	case codes.Internal, codes.Unknown, codes.DataLoss:
+		level = slog.LevelError
+	}
+	logging.FromContext(ctx).LogAttrs(ctx, level, "rpc processed",
+		slog.String("method", method),
+		slog.String("code", code.String()),
+		slog.Duration("latency", time.Since(start)),
+	)
+}
+
+// grpcServerStream replaces context of stream with authenticated one
+type grpcServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *grpcServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func postToProto(post *domain.Post) *postsv1.Post {
+	return &postsv1.Post{
+		Id:      int64(post.ID),
+		Title:   post.Title,
+		Content: post.Content,
+		Author:  post.Author,
+		Owner:   post.Owner,
+		Version: int64(post.Version),
+		Format:  post.Format,
+	}
+}
+
+func eventToProto(event events.Event) *postsv1.PostEvent {
+	message := &postsv1.PostEvent{
+		Id:      event.ID,
+		Epoch:   event.Epoch,
+		PostId:  int64(event.PostID),
+		Version: int64(event.Version),
+		Time:    timestamppb.New(event.Time),
+	}
+	switch event.Type {
+	case events.TypeCreated:
+		message.Type = postsv1.EventType_EVENT_TYPE_CREATED
+	case events.TypeUpdated:
+		message.Type = postsv1.EventType_EVENT_TYPE_UPDATED
+	case events.TypeDeleted:
+		message.Type = postsv1.EventType_EVENT_TYPE_DELETED
+	}
+	if event.Post != nil {
+		message.Post = postToProto(event.Post)
+	}
+	return message
+}
@@ -0,0 +1,385 @@
+package main
+
+import (
+	"api-service/internal/events"
+	"api-service/internal/metrics"
+	"api-service/internal/ratelimit"
+	"api-service/internal/rpc/postsv1"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+)
+
+// startTestGRPCServer serves gRPC services of application with in-memory listener and returns client connection
+func startTestGRPCServer(t *testing.T, app *App) *grpc.ClientConn {
+	t.Helper()
+
+	listener := bufconn.Listen(1 << 20)
+	srv, healthServer := app.newGRPCServer()
+	setGRPCServing(healthServer, true)
+	go func() {
+		_ = srv.Serve(listener)
+	}()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+	return conn
+}
+
+// withToken returns context sending bearer token in metadata
+func withToken(token string) context.Context {
+	if token == "" {
+		return context.Background()
+	}
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+// TestGRPC_PostService tests calls of post service and mapping of errors to status codes
+func TestGRPC_PostService(t *testing.T) {
+	t.Parallel()
+
+	input := &postsv1.PostInput{Title: "New", Content: "Text", Author: "Me"}
+	tests := []struct {
+		name         string
+		token        string
+		call         func(ctx context.Context, client postsv1.PostServiceClient) (proto.Message, error)
+		expectedCode codes.Code
+		expected     proto.Message
+	}{
+		{
+			"get post",
+			"",
+			func(ctx context.Context, client postsv1.PostServiceClient) (proto.Message, error) {
+				return client.GetPost(ctx, &postsv1.GetPostRequest{Id: 2})
+			},
+			codes.OK,
+			&postsv1.Post{Id: 2, Title: "Title 2", Owner: "author-1", Version: 1, Format: "plain"},
+		},
+		{
+			"missing post",
+			"",
+			func(ctx context.Context, client postsv1.PostServiceClient) (proto.Message, error) {
+				return client.GetPost(ctx, &postsv1.GetPostRequest{Id: 10})
+			},
+			codes.NotFound,
+			nil,
+		},
+		{
+			"list posts",
+			"",
+			func(ctx context.Context, client postsv1.PostServiceClient) (proto.Message, error) {
+				response, err := client.ListPosts(ctx, &postsv1.ListPostsRequest{Title: "title", Page: 2, Limit: 2})
+				if err != nil {
+					return nil, err
+				}
+				return response.GetPosts()[0], nil
+			},
+			codes.OK,
+			&postsv1.Post{Id: 3, Title: "Title 3", Owner: "author-1", Version: 1, Format: "plain"},
+		},
+		{
+			"create post",
+			"author-token",
+			func(ctx context.Context, client postsv1.PostServiceClient) (proto.Message, error) {
+				return client.CreatePost(ctx, &postsv1.CreatePostRequest{Post: input})
+			},
+			codes.OK,
+			&postsv1.Post{Id: 4, Title: "New", Content: "Text", Author: "Me", Owner: "author-1", Version: 1, Format: "plain"},
+		},
+		{
+			"create markdown post",
+			"author-token",
+			func(ctx context.Context, client postsv1.PostServiceClient) (proto.Message, error) {
+				return client.CreatePost(ctx, &postsv1.CreatePostRequest{Post: &postsv1.PostInput{Title: "New", Format: "markdown"}})
+			},
+			codes.OK,
+			&postsv1.Post{Id: 4, Title: "New", Owner: "author-1", Version: 1, Format: "markdown"},
+		},
+		{
+			"invalid format",
+			"author-token",
+			func(ctx context.Context, client postsv1.PostServiceClient) (proto.Message, error) {
+				return client.CreatePost(ctx, &postsv1.CreatePostRequest{Post: &postsv1.PostInput{Title: "New", Format: "html"}})
+			},
+			codes.InvalidArgument,
+			nil,
+		},
+		{
+			"anonymous create",
+			"",
+			func(ctx context.Context, client postsv1.PostServiceClient) (proto.Message, error) {
+				return client.CreatePost(ctx, &postsv1.CreatePostRequest{Post: input})
+			},
+			codes.Unauthenticated,
+			nil,
+		},
+		{
+			"invalid token",
+			"unknown-token",
+			func(ctx context.Context, client postsv1.PostServiceClient) (proto.Message, error) {
+				return client.GetPost(ctx, &postsv1.GetPostRequest{Id: 1})
+			},
+			codes.Unauthenticated,
+			nil,
+		},
+		{
+			"missing input",
+			"author-token",
+			func(ctx context.Context, client postsv1.PostServiceClient) (proto.Message, error) {
+				return client.UpdatePost(ctx, &postsv1.UpdatePostRequest{Id: 1})
+			},
+			codes.InvalidArgument,
+			nil,
+		},
+		{
+			"update own post",
+			"author-token",
+			func(ctx context.Context, client postsv1.PostServiceClient) (proto.Message, error) {
+				return client.UpdatePost(ctx, &postsv1.UpdatePostRequest{Id: 1, Post: input})
+			},
+			codes.OK,
+			&postsv1.Post{Id: 1, Title: "New", Content: "Text", Author: "Me", Owner: "author-1", Version: 2, Format: "plain"},
+		},
+		{
+			"delete post of another user",
+			"author-token",
+			func(ctx context.Context, client postsv1.PostServiceClient) (proto.Message, error) {
+				post, err := client.CreatePost(withToken("editor-token"), &postsv1.CreatePostRequest{Post: input})
+				if err != nil {
+					return nil, err
+				}
+				return client.DeletePost(ctx, &postsv1.DeletePostRequest{Id: post.GetId()})
+			},
+			codes.PermissionDenied,
+			nil,
+		},
+		{
+			"delete post",
+			"editor-token",
+			func(ctx context.Context, client postsv1.PostServiceClient) (proto.Message, error) {
+				return client.DeletePost(ctx, &postsv1.DeletePostRequest{Id: 1})
+			},
+			codes.OK,
+			nil,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			app, _ := newTestTransferApp(t, 3)
+			client := postsv1.NewPostServiceClient(startTestGRPCServer(t, app))
+
+			response, err := tt.call(withToken(tt.token), client)
+			if code := status.Code(err); code != tt.expectedCode {
+				t.Fatalf("expected %v, but got %v", tt.expectedCode, err)
+			}
+			if tt.expected != nil && !proto.Equal(response, tt.expected) {
+				t.Errorf("expected %v, but got %v", tt.expected, response)
+			}
+		})
+	}
+}
+
+// TestGRPC_WatchPosts tests streaming of filtered post changes
+func TestGRPC_WatchPosts(t *testing.T) {
+	t.Parallel()
+	app, memoryStore := newTestTransferApp(t, 2)
+	app.Events = events.NewBroker(10, 10)
+	memoryStore.Observe(app.Events.Observer())
+	client := postsv1.NewPostServiceClient(startTestGRPCServer(t, app))
+
+	ctx, cancel := context.WithTimeout(withToken("editor-token"), 5*time.Second)
+	defer cancel()
+	stream, err := client.WatchPosts(ctx, &postsv1.WatchPostsRequest{
+		Types: []postsv1.EventType{postsv1.EventType_EVENT_TYPE_UPDATED, postsv1.EventType_EVENT_TYPE_DELETED},
+		Ids:   []int64{2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for app.Events.Subscribers() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// only changes of the second post are streamed
+	for _, id := range []int64{1, 2} {
+		_, err = client.UpdatePost(withToken("editor-token"), &postsv1.UpdatePostRequest{Id: id, Post: &postsv1.PostInput{Title: "Updated"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, _ = client.DeletePost(withToken("editor-token"), &postsv1.DeletePostRequest{Id: 2})
+
+	expected := []struct {
+		eventType postsv1.EventType
+		version   int64
+		title     string
+	}{
+		{postsv1.EventType_EVENT_TYPE_UPDATED, 2, "Updated"},
+		{postsv1.EventType_EVENT_TYPE_DELETED, 3, ""},
+	}
+	var received []*postsv1.PostEvent
+	for _, e := range expected {
+		event, err := stream.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if event.GetType() != e.eventType || event.GetPostId() != 2 || event.GetVersion() != e.version || event.GetPost().GetTitle() != e.title {
+			t.Errorf("expected %v event, but got %v", e, event)
+		}
+		if event.GetEpoch() != app.Events.Epoch() {
+			t.Errorf("expected epoch %d, but got %d", app.Events.Epoch(), event.GetEpoch())
+		}
+		received = append(received, event)
+	}
+
+	// watch is resumed after events of the same epoch, events of other epochs are reported as missed
+	for _, epoch := range []uint64{app.Events.Epoch(), app.Events.Epoch() + 1} {
+		resumed, err := client.WatchPosts(ctx, &postsv1.WatchPostsRequest{Ids: []int64{2}, Epoch: epoch, LastEventId: received[0].GetId()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		header, err := resumed.Header()
+		if err != nil {
+			t.Fatal(err)
+		}
+		missed := len(header.Get(missedEventsHeader)) > 0
+		if missed != (epoch != app.Events.Epoch()) {
+			t.Errorf("epoch %d: expected missed events header %v, but got %v", epoch, !missed, missed)
+		}
+		if !missed {
+			if event, err := resumed.Recv(); err != nil || event.GetId() != received[1].GetId() {
+				t.Errorf("expected replayed event %v, but got %v, %v", received[1], event, err)
+			}
+		}
+	}
+
+	// stream is finished when service stops
+	app.Events.Close()
+	if _, err := stream.Recv(); status.Code(err) != codes.Unavailable {
+		t.Errorf("expected unavailable watch, but got %v", err)
+	}
+}
+
+// TestGRPC_HealthAndReflection tests health service and reflection service enabled by configuration
+func TestGRPC_HealthAndReflection(t *testing.T) {
+	t.Parallel()
+
+	for _, enabled := range []bool{false, true} {
+		app, _ := newTestTransferApp(t, 0)
+		app.GRPCReflection = enabled
+		conn := startTestGRPCServer(t, app)
+
+		health, err := grpc_health_v1.NewHealthClient(conn).Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: "posts.v1.PostService"})
+		if err != nil || health.GetStatus() != grpc_health_v1.HealthCheckResponse_SERVING {
+			t.Errorf("expected serving service, but got %v, %v", health, err)
+		}
+
+		stream, err := grpc_reflection_v1.NewServerReflectionClient(conn).ServerReflectionInfo(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = stream.Send(&grpc_reflection_v1.ServerReflectionRequest{
+			MessageRequest: &grpc_reflection_v1.ServerReflectionRequest_ListServices{},
+		})
+		response, err := stream.Recv()
+		if !enabled {
+			if status.Code(err) != codes.Unimplemented {
+				t.Errorf("expected reflection disabled by default, but got %v", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		services := make(map[string]bool)
+		for _, service := range response.GetListServicesResponse().GetService() {
+			services[service.GetName()] = true
+		}
+		if !services["posts.v1.PostService"] || !services["grpc.health.v1.Health"] {
+			t.Errorf("expected post and health services, but got %v", services)
+		}
+	}
+}
+
+// TestGRPC_Interceptors tests rate limits, metrics and tracing of calls
+func TestGRPC_Interceptors(t *testing.T) {
+	t.Parallel()
+	recorder := tracetest.NewSpanRecorder()
+	app, _ := newTestTransferApp(t, 1)
+	app.Limiter = ratelimit.NewMemoryLimiter()
+	app.RateLimits = RateLimits{
+		Read:  ratelimit.Limit{Burst: 1, Rate: 0.1},
+		Write: ratelimit.Limit{Burst: 1, Rate: 0.1},
+	}
+	app.Metrics = metrics.New()
+	app.Tracer = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	client := postsv1.NewPostServiceClient(startTestGRPCServer(t, app))
+
+	// reads and writes have separate buckets, invalid tokens do not get a new bucket
+	var header metadata.MD
+	if _, err := client.GetPost(context.Background(), &postsv1.GetPostRequest{Id: 1}, grpc.Header(&header)); err != nil {
+		t.Fatal(err)
+	}
+	if len(header.Get("x-trace-id")) != 1 {
+		t.Errorf("expected trace id in header, but got %v", header)
+	}
+	_, err := client.GetPost(withToken("invalid"), &postsv1.GetPostRequest{Id: 1}, grpc.Header(&header))
+	if status.Code(err) != codes.ResourceExhausted || len(header.Get("retry-after")) != 1 {
+		t.Errorf("expected codes.ResourceExhausted with retry-after, but got %v %v", err, header)
+	}
+	if _, err := client.DeletePost(withToken("author-token"), &postsv1.DeletePostRequest{Id: 1}); err != nil {
+		t.Errorf("expected write allowed, but got %v", err)
+	}
+
+	// calls are counted by method and code
+	req, _ := http.NewRequest("GET", "/metrics", nil)
+	rr := httptest.NewRecorder()
+	app.Metrics.Handler().ServeHTTP(rr, req)
+	for _, line := range []string{
+		`api_grpc_requests_total{code="OK",method="/posts.v1.PostService/GetPost"} 1`,
+		`api_grpc_requests_total{code="ResourceExhausted",method="/posts.v1.PostService/GetPost"} 1`,
+		`api_grpc_requests_total{code="OK",method="/posts.v1.PostService/DeletePost"} 1`,
+	} {
+		if !strings.Contains(rr.Body.String(), line) {
+			t.Errorf("expected %s in metrics", line)
+		}
+	}
+
+	// server spans are named by method
+	spans := recorder.Ended()
+	if len(spans) == 0 || spans[0].Name() != "posts.v1.PostService/GetPost" || spans[0].SpanKind() != trace.SpanKindServer {
+		t.Errorf("unexpected spans %v", spans)
+	}
+}
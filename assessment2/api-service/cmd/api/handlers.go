@@ -1,10 +1,14 @@
 package main
 
 import (
+	"api-service/internal/auth"
 	"api-service/internal/domain"
+	"api-service/internal/logging"
+	"api-service/internal/render"
 	"api-service/internal/server"
 	"context"
 	"errors"
+	"log/slog"
 	"net/http"
 	"strconv"
 	"time"
@@ -12,12 +16,35 @@ import (
 	"github.com/go-chi/chi/v5"
 )
 
+// JsonPostPayload represent post input data, it is decoded from any of supported request formats,
+// posts are created as plain text and keep their format on update when format is omitted
 type JsonPostPayload struct {
-	Title   string `json:"title"`
-	Content string `json:"content"`
-	Author  string `json:"author"`
+	Title   string `json:"title" xml:"title"`
+	Content string `json:"content" xml:"content"`
+	Author  string `json:"author" xml:"author"`
+	Format  string `json:"format,omitempty" xml:"format,omitempty"`
 }
 
+// JsonRenderedPost represent post with content rendered as sanitized HTML, fields are named as fields of post
+type JsonRenderedPost struct {
+	ID          int
+	Title       string
+	Content     string
+	Author      string
+	Owner       string
+	Version     int
+	Format      string
+	HTML        string
+	Excerpt     string
+	ReadingTime int // minutes
+}
+
+// RenderHTML is a value of render query parameter requesting rendered content
+const RenderHTML = "html"
+
+// ErrorRender is returned when unknown rendering is requested
+var ErrorRender = errors.New("render must be html")
+
 // DefaultPage and DefaultLimit are default pagination parameters
 const DefaultPage = 1
 const DefaultLimit = 5
@@ -38,163 +65,208 @@ func (app *App) PostsGetHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// fetch posts from store
-	ctx, cancel := context.WithTimeout(context.Background(), app.WebServer.Timeout*time.Second)
+	presenter := app.presenter(r)
+	ctx, cancel := context.WithTimeout(r.Context(), app.WebServer.Timeout*time.Second)
 	defer cancel()
 	posts, err := app.PostStore.Get(ctx, titleParam, int(page), int(limit))
 	if err != nil {
-		app.WebServer.ErrorJSON(w, err, http.StatusBadRequest)
+		logging.FromContext(r.Context()).Warn("failed to fetch posts", slog.Any("error", err))
+		presenter.Error(w, r, err, http.StatusBadRequest)
 		return
 	}
 
-	// return successful json response with list of posts
-	response := server.JsonResponse{
-		Error:   false,
-		Message: "",
-		Data:    posts,
+	// look up the first post of the next page, when presenter reports pagination metadata
+	pagination := JsonPageV2{Page: int(page), Limit: int(limit)}
+	if presenter.Pagination() && len(*posts) == int(limit) {
+		next, err := app.PostStore.Get(ctx, titleParam, int(page*limit)+1, 1)
+		if err != nil {
+			logging.FromContext(r.Context()).Warn("failed to fetch posts", slog.Any("error", err))
+			presenter.Error(w, r, err, http.StatusBadRequest)
+			return
+		}
+		pagination.HasMore = len(*next) > 0
 	}
-	_ = app.WebServer.WriteJSON(w, http.StatusOK, response)
+
+	// return successful response with list of posts
+	presenter.Posts(w, r, *posts, pagination)
 }
 
 // PostsGetOneHandler is an endpoint handler for specific post
 func (app *App) PostsGetOneHandler(w http.ResponseWriter, r *http.Request) {
 	// get post id from URL params
+	presenter := app.presenter(r)
 	idParam := chi.URLParam(r, "id")
 	id, err := strconv.ParseInt(idParam, 10, 32)
 	if err != nil {
-		app.WebServer.ErrorJSON(w, err, http.StatusBadRequest)
+		presenter.Error(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	renderParam := r.URL.Query().Get("render")
+	if renderParam != "" && renderParam != RenderHTML {
+		presenter.Error(w, r, ErrorRender, http.StatusBadRequest)
 		return
 	}
 
 	// fetch posts from store
-	ctx, cancel := context.WithTimeout(context.Background(), app.WebServer.Timeout*time.Second)
+	ctx, cancel := context.WithTimeout(r.Context(), app.WebServer.Timeout*time.Second)
 	defer cancel()
 	post, err := app.PostStore.GetOne(ctx, int(id))
 	if err != nil {
+		logging.FromContext(r.Context()).Warn("failed to fetch post", slog.Any("error", err))
 		if errors.Is(err, domain.ErrorPostNotFound) {
-			app.WebServer.ErrorJSON(w, err, http.StatusNotFound)
+			presenter.Error(w, r, err, http.StatusNotFound)
 			return
 		}
-		app.WebServer.ErrorJSON(w, err, http.StatusBadRequest)
+		presenter.Error(w, r, err, http.StatusBadRequest)
 		return
 	}
 
-	// return successful json response with list of posts
-	response := server.JsonResponse{
-		Error:   false,
-		Message: "",
-		Data:    post,
+	// return successful response with the post, content is rendered once per version of the post
+	var rendered *render.Result
+	if renderParam == RenderHTML {
+		rendered = app.render(post)
 	}
-	_ = app.WebServer.WriteJSON(w, http.StatusOK, response)
+	presenter.Post(w, r, post, rendered)
+}
+
+// render renders content of post, results are cached when render cache is configured
+func (app *App) render(post *domain.Post) *render.Result {
+	if app.Renderer != nil {
+		result := app.Renderer.Render(post)
+		return &result
+	}
+	result := render.Render(post.Format, post.Content)
+	return &result
 }
 
 // PostsAddHandler is an endpoint handler for add new post
 func (app *App) PostsAddHandler(w http.ResponseWriter, r *http.Request) {
 	// read json input
+	presenter := app.presenter(r)
 	var jsonPayload JsonPostPayload
-	err := app.WebServer.ReadJSON(w, r, &jsonPayload)
+	err := app.WebServer.Read(w, r, &jsonPayload)
 	if err != nil {
-		app.WebServer.ErrorJSON(w, err, http.StatusBadRequest)
+		presenter.Error(w, r, err, server.ReadErrorStatus(err))
 		return
 	}
 
-	// construct domain object from input data
+	// construct domain object from input data, post is owned by its creator
 	post := domain.Post{
 		Title:   jsonPayload.Title,
 		Content: jsonPayload.Content,
 		Author:  jsonPayload.Author,
+		Format:  jsonPayload.Format,
+	}
+	if principal, ok := auth.PrincipalFromContext(r.Context()); ok {
+		post.Owner = principal.ID
 	}
 
 	// create context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), app.WebServer.Timeout*time.Second)
+	ctx, cancel := context.WithTimeout(r.Context(), app.WebServer.Timeout*time.Second)
 	defer cancel()
 
 	// save post document to store
 	id, err := app.PostStore.Insert(ctx, post)
 	if err != nil {
-		app.WebServer.ErrorJSON(w, err, http.StatusBadRequest)
+		logging.FromContext(r.Context()).Warn("failed to add post", slog.Any("error", err))
+		presenter.Error(w, r, err, http.StatusBadRequest)
 		return
 	}
 
-	// return successful json response
-	response := server.JsonResponse{
-		Error:   false,
-		Message: "post added",
-		Data:    id,
-	}
-	_ = app.WebServer.WriteJSON(w, http.StatusOK, response)
+	// return successful response
+	presenter.Created(w, r, id)
 }
 
 // PostsUpdateHandler is an endpoint handler for update existing post
 func (app *App) PostsUpdateHandler(w http.ResponseWriter, r *http.Request) {
 	// get post id from URL params
+	presenter := app.presenter(r)
 	idParam := chi.URLParam(r, "id")
 	id, err := strconv.ParseInt(idParam, 10, 32)
 	if err != nil {
-		app.WebServer.ErrorJSON(w, err, http.StatusBadRequest)
+		presenter.Error(w, r, err, http.StatusBadRequest)
 		return
 	}
 
 	// read json input
 	var jsonPayload JsonPostPayload
-	err = app.WebServer.ReadJSON(w, r, &jsonPayload)
+	err = app.WebServer.Read(w, r, &jsonPayload)
+	if err != nil {
+		presenter.Error(w, r, err, server.ReadErrorStatus(err))
+		return
+	}
+
+	// update post document in store
+	err = app.updatePost(r.Context(), int(id), jsonPayload)
 	if err != nil {
-		app.WebServer.ErrorJSON(w, err, http.StatusBadRequest)
+		presenter.Error(w, r, err, storeErrorStatus(err))
 		return
 	}
 
+	// return successful response
+	presenter.Updated(w, r, int(id))
+}
+
+// storeErrorStatus returns HTTP status code of failed change of post, post could be deleted after its access
+// is authorized; store timeouts are reported as unavailable service and other errors as invalid input
+func storeErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrorPostNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// updatePost updates post with input data, it is shared by HTTP and WebSocket APIs
+func (app *App) updatePost(ctx context.Context, id int, jsonPayload JsonPostPayload) error {
 	// construct domain object from input data
 	post := domain.Post{
 		Title:   jsonPayload.Title,
 		Content: jsonPayload.Content,
 		Author:  jsonPayload.Author,
+		Format:  jsonPayload.Format,
 	}
 
 	// create context with deadline
-	ctx, cancel := context.WithTimeout(context.Background(), app.WebServer.Timeout*time.Second)
+	storeCtx, cancel := context.WithTimeout(ctx, app.WebServer.Timeout*time.Second)
 	defer cancel()
 
 	// update post document in store
-	err = app.PostStore.Update(ctx, int(id), post)
+	err := app.PostStore.Update(storeCtx, id, post)
 	if err != nil {
-		app.WebServer.ErrorJSON(w, err, http.StatusBadRequest)
-		return
+		logging.FromContext(ctx).Warn("failed to update post", slog.Any("error", err))
+		return err
 	}
-
-	// return successful json response
-	response := server.JsonResponse{
-		Error:   false,
-		Message: "post updated",
-		Data:    nil,
-	}
-	_ = app.WebServer.WriteJSON(w, http.StatusOK, response)
+	return nil
 }
 
 // PostsDeleteHandler is an endpoint handler for delete existing post
 func (app *App) PostsDeleteHandler(w http.ResponseWriter, r *http.Request) {
 	// get post id from URL params
+	presenter := app.presenter(r)
 	idParam := chi.URLParam(r, "id")
 	id, err := strconv.ParseInt(idParam, 10, 32)
 	if err != nil {
-		app.WebServer.ErrorJSON(w, err, http.StatusBadRequest)
+		presenter.Error(w, r, err, http.StatusBadRequest)
 		return
 	}
 
 	// create context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), app.WebServer.Timeout*time.Second)
+	ctx, cancel := context.WithTimeout(r.Context(), app.WebServer.Timeout*time.Second)
 	defer cancel()
 
 	// delete post document from store
 	err = app.PostStore.Delete(ctx, int(id))
 	if err != nil {
-		app.WebServer.ErrorJSON(w, err, http.StatusBadRequest)
+		logging.FromContext(r.Context()).Warn("failed to delete post", slog.Any("error", err))
+		presenter.Error(w, r, err, storeErrorStatus(err))
 		return
 	}
 
-	// return successful json response
-	response := server.JsonResponse{
-		Error:   false,
-		Message: "post deleted",
-		Data:    nil,
-	}
-	_ = app.WebServer.WriteJSON(w, http.StatusOK, response)
+	// return successful response
+	presenter.Deleted(w, r, int(id))
 }
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -71,7 +72,7 @@ func TestHandlers_PostsGet(t *testing.T) {
 		handler := http.HandlerFunc(app.PostsGetHandler)
 		handler.ServeHTTP(rr, req)
 
-		jsonPosts, _ := json.Marshal(posts)
+		jsonPosts, _ := json.Marshal([]JsonPostV1{newJsonPostV1(&testPost)})
 		expectedBody := fmt.Sprintf("{\"error\":false,\"message\":\"\",\"data\":%s}", string(jsonPosts))
 		if rr.Code != http.StatusOK {
 			t.Errorf("expected http.StatusOK, but got %d", rr.Code)
@@ -103,7 +104,7 @@ func TestHandlers_PostsGetOne(t *testing.T) {
 		handler := http.HandlerFunc(app.PostsGetOneHandler)
 		handler.ServeHTTP(rr, req)
 
-		jsonPost, _ := json.Marshal(testPost)
+		jsonPost, _ := json.Marshal(newJsonPostV1(&testPost))
 		expectedBody := fmt.Sprintf("{\"error\":false,\"message\":\"\",\"data\":%s}", string(jsonPost))
 		if rr.Code != http.StatusOK {
 			t.Errorf("expected http.StatusOK, but got %d", rr.Code)
@@ -185,6 +186,41 @@ func TestHandlers_PostsUpdate(t *testing.T) {
 			t.Errorf("incorrect response body, got %s", rr.Body.String())
 		}
 	})
+
+	// errors of store are mapped to statuses, post could be deleted after its access is authorized
+	failures := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{"not found", domain.ErrorPostNotFound, http.StatusNotFound},
+		{"timeout", fmt.Errorf("update: %w", context.DeadlineExceeded), http.StatusServiceUnavailable},
+		{"invalid format", domain.ErrorInvalidFormat, http.StatusBadRequest},
+	}
+	for _, tt := range failures {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			fixture := newHandlersFixture(t)
+			app := newTestApp(fixture)
+
+			fixture.store.EXPECT().
+				Update(gomock.Any(), testId, gomock.Any()).
+				Return(tt.err)
+
+			ctx := chi.NewRouteContext()
+			ctx.URLParams.Add("id", fmt.Sprintf("%d", testId))
+			req, _ := http.NewRequest("PUT", "/v1/posts/{id}", strings.NewReader(`{"title":"Title"}`))
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, ctx))
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(app.PostsUpdateHandler)
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expected {
+				t.Errorf("expected %d, but got %d", tt.expected, rr.Code)
+			}
+		})
+	}
 }
 
 // TestHandlers_PostsDelete tests PostsDelete endpoint
@@ -217,3 +253,141 @@ func TestHandlers_PostsDelete(t *testing.T) {
 		}
 	})
 }
+
+// TestHandlers_ContentNegotiation tests request and response formats negotiated by headers
+func TestHandlers_ContentNegotiation(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		method       string
+		contentType  string
+		accept       string
+		body         string
+		expected     int
+		expectedBody string
+	}{
+		{"csv list", "GET", "", "text/csv", "", http.StatusOK, "ID,Title,Content,Author,Owner,Version\n42,Title 123,Ipsum non tempora magnam neque tempora,Author 456,,0\n"},
+		{"not acceptable", "GET", "", "image/png", "", http.StatusNotAcceptable, "{\"error\":true,\"message\":\"response format is not acceptable\"}"},
+		{"xml body", "POST", "application/xml", "application/json", "<post><title>Title 123</title><content>Ipsum non tempora magnam neque tempora</content><author>Author 456</author></post>", http.StatusOK, "{\"error\":false,\"message\":\"post added\",\"data\":42}"},
+		{"csv body", "POST", "text/csv", "application/json", "title,content,author\nTitle 123,Ipsum non tempora magnam neque tempora,Author 456\n", http.StatusOK, "{\"error\":false,\"message\":\"post added\",\"data\":42}"},
+		{"unsupported body", "POST", "text/plain", "", "title", http.StatusUnsupportedMediaType, "{\"error\":true,\"message\":\"unsupported media type\"}"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			fixture := newHandlersFixture(t)
+			app := newTestAuthApp(t, fixture)
+
+			post := testPost
+			post.ID = testId
+			posts := []domain.Post{post}
+			fixture.store.EXPECT().Get(gomock.Any(), "", 1, 5).Return(&posts, nil).AnyTimes()
+			fixture.store.EXPECT().
+				Insert(gomock.Any(), domain.Post{Title: testPost.Title, Content: testPost.Content, Author: testPost.Author, Owner: "author-1"}).
+				Return(testId, nil).AnyTimes()
+
+			req, _ := http.NewRequest(tt.method, "/v1/posts", bytes.NewReader([]byte(tt.body)))
+			req.Header.Set("Authorization", "Bearer author-token")
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rr := httptest.NewRecorder()
+			app.routes().ServeHTTP(rr, req)
+
+			if rr.Code != tt.expected {
+				t.Errorf("expected status %d, but got %d", tt.expected, rr.Code)
+			}
+			if rr.Body.String() != tt.expectedBody {
+				t.Errorf("incorrect response body, got %s", rr.Body.String())
+			}
+		})
+	}
+}
+
+// TestHandlers_Render tests posts rendered as sanitized HTML by both API versions
+func TestHandlers_Render(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+		expectedBody   string // %[1]s is replaced by creation time of rendered post
+	}{
+		{
+			"v1", "/v1/posts/2?render=html", http.StatusOK,
+			`{"error":false,"message":"","data":{"ID":2,"Title":"Rendered","Content":"# Title\n\n*Hello* world","Author":"Author","Owner":"author-1","Version":1,"Format":"markdown","HTML":"\u003ch1\u003eTitle\u003c/h1\u003e\n\n\u003cp\u003e\u003cem\u003eHello\u003c/em\u003e world\u003c/p\u003e\n","Excerpt":"Title Hello world","ReadingTime":1}}`,
+		},
+		{
+			"v2", "/v2/posts/2?render=html", http.StatusOK,
+			`{"data":{"id":2,"title":"Rendered","content":"# Title\n\n*Hello* world","author":"Author","owner":"author-1","version":1,"format":"markdown","created_at":"%[1]s","updated_at":"%[1]s","rendered":{"html":"\u003ch1\u003eTitle\u003c/h1\u003e\n\n\u003cp\u003e\u003cem\u003eHello\u003c/em\u003e world\u003c/p\u003e\n","excerpt":"Title Hello world","reading_time":1}}}`,
+		},
+		{
+			"plain post", "/v1/posts/1?render=html", http.StatusOK,
+			`{"error":false,"message":"","data":{"ID":1,"Title":"Title 1","Content":"","Author":"","Owner":"author-1","Version":1,"Format":"plain","HTML":"","Excerpt":"","ReadingTime":0}}`,
+		},
+		{
+			"invalid render", "/v1/posts/2?render=pdf", http.StatusBadRequest,
+			`{"error":true,"message":"query parameter render must be one of html"}`,
+		},
+		{
+			"missing post", "/v2/posts/10?render=html", http.StatusNotFound,
+			`{"error":{"status":404,"message":"post not found"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			app, memoryStore := newTestTransferApp(t, 1)
+
+			req, _ := http.NewRequest("POST", "/v1/posts", bytes.NewBufferString(`{"title":"Rendered","content":"# Title\n\n*Hello* world","author":"Author","format":"markdown"}`))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer author-token")
+			rr := httptest.NewRecorder()
+			app.routes().ServeHTTP(rr, req)
+			if rr.Code != http.StatusOK {
+				t.Fatalf("expected http.StatusOK, but got %d: %s", rr.Code, rr.Body.String())
+			}
+
+			req, _ = http.NewRequest("GET", tt.path, nil)
+			rr = httptest.NewRecorder()
+			app.routes().ServeHTTP(rr, req)
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, but got %d", tt.expectedStatus, rr.Code)
+			}
+			expectedBody := tt.expectedBody
+			if strings.Contains(expectedBody, "%[") {
+				post, _ := memoryStore.GetOne(context.Background(), 2)
+				expectedBody = fmt.Sprintf(expectedBody, post.Created.UTC().Truncate(time.Second).Format(time.RFC3339))
+			}
+			if body := strings.TrimSpace(rr.Body.String()); body != expectedBody {
+				t.Errorf("expected body %s, but got %s", expectedBody, body)
+			}
+		})
+	}
+}
+
+// TestHandlers_InvalidFormat tests that posts could not be created in unknown formats
+func TestHandlers_InvalidFormat(t *testing.T) {
+	t.Parallel()
+	app, _ := newTestTransferApp(t, 1)
+
+	req, _ := http.NewRequest("PUT", "/v1/posts/1", bytes.NewBufferString(`{"title":"Title","format":"html"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer author-token")
+	rr := httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, req)
+
+	expectedBody := `{"error":true,"message":"request body /format must be one of plain, markdown"}`
+	if rr.Code != http.StatusBadRequest || strings.TrimSpace(rr.Body.String()) != expectedBody {
+		t.Errorf("expected http.StatusBadRequest, but got %d %s", rr.Code, rr.Body.String())
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"api-service/internal/server"
+	"net/http"
+)
+
+// LivezHandler is an endpoint handler for liveness probe, process is alive while it responds
+func (app *App) LivezHandler(w http.ResponseWriter, r *http.Request) {
+	response := server.JsonResponse{
+		Error:   false,
+		Message: "alive",
+		Data:    nil,
+	}
+	_ = app.WebServer.WriteJSON(w, http.StatusOK, response)
+}
+
+// ReadyzHandler is an endpoint handler for readiness probe, service is ready when it is started,
+// is not draining and all registered components are healthy
+func (app *App) ReadyzHandler(w http.ResponseWriter, r *http.Request) {
+	report := app.Health.Check(r.Context())
+
+	status := http.StatusOK
+	if !report.Ready {
+		status = http.StatusServiceUnavailable
+	}
+	response := server.JsonResponse{
+		Error:   !report.Ready,
+		Message: string(report.State),
+		Data:    report,
+	}
+	_ = app.WebServer.WriteJSON(w, status, response)
+}
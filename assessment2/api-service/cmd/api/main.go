@@ -1,40 +1,181 @@
 package main
 
 import (
+	"api-service/internal/auth"
+	"api-service/internal/cache"
+	"api-service/internal/events"
+	"api-service/internal/gql"
+	"api-service/internal/health"
+	"api-service/internal/idempotency"
+	"api-service/internal/logging"
+	"api-service/internal/metrics"
+	"api-service/internal/openapi"
+	"api-service/internal/outbox"
+	"api-service/internal/ratelimit"
+	"api-service/internal/render"
 	"api-service/internal/server"
 	"api-service/internal/store"
-	"log"
+	"api-service/internal/tracing"
+	"api-service/internal/webhooks"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
 )
 
 type Config struct {
 	HttpPort    string
 	HttpTimeout int
 	StoreInit   string
+	UsersFile   string
+
+	RateLimitRead  int // requests per minute
+	RateLimitWrite int // requests per minute
+	TrustedProxies string
+
+	IdempotencyTTL int // seconds
+
+	LogFormat string
+	LogLevel  string
+
+	MetricsPort string // serve metrics on separate port when specified
+
+	TracingExporter string
+	TracingFile     string
+
+	HealthCacheTTL int // seconds
+	DrainDelay     int // seconds
+
+	AdminPort      string // admin server is started only when port is specified
+	GrpcPort       string // gRPC server is started only when port is specified
+	GrpcReflection bool   // reflection service is registered only when enabled
+	SnapshotFile   string
+
+	CacheSize   int // entries, zero disables caching
+	CacheTTL    int // seconds
+	CacheMaxAge int // seconds, max-age of Cache-Control header
+
+	CompressionMinSize int // bytes, zero disables response compression
+
+	StreamReplaySize int // events kept for resuming of change streams
+	StreamBufferSize int // events buffered per stream client before it is disconnected
+	StreamHeartbeat  int // seconds
+
+	WebhooksFile        string // deliveries are kept in memory only when file is not specified
+	WebhooksWorkers     int
+	WebhooksTimeout     int // seconds
+	WebhooksMaxAttempts int
+	WebhooksBackoff     int    // seconds, delay after the first failed delivery
+	WebhooksNetworks    string // comma separated networks of receivers allowed besides public addresses
+
+	OutboxSinks     string // comma separated sinks of outbox relay: bus, webhooks, log, file
+	OutboxFile      string // file of file sink
+	OutboxBatchSize int
+
+	GraphQLMaxDepth       int    // levels of nested fields, zero disables limit
+	GraphQLMaxComplexity  int    // estimated resolved fields, zero disables limit
+	GraphQLPersistedCache int    // automatically persisted queries, zero disables them
+	GraphQLPersistedFile  string // JSON array of allowed queries, other queries are rejected when specified
+
+	OpenAPIValidation string // off, requests or all
+
+	RenderCacheSize int // rendered posts, zero disables caching
+	RenderCacheTTL  int // seconds
+
+	V1Deprecation string // date of v1 deprecation as YYYY-MM-DD, empty omits Deprecation header
+	V1Sunset      string // date of v1 sunset as YYYY-MM-DD, empty omits Sunset header
 }
 
 type App struct {
+	Config    *Config
+	Logger    *slog.Logger
 	PostStore store.PostStore
 	WebServer server.WebServer
+	Users     *auth.UserStore
+	Policy    *auth.Policy
+
+	Limiter        ratelimit.Limiter
+	RateLimits     RateLimits
+	TrustedProxies server.TrustedProxies
+
+	Idempotency    idempotency.Store
+	IdempotencyTTL time.Duration
+
+	Metrics         *metrics.Metrics
+	MetricsInternal bool // metrics are served on internal port instead of public routes
+
+	GRPCReflection bool // reflection service of gRPC server is registered for clients like grpcurl
+
+	Tracer trace.TracerProvider
+
+	Health *health.Registry
+
+	AdminStore store.PostStore // undecorated store used by admin operations
+
+	Cache       *cache.PostStore
+	CacheMaxAge time.Duration
+
+	CompressionMinSize int
+
+	Events          *events.Broker
+	StreamHeartbeat time.Duration
+
+	Webhooks *webhooks.Dispatcher
+
+	GraphQL *gql.Executor
+
+	Validator         *openapi.Validator // requests are not validated when validator is not set
+	ValidateResponses bool               // responses are validated too, mismatches are replaced by server errors
+
+	Deprecation Deprecation // deprecation of v1 post endpoints
+
+	Renderer *render.Cache // posts are rendered on every request when cache is not set
+}
+
+// RateLimits defines limits applied to read and write requests of a client
+type RateLimits struct {
+	Read  ratelimit.Limit
+	Write ratelimit.Limit
 }
 
 func main() {
-	// get service logger and configuration
-	logger := getLogger()
+	// get service configuration and logger
 	config := getConfig()
+	logger, err := getLogger(config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to create logger:", err)
+		os.Exit(1)
+	}
 
-	logger.Println("starting API service")
+	logger.Info("starting API service")
 
 	if err := do(config, logger); err != nil {
-		logger.Println("finishing service", err)
+		logger.Error("finishing service", slog.Any("error", err))
 		os.Exit(1)
 	}
 
-	logger.Println("finishing API service")
+	logger.Info("finishing API service")
 }
 
-func getLogger() *log.Logger {
-	return log.Default()
+func getLogger(config *Config) (*slog.Logger, error) {
+	logger, err := logging.New(os.Stdout, config.LogFormat, config.LogLevel)
+	if err != nil {
+		return nil, err
+	}
+	slog.SetDefault(logger)
+	return logger, nil
 }
 
 func getConfig() *Config {
@@ -42,29 +183,477 @@ func getConfig() *Config {
 		HttpPort:    os.Getenv("HTTP_PORT"),
 		HttpTimeout: 1, // seconds
 		StoreInit:   os.Getenv("STORE_INIT"),
+		UsersFile:   os.Getenv("USERS_FILE"),
+
+		RateLimitRead:  getEnvInt("RATE_LIMIT_READ", 600),
+		RateLimitWrite: getEnvInt("RATE_LIMIT_WRITE", 60),
+		TrustedProxies: os.Getenv("TRUSTED_PROXIES"),
+
+		IdempotencyTTL: getEnvInt("IDEMPOTENCY_TTL", 86400),
+
+		LogFormat: os.Getenv("LOG_FORMAT"),
+		LogLevel:  os.Getenv("LOG_LEVEL"),
+
+		MetricsPort: os.Getenv("METRICS_PORT"),
+
+		TracingExporter: os.Getenv("TRACING_EXPORTER"),
+		TracingFile:     os.Getenv("TRACING_FILE"),
+
+		HealthCacheTTL: getEnvInt("HEALTH_CACHE_TTL", 0),
+		DrainDelay:     getEnvInt("DRAIN_DELAY", 5),
+
+		AdminPort:      os.Getenv("ADMIN_PORT"),
+		GrpcPort:       os.Getenv("GRPC_PORT"),
+		GrpcReflection: getEnvBool("GRPC_REFLECTION", false),
+		SnapshotFile:   os.Getenv("SNAPSHOT_FILE"),
+
+		CacheSize:   getEnvInt("CACHE_SIZE", 1000),
+		CacheTTL:    getEnvInt("CACHE_TTL", 30),
+		CacheMaxAge: getEnvInt("CACHE_MAX_AGE", 10),
+
+		CompressionMinSize: getEnvInt("COMPRESSION_MIN_SIZE", 1024),
+
+		StreamReplaySize: getEnvInt("STREAM_REPLAY_SIZE", 1000),
+		StreamBufferSize: getEnvInt("STREAM_BUFFER_SIZE", 64),
+		StreamHeartbeat:  getEnvInt("STREAM_HEARTBEAT", 15),
+
+		WebhooksFile:        os.Getenv("WEBHOOKS_FILE"),
+		WebhooksWorkers:     getEnvInt("WEBHOOKS_WORKERS", 4),
+		WebhooksTimeout:     getEnvInt("WEBHOOKS_TIMEOUT", 10),
+		WebhooksMaxAttempts: getEnvInt("WEBHOOKS_MAX_ATTEMPTS", 8),
+		WebhooksBackoff:     getEnvInt("WEBHOOKS_BACKOFF", 5),
+		WebhooksNetworks:    os.Getenv("WEBHOOKS_ALLOWED_NETWORKS"),
+
+		OutboxSinks:     getEnv("OUTBOX_SINKS", "bus,webhooks"),
+		OutboxFile:      os.Getenv("OUTBOX_FILE"),
+		OutboxBatchSize: getEnvInt("OUTBOX_BATCH_SIZE", 100),
+
+		GraphQLMaxDepth:       getEnvInt("GRAPHQL_MAX_DEPTH", 8),
+		GraphQLMaxComplexity:  getEnvInt("GRAPHQL_MAX_COMPLEXITY", 1000),
+		GraphQLPersistedCache: getEnvInt("GRAPHQL_PERSISTED_CACHE", 1000),
+		GraphQLPersistedFile:  os.Getenv("GRAPHQL_PERSISTED_FILE"),
+
+		OpenAPIValidation: getEnv("OPENAPI_VALIDATION", ValidationRequests),
+
+		RenderCacheSize: getEnvInt("RENDER_CACHE_SIZE", 1000),
+		RenderCacheTTL:  getEnvInt("RENDER_CACHE_TTL", 3600),
+
+		V1Deprecation: os.Getenv("V1_DEPRECATION"),
+		V1Sunset:      os.Getenv("V1_SUNSET"),
 	}
 	return &config
 }
 
-func do(config *Config, logger *log.Logger) error {
+// getEnv reads environment variable, falling back to default value when it is not set
+func getEnv(name string, defaultValue string) string {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return defaultValue
+	}
+	return value
+}
+
+// getOutboxSinks creates sinks of outbox relay listed in configuration, bus sink is required
+// as change stream, live updates and gRPC watch get changes from it
+func getOutboxSinks(config *Config, logger *slog.Logger, broker *events.Broker, dispatcher *webhooks.Dispatcher) ([]outbox.Sink, error) {
+	var sinks []outbox.Sink
+	bus := false
+	for _, name := range strings.Split(config.OutboxSinks, ",") {
+		switch strings.TrimSpace(name) {
+		case "":
+		case "bus":
+			bus = true
+			sinks = append(sinks, outbox.NewBusSink(broker))
+		case "webhooks":
+			sinks = append(sinks, outbox.NewWebhookSink(dispatcher))
+		case "log":
+			sinks = append(sinks, outbox.NewLogSink(logger))
+		case "file":
+			if config.OutboxFile == "" {
+				return nil, errors.New("OUTBOX_FILE is required by file sink")
+			}
+			sinks = append(sinks, outbox.NewFileSink(config.OutboxFile))
+		default:
+			return nil, fmt.Errorf("unknown outbox sink %q", name)
+		}
+	}
+	if !bus {
+		return nil, errors.New("OUTBOX_SINKS must include bus sink")
+	}
+	return sinks, nil
+}
+
+// getGraphQLExecutor creates executor of GraphQL schema with configured limits and persisted queries
+func getGraphQLExecutor(config *Config, app *App) (*gql.Executor, error) {
+	schema, err := app.newGraphQLSchema()
+	if err != nil {
+		return nil, err
+	}
+	var allowlist map[string]string
+	if config.GraphQLPersistedFile != "" {
+		allowlist, err = gql.LoadAllowlist(config.GraphQLPersistedFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &gql.Executor{
+		Schema: schema,
+		Limits: gql.Limits{
+			MaxDepth:      config.GraphQLMaxDepth,
+			MaxComplexity: config.GraphQLMaxComplexity,
+		},
+		Persisted: gql.NewPersistedQueries(config.GraphQLPersistedCache, allowlist, allowlist != nil),
+	}, nil
+}
+
+// getRateLimits returns limits of read and write requests, limits must be positive
+func getRateLimits(config *Config) (RateLimits, error) {
+	if config.RateLimitRead < 1 {
+		return RateLimits{}, fmt.Errorf("invalid RATE_LIMIT_READ: %d is not positive", config.RateLimitRead)
+	}
+	if config.RateLimitWrite < 1 {
+		return RateLimits{}, fmt.Errorf("invalid RATE_LIMIT_WRITE: %d is not positive", config.RateLimitWrite)
+	}
+	return RateLimits{
+		Read:  ratelimit.PerMinute(config.RateLimitRead),
+		Write: ratelimit.PerMinute(config.RateLimitWrite),
+	}, nil
+}
+
+// getDeprecation parses deprecation and sunset dates of v1 post endpoints
+func getDeprecation(config *Config) (Deprecation, error) {
+	deprecation := Deprecation{Successor: ApiV2}
+	var err error
+	if config.V1Deprecation != "" {
+		deprecation.Deprecated, err = time.Parse(time.DateOnly, config.V1Deprecation)
+		if err != nil {
+			return deprecation, fmt.Errorf("invalid V1_DEPRECATION: %w", err)
+		}
+	}
+	if config.V1Sunset != "" {
+		deprecation.Sunset, err = time.Parse(time.DateOnly, config.V1Sunset)
+		if err != nil {
+			return deprecation, fmt.Errorf("invalid V1_SUNSET: %w", err)
+		}
+	}
+	return deprecation, nil
+}
+
+// stopGRPCServer waits for calls in progress until context is done, then closes remaining connections
+func stopGRPCServer(ctx context.Context, srv *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		srv.Stop()
+	}
+}
+
+// getEnvInt reads integer environment variable, falling back to default value
+func getEnvInt(name string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(name))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+func getEnvBool(name string, defaultValue bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(name))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+func do(config *Config, logger *slog.Logger) error {
 	// initialize application
-	logger.Println("initializing application")
-	memoryStore, err := store.NewMemoryPostStore(config.StoreInit)
+	logger.Info("initializing application")
+	memoryStore, err := store.NewMemoryPostStore("")
 	if err != nil {
 		return err
 	}
 
-	app := App{
-		PostStore: memoryStore,
-		WebServer: server.NewWebServer(config.HttpPort),
+	// service is not ready until seed data is loaded
+	healthRegistry := health.NewRegistry(time.Duration(config.HealthCacheTTL)*time.Second, time.Duration(config.HttpTimeout)*time.Second)
+	healthRegistry.Register("post_store", memoryStore)
+
+	users, err := auth.NewUserStore(config.UsersFile)
+	if err != nil {
+		return err
+	}
+	if users.Len() == 0 {
+		logger.Warn("no users configured, service is read-only", slog.String("file", config.UsersFile))
+	}
+
+	proxies, err := server.ParseTrustedProxies(config.TrustedProxies)
+	if err != nil {
+		return err
+	}
+
+	deprecation, err := getDeprecation(config)
+	if err != nil {
+		return err
+	}
+	rateLimits, err := getRateLimits(config)
+	if err != nil {
+		return err
+	}
+
+	serviceMetrics := metrics.New()
+	serviceMetrics.RegisterStoreStats(memoryStore)
+
+	tracerProvider, err := tracing.NewProvider(context.Background(), tracing.Config{
+		Exporter: config.TracingExporter,
+		File:     config.TracingFile,
+	})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = tracerProvider.Shutdown(ctx)
+	}()
+
+	// change stream gets changes of posts from outbox
+	broker := events.NewBroker(config.StreamReplaySize, config.StreamBufferSize)
+
+	// changes are delivered to webhook subscriptions from persistent queue
+	webhookStore, err := webhooks.NewFileStore(config.WebhooksFile, webhooks.DefaultRetention)
+	if err != nil {
+		return err
+	}
+	networks, err := webhooks.ParseNetworks(config.WebhooksNetworks)
+	if err != nil {
+		return fmt.Errorf("invalid WEBHOOKS_ALLOWED_NETWORKS: %w", err)
+	}
+	dispatcher := webhooks.NewDispatcher(webhookStore, nil, webhooks.Config{
+		Workers:     config.WebhooksWorkers,
+		Timeout:     time.Duration(config.WebhooksTimeout) * time.Second,
+		MaxAttempts: config.WebhooksMaxAttempts,
+		BaseBackoff: time.Duration(config.WebhooksBackoff) * time.Second,
+		Addresses:   webhooks.AddressPolicy{Allowed: networks},
+	})
+
+	// every change of posts is written to outbox with the change and relayed to sinks
+	sinks, err := getOutboxSinks(config, logger, broker, dispatcher)
+	if err != nil {
+		return err
+	}
+	memoryStore.EnableOutbox()
+	relay := outbox.NewRelay(memoryStore, sinks, outbox.Config{BatchSize: config.OutboxBatchSize})
+	memoryStore.Observe(relay.Observer())
+
+	// decorate store with cache, tracing and metrics
+	var postStore store.PostStore = memoryStore
+	var cacheStore *cache.PostStore
+	if config.CacheSize > 0 {
+		cacheStore = cache.NewPostStore(postStore, config.CacheSize, time.Duration(config.CacheTTL)*time.Second)
+		serviceMetrics.RegisterCacheStats(cacheStore.Stats)
+		postStore = cacheStore
 	}
+	postStore = tracing.NewPostStore(postStore, tracerProvider)
+	postStore = metrics.NewPostStore(postStore, serviceMetrics)
 
-	// start HTTP server
-	logger.Printf("starting http server on port %s\n", config.HttpPort)
-	err = app.WebServer.Serve(app.routes())
+	webServer := server.NewWebServer(config.HttpPort)
+	webServer.Timeout = time.Duration(config.HttpTimeout)
+
+	app := App{
+		Config:    config,
+		Logger:    logger,
+		PostStore: postStore,
+		WebServer: webServer,
+		Users:     users,
+		Policy:    auth.NewPolicy(),
+
+		Limiter:        ratelimit.NewMemoryLimiter(),
+		RateLimits:     rateLimits,
+		TrustedProxies: proxies,
+
+		Idempotency:    idempotency.NewMemoryStore(),
+		IdempotencyTTL: time.Duration(config.IdempotencyTTL) * time.Second,
+
+		Metrics:         serviceMetrics,
+		MetricsInternal: config.MetricsPort != "",
+
+		GRPCReflection: config.GrpcReflection,
+
+		Tracer: tracerProvider,
+
+		Health: healthRegistry,
+
+		AdminStore: memoryStore,
+
+		Cache:       cacheStore,
+		CacheMaxAge: time.Duration(config.CacheMaxAge) * time.Second,
+
+		CompressionMinSize: config.CompressionMinSize,
+
+		Events:          broker,
+		StreamHeartbeat: time.Duration(config.StreamHeartbeat) * time.Second,
+
+		Webhooks: dispatcher,
+
+		Deprecation: deprecation,
+	}
+	if config.RenderCacheSize > 0 {
+		app.Renderer = render.NewCache(config.RenderCacheSize, time.Duration(config.RenderCacheTTL)*time.Second)
+	}
+	app.GraphQL, err = getGraphQLExecutor(config, &app)
 	if err != nil {
 		return err
 	}
+	switch config.OpenAPIValidation {
+	case ValidationOff:
+	case ValidationRequests, ValidationAll:
+		app.Validator = openapi.NewValidator(openAPIDocument())
+		app.ValidateResponses = config.OpenAPIValidation == ValidationAll
+	default:
+		return fmt.Errorf("unknown OpenAPI validation mode %q", config.OpenAPIValidation)
+	}
+
+	// relay outbox and deliver webhooks in background, pending deliveries are resumed after restart
+	workersCtx, stopWorkers := context.WithCancel(logging.WithLogger(context.Background(), logger))
+	defer stopWorkers()
+	var workers sync.WaitGroup
+	workers.Add(3)
+	go func() {
+		defer workers.Done()
+		relay.Run(workersCtx)
+	}()
+	go func() {
+		defer workers.Done()
+		dispatcher.Run(workersCtx)
+	}()
+	go func() {
+		defer workers.Done()
+		webhookStore.Run(workersCtx, webhooks.DefaultFlushInterval)
+	}()
+
+	// gRPC port is bound before HTTP servers start, gRPC server has no readiness probe of its own,
+	// so it is started when service is ready
+	var grpcListener net.Listener
+	if config.GrpcPort != "" {
+		grpcListener, err = net.Listen("tcp", ":"+config.GrpcPort)
+		if err != nil {
+			return err
+		}
+	}
 
-	return nil
+	// start HTTP servers, service is finished when any of servers stops
+	errs := make(chan error, 4)
+	servers := []*server.WebServer{&app.WebServer}
+	if app.MetricsInternal {
+		metricsServer := server.NewWebServer(config.MetricsPort)
+		servers = append(servers, &metricsServer)
+		logger.Info("starting metrics http server", slog.String("port", config.MetricsPort))
+		go func() {
+			errs <- metricsServer.Serve(app.metricsRoutes())
+		}()
+	}
+	if config.AdminPort != "" {
+		adminServer := server.NewWebServer(config.AdminPort)
+		servers = append(servers, &adminServer)
+		logger.Info("starting admin http server", slog.String("port", config.AdminPort))
+		go func() {
+			errs <- adminServer.Serve(app.adminRoutes())
+		}()
+	}
+	logger.Info("starting http server", slog.String("port", config.HttpPort))
+	go func() {
+		errs <- app.WebServer.Serve(app.routes())
+	}()
+
+	// load seed data while readiness probe fails, writes are rejected until service is ready,
+	// so that accepted writes are not replaced by seed data
+	if config.StoreInit != "" {
+		logger.Info("loading seed data", slog.String("file", config.StoreInit))
+		err = memoryStore.Load(config.StoreInit)
+		if err != nil {
+			for _, srv := range servers {
+				_ = srv.Shutdown(context.Background())
+			}
+			if grpcListener != nil {
+				_ = grpcListener.Close()
+			}
+			return err
+		}
+	}
+	healthRegistry.SetState(health.StateReady)
+
+	var grpcServer *grpc.Server
+	var grpcHealth *grpchealth.Server
+	if grpcListener != nil {
+		grpcServer, grpcHealth = app.newGRPCServer()
+		setGRPCServing(grpcHealth, true)
+		logger.Info("starting grpc server", slog.String("port", config.GrpcPort))
+		go func() {
+			errs <- grpcServer.Serve(grpcListener)
+		}()
+	}
+	logger.Info("service is ready")
+
+	// wait for termination signal
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	var serveErr error
+	select {
+	case serveErr = <-errs:
+		logger.Error("server stopped", slog.Any("error", serveErr))
+	case <-ctx.Done():
+	}
+
+	// fail readiness probe and give load balancers time to stop routing requests before shutdown,
+	// remaining servers are stopped without delay when one of them failed
+	healthRegistry.SetState(health.StateDraining)
+	if grpcHealth != nil {
+		setGRPCServing(grpcHealth, false)
+	}
+	if serveErr == nil {
+		logger.Info("draining service", slog.Int("delay", config.DrainDelay))
+		time.Sleep(time.Duration(config.DrainDelay) * time.Second)
+	}
+
+	// streams are never finished by clients, so they are closed before servers are stopped
+	broker.Close()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if grpcServer != nil {
+		stopGRPCServer(shutdownCtx, grpcServer)
+	}
+	// every server is stopped even when some of them fail to stop
+	shutdownErrs := []error{serveErr}
+	for _, srv := range servers {
+		shutdownErrs = append(shutdownErrs, srv.Shutdown(shutdownCtx))
+	}
+
+	// workers are stopped after requests are finished, webhook deliveries in progress are finished
+	// and queued ones are sent after restart
+	stopWorkers()
+	workers.Wait()
+
+	// outbox records written by the last requests are published, records left unpublished are saved with snapshot
+	drainCtx, cancelDrain := context.WithTimeout(logging.WithLogger(context.Background(), logger), 10*time.Second)
+	defer cancelDrain()
+	if err := relay.Drain(drainCtx); err != nil {
+		logger.Error("failed to publish outbox records", slog.Any("error", err))
+	}
+	if err := webhookStore.Flush(); err != nil {
+		shutdownErrs = append(shutdownErrs, fmt.Errorf("webhooks: %w", err))
+	}
+	if config.SnapshotFile != "" {
+		err = memoryStore.Snapshot(drainCtx, config.SnapshotFile)
+		if err != nil {
+			shutdownErrs = append(shutdownErrs, fmt.Errorf("snapshot: %w", err))
+		} else {
+			logger.Info("snapshot saved", slog.String("file", config.SnapshotFile))
+		}
+	}
+	return errors.Join(shutdownErrs...)
 }
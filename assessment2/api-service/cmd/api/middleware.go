@@ -0,0 +1,410 @@
+package main
+
+import (
+	"api-service/internal/auth"
+	"api-service/internal/domain"
+	"api-service/internal/health"
+	"api-service/internal/idempotency"
+	"api-service/internal/logging"
+	"api-service/internal/openapi"
+	"api-service/internal/ratelimit"
+	"api-service/internal/server"
+	"api-service/internal/store"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/gorilla/websocket"
+)
+
+// authenticate resolves bearer token of request into principal stored in request context,
+// requests without token are processed as anonymous
+func (app *App) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := authorizationHeader(r)
+		if header == "" || app.Users == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// parse authorization header
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok {
+			app.WebServer.ErrorJSON(w, r, auth.ErrorUnauthenticated, http.StatusUnauthorized)
+			return
+		}
+		principal, err := app.Users.Authenticate(strings.TrimSpace(token))
+		if err != nil {
+			logging.FromContext(r.Context()).Warn("authentication failed", slog.Any("error", err))
+			app.WebServer.ErrorJSON(w, r, err, http.StatusUnauthorized)
+			return
+		}
+
+		// annotate request logger with authenticated user
+		ctx := auth.WithPrincipal(r.Context(), principal)
+		ctx = logging.WithLogger(ctx, logging.FromContext(ctx).With(slog.String("user_id", principal.ID)))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// ErrorNotReady is returned for writes received while service is starting or seed data is reloaded
+var ErrorNotReady = errors.New("service is not ready to accept writes")
+
+// acceptWrites rejects writes until service is ready, so that they are not replaced by seed data;
+// reads are served, load balancers do not route them until readiness probe succeeds
+func (app *App) acceptWrites(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+		default:
+			if app.Health != nil && app.Health.State() == health.StateStarting {
+				w.Header().Set("Retry-After", "1")
+				app.WebServer.ErrorJSON(w, r, ErrorNotReady, http.StatusServiceUnavailable)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authorizationHeader returns authorization header of request,
+// browsers could not set headers of WebSocket handshake, so token is passed as query parameter
+func authorizationHeader(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	if token := r.URL.Query().Get("access_token"); header == "" && token != "" && websocket.IsWebSocketUpgrade(r) {
+		header = "Bearer " + token
+	}
+	return header
+}
+
+// authorize checks that request principal has permission which is not bound to specific post
+func (app *App) authorize(permission auth.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, _ := auth.PrincipalFromContext(r.Context())
+			err := app.Policy.Authorize(principal, permission, "")
+			if err != nil {
+				app.authorizationError(w, r, err)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// authorizePost checks that request principal has permission over post specified in URL params
+func (app *App) authorizePost(permission auth.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// get post id from URL params
+			idParam := chi.URLParam(r, "id")
+			id, err := strconv.ParseInt(idParam, 10, 32)
+			if err != nil {
+				app.WebServer.ErrorJSON(w, r, err, http.StatusBadRequest)
+				return
+			}
+
+			// fetch post to check its ownership
+			ctx, cancel := context.WithTimeout(r.Context(), app.WebServer.Timeout*time.Second)
+			defer cancel()
+			principal, _ := auth.PrincipalFromContext(r.Context())
+			err = app.authorizePostAccess(ctx, app.PostStore, principal, permission, int(id))
+			if err != nil {
+				app.authorizationError(w, r, err)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// authorizePostAccess evaluates policy for principal over specific post fetched from the store
+func (app *App) authorizePostAccess(ctx context.Context, st store.PostStore, principal *auth.Principal, permission auth.Permission, id int) error {
+	// anonymous users are rejected before the post lookup
+	if principal == nil {
+		return app.Policy.Authorize(principal, permission, "")
+	}
+	post, err := st.GetOne(ctx, id)
+	if err != nil {
+		return err
+	}
+	return app.Policy.Authorize(principal, permission, post.Owner)
+}
+
+// authorizationError writes error response with status matching authorization error
+func (app *App) authorizationError(w http.ResponseWriter, r *http.Request, err error) {
+	app.WebServer.ErrorJSON(w, r, err, authorizationStatus(err))
+}
+
+// authorizationStatus returns HTTP status code matching authorization or post lookup error
+func authorizationStatus(err error) int {
+	switch {
+	case errors.Is(err, auth.ErrorUnauthenticated):
+		return http.StatusUnauthorized
+	case errors.Is(err, auth.ErrorForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, domain.ErrorPostNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// rateLimit limits request rate per client, clients are identified by user or IP address;
+// it runs before authentication, so guessing of tokens is limited as well
+func (app *App) rateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// select bucket and limit according to request kind
+		key, limit := app.rateLimitKey(r), app.RateLimits.Write
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			key, limit = key+":read", app.RateLimits.Read
+		default:
+			key += ":write"
+		}
+
+		// limiter backend failures should not make API unavailable
+		result, err := app.Limiter.Allow(r.Context(), key, limit)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("rate limiter failed", slog.Any("error", err))
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// provide rate limit headers
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+		if !result.Allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			app.WebServer.ErrorJSON(w, r, ratelimit.ErrorRateLimited, http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rateLimitKey identifies request client by authenticated user, clients without valid token are identified
+// by IP address, so they could not get a new bucket by sending other credentials
+func (app *App) rateLimitKey(r *http.Request) string {
+	if principal, ok := auth.PrincipalFromContext(r.Context()); ok {
+		return "user:" + principal.ID
+	}
+	if token, ok := strings.CutPrefix(authorizationHeader(r), "Bearer "); ok && app.Users != nil {
+		if principal, err := app.Users.Authenticate(strings.TrimSpace(token)); err == nil {
+			return "user:" + principal.ID
+		}
+	}
+	return "ip:" + app.TrustedProxies.ClientIP(r)
+}
+
+// ceilSeconds rounds duration up to whole seconds
+func ceilSeconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
+
+// replayedHeaders are stored with idempotent responses, other headers describe the first request (request and trace ids,
+// rate limits) or its transfer (content encoding) and are set again when response is replayed
+var replayedHeaders = []string{"Content-Type", "Location", "Deprecation", "Sunset", "Link"}
+
+// idempotent replays stored response for requests repeated with the same Idempotency-Key header
+func (app *App) idempotent(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" || app.Idempotency == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// keys are scoped by user, anonymous clients share the same scope
+		if principal, ok := auth.PrincipalFromContext(r.Context()); ok {
+			key = principal.ID + ":" + key
+		}
+
+		// read request body to fingerprint it
+		maxBytes := 1048576 // one Mb
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, int64(maxBytes)))
+		if err != nil {
+			app.WebServer.ErrorJSON(w, r, err, http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		sum := sha256.Sum256(append([]byte(r.Method+" "+r.URL.Path+"\n"), body...))
+		fingerprint := hex.EncodeToString(sum[:])
+
+		// serialize concurrent requests with the same key
+		unlock, err := app.Idempotency.Lock(r.Context(), key)
+		if err != nil {
+			app.WebServer.ErrorJSON(w, r, err, http.StatusServiceUnavailable)
+			return
+		}
+		defer unlock()
+
+		// replay stored response
+		record, err := app.Idempotency.Get(r.Context(), key)
+		if err != nil {
+			app.WebServer.ErrorJSON(w, r, err, http.StatusServiceUnavailable)
+			return
+		}
+		if record != nil {
+			if record.Fingerprint != fingerprint {
+				app.WebServer.ErrorJSON(w, r, idempotency.ErrorKeyReused, http.StatusUnprocessableEntity)
+				return
+			}
+			for _, name := range replayedHeaders {
+				if values := record.Response.Header.Values(name); len(values) > 0 {
+					w.Header()[name] = values
+				}
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(record.Response.Status)
+			_, _ = w.Write(record.Response.Body)
+			return
+		}
+
+		// process request capturing its response
+		var buf bytes.Buffer
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		ww.Tee(&buf)
+		next.ServeHTTP(ww, r)
+
+		// server errors are not stored, so the request could be retried
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		if status >= http.StatusInternalServerError {
+			return
+		}
+		header := make(http.Header)
+		for _, name := range replayedHeaders {
+			if values := ww.Header().Values(name); len(values) > 0 {
+				header[name] = slices.Clone(values)
+			}
+		}
+		err = app.Idempotency.Save(r.Context(), key, idempotency.Record{
+			Fingerprint: fingerprint,
+			Response: idempotency.Response{
+				Status: status,
+				Header: header,
+				Body:   buf.Bytes(),
+			},
+			Expires: time.Now().Add(app.IdempotencyTTL),
+		})
+		if err != nil {
+			logging.FromContext(r.Context()).Error("failed to save idempotency record", slog.Any("error", err))
+		}
+	})
+}
+
+// validate rejects requests not matching OpenAPI description before they are processed, when response
+// validation is enabled responses not matching the description are replaced by server errors
+func (app *App) validate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		operation, pathParams, ok := app.Validator.Find(r.Method, r.URL.Path)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// anonymous requests of protected operations are rejected by authorization instead
+		_, authenticated := auth.PrincipalFromContext(r.Context())
+		checked := authenticated || !requiresAuthentication(operation)
+		if err := app.Validator.ValidateParameters(operation, r, pathParams); checked && err != nil {
+			app.WebServer.ErrorJSON(w, r, err, http.StatusBadRequest)
+			return
+		}
+
+		// read request body, it is passed to handler decompressed
+		contentType := r.Header.Get("Content-Type")
+		if checked && app.Validator.BodySchema(operation, contentType) != nil {
+			maxBytes := 1048576 // one Mb
+			reader, err := app.WebServer.Body(w, r, int64(maxBytes))
+			var body []byte
+			if err == nil {
+				body, err = io.ReadAll(reader)
+			}
+			if err != nil {
+				app.WebServer.ErrorJSON(w, r, err, server.ReadErrorStatus(err))
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			r.ContentLength = int64(len(body))
+			r.Header.Del("Content-Encoding")
+			if err := app.Validator.ValidateRequestBody(operation, contentType, body); err != nil {
+				app.WebServer.ErrorJSON(w, r, err, http.StatusBadRequest)
+				return
+			}
+		}
+
+		// streamed responses are sent as they are
+		if !app.ValidateResponses || operation.Streaming {
+			next.ServeHTTP(w, r)
+			return
+		}
+		bw := &bufferedWriter{header: w.Header().Clone()}
+		next.ServeHTTP(bw, r)
+		if bw.status == 0 {
+			bw.status = http.StatusOK
+		}
+		if err := app.Validator.ValidateResponse(operation, bw.status, bw.header, bw.body.Bytes()); err != nil {
+			logging.FromContext(r.Context()).Error("response does not match OpenAPI description",
+				slog.String("operation", operation.OperationID), slog.Int("status", bw.status), slog.Any("error", err))
+			app.WebServer.ErrorJSON(w, r, err, http.StatusInternalServerError)
+			return
+		}
+		for name, values := range bw.header {
+			w.Header()[name] = values
+		}
+		w.WriteHeader(bw.status)
+		_, _ = w.Write(bw.body.Bytes())
+	})
+}
+
+// requiresAuthentication tells whether operation is not allowed to anonymous clients
+func requiresAuthentication(operation *openapi.Operation) bool {
+	if len(operation.Security) == 0 {
+		return false
+	}
+	for _, requirement := range operation.Security {
+		if len(requirement) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// bufferedWriter keeps response in memory until it is validated
+type bufferedWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferedWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *bufferedWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *bufferedWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(b)
+}
@@ -0,0 +1,463 @@
+package main
+
+import (
+	"api-service/internal/auth"
+	"api-service/internal/domain"
+	"api-service/internal/health"
+	"api-service/internal/idempotency"
+	"api-service/internal/logging"
+	"api-service/internal/openapi"
+	"api-service/internal/ratelimit"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+)
+
+const testUsers = `{"users": [
+	{"id": "author-1", "token": "author-token", "role": "author"},
+	{"id": "editor-1", "token": "editor-token", "role": "editor"},
+	{"id": "admin-1", "token": "admin-token", "role": "admin"}
+]}`
+
+func newTestAuthApp(t *testing.T, fixture *handlersFixture) *App {
+	t.Helper()
+
+	usersFile := filepath.Join(t.TempDir(), "users.json")
+	if err := os.WriteFile(usersFile, []byte(testUsers), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	users, err := auth.NewUserStore(usersFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// responses of routes are checked against OpenAPI description too
+	app := newTestApp(fixture)
+	app.Users = users
+	app.Policy = auth.NewPolicy()
+	app.Validator = openapi.NewValidator(openAPIDocument())
+	app.ValidateResponses = true
+	return app
+}
+
+// TestMiddleware_Authorization tests authorization rules applied to post routes
+func TestMiddleware_Authorization(t *testing.T) {
+	t.Parallel()
+
+	ownPost := domain.Post{ID: testId, Title: "Title", Owner: "author-1"}
+	foreignPost := domain.Post{ID: testId, Title: "Title", Owner: "author-2"}
+
+	tests := []struct {
+		name     string
+		method   string
+		token    string
+		post     *domain.Post
+		expected int
+	}{
+		{"anonymous create", "POST", "", nil, http.StatusUnauthorized},
+		{"invalid token", "POST", "unknown-token", nil, http.StatusUnauthorized},
+		{"author update foreign post", "PUT", "author-token", &foreignPost, http.StatusForbidden},
+		{"author delete foreign post", "DELETE", "author-token", &foreignPost, http.StatusForbidden},
+		{"author update own post", "PUT", "author-token", &ownPost, http.StatusOK},
+		{"editor delete foreign post", "DELETE", "editor-token", &foreignPost, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			fixture := newHandlersFixture(t)
+			app := newTestAuthApp(t, fixture)
+
+			path := "/v1/posts"
+			if tt.post != nil {
+				path = "/v1/posts/42"
+				fixture.store.EXPECT().
+					GetOne(gomock.Any(), testId).
+					Return(tt.post, nil)
+			}
+			if tt.expected == http.StatusOK {
+				fixture.store.EXPECT().Update(gomock.Any(), testId, gomock.Any()).Return(nil).AnyTimes()
+				fixture.store.EXPECT().Delete(gomock.Any(), testId).Return(nil).AnyTimes()
+			}
+
+			req, _ := http.NewRequest(tt.method, path, bytes.NewReader([]byte("{}")))
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rr := httptest.NewRecorder()
+			app.routes().ServeHTTP(rr, req)
+
+			if rr.Code != tt.expected {
+				t.Errorf("expected status %d, but got %d: %s", tt.expected, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+// TestMiddleware_RateLimit tests rate limiting of client requests
+func TestMiddleware_RateLimit(t *testing.T) {
+	t.Parallel()
+
+	fixture := newHandlersFixture(t)
+	app := newTestApp(fixture)
+	app.Limiter = ratelimit.NewMemoryLimiter()
+	app.RateLimits = RateLimits{
+		Read:  ratelimit.Limit{Burst: 1, Rate: 0.1},
+		Write: ratelimit.Limit{Burst: 1, Rate: 0.1},
+	}
+
+	posts := []domain.Post{testPost}
+	fixture.store.EXPECT().
+		Get(gomock.Any(), "", 1, 5).
+		Return(&posts, nil)
+
+	send := func(remoteAddr string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/v1/posts", nil)
+		req.RemoteAddr = remoteAddr
+		rr := httptest.NewRecorder()
+		app.routes().ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := send("10.0.0.1:1234")
+	if rr.Code != http.StatusOK || rr.Header().Get("RateLimit-Remaining") != "0" {
+		t.Fatalf("expected allowed request, got %d with remaining %q", rr.Code, rr.Header().Get("RateLimit-Remaining"))
+	}
+
+	rr = send("10.0.0.1:1235")
+	expectedBody := "{\"error\":true,\"message\":\"rate limit exceeded\"}"
+	if rr.Code != http.StatusTooManyRequests {
+		t.Errorf("expected http.StatusTooManyRequests, but got %d", rr.Code)
+	}
+	if rr.Header().Get("Retry-After") != "10" {
+		t.Errorf("expected Retry-After 10, got %q", rr.Header().Get("Retry-After"))
+	}
+	if rr.Body.String() != expectedBody {
+		t.Errorf("incorrect response body, got %s", rr.Body.String())
+	}
+}
+
+// TestMiddleware_AcceptWrites tests that writes are rejected until service is ready and reads are served
+func TestMiddleware_AcceptWrites(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		state        health.State
+		method       string
+		url          string
+		expected     int
+		expectedBody string
+	}{
+		{"v1 write while starting", health.StateStarting, http.MethodPost, "/v1/posts", http.StatusServiceUnavailable,
+			"{\"error\":true,\"message\":\"service is not ready to accept writes\"}"},
+		{"v2 write while starting", health.StateStarting, http.MethodPost, "/v2/posts", http.StatusServiceUnavailable,
+			"{\"error\":{\"status\":503,\"message\":\"service is not ready to accept writes\"}}"},
+		{"read while starting", health.StateStarting, http.MethodGet, "/v1/posts", http.StatusOK, ""},
+		{"write when ready", health.StateReady, http.MethodPost, "/v2/posts", http.StatusCreated, ""},
+		{"write while draining", health.StateDraining, http.MethodPost, "/v2/posts", http.StatusCreated, ""},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			app, memoryStore := newTestTransferApp(t, 1)
+			app.Health = health.NewRegistry(0, 0)
+			app.Health.SetState(tt.state)
+
+			req, _ := http.NewRequest(tt.method, tt.url, strings.NewReader(`{"title":"Title","content":"Content","author":"Author"}`))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer author-token")
+			rr := httptest.NewRecorder()
+			app.routes().ServeHTTP(rr, req)
+
+			if rr.Code != tt.expected {
+				t.Errorf("expected %d, but got %d: %s", tt.expected, rr.Code, rr.Body.String())
+			}
+			if tt.expectedBody != "" && rr.Body.String() != tt.expectedBody {
+				t.Errorf("incorrect response body, got %s", rr.Body.String())
+			}
+			if tt.expected == http.StatusServiceUnavailable {
+				if rr.Header().Get("Retry-After") != "1" {
+					t.Errorf("expected Retry-After 1, got %q", rr.Header().Get("Retry-After"))
+				}
+				if stats, _ := memoryStore.Stats(context.Background()); stats.Posts != 1 {
+					t.Errorf("expected rejected write not to be applied, but store has %d posts", stats.Posts)
+				}
+			}
+		})
+	}
+}
+
+// TestMiddleware_RateLimitKey tests that clients are limited per user or IP address whatever credentials they send
+func TestMiddleware_RateLimitKey(t *testing.T) {
+	t.Parallel()
+
+	fixture := newHandlersFixture(t)
+	app := newTestAuthApp(t, fixture)
+	app.Validator = nil
+	app.Limiter = ratelimit.NewMemoryLimiter()
+	app.RateLimits = RateLimits{
+		Read:  ratelimit.Limit{Burst: 1, Rate: 0.1},
+		Write: ratelimit.Limit{Burst: 1, Rate: 0.1},
+	}
+
+	posts := []domain.Post{testPost}
+	fixture.store.EXPECT().
+		Get(gomock.Any(), "", 1, 5).
+		Return(&posts, nil).
+		AnyTimes()
+
+	// requests are sent in order, every client has a single request in its bucket
+	requests := []struct {
+		name       string
+		remoteAddr string
+		header     string
+		value      string
+		expected   int
+	}{
+		{"api key", "10.0.0.1:1234", "X-API-Key", "key-1", http.StatusOK},
+		{"another api key", "10.0.0.1:1234", "X-API-Key", "key-2", http.StatusTooManyRequests},
+		{"invalid token", "10.0.0.2:1234", "Authorization", "Bearer guess-1", http.StatusUnauthorized},
+		{"another invalid token", "10.0.0.2:1234", "Authorization", "Bearer guess-2", http.StatusTooManyRequests},
+		{"user", "10.0.0.1:1234", "Authorization", "Bearer author-token", http.StatusOK},
+		{"user from another address", "10.0.0.3:1234", "Authorization", "Bearer author-token", http.StatusTooManyRequests},
+	}
+	for _, tt := range requests {
+		req, _ := http.NewRequest("GET", "/v1/posts", nil)
+		req.RemoteAddr = tt.remoteAddr
+		req.Header.Set(tt.header, tt.value)
+		rr := httptest.NewRecorder()
+		app.routes().ServeHTTP(rr, req)
+		if rr.Code != tt.expected {
+			t.Errorf("%s: expected status %d, but got %d", tt.name, tt.expected, rr.Code)
+		}
+	}
+}
+
+// TestMiddleware_Idempotency tests replays of requests with the same Idempotency-Key
+func TestMiddleware_Idempotency(t *testing.T) {
+	t.Parallel()
+
+	fixture := newHandlersFixture(t)
+	app := newTestAuthApp(t, fixture)
+	app.Idempotency = idempotency.NewMemoryStore()
+	app.IdempotencyTTL = time.Minute
+
+	post := testPost
+	post.Owner = "author-1"
+	fixture.store.EXPECT().
+		Insert(gomock.Any(), post).
+		Return(testId, nil).
+		Times(1)
+
+	send := func(key string, payload domain.Post) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(JsonPostPayload{Title: payload.Title, Content: payload.Content, Author: payload.Author})
+		req, _ := http.NewRequest("POST", "/v1/posts", bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer author-token")
+		req.Header.Set("Idempotency-Key", key)
+		rr := httptest.NewRecorder()
+		app.routes().ServeHTTP(rr, req)
+		return rr
+	}
+
+	expectedBody := fmt.Sprintf("{\"error\":false,\"message\":\"post added\",\"data\":%d}", testId)
+	first := send("key-1", testPost)
+	replay := send("key-1", testPost)
+	for _, rr := range []*httptest.ResponseRecorder{first, replay} {
+		if rr.Code != http.StatusOK {
+			t.Errorf("expected http.StatusOK, but got %d", rr.Code)
+		}
+		if rr.Body.String() != expectedBody {
+			t.Errorf("incorrect response body, got %s", rr.Body.String())
+		}
+	}
+	if replay.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("expected replayed response")
+	}
+
+	changed := testPost
+	changed.Title = "Another title"
+	rr := send("key-1", changed)
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected http.StatusUnprocessableEntity, but got %d", rr.Code)
+	}
+}
+
+// TestMiddleware_IdempotencyHeaders tests that replays negotiate their encoding and do not repeat headers of the first request
+func TestMiddleware_IdempotencyHeaders(t *testing.T) {
+	t.Parallel()
+
+	fixture := newHandlersFixture(t)
+	app := newTestAuthApp(t, fixture)
+	app.Idempotency = idempotency.NewMemoryStore()
+	app.IdempotencyTTL = time.Minute
+	app.CompressionMinSize = 1
+	app.ValidateResponses = false // responses are not buffered with default settings
+
+	post := testPost
+	post.Owner = "author-1"
+	fixture.store.EXPECT().
+		Insert(gomock.Any(), post).
+		Return(testId, nil).
+		Times(1)
+
+	send := func(requestID string, acceptEncoding string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(JsonPostPayload{Title: testPost.Title, Content: testPost.Content, Author: testPost.Author})
+		req, _ := http.NewRequest("POST", "/v1/posts", bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer author-token")
+		req.Header.Set("Idempotency-Key", "key-1")
+		req.Header.Set(logging.RequestIDHeader, requestID)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		rr := httptest.NewRecorder()
+		app.routes().ServeHTTP(rr, req)
+		return rr
+	}
+
+	expectedBody := fmt.Sprintf("{\"error\":false,\"message\":\"post added\",\"data\":%d}", testId)
+	tests := []struct {
+		name             string
+		acceptEncoding   string
+		expectedEncoding string
+	}{
+		{"first request", "gzip", "gzip"},
+		{"replay without encoding", "", ""},
+		{"replay with encoding", "gzip", "gzip"},
+	}
+
+	// requests are sent in order, the first one is stored
+	for _, tt := range tests {
+		requestID := strings.ReplaceAll(tt.name, " ", "-")
+		rr := send(requestID, tt.acceptEncoding)
+		if encoding := rr.Header().Get("Content-Encoding"); encoding != tt.expectedEncoding {
+			t.Errorf("%s: expected encoding %q, but got %q", tt.name, tt.expectedEncoding, encoding)
+		}
+		if actual := rr.Header().Get(logging.RequestIDHeader); actual != requestID {
+			t.Errorf("%s: expected request id %q, but got %q", tt.name, requestID, actual)
+		}
+		body := rr.Body.Bytes()
+		if tt.expectedEncoding == "gzip" {
+			reader, err := gzip.NewReader(rr.Body)
+			if err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			body, _ = io.ReadAll(reader)
+		}
+		if string(body) != expectedBody {
+			t.Errorf("%s: incorrect response body, got %q", tt.name, body)
+		}
+	}
+}
+
+// TestMiddleware_Validation tests rejection of requests not matching OpenAPI description
+func TestMiddleware_Validation(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		token        string
+		body         string
+		expected     int
+		expectedBody string
+	}{
+		{"valid request", "GET", "/v1/posts?page=1&limit=1", "", "", http.StatusOK,
+			`{"error":false,"message":"","data":[{"ID":1,"Title":"Title 1","Content":"","Author":"","Owner":"author-1","Version":1,"Format":"plain"}]}`},
+		{"invalid query type", "GET", "/v1/posts?page=abc", "", "", http.StatusBadRequest,
+			`{"error":true,"message":"query parameter page must be integer"}`},
+		{"query below minimum", "GET", "/v1/posts?limit=0", "", "", http.StatusBadRequest,
+			`{"error":true,"message":"query parameter limit must be at least 1"}`},
+		{"invalid path parameter", "GET", "/v1/posts/abc", "", "", http.StatusBadRequest,
+			`{"error":true,"message":"path parameter id must be integer"}`},
+		{"invalid body type", "POST", "/v1/posts", "author-token", `{"title": 1, "content": "", "author": ""}`, http.StatusBadRequest,
+			`{"error":true,"message":"request body /title must be string"}`},
+		{"missing body field", "POST", "/v1/posts", "author-token", `{"title": "", "content": ""}`, http.StatusBadRequest,
+			`{"error":true,"message":"request body /author is required"}`},
+		{"malformed body", "PUT", "/v1/posts/1", "author-token", `{"title": `, http.StatusBadRequest,
+			`{"error":true,"message":"request body is not valid JSON"}`},
+		{"anonymous request is not validated", "POST", "/v1/posts", "", `{"title": 1}`, http.StatusUnauthorized,
+			`{"error":true,"message":"authentication required"}`},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			app, _ := newTestTransferApp(t, 1)
+
+			req, _ := http.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rr := httptest.NewRecorder()
+			app.routes().ServeHTTP(rr, req)
+
+			if rr.Code != tt.expected {
+				t.Errorf("expected status %d, but got %d", tt.expected, rr.Code)
+			}
+			if strings.TrimSpace(rr.Body.String()) != tt.expectedBody {
+				t.Errorf("incorrect response body, got %s", rr.Body.String())
+			}
+		})
+	}
+}
+
+// TestMiddleware_ResponseValidation tests that responses drifted from OpenAPI description are replaced by errors
+func TestMiddleware_ResponseValidation(t *testing.T) {
+	t.Parallel()
+
+	// description expects a field and does not describe missing posts
+	doc := newOpenAPIDocument()
+	post := doc.Components.Schemas["JsonPostV1"]
+	post.Properties["Slug"] = &openapi.Schema{Type: "string"}
+	post.Required = append(post.Required, "Slug")
+	operation, _ := doc.Operation("GET", "/v1/posts/{id}")
+	delete(operation.Responses, "404")
+
+	tests := []struct {
+		name         string
+		path         string
+		expectedBody string
+	}{
+		{"missing field", "/v1/posts/1", `{"error":true,"message":"response body /data/Slug is required"}`},
+		{"undescribed status", "/v1/posts/2", `{"error":true,"message":"response status 404 is not described"}`},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			app, _ := newTestTransferApp(t, 1)
+			app.Validator = openapi.NewValidator(doc)
+
+			req, _ := http.NewRequest("GET", tt.path, nil)
+			rr := httptest.NewRecorder()
+			app.routes().ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusInternalServerError {
+				t.Errorf("expected http.StatusInternalServerError, but got %d", rr.Code)
+			}
+			if strings.TrimSpace(rr.Body.String()) != tt.expectedBody {
+				t.Errorf("incorrect response body, got %s", rr.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,411 @@
+package main
+
+import (
+	"api-service/internal/domain"
+	"api-service/internal/events"
+	"api-service/internal/gql"
+	"api-service/internal/health"
+	"api-service/internal/openapi"
+	"api-service/internal/server"
+	"api-service/internal/store"
+	"api-service/internal/webhooks"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// OpenAPI validation modes
+const (
+	ValidationOff      = "off"
+	ValidationRequests = "requests"
+	ValidationAll      = "all" // responses are validated too, intended for development and tests
+)
+
+// openAPIDocument is a description of API, it is built once on first request
+var openAPIDocument = sync.OnceValue(newOpenAPIDocument)
+
+// openAPISpec is a JSON encoding of API description
+var openAPISpec = sync.OnceValues(func() ([]byte, error) {
+	return json.Marshal(openAPIDocument())
+})
+
+// OpenAPIHandler is an endpoint handler for OpenAPI description of the service
+func (app *App) OpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	spec, err := openAPISpec()
+	if err != nil {
+		app.WebServer.ErrorJSON(w, r, err, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(spec)
+}
+
+// DocsHandler is an endpoint handler for interactive API documentation page
+func (app *App) DocsHandler(w http.ResponseWriter, r *http.Request) {
+	openapi.DocsHandler("Posts API", ApiVersion+"/openapi.json").ServeHTTP(w, r)
+}
+
+// newOpenAPIDocument describes every route of public and admin servers, the description is checked
+// against registered routes by tests
+func newOpenAPIDocument() *openapi.Document {
+	doc := openapi.NewDocument(openapi.Info{
+		Title:       "Posts API",
+		Description: "Blog posts service. Anonymous clients have read only access, changes require bearer token.",
+		Version:     version,
+	})
+	c := doc.Components
+	doc.Servers = []openapi.Server{{URL: "/", Description: "public server (HTTP_PORT)"}}
+	adminServers := []openapi.Server{{URL: "/", Description: "admin server (ADMIN_PORT)"}}
+	doc.Tags = []openapi.Tag{
+		{Name: "posts", Description: "Posts and their changes"},
+		{Name: "transfer", Description: "Bulk export and import of posts"},
+		{Name: "webhooks", Description: "Webhook subscriptions and deliveries"},
+		{Name: "graphql", Description: "GraphQL queries and mutations of posts"},
+		{Name: "service", Description: "Probes, metrics and API description"},
+		{Name: "admin", Description: "Administration endpoints of admin server"},
+	}
+
+	// requests are anonymous unless bearer token is provided
+	c.SecuritySchemes["bearerAuth"] = &openapi.SecurityScheme{Type: "http", Scheme: "bearer", Description: "API token of user"}
+	doc.Security = []openapi.SecurityRequirement{{}, {"bearerAuth": {}}}
+	authenticated := []openapi.SecurityRequirement{{"bearerAuth": {}}}
+
+	// error responses share the envelope of all responses
+	errorSchema := c.SchemaOf(server.JsonResponse{})
+	for _, code := range []int{
+		http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound,
+		http.StatusNotAcceptable, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType,
+		http.StatusUnprocessableEntity, http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusServiceUnavailable,
+	} {
+		c.Responses[strconv.Itoa(code)] = &openapi.Response{
+			Description: http.StatusText(code),
+			Content:     openapi.JSONContent(errorSchema),
+		}
+	}
+	errorResponses := func(codes ...int) map[string]*openapi.Response {
+		responses := make(map[string]*openapi.Response)
+		for _, code := range codes {
+			responses[strconv.Itoa(code)] = &openapi.Response{Ref: "#/components/responses/" + strconv.Itoa(code)}
+		}
+		return responses
+	}
+	// envelope returns schema of response with data of the value
+	envelope := func(data any) *openapi.Schema {
+		if data == nil {
+			return errorSchema
+		}
+		return &openapi.Schema{AllOf: []*openapi.Schema{errorSchema, {
+			Type:       "object",
+			Properties: map[string]*openapi.Schema{"data": c.SchemaOf(data)},
+		}}}
+	}
+	// operation returns operation with successful response of the status and error responses
+	operation := func(id string, tag string, summary string, status int, data any, errorCodes ...int) *openapi.Operation {
+		responses := errorResponses(errorCodes...)
+		responses[strconv.Itoa(status)] = &openapi.Response{Description: http.StatusText(status), Content: openapi.JSONContent(envelope(data))}
+		return &openapi.Operation{OperationID: id, Summary: summary, Tags: []string{tag}, Responses: responses}
+	}
+
+	// posts are accepted and returned in negotiated formats
+	formats := func(schema *openapi.Schema) map[string]openapi.MediaType {
+		content := make(map[string]openapi.MediaType)
+		for _, mediaType := range []string{"application/json", "application/xml", "text/csv", "application/msgpack"} {
+			content[mediaType] = openapi.MediaType{Schema: schema}
+		}
+		return content
+	}
+	negotiated := func(op *openapi.Operation) *openapi.Operation {
+		for code, response := range op.Responses {
+			if response.Content != nil && code[0] == '2' {
+				response.Content = formats(response.Content["application/json"].Schema)
+			}
+		}
+		for code, response := range errorResponses(http.StatusNotAcceptable, http.StatusUnsupportedMediaType) {
+			op.Responses[code] = response
+		}
+		return op
+	}
+	postPayload := c.SchemaOf(JsonPostPayload{})
+	// content formats are validated, posts are created as plain text when format is omitted
+	for _, schema := range []struct {
+		value    any
+		property string
+	}{{JsonPostPayload{}, "format"}, {JsonPostV1{}, "Format"}, {domain.Post{}, "Format"}, {JsonRenderedPost{}, "Format"}, {JsonPostV2{}, "format"}, {JsonPostRecord{}, "format"}} {
+		c.Resolve(c.SchemaOf(schema.value)).Properties[schema.property] = &openapi.Schema{Type: "string", Enum: []any{domain.FormatPlain, domain.FormatMarkdown}}
+	}
+	c.Schemas["JsonPostPatch"] = openapi.Optional(c.Resolve(postPayload))
+	c.Schemas["JsonPostPatch"].Description = "post fields, omitted fields are empty"
+
+	idParam := &openapi.Parameter{Name: "id", In: "path", Required: true, Schema: &openapi.Schema{Type: "integer", Format: "int32"}, Description: "post id"}
+	webhookParam := &openapi.Parameter{Name: "id", In: "path", Required: true, Schema: &openapi.Schema{Type: "string"}, Description: "subscription id"}
+	idempotencyParam := &openapi.Parameter{Name: "Idempotency-Key", In: "header", Schema: &openapi.Schema{Type: "string"}, Description: "repeated requests with the same key are replayed"}
+
+	// service routes
+	op := &openapi.Operation{OperationID: "healthcheck", Summary: "Service healthcheck", Tags: []string{"service"},
+		Responses: map[string]*openapi.Response{"200": {Description: "service is running", Content: map[string]openapi.MediaType{"text/plain": {Schema: &openapi.Schema{Type: "string"}}}}}}
+	doc.Add(http.MethodGet, ApiVersion+"/healthcheck", op)
+	doc.Add(http.MethodGet, "/livez", operation("livez", "service", "Liveness probe", http.StatusOK, nil))
+	op = operation("readyz", "service", "Readiness probe with per component report", http.StatusOK, health.Report{}, http.StatusServiceUnavailable)
+	op.Responses["503"] = &openapi.Response{Description: "service is starting, draining or not healthy", Content: openapi.JSONContent(envelope(health.Report{}))}
+	doc.Add(http.MethodGet, "/readyz", op)
+	doc.Add(http.MethodGet, "/metrics", &openapi.Operation{OperationID: "metrics", Summary: "Prometheus metrics", Tags: []string{"service"},
+		Description: "Served by metrics server when METRICS_PORT is specified",
+		Responses:   map[string]*openapi.Response{"200": {Description: "metrics in text exposition format", Content: map[string]openapi.MediaType{"text/plain": {Schema: &openapi.Schema{Type: "string"}}}}}})
+	doc.Add(http.MethodGet, ApiVersion+"/openapi.json", &openapi.Operation{OperationID: "openapi", Summary: "OpenAPI description of the service", Tags: []string{"service"},
+		Responses: map[string]*openapi.Response{"200": {Description: "OpenAPI 3.1 document", Content: openapi.JSONContent(&openapi.Schema{Type: "object"})}}})
+	doc.Add(http.MethodGet, ApiVersion+"/docs", &openapi.Operation{OperationID: "docs", Summary: "Interactive API documentation", Tags: []string{"service"},
+		Responses: map[string]*openapi.Response{"200": {Description: "documentation page", Content: map[string]openapi.MediaType{"text/html": {Schema: &openapi.Schema{Type: "string"}}}}}})
+
+	// posts routes
+	op = negotiated(operation("listPosts", "posts", "Get filtered and paginated list of posts", http.StatusOK, []JsonPostV1{}, http.StatusBadRequest, http.StatusTooManyRequests))
+	op.Parameters = []*openapi.Parameter{
+		{Name: "title", In: "query", Schema: &openapi.Schema{Type: "string"}, Description: "case insensitive filter by title"},
+		{Name: "page", In: "query", Schema: &openapi.Schema{Type: "integer", Format: "int32", Minimum: openapi.Float(1), Default: DefaultPage}},
+		{Name: "limit", In: "query", Schema: &openapi.Schema{Type: "integer", Format: "int32", Minimum: openapi.Float(1), Default: DefaultLimit}},
+	}
+	doc.Add(http.MethodGet, ApiVersion+"/posts", op)
+	renderParam := &openapi.Parameter{Name: "render", In: "query", Schema: &openapi.Schema{Type: "string", Enum: []any{RenderHTML}},
+		Description: "include content rendered as sanitized HTML, excerpt and reading time"}
+	op = operation("getPost", "posts", "Get post", http.StatusOK, nil, http.StatusBadRequest, http.StatusNotFound, http.StatusTooManyRequests)
+	op.Responses["200"].Content = openapi.JSONContent(&openapi.Schema{AllOf: []*openapi.Schema{errorSchema, {
+		Type:       "object",
+		Properties: map[string]*openapi.Schema{"data": {AnyOf: []*openapi.Schema{c.SchemaOf(JsonRenderedPost{}), c.SchemaOf(JsonPostV1{})}}},
+	}}})
+	op = negotiated(op)
+	op.Parameters = []*openapi.Parameter{idParam, renderParam}
+	doc.Add(http.MethodGet, ApiVersion+"/posts/{id}", op)
+	op = negotiated(operation("createPost", "posts", "Add a new post owned by its creator", http.StatusOK, 0,
+		http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity, http.StatusTooManyRequests))
+	op.Parameters = []*openapi.Parameter{idempotencyParam}
+	op.RequestBody = &openapi.RequestBody{Required: true, Content: formats(postPayload)}
+	op.Security = authenticated
+	doc.Add(http.MethodPost, ApiVersion+"/posts", op)
+	op = negotiated(operation("updatePost", "posts", "Update post", http.StatusOK, nil,
+		http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusRequestEntityTooLarge, http.StatusTooManyRequests))
+	op.Parameters = []*openapi.Parameter{idParam}
+	op.RequestBody = &openapi.RequestBody{Required: true, Content: formats(openapi.Ref("JsonPostPatch"))}
+	op.Security = authenticated
+	doc.Add(http.MethodPut, ApiVersion+"/posts/{id}", op)
+	op = negotiated(operation("deletePost", "posts", "Delete post", http.StatusOK, nil,
+		http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusTooManyRequests))
+	op.Parameters = []*openapi.Parameter{idParam}
+	op.Security = authenticated
+	doc.Add(http.MethodDelete, ApiVersion+"/posts/{id}", op)
+	// v1 post routes are succeeded by v2 ones
+	deprecationHeaders := map[string]*openapi.Header{
+		"Deprecation": {Description: "time of deprecation as @ and Unix time", Schema: &openapi.Schema{Type: "string"}},
+		"Sunset":      {Description: "time after which the route is not served", Schema: &openapi.Schema{Type: "string"}},
+		"Link":        {Description: "route of successor version with successor-version relation", Schema: &openapi.Schema{Type: "string"}},
+	}
+	for _, route := range []struct{ method, path string }{
+		{http.MethodGet, "/posts"}, {http.MethodGet, "/posts/{id}"}, {http.MethodPost, "/posts"},
+		{http.MethodPut, "/posts/{id}"}, {http.MethodDelete, "/posts/{id}"},
+	} {
+		op, _ := doc.Operation(route.method, ApiVersion+route.path)
+		op.Deprecated = true
+		op.Description = "Deprecated in favor of " + route.method + " " + ApiV2 + route.path
+		op.Responses["200"].Headers = deprecationHeaders
+	}
+
+	// v2 post routes respond with JSON envelope without error flag, other formats are not acceptable
+	errorSchemaV2 := c.SchemaOf(JsonErrorResponseV2{})
+	operationV2 := func(id string, summary string, status int, data *openapi.Schema, errorCodes ...int) *openapi.Operation {
+		responses := make(map[string]*openapi.Response)
+		for _, code := range append(errorCodes, http.StatusNotAcceptable) {
+			responses[strconv.Itoa(code)] = &openapi.Response{Description: http.StatusText(code), Content: openapi.JSONContent(errorSchemaV2)}
+		}
+		responses[strconv.Itoa(status)] = &openapi.Response{Description: http.StatusText(status)}
+		if data != nil {
+			envelope := &openapi.Schema{Type: "object", Required: []string{"data"}, Properties: map[string]*openapi.Schema{"data": data}}
+			responses[strconv.Itoa(status)].Content = openapi.JSONContent(envelope)
+		}
+		return &openapi.Operation{OperationID: id, Summary: summary, Tags: []string{"posts"}, Responses: responses}
+	}
+	postSchemaV2 := c.SchemaOf(JsonPostV2{})
+	op = operationV2("listPostsV2", "Get filtered and paginated list of posts with pagination metadata", http.StatusOK,
+		&openapi.Schema{Type: "array", Items: postSchemaV2}, http.StatusBadRequest, http.StatusTooManyRequests)
+	listPosts, _ := doc.Operation(http.MethodGet, ApiVersion+"/posts")
+	op.Parameters = listPosts.Parameters
+	listSchemaV2 := op.Responses["200"].Content["application/json"].Schema
+	listSchemaV2.Properties["meta"] = c.SchemaOf(JsonPageV2{})
+	listSchemaV2.Required = append(listSchemaV2.Required, "meta")
+	doc.Add(http.MethodGet, ApiV2+"/posts", op)
+	op = operationV2("getPostV2", "Get post", http.StatusOK, postSchemaV2, http.StatusBadRequest, http.StatusNotFound, http.StatusTooManyRequests)
+	op.Parameters = []*openapi.Parameter{idParam, renderParam}
+	doc.Add(http.MethodGet, ApiV2+"/posts/{id}", op)
+	op = operationV2("createPostV2", "Add a new post owned by its creator", http.StatusCreated, c.SchemaOf(JsonCreatedV2{}),
+		http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType, http.StatusUnprocessableEntity, http.StatusTooManyRequests)
+	op.Responses["201"].Headers = map[string]*openapi.Header{"Location": {Description: "route of created post", Schema: &openapi.Schema{Type: "string"}}}
+	op.Parameters = []*openapi.Parameter{idempotencyParam}
+	op.RequestBody = &openapi.RequestBody{Required: true, Content: openapi.JSONContent(postPayload)}
+	op.Security = authenticated
+	doc.Add(http.MethodPost, ApiV2+"/posts", op)
+	op = operationV2("updatePostV2", "Update post", http.StatusNoContent, nil,
+		http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType, http.StatusTooManyRequests)
+	op.Parameters = []*openapi.Parameter{idParam}
+	op.RequestBody = &openapi.RequestBody{Required: true, Content: openapi.JSONContent(openapi.Ref("JsonPostPatch"))}
+	op.Security = authenticated
+	doc.Add(http.MethodPut, ApiV2+"/posts/{id}", op)
+	op = operationV2("deletePostV2", "Delete post", http.StatusNoContent, nil,
+		http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusTooManyRequests)
+	op.Parameters = []*openapi.Parameter{idParam}
+	op.Security = authenticated
+	doc.Add(http.MethodDelete, ApiV2+"/posts/{id}", op)
+
+	op = negotiated(operation("batchPosts", "posts", "Execute up to 100 post operations at once", http.StatusOK, JsonBatchReport{},
+		http.StatusBadRequest, http.StatusUnauthorized, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity, http.StatusTooManyRequests))
+	op.Parameters = []*openapi.Parameter{idempotencyParam}
+	op.RequestBody = &openapi.RequestBody{Required: true, Content: formats(c.SchemaOf(JsonBatchPayload{}))}
+	op.Security = authenticated
+	doc.Add(http.MethodPost, ApiVersion+"/posts/batch", op)
+	batchOperation := c.Resolve(c.SchemaOf(JsonBatchOperation{}))
+	// invalid operations are reported in results, so that other operations of the batch are applied
+	batchOperation.Properties["op"].Description = "one of create, update or delete"
+	batchOperation.Properties["post"] = openapi.Ref("JsonPostPatch")
+
+	op = &openapi.Operation{OperationID: "streamPosts", Summary: "Stream of post changes as Server-Sent Events", Tags: []string{"posts"}, Streaming: true,
+		Description: "Every event carries JSON encoded Event as data, stream is resumed with Last-Event-ID header; " +
+			"event ids are prefixed by epoch of the server, so streams of other epochs start with reset event",
+		Parameters: []*openapi.Parameter{
+			{Name: "type", In: "query", Schema: &openapi.Schema{Type: "string"}, Description: "comma separated event types: created, updated, deleted"},
+			{Name: "id", In: "query", Schema: &openapi.Schema{Type: "string"}, Description: "comma separated post ids"},
+			{Name: "author", In: "query", Schema: &openapi.Schema{Type: "string"}},
+			{Name: "title", In: "query", Schema: &openapi.Schema{Type: "string"}, Description: "case insensitive substring of title"},
+			{Name: "last_event_id", In: "query", Schema: &openapi.Schema{Type: "string"}, Description: "id of the last received event, <epoch>-<id>"},
+			{Name: "Last-Event-ID", In: "header", Schema: &openapi.Schema{Type: "string"}, Description: "id of the last received event, <epoch>-<id>"},
+		},
+		Responses: errorResponses(http.StatusBadRequest, http.StatusTooManyRequests),
+	}
+	c.SchemaOf(events.Event{})
+	op.Responses["200"] = &openapi.Response{Description: "stream of events", Content: map[string]openapi.MediaType{"text/event-stream": {Schema: &openapi.Schema{Type: "string"}}}}
+	doc.Add(http.MethodGet, ApiVersion+"/posts/stream", op)
+	op = &openapi.Operation{OperationID: "socketPosts", Summary: "WebSocket for live updates of subscribed posts", Tags: []string{"posts"}, Streaming: true,
+		Description: "Client sends JsonSocketRequest messages and receives JsonSocketMessage messages",
+		Parameters:  []*openapi.Parameter{{Name: "access_token", In: "query", Schema: &openapi.Schema{Type: "string"}, Description: "token of browser clients unable to send Authorization header"}},
+		Responses:   errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusTooManyRequests),
+	}
+	c.SchemaOf(JsonSocketRequest{})
+	c.SchemaOf(JsonSocketMessage{})
+	op.Responses["101"] = &openapi.Response{Description: "connection is upgraded to WebSocket"}
+	doc.Add(http.MethodGet, ApiVersion+"/posts/ws", op)
+
+	// transfer routes
+	records := &openapi.Schema{Type: "array", Items: c.SchemaOf(JsonPostRecord{})}
+	op = &openapi.Operation{OperationID: "exportPosts", Summary: "Export all posts", Tags: []string{"transfer"}, Streaming: true,
+		Parameters: []*openapi.Parameter{{Name: "format", In: "query", Schema: &openapi.Schema{Type: "string", Enum: []any{ExportFormatNDJSON, ExportFormatJSON}}, Description: "format of export, negotiated by Accept header when omitted"}},
+		Responses:  errorResponses(http.StatusBadRequest, http.StatusTooManyRequests, http.StatusInternalServerError),
+	}
+	op.Responses["200"] = &openapi.Response{Description: "streamed posts", Content: map[string]openapi.MediaType{
+		ndjsonContentType:  {Schema: &openapi.Schema{Type: "string", Description: "JsonPostRecord per line"}},
+		"application/json": {Schema: records},
+	}}
+	doc.Add(http.MethodGet, ApiVersion+"/posts/export", op)
+	op = operation("importPosts", "transfer", "Import posts from NDJSON or JSON", http.StatusOK, JsonImportReport{},
+		http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType, http.StatusTooManyRequests,
+		http.StatusInternalServerError)
+	op.Responses["422"] = &openapi.Response{Description: "atomic import failed and nothing was imported", Content: openapi.JSONContent(envelope(JsonImportReport{}))}
+	op.Parameters = []*openapi.Parameter{
+		{Name: "mode", In: "query", Schema: &openapi.Schema{Type: "string", Enum: []any{ImportModeFail, ImportModeSkip, ImportModeUpsert}, Default: ImportModeFail}, Description: "handling of posts with existing ids"},
+		{Name: "atomic", In: "query", Schema: &openapi.Schema{Type: "boolean"}, Description: "either all posts are imported or none of them"},
+	}
+	// imported posts are validated by handler, so large bodies are not read twice
+	op.RequestBody = &openapi.RequestBody{Required: true, Description: "JsonPostRecord per line, list of posts or seed data object with posts list",
+		Content: map[string]openapi.MediaType{ndjsonContentType: {}, "application/json": {}},
+	}
+	op.Security = authenticated
+	doc.Add(http.MethodPost, ApiVersion+"/posts/import", op)
+
+	// webhook routes
+	webhookPayload := c.SchemaOf(JsonWebhookPayload{})
+	webhookEvents := &openapi.Schema{Type: "array", Items: &openapi.Schema{Type: "string", Enum: []any{string(events.TypeCreated), string(events.TypeUpdated), string(events.TypeDeleted)}}}
+	c.Resolve(webhookPayload).Properties["events"] = webhookEvents
+	c.Resolve(webhookPayload).Required = []string{"url"}
+	webhookErrors := []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusTooManyRequests}
+	webhookOperation := func(method string, path string, op *openapi.Operation) {
+		op.Security = authenticated
+		if path != "" {
+			op.Parameters = append([]*openapi.Parameter{webhookParam}, op.Parameters...)
+		}
+		doc.Add(method, ApiVersion+"/webhooks"+path, op)
+	}
+	webhookOperation(http.MethodGet, "", operation("listWebhooks", "webhooks", "Get list of subscriptions", http.StatusOK, []webhooks.Subscription{}, webhookErrors...))
+	op = operation("addWebhook", "webhooks", "Add a new subscription, secret is generated when omitted", http.StatusCreated, webhooks.Subscription{}, webhookErrors...)
+	op.RequestBody = &openapi.RequestBody{Required: true, Content: openapi.JSONContent(webhookPayload)}
+	webhookOperation(http.MethodPost, "", op)
+	webhookOperation(http.MethodGet, "/{id}", operation("getWebhook", "webhooks", "Get subscription", http.StatusOK, webhooks.Subscription{}, webhookErrors...))
+	op = operation("updateWebhook", "webhooks", "Update subscription", http.StatusOK, webhooks.Subscription{}, webhookErrors...)
+	op.RequestBody = &openapi.RequestBody{Required: true, Content: openapi.JSONContent(webhookPayload)}
+	webhookOperation(http.MethodPut, "/{id}", op)
+	webhookOperation(http.MethodDelete, "/{id}", operation("deleteWebhook", "webhooks", "Delete subscription", http.StatusOK, nil, webhookErrors...))
+	op = operation("listWebhookDeliveries", "webhooks", "Get delivery log of subscription", http.StatusOK, []webhooks.Delivery{}, webhookErrors...)
+	op.Parameters = []*openapi.Parameter{{Name: "status", In: "query", Schema: &openapi.Schema{Type: "string", Enum: []any{string(webhooks.StatusPending), string(webhooks.StatusSucceeded), string(webhooks.StatusDead)}}}}
+	webhookOperation(http.MethodGet, "/{id}/deliveries", op)
+	op = operation("replayWebhookDelivery", "webhooks", "Replay delivery", http.StatusAccepted, webhooks.Delivery{}, webhookErrors...)
+	op.Parameters = []*openapi.Parameter{{Name: "delivery", In: "path", Required: true, Schema: &openapi.Schema{Type: "string"}, Description: "delivery id"}}
+	webhookOperation(http.MethodPost, "/{id}/deliveries/{delivery}/replay", op)
+	webhookOperation(http.MethodPost, "/{id}/replay", operation("replayWebhook", "webhooks", "Replay dead-lettered deliveries", http.StatusAccepted, JsonReplayReport{}, webhookErrors...))
+
+	// GraphQL routes, query is omitted by persisted query requests
+	c.Resolve(c.SchemaOf(gql.Request{})).Required = nil
+	graphqlResult := &openapi.Schema{Type: "object", Properties: map[string]*openapi.Schema{
+		"data":   {Description: "query result"},
+		"errors": {Type: "array", Items: &openapi.Schema{Type: "object"}},
+	}}
+	graphqlResponses := func() map[string]*openapi.Response {
+		responses := errorResponses(http.StatusTooManyRequests)
+		responses["200"] = &openapi.Response{Description: "result of query, errors are reported in the result", Content: openapi.JSONContent(graphqlResult)}
+		responses["405"] = &openapi.Response{Description: "mutation is sent with GET", Content: openapi.JSONContent(graphqlResult)}
+		return responses
+	}
+	doc.Add(http.MethodGet, ApiVersion+"/graphql", &openapi.Operation{OperationID: "graphqlQuery", Summary: "Execute GraphQL query", Tags: []string{"graphql"},
+		Parameters: []*openapi.Parameter{
+			{Name: "query", In: "query", Schema: &openapi.Schema{Type: "string"}, Description: "may be omitted for persisted query"},
+			{Name: "operationName", In: "query", Schema: &openapi.Schema{Type: "string"}},
+			{Name: "variables", In: "query", Schema: &openapi.Schema{Type: "string"}, Description: "JSON object of variables"},
+			{Name: "extensions", In: "query", Schema: &openapi.Schema{Type: "string"}, Description: "JSON object of extensions"},
+		},
+		Responses: graphqlResponses(),
+	})
+	doc.Add(http.MethodPost, ApiVersion+"/graphql", &openapi.Operation{OperationID: "graphqlExecute", Summary: "Execute GraphQL query or mutation", Tags: []string{"graphql"},
+		RequestBody: &openapi.RequestBody{Required: true, Content: map[string]openapi.MediaType{
+			"application/json":    {Schema: c.SchemaOf(gql.Request{})},
+			"application/graphql": {Schema: &openapi.Schema{Type: "string"}},
+		}},
+		Responses: graphqlResponses(),
+	})
+
+	// writes of API versions are rejected until service is ready
+	for path, item := range doc.Paths {
+		for method, op := range item {
+			if method == "get" || !strings.HasPrefix(path, ApiVersion+"/") && !strings.HasPrefix(path, ApiV2+"/") {
+				continue
+			}
+			if strings.HasPrefix(path, ApiV2+"/") {
+				op.Responses["503"] = &openapi.Response{Description: http.StatusText(http.StatusServiceUnavailable), Content: openapi.JSONContent(errorSchemaV2)}
+			} else {
+				op.Responses["503"] = errorResponses(http.StatusServiceUnavailable)["503"]
+			}
+		}
+	}
+
+	// admin routes
+	adminOperation := func(method string, path string, op *openapi.Operation) {
+		op.Security = authenticated
+		op.Servers = adminServers
+		for code, response := range errorResponses(http.StatusUnauthorized, http.StatusForbidden) {
+			op.Responses[code] = response
+		}
+		doc.Add(method, path, op)
+	}
+	adminOperation(http.MethodGet, "/admin/build", operation("adminBuild", "admin", "Build and runtime information", http.StatusOK, JsonBuildInfo{}))
+	adminOperation(http.MethodGet, "/admin/config", operation("adminConfig", "admin", "Effective configuration with redacted secrets", http.StatusOK, map[string]any{}))
+	adminOperation(http.MethodGet, "/admin/store/stats", operation("adminStoreStats", "admin", "Store statistics", http.StatusOK, store.Stats{}, http.StatusInternalServerError))
+	adminOperation(http.MethodPost, "/admin/store/snapshot", operation("adminStoreSnapshot", "admin", "Save store snapshot", http.StatusOK, "", http.StatusInternalServerError))
+	adminOperation(http.MethodPost, "/admin/store/reload", operation("adminStoreReload", "admin", "Reload seed data", http.StatusOK, nil, http.StatusInternalServerError))
+	adminOperation(http.MethodPost, "/admin/store/compact", operation("adminStoreCompact", "admin", "Compact store", http.StatusOK, nil, http.StatusInternalServerError))
+
+	return doc
+}
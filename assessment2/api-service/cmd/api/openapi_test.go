@@ -0,0 +1,177 @@
+package main
+
+import (
+	"api-service/internal/events"
+	"api-service/internal/gql"
+	"api-service/internal/health"
+	"api-service/internal/metrics"
+	"api-service/internal/webhooks"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// newTestFullApp creates application with every optional group of routes enabled
+func newTestFullApp(t *testing.T) *App {
+	t.Helper()
+
+	webhookStore, err := webhooks.NewFileStore("", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &App{
+		Health:   health.NewRegistry(0, 0),
+		Metrics:  metrics.New(),
+		Events:   events.NewBroker(1, 1),
+		Webhooks: webhooks.NewDispatcher(webhookStore, nil, webhooks.Config{}),
+		GraphQL:  &gql.Executor{},
+	}
+}
+
+// registeredRoutes returns sorted "METHOD path" list of routes registered in router
+func registeredRoutes(t *testing.T, router http.Handler) []string {
+	t.Helper()
+
+	var routes []string
+	err := chi.Walk(router.(chi.Router), func(method string, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		// profiler is a mounted third party router
+		if strings.HasPrefix(route, "/debug") {
+			return nil
+		}
+		// handlers registered for any method are described by GET operation
+		if route == "/metrics" && method != http.MethodGet {
+			return nil
+		}
+		if route != "/" {
+			route = strings.TrimSuffix(route, "/")
+		}
+		routes = append(routes, method+" "+route)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return routes
+}
+
+// TestOpenAPI_Routes tests that every registered route is described and every described route is registered
+func TestOpenAPI_Routes(t *testing.T) {
+	t.Parallel()
+	app := newTestFullApp(t)
+
+	// healthcheck is served by middleware, so it is not found by walking the routes
+	registered := append(registeredRoutes(t, app.routes()), registeredRoutes(t, app.adminRoutes())...)
+	registered = append(registered, "GET "+ApiVersion+"/healthcheck")
+	sort.Strings(registered)
+	described := newOpenAPIDocument().Routes()
+
+	missing, unknown := diffRoutes(registered, described)
+	for _, route := range missing {
+		t.Errorf("route %s is not described in OpenAPI document", route)
+	}
+	for _, route := range unknown {
+		t.Errorf("described route %s is not registered", route)
+	}
+}
+
+// diffRoutes returns routes of the first list missing in the second and routes of the second missing in the first
+func diffRoutes(first []string, second []string) ([]string, []string) {
+	inFirst := make(map[string]bool)
+	inSecond := make(map[string]bool)
+	for _, route := range first {
+		inFirst[route] = true
+	}
+	for _, route := range second {
+		inSecond[route] = true
+	}
+	var missing, unknown []string
+	for _, route := range first {
+		if !inSecond[route] {
+			missing = append(missing, route)
+		}
+	}
+	for _, route := range second {
+		if !inFirst[route] {
+			unknown = append(unknown, route)
+		}
+	}
+	return missing, unknown
+}
+
+// TestOpenAPI_Document tests served document and its references
+func TestOpenAPI_Document(t *testing.T) {
+	t.Parallel()
+	app := &App{}
+
+	req, _ := http.NewRequest(http.MethodGet, "/v1/openapi.json", nil)
+	rr := httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("expected JSON document, but got %d %s", rr.Code, rr.Header().Get("Content-Type"))
+	}
+
+	var doc map[string]any
+	if err := json.Unmarshal(rr.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc["openapi"] != "3.1.0" {
+		t.Errorf("expected OpenAPI 3.1.0, but got %v", doc["openapi"])
+	}
+
+	// schemas of payload and envelope are generated from their types
+	components := doc["components"].(map[string]any)
+	schemas := components["schemas"].(map[string]any)
+	payload, _ := json.Marshal(schemas["JsonPostPayload"])
+	expectedPayload := `{"properties":{"author":{"type":"string"},"content":{"type":"string"},"format":{"enum":["plain","markdown"],"type":"string"},"title":{"type":"string"}},"required":["title","content","author"],"type":"object"}`
+	if string(payload) != expectedPayload {
+		t.Errorf("expected %s, but got %s", expectedPayload, payload)
+	}
+	response, _ := json.Marshal(schemas["JsonResponse"])
+	expectedResponse := `{"properties":{"data":{},"error":{"type":"boolean"},"message":{"type":"string"},"trace_id":{"type":"string"}},"required":["error","message"],"type":"object"}`
+	if string(response) != expectedResponse {
+		t.Errorf("expected %s, but got %s", expectedResponse, response)
+	}
+
+	// every reference points to existing component
+	var check func(value any)
+	check = func(value any) {
+		switch value := value.(type) {
+		case map[string]any:
+			if ref, ok := value["$ref"].(string); ok {
+				parts := strings.Split(strings.TrimPrefix(ref, "#/components/"), "/")
+				if _, ok := components[parts[0]].(map[string]any)[parts[1]]; !ok {
+					t.Errorf("reference %s is not resolved", ref)
+				}
+			}
+			for _, v := range value {
+				check(v)
+			}
+		case []any:
+			for _, v := range value {
+				check(v)
+			}
+		}
+	}
+	check(doc)
+}
+
+// TestOpenAPI_Docs tests embedded documentation page
+func TestOpenAPI_Docs(t *testing.T) {
+	t.Parallel()
+	app := &App{}
+
+	req, _ := http.NewRequest(http.MethodGet, "/v1/docs", nil)
+	rr := httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK || !strings.HasPrefix(rr.Header().Get("Content-Type"), "text/html") {
+		t.Fatalf("expected documentation page, but got %d %s", rr.Code, rr.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(rr.Body.String(), `const specURL = "/v1/openapi.json";`) {
+		t.Errorf("expected page loading document, but got %s", rr.Body.String())
+	}
+}
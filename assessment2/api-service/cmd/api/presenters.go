@@ -0,0 +1,286 @@
+package main
+
+import (
+	"api-service/internal/domain"
+	"api-service/internal/render"
+	"api-service/internal/server"
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Presenter writes responses of post endpoints in the envelope of an API version,
+// so that handlers are shared by versions
+type Presenter interface {
+	// Pagination reports whether lists carry pagination metadata, it costs one more store query
+	Pagination() bool
+	// ErrorEnvelope builds body of error responses, nil keeps the standard envelope
+	ErrorEnvelope() server.ErrorEnvelope
+
+	Posts(w http.ResponseWriter, r *http.Request, posts []domain.Post, page JsonPageV2)
+	// Post writes post, rendered content is included when it is requested
+	Post(w http.ResponseWriter, r *http.Request, post *domain.Post, rendered *render.Result)
+	Created(w http.ResponseWriter, r *http.Request, id int)
+	Updated(w http.ResponseWriter, r *http.Request, id int)
+	Deleted(w http.ResponseWriter, r *http.Request, id int)
+	Error(w http.ResponseWriter, r *http.Request, err error, status int)
+}
+
+type presenterKey struct{}
+
+// present serves requests of router with presenter of API version
+func present(presenter Presenter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), presenterKey{}, presenter)
+			if envelope := presenter.ErrorEnvelope(); envelope != nil {
+				ctx = server.WithErrorEnvelope(ctx, envelope)
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// presenter returns presenter of request, v1 is used by default
+func (app *App) presenter(r *http.Request) Presenter {
+	if presenter, ok := r.Context().Value(presenterKey{}).(Presenter); ok {
+		return presenter
+	}
+	return v1Presenter{app: app}
+}
+
+// v1Presenter writes standard envelope with error flag in negotiated format
+type v1Presenter struct {
+	app *App
+}
+
+func (p v1Presenter) Pagination() bool {
+	return false
+}
+
+func (p v1Presenter) ErrorEnvelope() server.ErrorEnvelope {
+	return nil
+}
+
+func (p v1Presenter) Posts(w http.ResponseWriter, r *http.Request, posts []domain.Post, page JsonPageV2) {
+	data := make([]JsonPostV1, len(posts))
+	for i := range posts {
+		data[i] = newJsonPostV1(&posts[i])
+	}
+	_ = p.app.WebServer.Write(w, r, http.StatusOK, server.JsonResponse{Data: &data})
+}
+
+func (p v1Presenter) Post(w http.ResponseWriter, r *http.Request, post *domain.Post, rendered *render.Result) {
+	if rendered == nil {
+		data := newJsonPostV1(post)
+		_ = p.app.WebServer.Write(w, r, http.StatusOK, server.JsonResponse{Data: &data})
+		return
+	}
+	data := JsonRenderedPost{
+		ID:          post.ID,
+		Title:       post.Title,
+		Content:     post.Content,
+		Author:      post.Author,
+		Owner:       post.Owner,
+		Version:     post.Version,
+		Format:      post.Format,
+		HTML:        rendered.HTML,
+		Excerpt:     rendered.Excerpt,
+		ReadingTime: rendered.ReadingTime,
+	}
+	_ = p.app.WebServer.Write(w, r, http.StatusOK, server.JsonResponse{Data: data})
+}
+
+func (p v1Presenter) Created(w http.ResponseWriter, r *http.Request, id int) {
+	_ = p.app.WebServer.Write(w, r, http.StatusOK, server.JsonResponse{Message: "post added", Data: id})
+}
+
+func (p v1Presenter) Updated(w http.ResponseWriter, r *http.Request, id int) {
+	_ = p.app.WebServer.Write(w, r, http.StatusOK, server.JsonResponse{Message: "post updated"})
+}
+
+func (p v1Presenter) Deleted(w http.ResponseWriter, r *http.Request, id int) {
+	_ = p.app.WebServer.Write(w, r, http.StatusOK, server.JsonResponse{Message: "post deleted"})
+}
+
+func (p v1Presenter) Error(w http.ResponseWriter, r *http.Request, err error, status int) {
+	p.app.WebServer.Error(w, r, err, status)
+}
+
+// JsonPostV1 represents post in v1 responses, fields are named as fields of post and timestamps are not included
+type JsonPostV1 struct {
+	ID      int
+	Title   string
+	Content string
+	Author  string
+	Owner   string
+	Version int
+	Format  string `csv:"-"` // it is not a column of CSV tables
+}
+
+// newJsonPostV1 converts post to v1 representation
+func newJsonPostV1(post *domain.Post) JsonPostV1 {
+	return JsonPostV1{
+		ID:      post.ID,
+		Title:   post.Title,
+		Content: post.Content,
+		Author:  post.Author,
+		Owner:   post.Owner,
+		Version: post.Version,
+		Format:  post.Format,
+	}
+}
+
+// JsonPostV2 represents post in v2 responses, timestamps are RFC 3339 strings or null when unknown
+type JsonPostV2 struct {
+	ID        int        `json:"id"`
+	Title     string     `json:"title"`
+	Content   string     `json:"content"`
+	Author    string     `json:"author"`
+	Owner     string     `json:"owner"`
+	Version   int        `json:"version"`
+	Format    string     `json:"format"`
+	CreatedAt *time.Time `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at"`
+
+	Rendered *JsonRenderedV2 `json:"rendered,omitempty"` // included when rendering is requested
+}
+
+// JsonRenderedV2 is content of post rendered as sanitized HTML
+type JsonRenderedV2 struct {
+	HTML        string `json:"html"`
+	Excerpt     string `json:"excerpt"`
+	ReadingTime int    `json:"reading_time"` // minutes
+}
+
+// JsonPageV2 is pagination metadata of v2 lists
+type JsonPageV2 struct {
+	Page    int  `json:"page"`
+	Limit   int  `json:"limit"`
+	HasMore bool `json:"has_more"` // next page has posts
+}
+
+// JsonResponseV2 is an envelope of successful v2 responses
+type JsonResponseV2 struct {
+	Data any         `json:"data"`
+	Meta *JsonPageV2 `json:"meta,omitempty"`
+}
+
+// JsonCreatedV2 is data of v2 response to created post
+type JsonCreatedV2 struct {
+	ID int `json:"id"`
+}
+
+// JsonErrorV2 describes failed v2 request
+type JsonErrorV2 struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+	TraceID string `json:"trace_id,omitempty"`
+}
+
+// JsonErrorResponseV2 is an envelope of v2 error responses
+type JsonErrorResponseV2 struct {
+	Error JsonErrorV2 `json:"error"`
+}
+
+// v2Presenter writes JSON envelope without error flag, lists carry pagination metadata,
+// creation responds with 201 and location of the post, changes respond with 204
+type v2Presenter struct {
+	app *App
+}
+
+func (p v2Presenter) Pagination() bool {
+	return true
+}
+
+func (p v2Presenter) ErrorEnvelope() server.ErrorEnvelope {
+	return func(err error, status int, traceID string) any {
+		return JsonErrorResponseV2{Error: JsonErrorV2{Status: status, Message: err.Error(), TraceID: traceID}}
+	}
+}
+
+func (p v2Presenter) Posts(w http.ResponseWriter, r *http.Request, posts []domain.Post, page JsonPageV2) {
+	data := make([]JsonPostV2, len(posts))
+	for i := range posts {
+		data[i] = newJsonPostV2(&posts[i])
+	}
+	_ = p.app.WebServer.WriteJSON(w, http.StatusOK, JsonResponseV2{Data: data, Meta: &page})
+}
+
+func (p v2Presenter) Post(w http.ResponseWriter, r *http.Request, post *domain.Post, rendered *render.Result) {
+	data := newJsonPostV2(post)
+	if rendered != nil {
+		data.Rendered = &JsonRenderedV2{HTML: rendered.HTML, Excerpt: rendered.Excerpt, ReadingTime: rendered.ReadingTime}
+	}
+	_ = p.app.WebServer.WriteJSON(w, http.StatusOK, JsonResponseV2{Data: data})
+}
+
+func (p v2Presenter) Created(w http.ResponseWriter, r *http.Request, id int) {
+	headers := http.Header{"Location": {ApiV2 + "/posts/" + strconv.Itoa(id)}}
+	_ = p.app.WebServer.WriteJSON(w, http.StatusCreated, JsonResponseV2{Data: JsonCreatedV2{ID: id}}, headers)
+}
+
+func (p v2Presenter) Updated(w http.ResponseWriter, r *http.Request, id int) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (p v2Presenter) Deleted(w http.ResponseWriter, r *http.Request, id int) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (p v2Presenter) Error(w http.ResponseWriter, r *http.Request, err error, status int) {
+	p.app.WebServer.ErrorJSON(w, r, err, status)
+}
+
+// newJsonPostV2 converts post to v2 representation, timestamps are truncated to seconds in UTC
+func newJsonPostV2(post *domain.Post) JsonPostV2 {
+	timestamp := func(t time.Time) *time.Time {
+		if t.IsZero() {
+			return nil
+		}
+		t = t.UTC().Truncate(time.Second)
+		return &t
+	}
+	return JsonPostV2{
+		ID:        post.ID,
+		Title:     post.Title,
+		Content:   post.Content,
+		Author:    post.Author,
+		Owner:     post.Owner,
+		Version:   post.Version,
+		Format:    post.Format,
+		CreatedAt: timestamp(post.Created),
+		UpdatedAt: timestamp(post.Updated),
+	}
+}
+
+// Deprecation describes retirement of API version, zero times omit their headers
+type Deprecation struct {
+	Deprecated time.Time // announced by Deprecation header
+	Sunset     time.Time // version is not served after sunset
+	Successor  string    // path prefix of successor version
+}
+
+// deprecated announces deprecation and sunset of API version with RFC 9745 and RFC 8594 headers,
+// responses link the same resource of successor version
+func deprecated(version string, deprecation Deprecation) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !deprecation.Deprecated.IsZero() {
+				w.Header().Set("Deprecation", fmt.Sprintf("@%d", deprecation.Deprecated.Unix()))
+			}
+			if !deprecation.Sunset.IsZero() {
+				w.Header().Set("Sunset", deprecation.Sunset.UTC().Format(http.TimeFormat))
+			}
+			if deprecation.Successor != "" {
+				if path, ok := strings.CutPrefix(r.URL.Path, version); ok {
+					w.Header().Add("Link", fmt.Sprintf(`<%s%s>; rel="successor-version"`, deprecation.Successor, path))
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -1,6 +1,10 @@
 package main
 
 import (
+	"api-service/internal/auth"
+	"api-service/internal/logging"
+	"api-service/internal/server"
+	"api-service/internal/tracing"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
@@ -8,34 +12,217 @@ import (
 	"github.com/go-chi/cors"
 )
 
-// ApiVersion defines current version of API
+// ApiVersion defines version of API served by all routes, it is deprecated in favor of ApiV2
 const ApiVersion = "/v1"
 
+// ApiV2 defines version of API serving posts with envelope without error flag
+const ApiV2 = "/v2"
+
 func (app *App) routes() http.Handler {
 	mux := chi.NewRouter()
 
+	// Assign request ids, trace and log processed requests
+	mux.Use(logging.RequestID(app.Logger))
+	if app.Tracer != nil {
+		mux.Use(tracing.Middleware(app.Tracer))
+	}
+	mux.Use(logging.AccessLog)
+
+	// Collect HTTP metrics
+	if app.Metrics != nil {
+		mux.Use(app.Metrics.Middleware)
+	}
+
 	mux.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"https://*", "http://*"},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-Request-ID", "Idempotency-Key", "traceparent", "tracestate", "Content-Encoding"},
+		ExposedHeaders:   []string{"Link", "X-Request-ID", "X-Trace-ID", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After", "Idempotent-Replayed", "Deprecation", "Sunset", "Location"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
 
-	// Could be separated from public endpoints to internal http server in future (+metrics)
+	// Compress responses negotiated by Accept-Encoding header
+	if app.CompressionMinSize > 0 {
+		mux.Use(server.Compress(app.CompressionMinSize))
+	}
+
+	// Could be separated from public endpoints to internal http server in future
 	mux.Use(middleware.Heartbeat(ApiVersion + "/healthcheck"))
 
+	// Liveness and readiness probes
+	if app.Health != nil {
+		mux.Get("/livez", app.LivezHandler)
+		mux.Get("/readyz", app.ReadyzHandler)
+	}
+
+	// Metrics are served with public routes unless internal port is configured
+	if app.Metrics != nil && !app.MetricsInternal {
+		mux.Handle("/metrics", app.Metrics.Handler())
+	}
+
+	// Requests of API versions are authenticated, rate limited and validated the same way
+	protect := func(mux chi.Router) {
+		// Limit request rate per client, requests with invalid tokens are limited too
+		if app.Limiter != nil {
+			mux.Use(app.rateLimit)
+		}
+
+		// Resolve request principal, anonymous users have read only access
+		mux.Use(app.authenticate)
+
+		// Reject writes until seed data is loaded
+		mux.Use(app.acceptWrites)
+
+		// Validate requests and responses against OpenAPI description
+		if app.Validator != nil {
+			mux.Use(app.validate)
+		}
+	}
+
+	// API v1 responds with standard envelope in negotiated formats
+	mux.Route(ApiVersion, func(mux chi.Router) {
+		mux.Use(present(v1Presenter{app: app}))
+
+		// OpenAPI description and documentation of the API
+		mux.Get("/openapi.json", app.OpenAPIHandler)
+		mux.Get("/docs", app.DocsHandler)
+
+		mux.Group(func(mux chi.Router) {
+			protect(mux)
+
+			// Export posts endpoint, streamed as NDJSON or JSON array
+			mux.With(server.NoStore).
+				Get("/posts/export", app.PostsExportHandler)
+			// Stream of post changes endpoint
+			if app.Events != nil {
+				mux.Get("/posts/stream", app.PostsStreamHandler)
+				// WebSocket endpoint for live updates of subscribed posts
+				mux.Get("/posts/ws", app.PostsSocketHandler)
+			}
+			// Import posts endpoint
+			mux.With(server.NoStore, app.authorize(auth.PermissionPostsImport)).
+				Post("/posts/import", app.PostsImportHandler)
+
+			// Webhook subscriptions endpoints
+			if app.Webhooks != nil {
+				mux.Route("/webhooks", func(mux chi.Router) {
+					mux.Use(server.NoStore, app.authorize(auth.PermissionWebhooks))
+
+					// Get list of subscriptions endpoint
+					mux.Get("/", app.WebhooksGetHandler)
+					// Add a new subscription endpoint
+					mux.Post("/", app.WebhooksAddHandler)
+					// Get subscription endpoint
+					mux.Get("/{id}", app.WebhooksGetOneHandler)
+					// Update subscription endpoint
+					mux.Put("/{id}", app.WebhooksUpdateHandler)
+					// Delete subscription endpoint
+					mux.Delete("/{id}", app.WebhooksDeleteHandler)
+					// Delivery log endpoint
+					mux.Get("/{id}/deliveries", app.WebhooksDeliveriesHandler)
+					// Replay delivery endpoint
+					mux.Post("/{id}/deliveries/{delivery}/replay", app.WebhooksReplayDeliveryHandler)
+					// Replay dead-lettered deliveries endpoint
+					mux.Post("/{id}/replay", app.WebhooksReplayHandler)
+				})
+			}
+
+			// GraphQL endpoint, queries are accepted with GET and POST, mutations with POST only
+			if app.GraphQL != nil {
+				mux.With(server.NoStore).
+					Get("/graphql", app.GraphQLHandler)
+				mux.With(server.NoStore).
+					Post("/graphql", app.GraphQLHandler)
+			}
+
+			mux.Group(func(mux chi.Router) {
+				// Reject unsupported request and response formats before processing
+				mux.Use(app.WebServer.Negotiate)
+
+				// Batch of post operations endpoint
+				mux.With(server.NoStore, app.idempotent).
+					Post("/posts/batch", app.PostsBatchHandler)
+			})
+
+			mux.Group(func(mux chi.Router) {
+				// Post endpoints are succeeded by v2, responses announce deprecation and sunset of v1
+				mux.Use(deprecated(ApiVersion, app.Deprecation))
+				// Reject unsupported request and response formats before processing
+				mux.Use(app.WebServer.Negotiate)
+				app.postRoutes(mux)
+			})
+		})
+	})
+
+	// API v2 responds with JSON envelope without error flag, lists carry pagination metadata
+	mux.Route(ApiV2, func(mux chi.Router) {
+		mux.Use(present(v2Presenter{app: app}))
+
+		mux.Group(func(mux chi.Router) {
+			protect(mux)
+			// Reject request and response formats other than JSON before processing
+			mux.Use(app.WebServer.NegotiateJSON)
+			app.postRoutes(mux)
+		})
+	})
+
+	return mux
+}
+
+// postRoutes registers post endpoints shared by API versions, responses are written by presenter of version
+func (app *App) postRoutes(mux chi.Router) {
 	// Get paginated list of posts endpoint
-	mux.Get(ApiVersion+"/posts", app.PostsGetHandler)
+	mux.With(server.CacheControl(app.CacheMaxAge)).
+		Get("/posts", app.PostsGetHandler)
 	// Get post endpoint
-	mux.Get(ApiVersion+"/posts/{id}", app.PostsGetOneHandler)
+	mux.With(server.CacheControl(app.CacheMaxAge)).
+		Get("/posts/{id}", app.PostsGetOneHandler)
 	// Add a new post endpoint
-	mux.Post(ApiVersion+"/posts", app.PostsAddHandler)
+	mux.With(server.NoStore, app.authorize(auth.PermissionPostsCreate), app.idempotent).
+		Post("/posts", app.PostsAddHandler)
 	// Update post endpoint
-	mux.Put(ApiVersion+"/posts/{id}", app.PostsUpdateHandler)
+	mux.With(server.NoStore, app.authorizePost(auth.PermissionPostsUpdate)).
+		Put("/posts/{id}", app.PostsUpdateHandler)
 	// Delete post endpoint
-	mux.Delete(ApiVersion+"/posts/{id}", app.PostsDeleteHandler)
+	mux.With(server.NoStore, app.authorizePost(auth.PermissionPostsDelete)).
+		Delete("/posts/{id}", app.PostsDeleteHandler)
+}
+
+func (app *App) metricsRoutes() http.Handler {
+	mux := chi.NewRouter()
+
+	// Prometheus metrics endpoint
+	mux.Handle("/metrics", app.Metrics.Handler())
+
+	return mux
+}
+
+func (app *App) adminRoutes() http.Handler {
+	mux := chi.NewRouter()
+
+	mux.Use(logging.RequestID(app.Logger))
+	mux.Use(logging.AccessLog)
+
+	// Admin endpoints are available to administrators only
+	mux.Use(app.authenticate)
+	mux.Use(app.authorize(auth.PermissionAdmin))
+
+	// Go profiler endpoints
+	mux.Mount("/debug", middleware.Profiler())
+
+	// Build and runtime information endpoint
+	mux.Get("/admin/build", app.AdminBuildHandler)
+	// Effective configuration endpoint
+	mux.Get("/admin/config", app.AdminConfigHandler)
+	// Store statistics endpoint
+	mux.Get("/admin/store/stats", app.AdminStoreStatsHandler)
+	// Save store snapshot endpoint
+	mux.Post("/admin/store/snapshot", app.AdminStoreSnapshotHandler)
+	// Reload seed data endpoint
+	mux.Post("/admin/store/reload", app.AdminStoreReloadHandler)
+	// Compact store endpoint
+	mux.Post("/admin/store/compact", app.AdminStoreCompactHandler)
 
 	return mux
 }
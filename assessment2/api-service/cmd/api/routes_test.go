@@ -1,7 +1,10 @@
 package main
 
 import (
+	"api-service/internal/health"
+	"context"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/go-chi/chi/v5"
@@ -60,3 +63,33 @@ func routeExists(t *testing.T, routes chi.Router, route RouteDescriptor) {
 		t.Errorf("did not find %s: %s in registered routes", route.Method, route.Path)
 	}
 }
+
+func Test_routes_probes(t *testing.T) {
+	testApp := App{Health: health.NewRegistry(0, 0)}
+	testApp.Health.Register("post_store", health.CheckerFunc(func(ctx context.Context) error {
+		return nil
+	}))
+	routes := testApp.routes()
+
+	probe := func(path string) int {
+		req, _ := http.NewRequest("GET", path, nil)
+		rr := httptest.NewRecorder()
+		routes.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	if code := probe("/livez"); code != http.StatusOK {
+		t.Errorf("expected live service, got %d", code)
+	}
+	if code := probe("/readyz"); code != http.StatusServiceUnavailable {
+		t.Errorf("expected starting service to be not ready, got %d", code)
+	}
+	testApp.Health.SetState(health.StateReady)
+	if code := probe("/readyz"); code != http.StatusOK {
+		t.Errorf("expected ready service, got %d", code)
+	}
+	testApp.Health.SetState(health.StateDraining)
+	if code := probe("/readyz"); code != http.StatusServiceUnavailable {
+		t.Errorf("expected draining service to be not ready, got %d", code)
+	}
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"api-service/internal/auth"
+	"api-service/internal/domain"
+	"api-service/internal/events"
+	"api-service/internal/logging"
+	"api-service/internal/ratelimit"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	socketWriteTimeout     = 10 * time.Second
+	socketPongTimeout      = 60 * time.Second
+	socketPingInterval     = socketPongTimeout * 9 / 10
+	socketMaxMessageSize   = 64 << 10 // 64 Kb
+	socketMaxSubscriptions = 100
+	socketSendBufferSize   = 16
+)
+
+// Types of WebSocket messages sent by clients
+const (
+	SocketSubscribe   = "subscribe"
+	SocketUnsubscribe = "unsubscribe"
+	SocketUpdate      = "update"
+)
+
+// Types of WebSocket messages sent by server
+const (
+	SocketSubscribed = "subscribed"
+	SocketUpdated    = "updated"
+	SocketDeleted    = "deleted"
+	SocketResult     = "result"
+	SocketError      = "error"
+)
+
+var (
+	ErrorSocketMessage       = errors.New("message type must be one of subscribe, unsubscribe or update")
+	ErrorSocketSubscriptions = errors.New("too many subscribed posts")
+)
+
+// socketUpgrader accepts WebSocket connections from the same origin or from non browser clients
+var socketUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// JsonSocketRequest represent message sent by WebSocket client, request id is returned with result of update
+type JsonSocketRequest struct {
+	Type      string           `json:"type"`
+	RequestID string           `json:"request_id,omitempty"`
+	IDs       []int            `json:"ids,omitempty"`
+	ID        int              `json:"id,omitempty"`
+	Post      *JsonPostPayload `json:"post,omitempty"`
+}
+
+// JsonSocketMessage represent message sent to WebSocket client: subscription state,
+// notification about updated or deleted post, result of update or error
+type JsonSocketMessage struct {
+	Type      string       `json:"type"`
+	RequestID string       `json:"request_id,omitempty"`
+	Status    int          `json:"status,omitempty"`
+	Error     string       `json:"error,omitempty"`
+	IDs       []int        `json:"ids,omitempty"`
+	PostID    int          `json:"post_id,omitempty"`
+	Version   int          `json:"version,omitempty"`
+	Post      *domain.Post `json:"post,omitempty"`
+}
+
+// PostsSocketHandler is an endpoint handler for WebSocket connections, clients subscribe to posts,
+// get notified about their changes and update posts with permissions of authenticated user
+func (app *App) PostsSocketHandler(w http.ResponseWriter, r *http.Request) {
+	conn, err := socketUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// upgrader responds with error
+		logging.FromContext(r.Context()).Warn("failed to upgrade connection", slog.Any("error", err))
+		return
+	}
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	socket := &postSocket{
+		app:        app,
+		conn:       conn,
+		request:    r,
+		principal:  principal,
+		subscribed: make(map[int]bool),
+		send:       make(chan JsonSocketMessage, socketSendBufferSize),
+		quit:       make(chan struct{}),
+	}
+	logging.FromContext(r.Context()).Info("socket connected")
+	socket.serve()
+	logging.FromContext(r.Context()).Info("socket disconnected")
+}
+
+// postSocket is a WebSocket connection, it is read and written by separate goroutines
+type postSocket struct {
+	app       *App
+	conn      *websocket.Conn
+	request   *http.Request
+	principal *auth.Principal
+
+	mu         sync.Mutex
+	subscribed map[int]bool
+
+	send chan JsonSocketMessage
+	quit chan struct{} // closed when writing is finished
+}
+
+func (s *postSocket) serve() {
+	sub, _, _ := s.app.Events.Subscribe(0, 0, s.filter)
+	defer sub.Close()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.read()
+	}()
+	s.write(sub, done)
+	close(s.quit)
+	_ = s.conn.Close()
+	<-done
+}
+
+// filter selects changes of subscribed posts
+func (s *postSocket) filter(event events.Event) bool {
+	if event.Type != events.TypeUpdated && event.Type != events.TypeDeleted {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.subscribed[event.PostID]
+}
+
+// write sends messages and notifications until connection is closed, it is the only writer of the connection
+func (s *postSocket) write(sub *events.Subscription, done <-chan struct{}) {
+	ping := time.NewTicker(socketPingInterval)
+	defer ping.Stop()
+
+	for {
+		var err error
+		select {
+		case <-done:
+			return
+		case msg := <-s.send:
+			err = s.writeJSON(msg)
+		case event, ok := <-sub.Events():
+			if !ok {
+				// slow clients are asked to reconnect later, shutdown is reported as going away
+				code, reason := websocket.CloseGoingAway, "server is shutting down"
+				if errors.Is(sub.Err(), events.ErrorSlowConsumer) {
+					code, reason = websocket.CloseTryAgainLater, events.ErrorSlowConsumer.Error()
+				}
+				_ = s.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(socketWriteTimeout))
+				return
+			}
+			msg := JsonSocketMessage{Type: SocketUpdated, PostID: event.PostID, Version: event.Version, Post: event.Post}
+			if event.Type == events.TypeDeleted {
+				msg.Type = SocketDeleted
+			}
+			err = s.writeJSON(msg)
+		case <-ping.C:
+			err = s.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(socketWriteTimeout))
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (s *postSocket) writeJSON(msg JsonSocketMessage) error {
+	_ = s.conn.SetWriteDeadline(time.Now().Add(socketWriteTimeout))
+	return s.conn.WriteJSON(msg)
+}
+
+// read handles client messages until connection is closed, clients not answering pings are disconnected
+func (s *postSocket) read() {
+	s.conn.SetReadLimit(socketMaxMessageSize)
+	_ = s.conn.SetReadDeadline(time.Now().Add(socketPongTimeout))
+	s.conn.SetPongHandler(func(string) error {
+		return s.conn.SetReadDeadline(time.Now().Add(socketPongTimeout))
+	})
+
+	for {
+		_, data, err := s.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		_ = s.conn.SetReadDeadline(time.Now().Add(socketPongTimeout))
+
+		var req JsonSocketRequest
+		msg := JsonSocketMessage{}
+		if err := json.Unmarshal(data, &req); err != nil {
+			msg = JsonSocketMessage{Type: SocketError, Error: err.Error()}
+		} else {
+			msg = s.handle(req)
+		}
+		select {
+		case s.send <- msg:
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// handle processes client message and returns response message
+func (s *postSocket) handle(req JsonSocketRequest) JsonSocketMessage {
+	switch req.Type {
+	case SocketSubscribe, SocketUnsubscribe:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		for _, id := range req.IDs {
+			if req.Type == SocketUnsubscribe {
+				delete(s.subscribed, id)
+				continue
+			}
+			if len(s.subscribed) >= socketMaxSubscriptions && !s.subscribed[id] {
+				return JsonSocketMessage{Type: SocketError, RequestID: req.RequestID, Error: ErrorSocketSubscriptions.Error()}
+			}
+			s.subscribed[id] = true
+		}
+		ids := make([]int, 0, len(s.subscribed))
+		for id := range s.subscribed {
+			ids = append(ids, id)
+		}
+		slices.Sort(ids)
+		return JsonSocketMessage{Type: SocketSubscribed, RequestID: req.RequestID, IDs: ids}
+
+	case SocketUpdate:
+		status, err := s.update(req)
+		msg := JsonSocketMessage{Type: SocketResult, RequestID: req.RequestID, Status: status, PostID: req.ID}
+		if err != nil {
+			msg.Error = err.Error()
+		}
+		return msg
+
+	default:
+		return JsonSocketMessage{Type: SocketError, RequestID: req.RequestID, Error: ErrorSocketMessage.Error()}
+	}
+}
+
+// update applies post update with the same rules as update endpoint: write rate limit,
+// authorization of connection user and the same update path
+func (s *postSocket) update(req JsonSocketRequest) (int, error) {
+	ctx := s.request.Context()
+	if s.principal == nil {
+		return http.StatusUnauthorized, auth.ErrorUnauthenticated
+	}
+	if req.Post == nil {
+		return http.StatusBadRequest, ErrorBatchPost
+	}
+	if req.ID == 0 {
+		return http.StatusBadRequest, ErrorBatchID
+	}
+
+	// limiter backend failures should not make API unavailable
+	if s.app.Limiter != nil {
+		result, err := s.app.Limiter.Allow(ctx, s.app.rateLimitKey(s.request)+":write", s.app.RateLimits.Write)
+		if err == nil && !result.Allowed {
+			return http.StatusTooManyRequests, ratelimit.ErrorRateLimited
+		}
+	}
+
+	authCtx, cancel := context.WithTimeout(ctx, s.app.WebServer.Timeout*time.Second)
+	defer cancel()
+	err := s.app.authorizePostAccess(authCtx, s.app.PostStore, s.principal, auth.PermissionPostsUpdate, req.ID)
+	if err != nil {
+		return authorizationStatus(err), err
+	}
+
+	err = s.app.updatePost(ctx, req.ID, *req.Post)
+	if err != nil {
+		return storeErrorStatus(err), err
+	}
+	return http.StatusOK, nil
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"api-service/internal/domain"
+	"api-service/internal/events"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// dialTestSocket connects to WebSocket endpoint of test server with access token passed as query parameter
+func dialTestSocket(t *testing.T, srv *httptest.Server, token string) *websocket.Conn {
+	t.Helper()
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/v1/posts/ws"
+	if token != "" {
+		url += "?access_token=" + token
+	}
+	conn, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("unexpected status %d", resp.StatusCode)
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	return conn
+}
+
+// TestSocket_Update tests updates of posts submitted over WebSocket
+func TestSocket_Update(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		token          string
+		message        string
+		expectedStatus int
+		expectedError  string
+		expectedTitle  string
+	}{
+		{"own post", "author-token", `{"type":"update","request_id":"r1","id":1,"post":{"title":"Updated","content":"Content"}}`,
+			http.StatusOK, "", "Updated"},
+		{"foreign post", "author-token", `{"type":"update","request_id":"r1","id":2,"post":{"title":"Updated","content":"Content"}}`,
+			http.StatusForbidden, "access forbidden", "Title 1"},
+		{"anonymous user", "", `{"type":"update","request_id":"r1","id":1,"post":{"title":"Updated","content":"Content"}}`,
+			http.StatusUnauthorized, "authentication required", "Title 1"},
+		{"missing post", "author-token", `{"type":"update","request_id":"r1","id":1}`,
+			http.StatusBadRequest, "post is required", "Title 1"},
+		{"unknown post", "editor-token", `{"type":"update","request_id":"r1","id":42,"post":{"title":"Updated","content":"Content"}}`,
+			http.StatusNotFound, "post not found", "Title 1"},
+		{"invalid format", "author-token", `{"type":"update","request_id":"r1","id":1,"post":{"title":"Updated","format":"html"}}`,
+			http.StatusBadRequest, "format must be one of plain or markdown", "Title 1"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			app, memoryStore := newTestTransferApp(t, 1)
+			_, _ = memoryStore.Put(context.Background(), domain.Post{ID: 2, Title: "Title 2", Owner: "author-2"})
+			app.Events = events.NewBroker(10, 10)
+			memoryStore.Observe(app.Events.Observer())
+
+			srv := httptest.NewServer(app.routes())
+			defer srv.Close()
+			conn := dialTestSocket(t, srv, tt.token)
+			defer conn.Close()
+
+			_ = conn.WriteMessage(websocket.TextMessage, []byte(tt.message))
+			var result JsonSocketMessage
+			if err := conn.ReadJSON(&result); err != nil {
+				t.Fatal(err)
+			}
+			if result.Type != SocketResult || result.RequestID != "r1" || result.Status != tt.expectedStatus || result.Error != tt.expectedError {
+				t.Errorf("unexpected result %+v", result)
+			}
+			post, _ := memoryStore.GetOne(context.Background(), 1)
+			if post.Title != tt.expectedTitle {
+				t.Errorf("expected title %q, but got %q", tt.expectedTitle, post.Title)
+			}
+		})
+	}
+}
+
+// TestSocket_Subscribe tests notifications about changes of subscribed posts
+func TestSocket_Subscribe(t *testing.T) {
+	t.Parallel()
+	app, memoryStore := newTestTransferApp(t, 3)
+	app.Events = events.NewBroker(10, 10)
+	memoryStore.Observe(app.Events.Observer())
+
+	srv := httptest.NewServer(app.routes())
+	defer srv.Close()
+	conn := dialTestSocket(t, srv, "")
+	defer conn.Close()
+
+	_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"subscribe","ids":[3,1,2]}`))
+	_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"unsubscribe","ids":[2]}`))
+	var subscribed JsonSocketMessage
+	for i := 0; i < 2; i++ {
+		if err := conn.ReadJSON(&subscribed); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if subscribed.Type != SocketSubscribed || len(subscribed.IDs) != 2 || subscribed.IDs[0] != 1 || subscribed.IDs[1] != 3 {
+		t.Fatalf("unexpected subscription %+v", subscribed)
+	}
+
+	// changes of not subscribed posts are not delivered
+	ctx := context.Background()
+	_ = memoryStore.Update(ctx, 2, domain.Post{Title: "Ignored"})
+	_ = memoryStore.Update(ctx, 1, domain.Post{Title: "Updated"})
+	_ = memoryStore.Delete(ctx, 3)
+
+	expected := []JsonSocketMessage{
+		{Type: SocketUpdated, PostID: 1, Version: 2},
+		{Type: SocketDeleted, PostID: 3, Version: 2},
+	}
+	for _, e := range expected {
+		var msg JsonSocketMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatal(err)
+		}
+		if msg.Type != e.Type || msg.PostID != e.PostID || msg.Version != e.Version {
+			t.Errorf("expected %+v, but got %+v", e, msg)
+		}
+	}
+
+	_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"unknown"}`))
+	var msg JsonSocketMessage
+	if err := conn.ReadJSON(&msg); err != nil || msg.Type != SocketError || msg.Error != ErrorSocketMessage.Error() {
+		t.Errorf("unexpected message %+v", msg)
+	}
+
+	// shutdown of broker closes connections
+	app.Events.Close()
+	_, _, err := conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Errorf("expected going away close, but got %v", err)
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"api-service/internal/events"
+	"api-service/internal/logging"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// streamWriteTimeout limits time of writing an event to a stream client
+const streamWriteTimeout = 10 * time.Second
+
+// defaultStreamHeartbeat is an interval of heartbeat comments keeping idle streams open
+const defaultStreamHeartbeat = 15 * time.Second
+
+// streamRetry is a reconnection delay suggested to stream clients, in milliseconds
+const streamRetry = 3000
+
+var (
+	ErrorStreamEventType   = errors.New("event type must be one of created, updated or deleted")
+	ErrorStreamLastEventID = errors.New("last event id must be an id of received event")
+)
+
+// PostsStreamHandler is an endpoint handler for Server-Sent Events stream of post changes,
+// clients resume the stream after reconnection with Last-Event-ID header, ids are prefixed by epoch of the broker
+// and clients of other epochs get reset event
+func (app *App) PostsStreamHandler(w http.ResponseWriter, r *http.Request) {
+	filter, err := streamFilter(r.URL.Query())
+	if err != nil {
+		app.WebServer.ErrorJSON(w, r, err, http.StatusBadRequest)
+		return
+	}
+	lastIDParam := r.Header.Get("Last-Event-ID")
+	if lastIDParam == "" {
+		lastIDParam = r.URL.Query().Get("last_event_id")
+	}
+	var epoch, lastID uint64
+	if lastIDParam != "" {
+		epoch, lastID, err = events.ParseStreamID(lastIDParam)
+		if err != nil {
+			app.WebServer.ErrorJSON(w, r, ErrorStreamLastEventID, http.StatusBadRequest)
+			return
+		}
+	}
+
+	sub, replay, complete := app.Events.Subscribe(epoch, lastID, filter)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // disable buffering of proxies
+	w.WriteHeader(http.StatusOK)
+
+	stream := &eventStream{w: w, rc: http.NewResponseController(w)}
+	stream.write(fmt.Sprintf("retry: %d\n\n", streamRetry))
+	// clients should reload posts when some of missed events are no longer available
+	if !complete {
+		stream.write("event: reset\ndata: {\"message\":\"missed events are not available\"}\n\n")
+	}
+	for _, event := range replay {
+		stream.event(event)
+	}
+	stream.flush()
+
+	interval := app.StreamHeartbeat
+	if interval <= 0 {
+		interval = defaultStreamHeartbeat
+	}
+	heartbeat := time.NewTicker(interval)
+	defer heartbeat.Stop()
+	for stream.err == nil {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-sub.Events():
+			if !ok {
+				// slow clients are disconnected and resume from replay buffer
+				if err := sub.Err(); err != nil {
+					logging.FromContext(r.Context()).Info("stream closed", slog.Any("reason", err))
+				}
+				return
+			}
+			stream.event(event)
+		case <-heartbeat.C:
+			stream.write(": heartbeat\n\n")
+		}
+		stream.flush()
+	}
+	logging.FromContext(r.Context()).Info("stream client disconnected", slog.Any("error", stream.err))
+}
+
+// eventStream writes events in Server-Sent Events format, writing is stopped after the first error
+type eventStream struct {
+	w   http.ResponseWriter
+	rc  *http.ResponseController
+	err error
+}
+
+func (s *eventStream) event(event events.Event) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		s.err = err
+		return
+	}
+	s.write(fmt.Sprintf("id: %s\nevent: %s\ndata: %s\n\n", event.StreamID(), event.Type, data))
+}
+
+func (s *eventStream) write(text string) {
+	if s.err != nil {
+		return
+	}
+	// slow clients could not block the stream forever
+	_ = s.rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+	_, s.err = fmt.Fprint(s.w, text)
+}
+
+func (s *eventStream) flush() {
+	if s.err != nil {
+		return
+	}
+	if err := s.rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		s.err = err
+	}
+}
+
+// streamFilter creates events filter from query parameters: comma separated event types and post ids,
+// author and case insensitive part of title, deleted posts are matched by type and id only
+func streamFilter(query url.Values) (events.Filter, error) {
+	types := make(map[events.Type]bool)
+	for _, value := range splitParam(query.Get("type")) {
+		eventType := events.Type(value)
+		if eventType != events.TypeCreated && eventType != events.TypeUpdated && eventType != events.TypeDeleted {
+			return nil, ErrorStreamEventType
+		}
+		types[eventType] = true
+	}
+	ids := make(map[int]bool)
+	for _, value := range splitParam(query.Get("id")) {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, err
+		}
+		ids[id] = true
+	}
+	author := query.Get("author")
+	title := strings.ToLower(query.Get("title"))
+
+	return func(event events.Event) bool {
+		if len(types) > 0 && !types[event.Type] {
+			return false
+		}
+		if len(ids) > 0 && !ids[event.PostID] {
+			return false
+		}
+		if event.Post == nil {
+			return true
+		}
+		if author != "" && event.Post.Author != author {
+			return false
+		}
+		return title == "" || strings.Contains(strings.ToLower(event.Post.Title), title)
+	}, nil
+}
+
+// splitParam splits comma separated query parameter
+func splitParam(param string) []string {
+	var values []string
+	for _, value := range strings.Split(param, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"api-service/internal/domain"
+	"api-service/internal/events"
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readStreamEvents reads names and ids of events until count events are received, epoch is removed from ids
+func readStreamEvents(t *testing.T, reader *bufio.Reader, count int) []string {
+	t.Helper()
+
+	var received []string
+	id := ""
+	for len(received) < count {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("stream finished after %v: %v", received, err)
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "id: "):
+			_, id, _ = strings.Cut(strings.TrimPrefix(line, "id: "), "-")
+		case strings.HasPrefix(line, "event: "):
+			received = append(received, strings.TrimPrefix(line, "event: ")+":"+id)
+			id = ""
+		}
+	}
+	return received
+}
+
+// TestStream_PostsStream tests streaming of post changes with filters and resumption
+func TestStream_PostsStream(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		query       string
+		lastEventID string // %[1]d is replaced by epoch of broker
+		expected    []string
+	}{
+		{"all events", "", "", []string{"created:3", "updated:4", "deleted:5"}},
+		{"filtered by type", "?type=deleted", "", []string{"deleted:5"}},
+		{"filtered by title", "?title=title%203", "", []string{"created:3", "deleted:5"}},
+		{"resumed stream", "", "%[1]d-1", []string{"created:2", "created:3", "updated:4", "deleted:5"}},
+		{"lost events", "", "%[1]d-42", []string{"reset:", "created:3", "updated:4", "deleted:5"}},
+		{"other epoch", "", "1-1", []string{"reset:", "created:3", "updated:4", "deleted:5"}},
+		{"id without epoch", "", "1", []string{"reset:", "created:3", "updated:4", "deleted:5"}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			app, memoryStore := newTestTransferApp(t, 0)
+			app.Events = events.NewBroker(10, 10)
+			memoryStore.Observe(app.Events.Observer())
+			ctx := context.Background()
+			_, _ = memoryStore.Insert(ctx, domain.Post{Title: "Title 1"})
+			_, _ = memoryStore.Insert(ctx, domain.Post{Title: "Title 2"})
+
+			srv := httptest.NewServer(app.routes())
+			defer srv.Close()
+			defer app.Events.Close()
+
+			req, _ := http.NewRequest("GET", srv.URL+"/v1/posts/stream"+tt.query, nil)
+			lastEventID := tt.lastEventID
+			if strings.Contains(lastEventID, "%[") {
+				lastEventID = fmt.Sprintf(lastEventID, app.Events.Epoch())
+			}
+			if lastEventID != "" {
+				req.Header.Set("Last-Event-ID", lastEventID)
+			}
+			resp, err := srv.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+				t.Fatalf("unexpected response %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+			}
+
+			// changes are made after subscription is established
+			for app.Events.Subscribers() == 0 {
+				time.Sleep(time.Millisecond)
+			}
+			id, _ := memoryStore.Insert(ctx, domain.Post{Title: "Title 3"})
+			_ = memoryStore.Update(ctx, 1, domain.Post{Title: "Updated"})
+			_ = memoryStore.Delete(ctx, id)
+
+			received := readStreamEvents(t, bufio.NewReader(resp.Body), len(tt.expected))
+			if strings.Join(received, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("expected %v, but got %v", tt.expected, received)
+			}
+		})
+	}
+}
@@ -0,0 +1,422 @@
+package main
+
+import (
+	"api-service/internal/auth"
+	"api-service/internal/domain"
+	"api-service/internal/logging"
+	"api-service/internal/server"
+	"api-service/internal/store"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// exportPageSize is a number of posts fetched from store at once during export
+const exportPageSize = 100
+
+// importMaxBytes limits size of imported data
+const importMaxBytes = 32 << 20 // 32 Mb
+
+// importMaxErrors limits number of line errors included into import report
+const importMaxErrors = 100
+
+// Import conflict modes define handling of imported posts whose id already exists
+const (
+	ImportModeUpsert = "upsert"
+	ImportModeSkip   = "skip"
+	ImportModeFail   = "fail"
+)
+
+// Export formats
+const (
+	ExportFormatNDJSON = "ndjson"
+	ExportFormatJSON   = "json"
+)
+
+// ndjsonContentType is a media type of newline delimited JSON
+const ndjsonContentType = "application/x-ndjson"
+
+var (
+	ErrorExportFormat  = errors.New("export format must be one of ndjson or json")
+	ErrorImportMode    = errors.New("import mode must be one of upsert, skip or fail")
+	ErrorImportFailed  = errors.New("import failed, no posts were imported")
+	ErrorPostExists    = errors.New("post already exists")
+	ErrorTitleRequired = errors.New("title is required")
+)
+
+// JsonPostRecord represent post in exported and imported data, the same format is used by store seed data
+type JsonPostRecord struct {
+	ID      int    `json:"id,omitempty"`
+	Title   string `json:"title"`
+	Content string `json:"content"`
+	Author  string `json:"author"`
+	Owner   string `json:"owner,omitempty"`
+	Format  string `json:"format,omitempty"` // plain when omitted
+}
+
+// JsonImportError represent failed import of a post, line is a line of NDJSON input or position in list of posts
+type JsonImportError struct {
+	Line  int    `json:"line"`
+	ID    int    `json:"id,omitempty"`
+	Error string `json:"error"`
+}
+
+// JsonImportReport represent result of posts import
+type JsonImportReport struct {
+	Created   int               `json:"created"`
+	Updated   int               `json:"updated"`
+	Skipped   int               `json:"skipped"`
+	Failed    int               `json:"failed"`
+	Committed bool              `json:"committed"`
+	Errors    []JsonImportError `json:"errors,omitempty"`
+}
+
+// PostsExportHandler is an endpoint handler for export of all posts as NDJSON or JSON array,
+// posts are streamed page by page in the order of ids, so the whole collection is never kept in memory
+func (app *App) PostsExportHandler(w http.ResponseWriter, r *http.Request) {
+	format, err := exportFormat(r)
+	if err != nil {
+		app.WebServer.ErrorJSON(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	// headers are sent with the first page, so failure of the first request could still be reported
+	started := false
+	start := func() {
+		started = true
+		contentType, prefix := ndjsonContentType, ""
+		if format == ExportFormatJSON {
+			contentType, prefix = "application/json", "["
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"posts.%s\"", format))
+		w.WriteHeader(http.StatusOK)
+		_, _ = io.WriteString(w, prefix)
+	}
+
+	count, afterID := 0, 0
+	for {
+		posts, err := app.exportPage(r.Context(), afterID)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("failed to export posts", slog.Int("exported", count), slog.Any("error", err))
+			if !started {
+				app.WebServer.ErrorJSON(w, r, err, http.StatusInternalServerError)
+				return
+			}
+			// connection is aborted, so that partial NDJSON output is not taken for complete export
+			panic(http.ErrAbortHandler)
+		}
+		if !started {
+			start()
+		}
+
+		for _, post := range posts {
+			out, err := json.Marshal(newPostRecord(post))
+			if err != nil {
+				panic(http.ErrAbortHandler)
+			}
+			switch {
+			case format == ExportFormatNDJSON:
+				out = append(out, '\n')
+			case count > 0:
+				out = append([]byte{','}, out...)
+			}
+			if _, err := w.Write(out); err != nil {
+				return
+			}
+			count++
+			afterID = post.ID
+		}
+		_ = http.NewResponseController(w).Flush()
+
+		if len(posts) < exportPageSize {
+			break
+		}
+	}
+
+	if format == ExportFormatJSON {
+		_, _ = io.WriteString(w, "]")
+	}
+	logging.FromContext(r.Context()).Info("posts exported", slog.Int("count", count))
+}
+
+// PostsImportHandler is an endpoint handler for bulk import of posts from NDJSON or seed data format,
+// with atomic option either all posts are imported or none of them; the whole input is read and parsed
+// before the store is changed, so slow uploads do not hold store transaction
+func (app *App) PostsImportHandler(w http.ResponseWriter, r *http.Request) {
+	// read and parse query parameters
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = ImportModeFail
+	}
+	if mode != ImportModeUpsert && mode != ImportModeSkip && mode != ImportModeFail {
+		app.WebServer.ErrorJSON(w, r, ErrorImportMode, http.StatusBadRequest)
+		return
+	}
+	atomic := false
+	if atomicParam := r.URL.Query().Get("atomic"); atomicParam != "" {
+		var err error
+		atomic, err = strconv.ParseBool(atomicParam)
+		if err != nil {
+			app.WebServer.ErrorJSON(w, r, err, http.StatusBadRequest)
+			return
+		}
+	}
+
+	body, err := app.WebServer.Body(w, r, importMaxBytes)
+	if err != nil {
+		app.WebServer.ErrorJSON(w, r, err, server.ReadErrorStatus(err))
+		return
+	}
+
+	// posts without owner are owned by importing user
+	owner := ""
+	if principal, ok := auth.PrincipalFromContext(r.Context()); ok {
+		owner = principal.ID
+	}
+
+	// input is limited by importMaxBytes, so parsed records fit in memory
+	type parsedRecord struct {
+		line   int
+		record JsonPostRecord
+		err    error
+	}
+	var records []parsedRecord
+	err = readPostRecords(r.Header.Get("Content-Type"), body, func(line int, record JsonPostRecord, err error) {
+		records = append(records, parsedRecord{line: line, record: record, err: err})
+	})
+	if err != nil {
+		app.WebServer.ErrorJSON(w, r, err, server.ReadErrorStatus(err))
+		return
+	}
+
+	var report JsonImportReport
+	importPosts := func(ctx context.Context, st store.PostStore) error {
+		report = JsonImportReport{}
+		for _, parsed := range records {
+			err := parsed.err
+			if err == nil {
+				postCtx, cancel := context.WithTimeout(ctx, app.WebServer.Timeout*time.Second)
+				err = importPost(postCtx, st, parsed.record, mode, owner, &report)
+				cancel()
+			}
+			if err != nil {
+				report.Failed++
+				if len(report.Errors) < importMaxErrors {
+					report.Errors = append(report.Errors, JsonImportError{Line: parsed.line, ID: parsed.record.ID, Error: err.Error()})
+				}
+			}
+		}
+		if atomic && report.Failed > 0 {
+			return ErrorImportFailed
+		}
+		return nil
+	}
+
+	// atomic import holds store transaction, so it is limited as a whole
+	if atomic {
+		ctx, cancel := context.WithTimeout(r.Context(), app.WebServer.Timeout*time.Second)
+		defer cancel()
+		err = app.PostStore.WithTx(ctx, func(tx store.PostStore) error {
+			return importPosts(ctx, tx)
+		})
+	} else {
+		err = importPosts(r.Context(), app.PostStore)
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Warn("failed to import posts", slog.Any("error", err))
+		if errors.Is(err, ErrorImportFailed) {
+			response := server.JsonResponse{
+				Error:   true,
+				Message: err.Error(),
+				Data:    report,
+				TraceID: w.Header().Get(server.TraceIDHeader),
+			}
+			_ = app.WebServer.WriteJSON(w, http.StatusUnprocessableEntity, response)
+			return
+		}
+		app.WebServer.ErrorJSON(w, r, err, http.StatusInternalServerError)
+		return
+	}
+	report.Committed = true
+	logging.FromContext(r.Context()).Info("posts imported",
+		slog.Int("created", report.Created),
+		slog.Int("updated", report.Updated),
+		slog.Int("skipped", report.Skipped),
+		slog.Int("failed", report.Failed),
+	)
+
+	// return successful json response with import report
+	response := server.JsonResponse{
+		Error:   false,
+		Message: "posts imported",
+		Data:    report,
+	}
+	_ = app.WebServer.WriteJSON(w, http.StatusOK, response)
+}
+
+// exportPage fetches a page of posts following afterID
+func (app *App) exportPage(ctx context.Context, afterID int) ([]domain.Post, error) {
+	ctx, cancel := context.WithTimeout(ctx, app.WebServer.Timeout*time.Second)
+	defer cancel()
+
+	return store.Scan(ctx, app.PostStore, afterID, exportPageSize)
+}
+
+// exportFormat selects export format by format query parameter or Accept header, NDJSON is used by default
+func exportFormat(r *http.Request) (string, error) {
+	switch format := r.URL.Query().Get("format"); format {
+	case ExportFormatNDJSON, ExportFormatJSON:
+		return format, nil
+	case "":
+	default:
+		return "", ErrorExportFormat
+	}
+
+	accept := r.Header.Get("Accept")
+	if strings.Contains(accept, "application/json") && !strings.Contains(accept, ndjsonContentType) {
+		return ExportFormatJSON, nil
+	}
+	return ExportFormatNDJSON, nil
+}
+
+// importPost stores imported post according to conflict mode and updates import report
+func importPost(ctx context.Context, st store.PostStore, record JsonPostRecord, mode string, owner string, report *JsonImportReport) error {
+	if strings.TrimSpace(record.Title) == "" {
+		return ErrorTitleRequired
+	}
+	post := domain.Post{
+		ID:      record.ID,
+		Title:   record.Title,
+		Content: record.Content,
+		Author:  record.Author,
+		Owner:   record.Owner,
+		Format:  record.Format,
+	}
+
+	// posts without id are always created
+	if record.ID == 0 {
+		if post.Owner == "" {
+			post.Owner = owner
+		}
+		_, err := st.Insert(ctx, post)
+		if err != nil {
+			return err
+		}
+		report.Created++
+		return nil
+	}
+
+	_, err := st.GetOne(ctx, record.ID)
+	exists := err == nil
+	if err != nil && !errors.Is(err, domain.ErrorPostNotFound) {
+		return err
+	}
+	if exists {
+		switch mode {
+		case ImportModeSkip:
+			report.Skipped++
+			return nil
+		case ImportModeFail:
+			return ErrorPostExists
+		}
+	} else if post.Owner == "" {
+		post.Owner = owner
+	}
+
+	created, err := st.Put(ctx, post)
+	if err != nil {
+		return err
+	}
+	if created {
+		report.Created++
+	} else {
+		report.Updated++
+	}
+	return nil
+}
+
+// readPostRecords reads posts from NDJSON input or JSON input in seed data format or as a list of posts,
+// fn is called for every post, malformed posts are reported to fn with an error
+func readPostRecords(contentType string, body io.Reader, fn func(line int, record JsonPostRecord, err error)) error {
+	mediaType := "application/json"
+	if contentType != "" {
+		var err error
+		mediaType, _, err = mime.ParseMediaType(contentType)
+		if err != nil {
+			return server.ErrorUnsupportedMediaType
+		}
+	}
+
+	switch mediaType {
+	case ndjsonContentType, "application/ndjson", "application/jsonl":
+		scanner := bufio.NewScanner(body)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+		line := 0
+		for scanner.Scan() {
+			line++
+			if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+				continue
+			}
+			var record JsonPostRecord
+			err := json.Unmarshal(scanner.Bytes(), &record)
+			fn(line, record, err)
+		}
+		return scanner.Err()
+
+	case "application/json":
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return err
+		}
+		var posts []json.RawMessage
+		if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+			err = json.Unmarshal(trimmed, &posts)
+		} else {
+			var fileData struct {
+				Posts []json.RawMessage `json:"posts"`
+			}
+			err = json.Unmarshal(trimmed, &fileData)
+			posts = fileData.Posts
+		}
+		if err != nil {
+			return err
+		}
+		for i, raw := range posts {
+			var record JsonPostRecord
+			err := json.Unmarshal(raw, &record)
+			fn(i+1, record, err)
+		}
+		return nil
+
+	default:
+		return server.ErrorUnsupportedMediaType
+	}
+}
+
+// newPostRecord converts post into exported record
+func newPostRecord(post domain.Post) JsonPostRecord {
+	record := JsonPostRecord{
+		ID:      post.ID,
+		Title:   post.Title,
+		Content: post.Content,
+		Author:  post.Author,
+		Owner:   post.Owner,
+		Format:  post.Format,
+	}
+	// plain posts are exported without format, so that exports are read by older versions
+	if record.Format == domain.FormatPlain {
+		record.Format = ""
+	}
+	return record
+}
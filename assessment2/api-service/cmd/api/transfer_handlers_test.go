@@ -0,0 +1,215 @@
+package main
+
+import (
+	"api-service/internal/domain"
+	"api-service/internal/store"
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newTestTransferApp(t *testing.T, posts int) (*App, *store.MemoryPostStore) {
+	t.Helper()
+
+	memoryStore, _ := store.NewMemoryPostStore("")
+	for i := 1; i <= posts; i++ {
+		_, _ = memoryStore.Insert(context.Background(), domain.Post{Title: fmt.Sprintf("Title %d", i), Owner: "author-1"})
+	}
+	app := newTestAuthApp(t, newHandlersFixture(t))
+	app.PostStore = memoryStore
+	app.WebServer.Timeout = 2
+	return app, memoryStore
+}
+
+// TestTransfer_Export tests streaming export of all posts
+func TestTransfer_Export(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		query       string
+		accept      string
+		contentType string
+	}{
+		{"ndjson by default", "", "", "application/x-ndjson"},
+		{"json array by accept header", "", "application/json", "application/json"},
+		{"json array by format parameter", "?format=json", "application/x-ndjson", "application/json"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			// export spans several store pages
+			app, _ := newTestTransferApp(t, exportPageSize+exportPageSize/2)
+
+			req, _ := http.NewRequest("GET", "/v1/posts/export"+tt.query, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rr := httptest.NewRecorder()
+			app.routes().ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("expected http.StatusOK, but got %d", rr.Code)
+			}
+			if contentType := rr.Header().Get("Content-Type"); contentType != tt.contentType {
+				t.Errorf("expected content type %s, but got %s", tt.contentType, contentType)
+			}
+
+			var records []JsonPostRecord
+			if tt.contentType == "application/json" {
+				if err := json.Unmarshal(rr.Body.Bytes(), &records); err != nil {
+					t.Fatal(err)
+				}
+			} else {
+				scanner := bufio.NewScanner(rr.Body)
+				for scanner.Scan() {
+					var record JsonPostRecord
+					if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+						t.Fatal(err)
+					}
+					records = append(records, record)
+				}
+			}
+			if len(records) != exportPageSize+exportPageSize/2 {
+				t.Fatalf("expected all posts to be exported, but got %d", len(records))
+			}
+			for i, record := range records {
+				if record.ID != i+1 || record.Title != fmt.Sprintf("Title %d", i+1) {
+					t.Fatalf("unexpected record %+v at %d", record, i)
+				}
+			}
+		})
+	}
+}
+
+// failingScanStore fails to scan posts following the first page
+type failingScanStore struct {
+	*store.MemoryPostStore
+}
+
+func (s failingScanStore) Scan(ctx context.Context, afterID int, limit int) ([]domain.Post, error) {
+	if afterID > 0 {
+		return nil, errors.New("store is not available")
+	}
+	return s.MemoryPostStore.Scan(ctx, afterID, limit)
+}
+
+// TestTransfer_ExportFailure tests that export failing after the first page aborts connection
+func TestTransfer_ExportFailure(t *testing.T) {
+	t.Parallel()
+	app, memoryStore := newTestTransferApp(t, exportPageSize+1)
+	app.PostStore = failingScanStore{memoryStore}
+	srv := httptest.NewServer(app.routes())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/v1/posts/export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected http.StatusOK, but got %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err == nil {
+		t.Errorf("expected aborted response, but got complete body of %d bytes", len(body))
+	}
+	if lines := strings.Count(string(body), "\n"); lines > exportPageSize {
+		t.Errorf("expected at most the first page, but got %d lines", lines)
+	}
+}
+
+// TestTransfer_Import tests import of posts with conflict modes and atomic option
+func TestTransfer_Import(t *testing.T) {
+	t.Parallel()
+
+	ndjson := `{"id": 1, "title": "Replaced"}
+{"title": "New post"}
+
+{"id": 10, "title": "Post with id", "owner": "author-2"}
+{"id": 11, "title": ""}
+not a json
+`
+	tests := []struct {
+		name           string
+		query          string
+		contentType    string
+		body           string
+		token          string
+		expected       int
+		expectedReport JsonImportReport
+		expectedTitle  string // title of the first post after import
+		expectedPosts  int
+	}{
+		{"author is forbidden", "", "application/x-ndjson", ndjson, "author-token", http.StatusForbidden, JsonImportReport{}, "Title 1", 2},
+		{"fail mode", "", "application/x-ndjson", ndjson, "editor-token", http.StatusOK,
+			JsonImportReport{Created: 2, Failed: 3, Committed: true, Errors: []JsonImportError{
+				{Line: 1, ID: 1, Error: "post already exists"},
+				{Line: 5, ID: 11, Error: "title is required"},
+				{Line: 6, Error: "invalid character 'o' in literal null (expecting 'u')"},
+			}}, "Title 1", 4},
+		{"upsert mode", "?mode=upsert", "application/x-ndjson", ndjson, "editor-token", http.StatusOK,
+			JsonImportReport{Created: 2, Updated: 1, Failed: 2, Committed: true, Errors: []JsonImportError{
+				{Line: 5, ID: 11, Error: "title is required"},
+				{Line: 6, Error: "invalid character 'o' in literal null (expecting 'u')"},
+			}}, "Replaced", 4},
+		{"skip mode", "?mode=skip", "application/json", `{"posts": [{"id": 1, "title": "Replaced"}, {"id": 3, "title": "Seed post"}]}`, "editor-token", http.StatusOK,
+			JsonImportReport{Created: 1, Skipped: 1, Committed: true}, "Title 1", 3},
+		{"atomic failure", "?mode=upsert&atomic=true", "application/x-ndjson", ndjson, "editor-token", http.StatusUnprocessableEntity,
+			JsonImportReport{Created: 2, Updated: 1, Failed: 2, Errors: []JsonImportError{
+				{Line: 5, ID: 11, Error: "title is required"},
+				{Line: 6, Error: "invalid character 'o' in literal null (expecting 'u')"},
+			}}, "Title 1", 2},
+		{"atomic success", "?mode=upsert&atomic=true", "application/json", `[{"id": 1, "title": "Replaced"}, {"title": "New post"}]`, "editor-token", http.StatusOK,
+			JsonImportReport{Created: 1, Updated: 1, Committed: true}, "Replaced", 3},
+		{"unknown mode", "?mode=merge", "application/x-ndjson", ndjson, "editor-token", http.StatusBadRequest, JsonImportReport{}, "Title 1", 2},
+		{"unsupported format", "", "text/csv", "id,title", "editor-token", http.StatusUnsupportedMediaType, JsonImportReport{}, "Title 1", 2},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			app, memoryStore := newTestTransferApp(t, 2)
+
+			req, _ := http.NewRequest("POST", "/v1/posts/import"+tt.query, strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			req.Header.Set("Content-Type", tt.contentType)
+			rr := httptest.NewRecorder()
+			app.routes().ServeHTTP(rr, req)
+
+			if rr.Code != tt.expected {
+				t.Fatalf("expected status %d, but got %d: %s", tt.expected, rr.Code, rr.Body.String())
+			}
+			if tt.expected == http.StatusOK || tt.expected == http.StatusUnprocessableEntity {
+				var response struct {
+					Data JsonImportReport `json:"data"`
+				}
+				_ = json.Unmarshal(rr.Body.Bytes(), &response)
+				expected, _ := json.Marshal(tt.expectedReport)
+				actual, _ := json.Marshal(response.Data)
+				if string(actual) != string(expected) {
+					t.Errorf("expected report %s, but got %s", expected, actual)
+				}
+			}
+
+			post, _ := memoryStore.GetOne(context.Background(), 1)
+			if post.Title != tt.expectedTitle || post.Owner != "author-1" {
+				t.Errorf("unexpected post after import %+v", post)
+			}
+			stats, _ := memoryStore.Stats(context.Background())
+			if stats.Posts != tt.expectedPosts {
+				t.Errorf("expected %d posts, but got %d", tt.expectedPosts, stats.Posts)
+			}
+		})
+	}
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestVersions_Routes tests that post routes are served by both API versions
+func TestVersions_Routes(t *testing.T) {
+	t.Parallel()
+
+	registered := registeredRoutes(t, newTestFullApp(t).routes())
+	for _, route := range []string{"GET /posts", "GET /posts/{id}", "POST /posts", "PUT /posts/{id}", "DELETE /posts/{id}"} {
+		method, path, _ := strings.Cut(route, " ")
+		for _, version := range []string{ApiVersion, ApiV2} {
+			if !slices.Contains(registered, method+" "+version+path) {
+				t.Errorf("route %s %s%s is not registered", method, version, path)
+			}
+		}
+	}
+}
+
+// TestVersions_Posts tests envelopes of post responses and deprecation headers of API versions
+func TestVersions_Posts(t *testing.T) {
+	t.Parallel()
+
+	deprecation := Deprecation{
+		Deprecated: time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC),
+		Sunset:     time.Date(2027, 4, 19, 0, 0, 0, 0, time.UTC),
+		Successor:  ApiV2,
+	}
+
+	tests := []struct {
+		name             string
+		method           string
+		path             string
+		token            string
+		body             string
+		expectedStatus   int
+		expectedBody     string // %[1]s, %[2]s and %[3]s are replaced by v2 representations of posts
+		expectedLocation string
+		expectedLink     string // successor of deprecated route, empty when route is not deprecated
+	}{
+		{
+			"v1 list", "GET", "/v1/posts?limit=2", "", "", http.StatusOK,
+			`{"error":false,"message":"","data":[{"ID":1,"Title":"Title 1","Content":"","Author":"","Owner":"author-1","Version":1,"Format":"plain"},{"ID":2,"Title":"Title 2","Content":"","Author":"","Owner":"author-1","Version":1,"Format":"plain"}]}`,
+			"", `</v2/posts>; rel="successor-version"`,
+		},
+		{
+			"v1 missing post", "GET", "/v1/posts/10", "", "", http.StatusNotFound,
+			`{"error":true,"message":"post not found"}`,
+			"", `</v2/posts/10>; rel="successor-version"`,
+		},
+		{
+			"v1 create", "POST", "/v1/posts", "author-token", `{"title":"New","content":"Content","author":"Author"}`, http.StatusOK,
+			`{"error":false,"message":"post added","data":4}`,
+			"", `</v2/posts>; rel="successor-version"`,
+		},
+		{
+			"v1 delete", "DELETE", "/v1/posts/1", "author-token", "", http.StatusOK,
+			`{"error":false,"message":"post deleted"}`,
+			"", `</v2/posts/1>; rel="successor-version"`,
+		},
+		{
+			"v2 first page", "GET", "/v2/posts?limit=2", "", "", http.StatusOK,
+			`{"data":[%[1]s,%[2]s],"meta":{"page":1,"limit":2,"has_more":true}}`,
+			"", "",
+		},
+		{
+			"v2 last page", "GET", "/v2/posts?page=2&limit=2", "", "", http.StatusOK,
+			`{"data":[%[3]s],"meta":{"page":2,"limit":2,"has_more":false}}`,
+			"", "",
+		},
+		{
+			"v2 empty page", "GET", "/v2/posts?title=missing", "", "", http.StatusOK,
+			`{"data":[],"meta":{"page":1,"limit":5,"has_more":false}}`,
+			"", "",
+		},
+		{
+			"v2 post", "GET", "/v2/posts/1", "", "", http.StatusOK,
+			`{"data":%[1]s}`,
+			"", "",
+		},
+		{
+			"v2 missing post", "GET", "/v2/posts/10", "", "", http.StatusNotFound,
+			`{"error":{"status":404,"message":"post not found"}}`,
+			"", "",
+		},
+		{
+			"v2 anonymous create", "POST", "/v2/posts", "", `{"title":"New","content":"Content","author":"Author"}`, http.StatusUnauthorized,
+			`{"error":{"status":401,"message":"authentication required"}}`,
+			"", "",
+		},
+		{
+			"v2 create", "POST", "/v2/posts", "author-token", `{"title":"New","content":"Content","author":"Author"}`, http.StatusCreated,
+			`{"data":{"id":4}}`,
+			"/v2/posts/4", "",
+		},
+		{
+			"v2 update", "PUT", "/v2/posts/1", "author-token", `{"title":"Updated","content":"Content","author":"Author"}`, http.StatusNoContent,
+			"",
+			"", "",
+		},
+		{
+			"v2 delete", "DELETE", "/v2/posts/1", "author-token", "", http.StatusNoContent,
+			"",
+			"", "",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			app, memoryStore := newTestTransferApp(t, 3)
+			app.Deprecation = deprecation
+
+			// timestamps of posts are set by the store
+			var posts []any
+			for id := 1; id <= 3; id++ {
+				post, _ := memoryStore.GetOne(context.Background(), id)
+				timestamp := post.Created.UTC().Truncate(time.Second).Format(time.RFC3339)
+				posts = append(posts, fmt.Sprintf(`{"id":%d,"title":%q,"content":"","author":"","owner":"author-1","version":1,"format":"plain","created_at":%q,"updated_at":%q}`,
+					post.ID, post.Title, timestamp, timestamp))
+			}
+
+			req, _ := http.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rr := httptest.NewRecorder()
+			app.routes().ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, but got %d", tt.expectedStatus, rr.Code)
+			}
+			expectedBody := tt.expectedBody
+			if strings.Contains(expectedBody, "%[") {
+				expectedBody = fmt.Sprintf(expectedBody, posts...)
+			}
+			if body := strings.TrimSpace(rr.Body.String()); body != expectedBody {
+				t.Errorf("expected body %s, but got %s", expectedBody, body)
+			}
+			if location := rr.Header().Get("Location"); location != tt.expectedLocation {
+				t.Errorf("expected location %q, but got %q", tt.expectedLocation, location)
+			}
+
+			// deprecated routes announce their sunset and successor
+			expectedDeprecation, expectedSunset := "", ""
+			if tt.expectedLink != "" {
+				expectedDeprecation, expectedSunset = "@1792368000", "Mon, 19 Apr 2027 00:00:00 GMT"
+			}
+			if actual := rr.Header().Get("Deprecation"); actual != expectedDeprecation {
+				t.Errorf("expected Deprecation header %q, but got %q", expectedDeprecation, actual)
+			}
+			if actual := rr.Header().Get("Sunset"); actual != expectedSunset {
+				t.Errorf("expected Sunset header %q, but got %q", expectedSunset, actual)
+			}
+			if actual := rr.Header().Get("Link"); actual != tt.expectedLink {
+				t.Errorf("expected Link header %q, but got %q", tt.expectedLink, actual)
+			}
+		})
+	}
+}
+
+// TestVersions_Formats tests that v2 is served in JSON only and v1 announces deprecation only when it is configured
+func TestVersions_Formats(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name                string
+		method              string
+		path                string
+		accept              string
+		contentType         string
+		expectedStatus      int
+		expectedContentType string
+	}{
+		{"v1 csv", "GET", "/v1/posts", "text/csv", "", http.StatusOK, "text/csv"},
+		{"v2 json", "GET", "/v2/posts", "application/json, text/csv;q=0.5", "", http.StatusOK, "application/json"},
+		{"v2 any format", "GET", "/v2/posts/1", "*/*", "", http.StatusOK, "application/json"},
+		{"v2 csv", "GET", "/v2/posts", "text/csv", "", http.StatusNotAcceptable, "application/json"},
+		{"v2 xml body", "POST", "/v2/posts", "", "application/xml", http.StatusUnsupportedMediaType, "application/json"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			app, _ := newTestTransferApp(t, 1)
+			app.Deprecation = Deprecation{Successor: ApiV2}
+
+			req, _ := http.NewRequest(tt.method, tt.path, strings.NewReader("<post><title>New</title></post>"))
+			if tt.method == "GET" {
+				req.Body, req.ContentLength = http.NoBody, 0
+			}
+			req.Header.Set("Authorization", "Bearer author-token")
+			req.Header.Set("Accept", tt.accept)
+			req.Header.Set("Content-Type", tt.contentType)
+			rr := httptest.NewRecorder()
+			app.routes().ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, but got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if contentType := rr.Header().Get("Content-Type"); !strings.HasPrefix(contentType, tt.expectedContentType) {
+				t.Errorf("expected content type %q, but got %q", tt.expectedContentType, contentType)
+			}
+			if status := rr.Code; status >= 400 && strings.HasPrefix(tt.path, ApiV2) &&
+				!strings.HasPrefix(rr.Body.String(), fmt.Sprintf(`{"error":{"status":%d`, status)) {
+				t.Errorf("expected v2 error envelope, but got %s", rr.Body.String())
+			}
+
+			// dates of deprecation are not announced until they are configured
+			if rr.Header().Get("Deprecation") != "" || rr.Header().Get("Sunset") != "" {
+				t.Errorf("unexpected deprecation headers %v", rr.Header())
+			}
+		})
+	}
+}
+
+// TestVersions_Timestamps tests that v2 reports update time of changed posts
+func TestVersions_Timestamps(t *testing.T) {
+	t.Parallel()
+	app, memoryStore := newTestTransferApp(t, 1)
+	created, _ := memoryStore.GetOne(context.Background(), 1)
+
+	// timestamps have a precision of seconds
+	time.Sleep(time.Until(created.Created.Truncate(time.Second).Add(time.Second)))
+	req, _ := http.NewRequest("PUT", "/v2/posts/1", strings.NewReader(`{"title":"Updated"}`))
+	req.Header.Set("Authorization", "Bearer author-token")
+	rr := httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, req)
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("expected status %d, but got %d: %s", http.StatusNoContent, rr.Code, rr.Body.String())
+	}
+
+	updated, _ := memoryStore.GetOne(context.Background(), 1)
+	req, _ = http.NewRequest("GET", "/v2/posts/1", nil)
+	rr = httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, req)
+	expected := fmt.Sprintf(`{"data":{"id":1,"title":"Updated","content":"","author":"","owner":"author-1","version":2,"format":"plain","created_at":%q,"updated_at":%q}}`,
+		created.Created.Truncate(time.Second).Format(time.RFC3339), updated.Updated.Truncate(time.Second).Format(time.RFC3339))
+	if body := strings.TrimSpace(rr.Body.String()); body != expected || !updated.Updated.After(created.Created) {
+		t.Errorf("expected body %s, but got %s", expected, body)
+	}
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"api-service/internal/events"
+	"api-service/internal/logging"
+	"api-service/internal/server"
+	"api-service/internal/webhooks"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+var (
+	ErrorWebhookURL    = errors.New("webhook url must be an absolute http or https url")
+	ErrorWebhookEvent  = errors.New("webhook events must be some of created, updated or deleted")
+	ErrorWebhookStatus = errors.New("delivery status must be one of pending, succeeded or dead")
+)
+
+// JsonWebhookPayload represent webhook subscription input data, secret is generated when it is empty,
+// subscriptions are active unless disabled explicitly
+type JsonWebhookPayload struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Secret string   `json:"secret"`
+	Active *bool    `json:"active"`
+}
+
+// JsonReplayReport represent number of deliveries queued by replay
+type JsonReplayReport struct {
+	Replayed int `json:"replayed"`
+}
+
+// WebhooksGetHandler is an endpoint handler for list of webhook subscriptions
+func (app *App) WebhooksGetHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), app.WebServer.Timeout*time.Second)
+	defer cancel()
+	subs, err := app.Webhooks.Store().Subscriptions(ctx)
+	if err != nil {
+		app.webhookError(w, r, err)
+		return
+	}
+
+	// secrets are returned only when subscription is created or secret is rotated
+	for i := range subs {
+		subs[i].Secret = ""
+	}
+	_ = app.WebServer.WriteJSON(w, http.StatusOK, server.JsonResponse{Data: subs})
+}
+
+// WebhooksGetOneHandler is an endpoint handler for specific webhook subscription
+func (app *App) WebhooksGetOneHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), app.WebServer.Timeout*time.Second)
+	defer cancel()
+	sub, err := app.Webhooks.Store().Subscription(ctx, chi.URLParam(r, "id"))
+	if err != nil {
+		app.webhookError(w, r, err)
+		return
+	}
+
+	sub.Secret = ""
+	_ = app.WebServer.WriteJSON(w, http.StatusOK, server.JsonResponse{Data: sub})
+}
+
+// WebhooksAddHandler is an endpoint handler for creating webhook subscription
+func (app *App) WebhooksAddHandler(w http.ResponseWriter, r *http.Request) {
+	var jsonPayload JsonWebhookPayload
+	if err := app.WebServer.Read(w, r, &jsonPayload); err != nil {
+		app.WebServer.ErrorJSON(w, r, err, server.ReadErrorStatus(err))
+		return
+	}
+
+	now := time.Now().UTC()
+	sub := webhooks.Subscription{ID: webhooks.NewID(), Active: true, CreatedAt: now}
+	if err := app.applyWebhookPayload(&sub, jsonPayload); err != nil {
+		app.WebServer.ErrorJSON(w, r, err, http.StatusBadRequest)
+		return
+	}
+	if sub.Secret == "" {
+		sub.Secret = webhooks.NewSecret()
+	}
+	sub.UpdatedAt = now
+
+	ctx, cancel := context.WithTimeout(r.Context(), app.WebServer.Timeout*time.Second)
+	defer cancel()
+	if err := app.Webhooks.Store().CreateSubscription(ctx, sub); err != nil {
+		app.webhookError(w, r, err)
+		return
+	}
+
+	headers := http.Header{"Location": []string{ApiVersion + "/webhooks/" + sub.ID}}
+	_ = app.WebServer.WriteJSON(w, http.StatusCreated, server.JsonResponse{Data: sub}, headers)
+}
+
+// WebhooksUpdateHandler is an endpoint handler for updating webhook subscription,
+// secret is kept unless a new one is provided
+func (app *App) WebhooksUpdateHandler(w http.ResponseWriter, r *http.Request) {
+	var jsonPayload JsonWebhookPayload
+	if err := app.WebServer.Read(w, r, &jsonPayload); err != nil {
+		app.WebServer.ErrorJSON(w, r, err, server.ReadErrorStatus(err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), app.WebServer.Timeout*time.Second)
+	defer cancel()
+	sub, err := app.Webhooks.Store().Subscription(ctx, chi.URLParam(r, "id"))
+	if err != nil {
+		app.webhookError(w, r, err)
+		return
+	}
+	if err := app.applyWebhookPayload(sub, jsonPayload); err != nil {
+		app.WebServer.ErrorJSON(w, r, err, http.StatusBadRequest)
+		return
+	}
+	sub.UpdatedAt = time.Now().UTC()
+	if err := app.Webhooks.Store().UpdateSubscription(ctx, *sub); err != nil {
+		app.webhookError(w, r, err)
+		return
+	}
+
+	if jsonPayload.Secret == "" {
+		sub.Secret = ""
+	}
+	_ = app.WebServer.WriteJSON(w, http.StatusOK, server.JsonResponse{Data: sub})
+}
+
+// WebhooksDeleteHandler is an endpoint handler for deleting webhook subscription with its deliveries
+func (app *App) WebhooksDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), app.WebServer.Timeout*time.Second)
+	defer cancel()
+	if err := app.Webhooks.Store().DeleteSubscription(ctx, chi.URLParam(r, "id")); err != nil {
+		app.webhookError(w, r, err)
+		return
+	}
+
+	_ = app.WebServer.WriteJSON(w, http.StatusOK, server.JsonResponse{})
+}
+
+// WebhooksDeliveriesHandler is an endpoint handler for delivery log of webhook subscription,
+// deliveries are filtered by status query parameter
+func (app *App) WebhooksDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	status := webhooks.Status(r.URL.Query().Get("status"))
+	if status != "" && status != webhooks.StatusPending && status != webhooks.StatusSucceeded && status != webhooks.StatusDead {
+		app.WebServer.ErrorJSON(w, r, ErrorWebhookStatus, http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), app.WebServer.Timeout*time.Second)
+	defer cancel()
+	deliveries, err := app.Webhooks.Store().Deliveries(ctx, chi.URLParam(r, "id"), status)
+	if err != nil {
+		app.webhookError(w, r, err)
+		return
+	}
+
+	_ = app.WebServer.WriteJSON(w, http.StatusOK, server.JsonResponse{Data: deliveries})
+}
+
+// WebhooksReplayDeliveryHandler is an endpoint handler for queueing a delivery again
+func (app *App) WebhooksReplayDeliveryHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), app.WebServer.Timeout*time.Second)
+	defer cancel()
+	delivery, err := app.Webhooks.Replay(ctx, chi.URLParam(r, "id"), chi.URLParam(r, "delivery"))
+	if err != nil {
+		app.webhookError(w, r, err)
+		return
+	}
+
+	_ = app.WebServer.WriteJSON(w, http.StatusAccepted, server.JsonResponse{Data: delivery})
+}
+
+// WebhooksReplayHandler is an endpoint handler for queueing again all dead-lettered deliveries of subscription
+func (app *App) WebhooksReplayHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), app.WebServer.Timeout*time.Second)
+	defer cancel()
+	replayed, err := app.Webhooks.ReplayDead(ctx, chi.URLParam(r, "id"))
+	if err != nil {
+		app.webhookError(w, r, err)
+		return
+	}
+
+	_ = app.WebServer.WriteJSON(w, http.StatusAccepted, server.JsonResponse{Data: JsonReplayReport{Replayed: replayed}})
+}
+
+// webhookError responds with status matching webhook store error
+func (app *App) webhookError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, webhooks.ErrorSubscriptionNotFound) || errors.Is(err, webhooks.ErrorDeliveryNotFound) {
+		app.WebServer.ErrorJSON(w, r, err, http.StatusNotFound)
+		return
+	}
+	logging.FromContext(r.Context()).Error("webhook store failed", slog.Any("error", err))
+	app.WebServer.ErrorJSON(w, r, err, http.StatusInternalServerError)
+}
+
+// applyWebhookPayload validates payload and copies it into subscription
+func (app *App) applyWebhookPayload(sub *webhooks.Subscription, jsonPayload JsonWebhookPayload) error {
+	target, err := url.Parse(jsonPayload.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return ErrorWebhookURL
+	}
+	if err := app.Webhooks.CheckURL(target); err != nil {
+		return err
+	}
+	types := make([]events.Type, 0, len(jsonPayload.Events))
+	for _, value := range jsonPayload.Events {
+		eventType := events.Type(value)
+		if eventType != events.TypeCreated && eventType != events.TypeUpdated && eventType != events.TypeDeleted {
+			return ErrorWebhookEvent
+		}
+		types = append(types, eventType)
+	}
+
+	sub.URL = target.String()
+	sub.Events = types
+	if jsonPayload.Secret != "" {
+		sub.Secret = jsonPayload.Secret
+	}
+	if jsonPayload.Active != nil {
+		sub.Active = *jsonPayload.Active
+	}
+	return nil
+}
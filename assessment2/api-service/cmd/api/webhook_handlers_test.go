@@ -0,0 +1,191 @@
+package main
+
+import (
+	"api-service/internal/events"
+	"api-service/internal/store"
+	"api-service/internal/webhooks"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestWebhookApp creates application delivering webhooks of post changes until test is finished,
+// receivers on loopback addresses are allowed
+func newTestWebhookApp(t *testing.T) *App {
+	t.Helper()
+
+	app, memoryStore := newTestTransferApp(t, 1)
+	webhookStore, _ := webhooks.NewFileStore("", 0)
+	app.Webhooks = webhooks.NewDispatcher(webhookStore, nil, webhooks.Config{
+		PollInterval: 5 * time.Millisecond,
+		Addresses:    webhooks.AddressPolicy{Allowed: []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}},
+	})
+	memoryStore.Observe(func(ctx context.Context, change store.Change) {
+		_ = app.Webhooks.Enqueue(ctx, events.FromChange(change))
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		app.Webhooks.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return app
+}
+
+// serveWebhookRequest serves request of admin and decodes data of response
+func serveWebhookRequest(t *testing.T, app *App, method string, path string, body string, data any) int {
+	t.Helper()
+
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer admin-token")
+	rr := httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, req)
+
+	if data != nil {
+		response := struct {
+			Data any `json:"data"`
+		}{Data: data}
+		_ = json.Unmarshal(rr.Body.Bytes(), &response)
+	}
+	return rr.Code
+}
+
+// TestWebhooks_Validation tests validation and authorization of subscription routes
+func TestWebhooks_Validation(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		token        string
+		body         string
+		expected     int
+		expectedBody string
+	}{
+		{"author is forbidden", "GET", "/v1/webhooks", "author-token", "", http.StatusForbidden,
+			`{"error":true,"message":"access forbidden"}`},
+		{"editor is forbidden", "POST", "/v1/webhooks", "editor-token", `{"url": "https://example.com"}`, http.StatusForbidden,
+			`{"error":true,"message":"access forbidden"}`},
+		{"invalid url", "POST", "/v1/webhooks", "admin-token", `{"url": "ftp://localhost"}`, http.StatusBadRequest,
+			`{"error":true,"message":"webhook url must be an absolute http or https url"}`},
+		{"loopback url", "POST", "/v1/webhooks", "admin-token", `{"url": "http://localhost:8080/hook"}`, http.StatusBadRequest,
+			`{"error":true,"message":"webhook receiver address is not allowed"}`},
+		{"metadata url", "POST", "/v1/webhooks", "admin-token", `{"url": "http://169.254.169.254/latest/meta-data"}`, http.StatusBadRequest,
+			`{"error":true,"message":"webhook receiver address is not allowed"}`},
+		{"invalid event", "POST", "/v1/webhooks", "admin-token", `{"url": "http://localhost", "events": ["read"]}`, http.StatusBadRequest,
+			`{"error":true,"message":"request body /events/0 must be one of created, updated, deleted"}`},
+		{"unknown subscription", "GET", "/v1/webhooks/unknown/deliveries", "admin-token", "", http.StatusNotFound,
+			`{"error":true,"message":"webhook subscription not found"}`},
+		{"invalid status", "GET", "/v1/webhooks/unknown/deliveries?status=failed", "admin-token", "", http.StatusBadRequest,
+			`{"error":true,"message":"query parameter status must be one of pending, succeeded, dead"}`},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			app, _ := newTestTransferApp(t, 0)
+			webhookStore, _ := webhooks.NewFileStore("", 0)
+			app.Webhooks = webhooks.NewDispatcher(webhookStore, nil, webhooks.Config{})
+
+			req, _ := http.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			rr := httptest.NewRecorder()
+			app.routes().ServeHTTP(rr, req)
+
+			if rr.Code != tt.expected {
+				t.Errorf("expected status %d, but got %d", tt.expected, rr.Code)
+			}
+			if strings.TrimSpace(rr.Body.String()) != tt.expectedBody {
+				t.Errorf("incorrect response body, got %s", rr.Body.String())
+			}
+		})
+	}
+}
+
+// TestWebhooks_Delivery tests delivery of post changes to subscribed receiver and replay of deliveries
+func TestWebhooks_Delivery(t *testing.T) {
+	t.Parallel()
+	received := make(chan webhooks.Payload, 10)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := webhooks.Verify("secret", r.Header.Get(webhooks.HeaderSignature), r.Header.Get(webhooks.HeaderTimestamp), body, time.Minute); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var payload webhooks.Payload
+		_ = json.Unmarshal(body, &payload)
+		received <- payload
+	}))
+	defer receiver.Close()
+	app := newTestWebhookApp(t)
+
+	var sub webhooks.Subscription
+	status := serveWebhookRequest(t, app, "POST", "/v1/webhooks", `{"url": "`+receiver.URL+`", "events": ["updated"], "secret": "secret"}`, &sub)
+	if status != http.StatusCreated || sub.ID == "" || sub.Secret != "secret" || !sub.Active {
+		t.Fatalf("unexpected subscription %d %+v", status, sub)
+	}
+	var subs []webhooks.Subscription
+	_ = serveWebhookRequest(t, app, "GET", "/v1/webhooks", "", &subs)
+	if len(subs) != 1 || subs[0].Secret != "" {
+		t.Errorf("expected subscription without secret, but got %+v", subs)
+	}
+
+	// only updates are subscribed
+	_ = serveWebhookRequest(t, app, "POST", "/v1/posts", `{"title": "New", "content": "Content", "author": "Author"}`, nil)
+	status = serveWebhookRequest(t, app, "PUT", "/v1/posts/1", `{"title": "Updated", "content": "Content", "author": "Author"}`, nil)
+	if status != http.StatusOK {
+		t.Fatalf("failed to update post: %d", status)
+	}
+	var payload webhooks.Payload
+	select {
+	case payload = <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook was not delivered")
+	}
+	if payload.Event.Type != events.TypeUpdated || payload.Event.PostID != 1 || payload.Event.Post.Title != "Updated" {
+		t.Errorf("unexpected payload %+v", payload)
+	}
+
+	// delivery log is updated after receiver responds
+	var deliveries []webhooks.Delivery
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		_ = serveWebhookRequest(t, app, "GET", "/v1/webhooks/"+sub.ID+"/deliveries?status=succeeded", "", &deliveries)
+		if len(deliveries) > 0 {
+			break
+		}
+	}
+	if len(deliveries) != 1 || deliveries[0].ID != payload.ID || len(deliveries[0].Attempts) != 1 {
+		t.Fatalf("unexpected delivery log %+v", deliveries)
+	}
+
+	status = serveWebhookRequest(t, app, "POST", "/v1/webhooks/"+sub.ID+"/deliveries/"+payload.ID+"/replay", "", nil)
+	if status != http.StatusAccepted {
+		t.Errorf("expected http.StatusAccepted, but got %d", status)
+	}
+	select {
+	case replayed := <-received:
+		if replayed.ID != payload.ID {
+			t.Errorf("expected replay of the same delivery, but got %+v", replayed)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook was not replayed")
+	}
+
+	status = serveWebhookRequest(t, app, "DELETE", "/v1/webhooks/"+sub.ID, "", nil)
+	if status != http.StatusOK {
+		t.Errorf("expected http.StatusOK, but got %d", status)
+	}
+}
@@ -0,0 +1,383 @@
+package main
+
+import (
+	"api-service/client"
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// newFlags creates flag set of command, usage is printed to standard error of CLI
+func (cli *CLI) newFlags(name string, arguments string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(cli.Stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(cli.Stderr, "Usage: postsctl %s [flags] %s\n", name, arguments)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// parse parses flags mixed with exactly count positional arguments, which are returned
+func parse(flags *flag.FlagSet, args []string, count int) ([]string, error) {
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%w: %v", ErrorUsage, err)
+		}
+		args = flags.Args()
+		if len(args) == 0 {
+			break
+		}
+		positional, args = append(positional, args[0]), args[1:]
+	}
+	if len(positional) != count {
+		flags.Usage()
+		return nil, fmt.Errorf("%w: %s expects %d arguments", ErrorUsage, flags.Name(), count)
+	}
+	return positional, nil
+}
+
+// parseID parses post id argument
+func parseID(arg string) (int, error) {
+	id, err := strconv.Atoi(arg)
+	if err != nil || id < 1 {
+		return 0, fmt.Errorf("%w: post id must be positive integer, got %q", ErrorUsage, arg)
+	}
+	return id, nil
+}
+
+// List lists posts of one page, or of all pages with -all flag
+func (cli *CLI) List(args []string) error {
+	flags := cli.newFlags("list", "")
+	title := flags.String("title", "", "list posts with titles containing the text")
+	page := flags.Int("page", 1, "page number")
+	limit := flags.Int("limit", client.DefaultPageSize, "posts per page")
+	all := flags.Bool("all", false, "list posts of all pages starting from the page")
+	output := flags.String("o", OutputTable, "output format: table, json or yaml")
+	if _, err := parse(flags, args, 0); err != nil {
+		return err
+	}
+	return cli.list(client.ListOptions{Title: *title, Page: *page, Limit: *limit}, *all, *output)
+}
+
+// Search lists posts of all pages with titles containing the text
+func (cli *CLI) Search(args []string) error {
+	flags := cli.newFlags("search", "<text>")
+	output := flags.String("o", OutputTable, "output format: table, json or yaml")
+	args, err := parse(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	return cli.list(client.ListOptions{Title: args[0]}, true, *output)
+}
+
+func (cli *CLI) list(options client.ListOptions, all bool, output string) error {
+	if err := validOutput(output); err != nil {
+		return err
+	}
+	c, err := cli.Client()
+	if err != nil {
+		return err
+	}
+
+	var posts []client.Post
+	if all {
+		for post, err := range c.All(context.Background(), options) {
+			if err != nil {
+				return err
+			}
+			posts = append(posts, post)
+		}
+	} else if posts, err = c.List(context.Background(), options); err != nil {
+		return err
+	}
+	return writePosts(cli.Stdout, output, posts)
+}
+
+// Get shows post
+func (cli *CLI) Get(args []string) error {
+	flags := cli.newFlags("get", "<id>")
+	output := flags.String("o", OutputTable, "output format: table, json or yaml")
+	args, err := parse(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	id, err := parseID(args[0])
+	if err != nil {
+		return err
+	}
+	if err := validOutput(*output); err != nil {
+		return err
+	}
+	c, err := cli.Client()
+	if err != nil {
+		return err
+	}
+
+	post, err := c.Get(context.Background(), id)
+	if err != nil {
+		return err
+	}
+	return writePost(cli.Stdout, *output, post)
+}
+
+// Create creates post from flags or Markdown file, flags override fields of the file
+func (cli *CLI) Create(args []string) error {
+	flags := cli.newFlags("create", "")
+	file := flags.String("f", "", "Markdown file of post with title and author in front matter, - reads standard input")
+	title := flags.String("title", "", "post title")
+	content := flags.String("content", "", "post content")
+	author := flags.String("author", "", "post author")
+	format := flags.String("format", "", "post format: plain or markdown")
+	if _, err := parse(flags, args, 0); err != nil {
+		return err
+	}
+
+	var input client.PostInput
+	if *file != "" {
+		data, err := cli.readFile(*file)
+		if err != nil {
+			return err
+		}
+		// title could be provided by flag
+		if input, err = ParsePost(data); err != nil && !(errors.Is(err, ErrorTitleRequired) && *title != "") {
+			return fmt.Errorf("failed to parse %s: %w", *file, err)
+		}
+	}
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "title":
+			input.Title = *title
+		case "content":
+			input.Content = *content
+		case "author":
+			input.Author = *author
+		case "format":
+			input.Format = *format
+		}
+	})
+	if strings.TrimSpace(input.Title) == "" {
+		return fmt.Errorf("%w: %v", ErrorUsage, ErrorTitleRequired)
+	}
+	c, err := cli.Client()
+	if err != nil {
+		return err
+	}
+
+	id, err := c.Create(context.Background(), input)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(cli.Stdout, "post %d created\n", id)
+	return nil
+}
+
+// Edit opens post as Markdown file in editor and updates post when the file is changed
+func (cli *CLI) Edit(args []string) error {
+	flags := cli.newFlags("edit", "<id>")
+	args, err := parse(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	id, err := parseID(args[0])
+	if err != nil {
+		return err
+	}
+	c, err := cli.Client()
+	if err != nil {
+		return err
+	}
+
+	post, err := c.Get(context.Background(), id)
+	if err != nil {
+		return err
+	}
+	original, err := FormatPost(client.PostInput{Title: post.Title, Content: post.Content, Author: post.Author, Format: post.Format})
+	if err != nil {
+		return err
+	}
+	edited, err := cli.edit(fmt.Sprintf("post-%d-*.md", id), original)
+	if err != nil {
+		return err
+	}
+
+	// files are compared after parsing, so that whitespace changes are ignored
+	before, _ := ParsePost(original)
+	after, err := ParsePost(edited)
+	if err != nil {
+		return fmt.Errorf("post is not updated: %w", err)
+	}
+	if after == before {
+		fmt.Fprintf(cli.Stdout, "post %d is not changed\n", id)
+		return nil
+	}
+	if err := c.Update(context.Background(), id, after); err != nil {
+		return err
+	}
+	fmt.Fprintf(cli.Stdout, "post %d updated\n", id)
+	return nil
+}
+
+// edit writes data to temporary file, opens it in editor and returns edited data
+func (cli *CLI) edit(pattern string, data []byte) ([]byte, error) {
+	file, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	if err := file.Close(); err != nil {
+		return nil, err
+	}
+
+	// editor could be a command with arguments, such as "code --wait"
+	cmd := exec.Command("sh", "-c", editor()+` "$1"`, "sh", file.Name())
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = cli.Stdin, cli.Stdout, cli.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("editor failed: %w", err)
+	}
+	return os.ReadFile(file.Name())
+}
+
+// editor returns editor command of VISUAL or EDITOR environment variables, vi by default
+func editor() string {
+	for _, key := range []string{"VISUAL", "EDITOR"} {
+		if value := strings.TrimSpace(os.Getenv(key)); value != "" {
+			return value
+		}
+	}
+	return "vi"
+}
+
+// Delete deletes post after confirmation, -y flag skips confirmation
+func (cli *CLI) Delete(args []string) error {
+	flags := cli.newFlags("delete", "<id>")
+	yes := flags.Bool("y", false, "delete without confirmation")
+	args, err := parse(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	id, err := parseID(args[0])
+	if err != nil {
+		return err
+	}
+	c, err := cli.Client()
+	if err != nil {
+		return err
+	}
+
+	if !*yes {
+		post, err := c.Get(context.Background(), id)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(cli.Stderr, "Delete post %d %q? [y/N] ", post.ID, post.Title)
+		answer, _ := bufio.NewReader(cli.Stdin).ReadString('\n')
+		if !slices.Contains([]string{"y", "yes"}, strings.ToLower(strings.TrimSpace(answer))) {
+			fmt.Fprintln(cli.Stdout, "deletion cancelled")
+			return nil
+		}
+	}
+	if err := c.Delete(context.Background(), id); err != nil {
+		return err
+	}
+	fmt.Fprintf(cli.Stdout, "post %d deleted\n", id)
+	return nil
+}
+
+// Import imports posts from NDJSON or JSON file and shows import report
+func (cli *CLI) Import(args []string) error {
+	flags := cli.newFlags("import", "<file>")
+	mode := flags.String("mode", client.ImportModeFail, "handling of existing posts: upsert, skip or fail")
+	atomic := flags.Bool("atomic", false, "import either all posts or none of them")
+	format := flags.String("format", "", "input format: ndjson or json, detected by file extension by default")
+	output := flags.String("o", OutputTable, "output format: table, json or yaml")
+	args, err := parse(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	if err := validOutput(*output); err != nil {
+		return err
+	}
+	if *format == "" {
+		*format = "ndjson"
+		if strings.EqualFold(filepath.Ext(args[0]), ".json") {
+			*format = "json"
+		}
+	}
+	contentType := map[string]string{"ndjson": client.ContentTypeNDJSON, "json": client.ContentTypeJSON}[*format]
+	if contentType == "" {
+		return fmt.Errorf("%w: format must be one of ndjson or json", ErrorUsage)
+	}
+	data, err := cli.readFile(args[0])
+	if err != nil {
+		return err
+	}
+	c, err := cli.Client()
+	if err != nil {
+		return err
+	}
+
+	report, err := c.Import(context.Background(), data, contentType, client.ImportOptions{Mode: *mode, Atomic: *atomic})
+	if report != nil {
+		if writeErr := writeReport(cli.Stdout, *output, report); writeErr != nil {
+			return writeErr
+		}
+	}
+	return err
+}
+
+// Export exports all posts as NDJSON to standard output or file
+func (cli *CLI) Export(args []string) error {
+	flags := cli.newFlags("export", "")
+	file := flags.String("f", "", "output file, standard output by default")
+	if _, err := parse(flags, args, 0); err != nil {
+		return err
+	}
+	c, err := cli.Client()
+	if err != nil {
+		return err
+	}
+
+	if *file != "" {
+		// posts are exported into temporary file, so that failed export does not replace existing file
+		f, err := os.CreateTemp(filepath.Dir(*file), filepath.Base(*file)+".*.tmp")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(f.Name())
+		defer f.Close()
+		if err := c.Export(context.Background(), f); err != nil {
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		return os.Rename(f.Name(), *file)
+	}
+	return c.Export(context.Background(), cli.Stdout)
+}
+
+// readFile reads file, - reads standard input
+func (cli *CLI) readFile(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(cli.Stdin)
+	}
+	return os.ReadFile(path)
+}
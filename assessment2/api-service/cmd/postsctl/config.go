@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultURL is a base URL of profiles without URL, it matches local Docker Compose deployment
+const DefaultURL = "http://localhost:8080"
+
+// DefaultProfile is a name of profile used when none is selected
+const DefaultProfile = "default"
+
+// ErrorUnknownProfile is returned when selected profile is not configured
+var ErrorUnknownProfile = errors.New("unknown profile")
+
+// Profile defines base URL and credentials of the API
+type Profile struct {
+	URL    string `yaml:"url"`
+	Token  string `yaml:"token,omitempty"`   // user token sent as bearer token
+	APIKey string `yaml:"api_key,omitempty"` // sent with X-API-Key header when token is not set
+}
+
+// Config is a configuration file of profiles
+type Config struct {
+	Current  string              `yaml:"current,omitempty"` // profile used when none is selected by flags
+	Profiles map[string]*Profile `yaml:"profiles"`
+}
+
+// defaultConfigFile returns path of configuration file in user configuration directory
+func defaultConfigFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ".postsctl.yaml"
+	}
+	return filepath.Join(dir, "postsctl", "config.yaml")
+}
+
+// LoadConfig reads configuration file, empty configuration is returned when file does not exist
+func LoadConfig(path string) (*Config, error) {
+	config := &Config{Profiles: make(map[string]*Profile)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return config, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return nil, err
+	}
+	if config.Profiles == nil {
+		config.Profiles = make(map[string]*Profile)
+	}
+	return config, nil
+}
+
+// Save writes configuration file, it is readable by owner only as it holds credentials
+func (c *Config) Save(path string) error {
+	var data bytes.Buffer
+	if err := writeValue(&data, OutputYAML, c); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data.Bytes(), 0o600)
+}
+
+// CurrentName returns name of profile used when none is selected
+func (c *Config) CurrentName() string {
+	if c.Current != "" {
+		return c.Current
+	}
+	return DefaultProfile
+}
+
+// Select returns copy of profile by name or current profile when name is empty, base URL
+// is set to default when it is missing, only selection of unknown profile by name is an error
+func (c *Config) Select(name string) (Profile, error) {
+	var profile Profile
+	p, ok := c.Profiles[name]
+	switch {
+	case name == "":
+		p, ok = c.Profiles[c.CurrentName()]
+	case !ok:
+		return profile, fmt.Errorf("%w %q", ErrorUnknownProfile, name)
+	}
+	if ok {
+		profile = *p
+	}
+	if profile.URL == "" {
+		profile.URL = DefaultURL
+	}
+	return profile, nil
+}
@@ -0,0 +1,159 @@
+// Command postsctl manages posts of the API from the command line
+package main
+
+import (
+	"api-service/client"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+)
+
+const usage = `Usage: postsctl [flags] <command> [arguments]
+
+Commands:
+  list      list posts, one page or all pages
+  search    list posts of all pages with titles containing the text
+  get       show post
+  create    create post from flags or Markdown file
+  edit      edit post in $EDITOR
+  delete    delete post after confirmation
+  import    import posts from NDJSON or JSON file
+  export    export all posts as NDJSON
+  profile   manage profiles of base URL and credentials
+
+Flags:
+`
+
+// ErrorUsage is returned when command is invoked with invalid arguments
+var ErrorUsage = errors.New("invalid usage")
+
+// CLI holds streams and settings of invoked command
+type CLI struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+
+	ConfigFile string
+	Config     *Config
+	Profile    Profile // profile selected by flags, overridden by URL and token flags
+}
+
+// command runs with arguments following its name
+type command func(cli *CLI, args []string) error
+
+var commands = map[string]command{
+	"list":    (*CLI).List,
+	"search":  (*CLI).Search,
+	"get":     (*CLI).Get,
+	"create":  (*CLI).Create,
+	"edit":    (*CLI).Edit,
+	"delete":  (*CLI).Delete,
+	"import":  (*CLI).Import,
+	"export":  (*CLI).Export,
+	"profile": (*CLI).ProfileCommand,
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run parses global flags and runs command, it returns exit code of the process
+func run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("postsctl", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		flags.PrintDefaults()
+	}
+	configFile := flags.String("config", getEnv("POSTSCTL_CONFIG", defaultConfigFile()), "configuration file of profiles")
+	profileName := flags.String("profile", os.Getenv("POSTSCTL_PROFILE"), "profile name, current profile is used by default")
+	baseURL := flags.String("url", os.Getenv("POSTSCTL_URL"), "base URL of the API, overrides profile")
+	token := flags.String("token", os.Getenv("POSTSCTL_TOKEN"), "user token, overrides profile")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+	cmd, ok := commands[flags.Arg(0)]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command %q, available commands: %v\n", flags.Arg(0), commandNames())
+		return 2
+	}
+
+	config, err := LoadConfig(*configFile)
+	if err != nil {
+		fmt.Fprintln(stderr, "failed to load configuration:", err)
+		return 1
+	}
+	cli := &CLI{Stdin: stdin, Stdout: stdout, Stderr: stderr, ConfigFile: *configFile, Config: config}
+	cli.Profile, err = config.Select(*profileName)
+	if err != nil && flags.Arg(0) != "profile" {
+		fmt.Fprintln(stderr, "error:", err)
+		return 1
+	}
+	if *baseURL != "" {
+		cli.Profile.URL = *baseURL
+	}
+	if *token != "" {
+		cli.Profile.Token = *token
+	}
+
+	if err := cmd(cli, flags.Args()[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		fmt.Fprintln(stderr, "error:", err)
+		if errors.Is(err, ErrorUsage) {
+			return 2
+		}
+		return 1
+	}
+	return 0
+}
+
+// Client creates client of the API of selected profile
+func (cli *CLI) Client() (*client.Client, error) {
+	var credentials []client.Authenticator
+	if cli.Profile.Token != "" {
+		credentials = append(credentials, client.BearerToken(cli.Profile.Token))
+	}
+	if cli.Profile.APIKey != "" {
+		credentials = append(credentials, client.APIKey(cli.Profile.APIKey))
+	}
+	config := client.Config{
+		UserAgent: "postsctl/1.0",
+		Auth: client.AuthenticatorFunc(func(r *http.Request) error {
+			for _, credential := range credentials {
+				if err := credential.Authenticate(r); err != nil {
+					return err
+				}
+			}
+			return nil
+		}),
+	}
+	return client.New(cli.Profile.URL, nil, config)
+}
+
+// commandNames returns sorted names of commands
+func commandNames() []string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// getEnv returns value of environment variable or default value when it is not set
+func getEnv(key string, defaultValue string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testAPI serves post 1 and records requests changing posts
+type testAPI struct {
+	mu       sync.Mutex
+	requests []string
+}
+
+func (api *testAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	api.mu.Lock()
+	if r.Method != http.MethodGet {
+		api.requests = append(api.requests, strings.TrimSpace(r.Method+" "+r.URL.Path+" "+string(body)))
+	}
+	api.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	post := `{"ID":1,"Title":"Title 1","Content":"Hello world","Author":"Author","Owner":"author-1","Version":1,"Format":"markdown"}`
+	switch {
+	case r.Header.Get("Authorization") != "Bearer token":
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"error":true,"message":"authentication required"}`))
+	case r.Method == http.MethodGet && r.URL.Path == "/v1/posts":
+		_, _ = w.Write([]byte(`{"error":false,"message":"","data":[` + post + `]}`))
+	case r.URL.Path == "/v1/posts/1":
+		_, _ = w.Write([]byte(`{"error":false,"message":"","data":` + post + `}`))
+	case r.Method == http.MethodPost && r.URL.Path == "/v1/posts":
+		_, _ = w.Write([]byte(`{"error":false,"message":"post added","data":2}`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error":true,"message":"post not found"}`))
+	}
+}
+
+// newTestConfig writes configuration file with current profile of test API
+func newTestConfig(t *testing.T) (string, *testAPI) {
+	t.Helper()
+
+	api := &testAPI{}
+	srv := httptest.NewServer(api)
+	t.Cleanup(srv.Close)
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	config := &Config{Current: "test", Profiles: map[string]*Profile{"test": {URL: srv.URL, Token: "token"}}}
+	if err := config.Save(configFile); err != nil {
+		t.Fatal(err)
+	}
+	return configFile, api
+}
+
+// runCommand runs command with configuration file and standard input
+func runCommand(configFile string, stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(append([]string{"-config", configFile}, args...), strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+// TestRun_Commands tests commands against test API
+func TestRun_Commands(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name             string
+		args             []string
+		stdin            string
+		expectedCode     int
+		expectedOutput   string
+		expectedRequests []string
+	}{
+		{"list table", []string{"list"}, "", 0, "ID  TITLE    AUTHOR  OWNER     VERSION\n1   Title 1  Author  author-1  1\n", nil},
+		{"search yaml", []string{"search", "-o", "yaml", "title"}, "", 0, "- id: 1\n  title: Title 1\n  content: Hello world\n  author: Author\n  owner: author-1\n  version: 1\n  format: markdown\n", nil},
+		{"get json", []string{"get", "1", "-o", "json"}, "", 0, "{\n  \"ID\": 1,\n  \"Title\": \"Title 1\",\n  \"Content\": \"Hello world\",\n  \"Author\": \"Author\",\n  \"Owner\": \"author-1\",\n  \"Version\": 1,\n  \"Format\": \"markdown\"\n}\n", nil},
+		{"create from flags", []string{"create", "-title", "New", "-content", "Content"}, "", 0, "post 2 created\n", []string{`POST /v1/posts {"title":"New","content":"Content","author":""}`}},
+		{"create from standard input", []string{"create", "-f", "-", "-author", "Me"}, "# New\n\nContent\n", 0, "post 2 created\n", []string{`POST /v1/posts {"title":"New","content":"Content","author":"Me"}`}},
+		{"create with format", []string{"create", "-title", "New", "-format", "markdown"}, "", 0, "post 2 created\n", []string{`POST /v1/posts {"title":"New","content":"","author":"","format":"markdown"}`}},
+		{"cancelled deletion", []string{"delete", "1"}, "n\n", 0, "deletion cancelled\n", nil},
+		{"confirmed deletion", []string{"delete", "1"}, "y\n", 0, "post 1 deleted\n", []string{"DELETE /v1/posts/1"}},
+		{"deletion without confirmation", []string{"delete", "1", "-y"}, "", 0, "post 1 deleted\n", []string{"DELETE /v1/posts/1"}},
+		{"missing post", []string{"get", "2"}, "", 1, "", nil},
+		{"invalid id", []string{"get", "first"}, "", 2, "", nil},
+		{"invalid output", []string{"list", "-o", "xml"}, "", 2, "", nil},
+		{"unknown command", []string{"publish"}, "", 2, "", nil},
+		{"unknown profile", []string{"-profile", "prod", "list"}, "", 1, "", nil},
+		{"token flag", []string{"-token", "other", "list"}, "", 1, "", nil},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			configFile, api := newTestConfig(t)
+
+			code, stdout, stderr := runCommand(configFile, tt.stdin, tt.args...)
+			if code != tt.expectedCode {
+				t.Errorf("expected exit code %d, but got %d: %s", tt.expectedCode, code, stderr)
+			}
+			if stdout != tt.expectedOutput {
+				t.Errorf("expected output %q, but got %q", tt.expectedOutput, stdout)
+			}
+			if strings.Join(api.requests, "\n") != strings.Join(tt.expectedRequests, "\n") {
+				t.Errorf("expected requests %q, but got %q", tt.expectedRequests, api.requests)
+			}
+		})
+	}
+}
+
+// TestRun_Edit tests editing of post with editor command
+func TestRun_Edit(t *testing.T) {
+	configFile, api := newTestConfig(t)
+	script := filepath.Join(t.TempDir(), "editor.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\nsed -i.bak \"s/$1/there/\" \"$2\"\n"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", script+" nothing")
+	code, stdout, stderr := runCommand(configFile, "", "edit", "1")
+	if code != 0 || stdout != "post 1 is not changed\n" || len(api.requests) != 0 {
+		t.Errorf("expected unchanged post, but got %d %q %q, requests %q", code, stdout, stderr, api.requests)
+	}
+
+	t.Setenv("EDITOR", script+" world")
+	code, stdout, stderr = runCommand(configFile, "", "edit", "1")
+	expectedRequest := `PUT /v1/posts/1 {"title":"Title 1","content":"Hello there","author":"Author","format":"markdown"}`
+	if code != 0 || stdout != "post 1 updated\n" || len(api.requests) != 1 || api.requests[0] != expectedRequest {
+		t.Errorf("expected updated post, but got %d %q %q, requests %q", code, stdout, stderr, api.requests)
+	}
+}
+
+// TestRun_Profiles tests management of profiles
+func TestRun_Profiles(t *testing.T) {
+	t.Parallel()
+	configFile := filepath.Join(t.TempDir(), "postsctl", "config.yaml")
+
+	steps := []struct {
+		args           []string
+		expectedCode   int
+		expectedOutput string
+	}{
+		{[]string{"profile", "set", "local", "-token", "secret"}, 0, "profile local saved\n"},
+		{[]string{"profile", "set", "prod", "-url", "https://api.example.com", "-api-key", "key"}, 0, "profile prod saved\n"},
+		{[]string{"profile", "use", "prod"}, 0, "profile prod is current\n"},
+		{[]string{"profile", "use", "staging"}, 1, ""},
+		{[]string{"profile", "list"}, 0, "CURRENT  NAME   URL                      CREDENTIALS\n         local  http://localhost:8080    token\n*        prod   https://api.example.com  api key\n"},
+		{[]string{"profile", "show", "local"}, 0, "Name:        local\nURL:         http://localhost:8080\nCredentials: token\n"},
+		{[]string{"profile", "delete", "prod"}, 0, "profile prod deleted\n"},
+		{[]string{"profile", "show"}, 0, "Name:        default\nURL:         http://localhost:8080\nCredentials: anonymous\n"},
+	}
+	for _, step := range steps {
+		code, stdout, stderr := runCommand(configFile, "", step.args...)
+		if code != step.expectedCode || stdout != step.expectedOutput {
+			t.Fatalf("%v: expected %d %q, but got %d %q %q", step.args, step.expectedCode, step.expectedOutput, code, stdout, stderr)
+		}
+	}
+
+	// credentials are readable by owner only
+	info, err := os.Stat(configFile)
+	if err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("expected configuration file with 0600 permissions, but got %v, %v", info, err)
+	}
+	config, _ := LoadConfig(configFile)
+	expected, _ := json.Marshal(Config{Profiles: map[string]*Profile{"local": {URL: DefaultURL, Token: "secret"}}})
+	if actual, _ := json.Marshal(config); string(actual) != string(expected) {
+		t.Errorf("expected configuration %s, but got %s", expected, actual)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"api-service/client"
+	"bytes"
+	"errors"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrorTitleRequired is returned when post file has no title
+var ErrorTitleRequired = errors.New("title is required")
+
+// frontMatterDelimiter separates front matter of post file from its content
+const frontMatterDelimiter = "---"
+
+// frontMatter holds fields of post file other than content
+type frontMatter struct {
+	Title  string `yaml:"title"`
+	Author string `yaml:"author,omitempty"`
+	Format string `yaml:"format,omitempty"` // format of content, plain or markdown
+}
+
+// FormatPost formats post as Markdown file with title, author and format in front matter
+func FormatPost(input client.PostInput) ([]byte, error) {
+	header, err := yaml.Marshal(frontMatter{Title: input.Title, Author: input.Author, Format: input.Format})
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	out.WriteString(frontMatterDelimiter + "\n")
+	out.Write(header)
+	out.WriteString(frontMatterDelimiter + "\n\n")
+	if input.Content != "" {
+		out.WriteString(strings.TrimRight(input.Content, "\n") + "\n")
+	}
+	return out.Bytes(), nil
+}
+
+// ParsePost parses Markdown file of post, title, author and format are read from front matter,
+// files without front matter are titled by their first level one heading
+func ParsePost(data []byte) (client.PostInput, error) {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	var input client.PostInput
+
+	if rest, ok := strings.CutPrefix(text, frontMatterDelimiter+"\n"); ok {
+		header, content, found := strings.Cut(rest, "\n"+frontMatterDelimiter+"\n")
+		if !found {
+			header, found = strings.CutSuffix(rest, "\n"+frontMatterDelimiter)
+		}
+		if !found {
+			return input, errors.New("front matter is not closed")
+		}
+		var fields frontMatter
+		if err := yaml.Unmarshal([]byte(header), &fields); err != nil {
+			return input, err
+		}
+		input.Title, input.Author, input.Format, text = fields.Title, fields.Author, fields.Format, content
+	} else if first, content, _ := strings.Cut(strings.TrimLeft(text, "\n"), "\n"); strings.HasPrefix(first, "# ") {
+		input.Title, text = strings.TrimPrefix(first, "# "), content
+	}
+
+	input.Title = strings.TrimSpace(input.Title)
+	input.Content = strings.Trim(text, "\n")
+	if input.Title == "" {
+		return input, ErrorTitleRequired
+	}
+	return input, nil
+}
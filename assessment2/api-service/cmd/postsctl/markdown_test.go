@@ -0,0 +1,72 @@
+package main
+
+import (
+	"api-service/client"
+	"errors"
+	"testing"
+)
+
+// TestMarkdown_ParsePost tests parsing of post files
+func TestMarkdown_ParsePost(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		file          string
+		expectedInput client.PostInput
+		expectedError error
+	}{
+		{
+			"front matter",
+			"---\ntitle: 'Title: quoted'\nauthor: Author\n---\n\nFirst line\n\nSecond line\n",
+			client.PostInput{Title: "Title: quoted", Author: "Author", Content: "First line\n\nSecond line"},
+			nil,
+		},
+		{"front matter without content", "---\ntitle: Title\n---", client.PostInput{Title: "Title"}, nil},
+		{"windows line endings", "---\r\ntitle: Title\r\n---\r\nContent\r\n", client.PostInput{Title: "Title", Content: "Content"}, nil},
+		{"heading", "\n# Title\n\nContent\n## Section\n", client.PostInput{Title: "Title", Content: "Content\n## Section"}, nil},
+		{"missing title", "Content", client.PostInput{Content: "Content"}, ErrorTitleRequired},
+		{"empty title", "---\nauthor: Author\n---\nContent", client.PostInput{Author: "Author", Content: "Content"}, ErrorTitleRequired},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			input, err := ParsePost([]byte(tt.file))
+			if !errors.Is(err, tt.expectedError) {
+				t.Errorf("expected %v error, but got %v", tt.expectedError, err)
+			}
+			if input != tt.expectedInput {
+				t.Errorf("expected %+v, but got %+v", tt.expectedInput, input)
+			}
+		})
+	}
+
+	t.Run("unclosed front matter", func(t *testing.T) {
+		t.Parallel()
+		if _, err := ParsePost([]byte("---\ntitle: Title\n\nContent")); err == nil {
+			t.Error("expected error, but got none")
+		}
+	})
+}
+
+// TestMarkdown_FormatPost tests that formatted posts are parsed back
+func TestMarkdown_FormatPost(t *testing.T) {
+	t.Parallel()
+
+	input := client.PostInput{Title: "---", Author: "Author: name", Content: "---\n\nContent\n", Format: client.FormatMarkdown}
+	file, err := FormatPost(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedFile := "---\ntitle: '---'\nauthor: 'Author: name'\nformat: markdown\n---\n\n---\n\nContent\n"
+	if string(file) != expectedFile {
+		t.Errorf("expected %q, but got %q", expectedFile, file)
+	}
+	parsed, err := ParsePost(file)
+	input.Content = "---\n\nContent"
+	if err != nil || parsed != input {
+		t.Errorf("expected %+v, but got %+v, %v", input, parsed, err)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"api-service/client"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats
+const (
+	OutputTable = "table"
+	OutputJSON  = "json"
+	OutputYAML  = "yaml"
+)
+
+// tableTitleWidth limits width of title column of tables
+const tableTitleWidth = 48
+
+// validOutput checks that output format is supported
+func validOutput(format string) error {
+	switch format {
+	case OutputTable, OutputJSON, OutputYAML:
+		return nil
+	default:
+		return fmt.Errorf("%w: output must be one of table, json or yaml", ErrorUsage)
+	}
+}
+
+// writeValue writes value as indented JSON or YAML document
+func writeValue(w io.Writer, format string, value any) error {
+	if format == OutputYAML {
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(value); err != nil {
+			return err
+		}
+		return encoder.Close()
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// writePosts writes list of posts, table lists posts without content
+func writePosts(w io.Writer, format string, posts []client.Post) error {
+	if format != OutputTable {
+		if posts == nil {
+			posts = []client.Post{}
+		}
+		return writeValue(w, format, posts)
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tTITLE\tAUTHOR\tOWNER\tVERSION")
+	for _, post := range posts {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%d\n", post.ID, truncate(post.Title, tableTitleWidth), post.Author, post.Owner, post.Version)
+	}
+	return tw.Flush()
+}
+
+// writePost writes post, table shows fields followed by content
+func writePost(w io.Writer, format string, post *client.Post) error {
+	if format != OutputTable {
+		return writeValue(w, format, post)
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 1, ' ', 0)
+	fmt.Fprintf(tw, "ID:\t%d\n", post.ID)
+	fmt.Fprintf(tw, "Title:\t%s\n", post.Title)
+	fmt.Fprintf(tw, "Author:\t%s\n", post.Author)
+	fmt.Fprintf(tw, "Owner:\t%s\n", post.Owner)
+	fmt.Fprintf(tw, "Version:\t%d\n", post.Version)
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if post.Content != "" {
+		_, err := fmt.Fprintf(w, "\n%s\n", strings.TrimRight(post.Content, "\n"))
+		return err
+	}
+	return nil
+}
+
+// writeReport writes import report, table shows counters followed by failed posts
+func writeReport(w io.Writer, format string, report *client.ImportReport) error {
+	if format != OutputTable {
+		return writeValue(w, format, report)
+	}
+	fmt.Fprintf(w, "created %d, updated %d, skipped %d, failed %d, committed %t\n",
+		report.Created, report.Updated, report.Skipped, report.Failed, report.Committed)
+	if len(report.Errors) == 0 {
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "LINE\tID\tERROR")
+	for _, failed := range report.Errors {
+		fmt.Fprintf(tw, "%d\t%d\t%s\n", failed.Line, failed.ID, failed.Error)
+	}
+	return tw.Flush()
+}
+
+// truncate shortens text to width runes, shortened text ends with ellipsis
+func truncate(text string, width int) string {
+	runes := []rune(text)
+	if len(runes) <= width {
+		return text
+	}
+	return string(runes[:width-1]) + "…"
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"text/tabwriter"
+)
+
+const profileUsage = `Usage: postsctl profile <command> [arguments]
+
+Commands:
+  list           list profiles, current profile is marked with *
+  show [name]    show profile, current profile by default
+  set <name>     create or update profile with -url, -token and -api-key flags
+  use <name>     make profile current
+  delete <name>  delete profile
+`
+
+// ProfileCommand manages profiles of configuration file
+func (cli *CLI) ProfileCommand(args []string) error {
+	if len(args) == 0 {
+		fmt.Fprint(cli.Stderr, profileUsage)
+		return fmt.Errorf("%w: profile command is required", ErrorUsage)
+	}
+	switch args[0] {
+	case "list":
+		return cli.profileList(args[1:])
+	case "show":
+		return cli.profileShow(args[1:])
+	case "set":
+		return cli.profileSet(args[1:])
+	case "use":
+		return cli.profileUse(args[1:])
+	case "delete":
+		return cli.profileDelete(args[1:])
+	default:
+		fmt.Fprint(cli.Stderr, profileUsage)
+		return fmt.Errorf("%w: unknown profile command %q", ErrorUsage, args[0])
+	}
+}
+
+func (cli *CLI) profileList(args []string) error {
+	if _, err := parse(cli.newFlags("profile list", ""), args, 0); err != nil {
+		return err
+	}
+	names := make([]string, 0, len(cli.Config.Profiles))
+	for name := range cli.Config.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tw := tabwriter.NewWriter(cli.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CURRENT\tNAME\tURL\tCREDENTIALS")
+	for _, name := range names {
+		current := ""
+		if name == cli.Config.CurrentName() {
+			current = "*"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", current, name, cli.Config.Profiles[name].URL, credentials(cli.Config.Profiles[name]))
+	}
+	return tw.Flush()
+}
+
+func (cli *CLI) profileShow(args []string) error {
+	flags := cli.newFlags("profile show", "[name]")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	profile, err := cli.Config.Select(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	name := flags.Arg(0)
+	if name == "" {
+		name = cli.Config.CurrentName()
+	}
+	tw := tabwriter.NewWriter(cli.Stdout, 0, 0, 1, ' ', 0)
+	fmt.Fprintf(tw, "Name:\t%s\n", name)
+	fmt.Fprintf(tw, "URL:\t%s\n", profile.URL)
+	fmt.Fprintf(tw, "Credentials:\t%s\n", credentials(&profile))
+	return tw.Flush()
+}
+
+func (cli *CLI) profileSet(args []string) error {
+	flags := cli.newFlags("profile set", "<name>")
+	url := flags.String("url", "", "base URL of the API")
+	token := flags.String("token", "", "user token")
+	apiKey := flags.String("api-key", "", "API key")
+	args, err := parse(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	name := args[0]
+	profile, ok := cli.Config.Profiles[name]
+	if !ok {
+		profile = &Profile{URL: DefaultURL}
+		cli.Config.Profiles[name] = profile
+	}
+	// only provided flags are changed, empty values clear credentials
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "url":
+			profile.URL = *url
+		case "token":
+			profile.Token = *token
+		case "api-key":
+			profile.APIKey = *apiKey
+		}
+	})
+	if err := cli.Config.Save(cli.ConfigFile); err != nil {
+		return err
+	}
+	fmt.Fprintf(cli.Stdout, "profile %s saved\n", name)
+	return nil
+}
+
+func (cli *CLI) profileUse(args []string) error {
+	flags := cli.newFlags("profile use", "<name>")
+	args, err := parse(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	name := args[0]
+	if _, ok := cli.Config.Profiles[name]; !ok {
+		return fmt.Errorf("%w %q", ErrorUnknownProfile, name)
+	}
+	cli.Config.Current = name
+	if err := cli.Config.Save(cli.ConfigFile); err != nil {
+		return err
+	}
+	fmt.Fprintf(cli.Stdout, "profile %s is current\n", name)
+	return nil
+}
+
+func (cli *CLI) profileDelete(args []string) error {
+	flags := cli.newFlags("profile delete", "<name>")
+	args, err := parse(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	name := args[0]
+	if _, ok := cli.Config.Profiles[name]; !ok {
+		return fmt.Errorf("%w %q", ErrorUnknownProfile, name)
+	}
+	delete(cli.Config.Profiles, name)
+	if cli.Config.Current == name {
+		cli.Config.Current = ""
+	}
+	if err := cli.Config.Save(cli.ConfigFile); err != nil {
+		return err
+	}
+	fmt.Fprintf(cli.Stdout, "profile %s deleted\n", name)
+	return nil
+}
+
+// credentials describes credentials of profile without revealing them
+func credentials(profile *Profile) string {
+	switch {
+	case profile.Token != "" && profile.APIKey != "":
+		return "token, api key"
+	case profile.Token != "":
+		return "token"
+	case profile.APIKey != "":
+		return "api key"
+	default:
+		return "anonymous"
+	}
+}
@@ -0,0 +1,81 @@
+package auth
+
+// Permission names an action which could be performed over a resource
+type Permission string
+
+// Permissions used by API routes
+const (
+	PermissionPostsRead   Permission = "posts:read"
+	PermissionPostsCreate Permission = "posts:create"
+	PermissionPostsUpdate Permission = "posts:update"
+	PermissionPostsDelete Permission = "posts:delete"
+	PermissionPostsImport Permission = "posts:import"
+	PermissionWebhooks    Permission = "webhooks:manage"
+	PermissionAdmin       Permission = "admin:manage"
+)
+
+// Grant allows a permission, optionally restricted to resources owned by the user
+type Grant struct {
+	Permission Permission
+	OwnOnly    bool
+}
+
+// Policy evaluates permissions of principals according to their roles
+type Policy struct {
+	public []Permission
+	roles  map[Role][]Grant
+	admin  Role
+}
+
+// NewPolicy creates a policy with default set of roles:
+// authors manage their own posts, editors manage and import any post,
+// admins manage everything including webhooks, as subscriptions make the service send requests to any url
+func NewPolicy() *Policy {
+	return &Policy{
+		public: []Permission{PermissionPostsRead},
+		roles: map[Role][]Grant{
+			RoleAuthor: {
+				{Permission: PermissionPostsCreate},
+				{Permission: PermissionPostsUpdate, OwnOnly: true},
+				{Permission: PermissionPostsDelete, OwnOnly: true},
+			},
+			RoleEditor: {
+				{Permission: PermissionPostsCreate},
+				{Permission: PermissionPostsUpdate},
+				{Permission: PermissionPostsDelete},
+				{Permission: PermissionPostsImport},
+			},
+		},
+		admin: RoleAdmin,
+	}
+}
+
+// Authorize checks that principal is allowed to perform action over a resource owned by owner,
+// an empty owner means that resource has no owner or the action is not resource bound
+func (p *Policy) Authorize(principal *Principal, permission Permission, owner string) error {
+	// public permissions are granted to everyone
+	for _, public := range p.public {
+		if public == permission {
+			return nil
+		}
+	}
+	if principal == nil {
+		return ErrorUnauthenticated
+	}
+
+	// administrators can manage everything
+	if principal.Role == p.admin {
+		return nil
+	}
+
+	// find matching grant for principal role
+	for _, grant := range p.roles[principal.Role] {
+		if grant.Permission != permission {
+			continue
+		}
+		if !grant.OwnOnly || (owner != "" && owner == principal.ID) {
+			return nil
+		}
+	}
+	return ErrorForbidden
+}
@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestPolicy_Authorize tests default policy rules
+func TestPolicy_Authorize(t *testing.T) {
+	t.Parallel()
+
+	author := &Principal{ID: "author-1", Role: RoleAuthor}
+	editor := &Principal{ID: "editor-1", Role: RoleEditor}
+	admin := &Principal{ID: "admin-1", Role: RoleAdmin}
+	unknown := &Principal{ID: "guest-1", Role: Role("guest")}
+
+	tests := []struct {
+		name       string
+		principal  *Principal
+		permission Permission
+		owner      string
+		expected   error
+	}{
+		{"anonymous reads posts", nil, PermissionPostsRead, "", nil},
+		{"anonymous creates post", nil, PermissionPostsCreate, "", ErrorUnauthenticated},
+		{"anonymous updates post", nil, PermissionPostsUpdate, "author-1", ErrorUnauthenticated},
+		{"anonymous deletes post", nil, PermissionPostsDelete, "author-1", ErrorUnauthenticated},
+		{"author creates post", author, PermissionPostsCreate, "", nil},
+		{"author updates own post", author, PermissionPostsUpdate, "author-1", nil},
+		{"author updates foreign post", author, PermissionPostsUpdate, "author-2", ErrorForbidden},
+		{"author updates ownerless post", author, PermissionPostsUpdate, "", ErrorForbidden},
+		{"author deletes own post", author, PermissionPostsDelete, "author-1", nil},
+		{"author deletes foreign post", author, PermissionPostsDelete, "author-2", ErrorForbidden},
+		{"editor updates foreign post", editor, PermissionPostsUpdate, "author-2", nil},
+		{"editor updates ownerless post", editor, PermissionPostsUpdate, "", nil},
+		{"editor deletes foreign post", editor, PermissionPostsDelete, "author-2", nil},
+		{"author imports posts", author, PermissionPostsImport, "", ErrorForbidden},
+		{"editor imports posts", editor, PermissionPostsImport, "", nil},
+		{"editor manages webhooks", editor, PermissionWebhooks, "", ErrorForbidden},
+		{"admin manages webhooks", admin, PermissionWebhooks, "", nil},
+		{"admin deletes foreign post", admin, PermissionPostsDelete, "author-2", nil},
+		{"admin has unknown permission", admin, Permission("posts:archive"), "", nil},
+		{"editor has unknown permission", editor, Permission("posts:archive"), "", ErrorForbidden},
+		{"unknown role creates post", unknown, PermissionPostsCreate, "", ErrorForbidden},
+		{"unknown role reads posts", unknown, PermissionPostsRead, "", nil},
+	}
+
+	policy := NewPolicy()
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := policy.Authorize(tt.principal, tt.permission, tt.owner)
+			if !errors.Is(err, tt.expected) {
+				t.Errorf("expected %v, but got %v", tt.expected, err)
+			}
+		})
+	}
+}
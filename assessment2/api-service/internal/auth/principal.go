@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"context"
+	"errors"
+)
+
+// Role defines a named set of permissions granted to a user
+type Role string
+
+// Roles supported by default policy
+const (
+	RoleAuthor Role = "author"
+	RoleEditor Role = "editor"
+	RoleAdmin  Role = "admin"
+)
+
+// Principal represents an authenticated user
+type Principal struct {
+	ID   string
+	Role Role
+}
+
+// ErrorUnauthenticated is returned when an action requires an authenticated user
+var ErrorUnauthenticated = errors.New("authentication required")
+
+// ErrorForbidden is returned when a user is not allowed to perform an action
+var ErrorForbidden = errors.New("access forbidden")
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of context carrying specified principal
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns principal stored in context, if any
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+	return principal, ok && principal != nil
+}
@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"os"
+)
+
+// UserEntry represent user record in users file
+type UserEntry struct {
+	ID    string `json:"id"`
+	Token string `json:"token"`
+	Role  Role   `json:"role"`
+}
+
+type FileData struct {
+	Users []UserEntry `json:"users"`
+}
+
+// UserStore resolves API tokens into principals
+type UserStore struct {
+	users []UserEntry
+}
+
+// NewUserStore creates a users store, initialized from file when it is specified
+func NewUserStore(initFile string) (*UserStore, error) {
+	if initFile == "" {
+		return &UserStore{}, nil
+	}
+
+	file, err := os.Open(initFile)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	bytes, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	var data FileData
+	err = json.Unmarshal(bytes, &data)
+	if err != nil {
+		return nil, err
+	}
+	return &UserStore{users: data.Users}, nil
+}
+
+// Authenticate returns principal owning specified token
+func (s *UserStore) Authenticate(token string) (*Principal, error) {
+	if token == "" {
+		return nil, ErrorUnauthenticated
+	}
+	for _, user := range s.users {
+		if subtle.ConstantTimeCompare([]byte(user.Token), []byte(token)) == 1 {
+			return &Principal{ID: user.ID, Role: user.Role}, nil
+		}
+	}
+	return nil, ErrorUnauthenticated
+}
+
+// Len returns number of users, store without users authenticates nobody
+func (s *UserStore) Len() int {
+	return len(s.users)
+}
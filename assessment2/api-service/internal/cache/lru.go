@@ -0,0 +1,118 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+type entry[K comparable, V any] struct {
+	key     K
+	value   V
+	expires time.Time
+}
+
+// LRU is a size bounded cache evicting least recently used entries, entries expire after TTL
+type LRU[K comparable, V any] struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	items    map[K]*list.Element
+	order    *list.List // front is the most recently used entry
+	now      func() time.Time
+}
+
+// NewLRU creates a new cache holding up to capacity entries
+func NewLRU[K comparable, V any](capacity int, ttl time.Duration) *LRU[K, V] {
+	return &LRU[K, V]{
+		capacity: capacity,
+		ttl:      ttl,
+		items:    make(map[K]*list.Element),
+		order:    list.New(),
+		now:      time.Now,
+	}
+}
+
+// Get returns cached value if it is present and not expired
+func (c *LRU[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var zero V
+	element, ok := c.items[key]
+	if !ok {
+		return zero, false
+	}
+	e := element.Value.(*entry[K, V])
+	if !c.now().Before(e.expires) {
+		c.removeElement(element)
+		return zero, false
+	}
+	c.order.MoveToFront(element)
+	return e.value, true
+}
+
+// Set adds or replaces cached value, evicting least recently used entry when cache is full
+func (c *LRU[K, V]) Set(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.items[key]; ok {
+		e := element.Value.(*entry[K, V])
+		e.value = value
+		e.expires = c.now().Add(c.ttl)
+		c.order.MoveToFront(element)
+		return
+	}
+	c.items[key] = c.order.PushFront(&entry[K, V]{key: key, value: value, expires: c.now().Add(c.ttl)})
+	for c.order.Len() > c.capacity {
+		c.removeElement(c.order.Back())
+	}
+}
+
+// Delete removes cached value
+func (c *LRU[K, V]) Delete(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.items[key]; ok {
+		c.removeElement(element)
+	}
+}
+
+// DeleteFunc removes cached values matching predicate
+func (c *LRU[K, V]) DeleteFunc(match func(key K, value V) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for element := c.order.Front(); element != nil; {
+		next := element.Next()
+		e := element.Value.(*entry[K, V])
+		if match(e.key, e.value) {
+			c.removeElement(element)
+		}
+		element = next
+	}
+}
+
+// Purge removes all cached values
+func (c *LRU[K, V]) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.items = make(map[K]*list.Element)
+	c.order.Init()
+}
+
+// Len returns number of cached entries, including expired ones not evicted yet
+func (c *LRU[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
+
+func (c *LRU[K, V]) removeElement(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.items, element.Value.(*entry[K, V]).key)
+}
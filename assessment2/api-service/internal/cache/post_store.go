@@ -0,0 +1,243 @@
+package cache
+
+import (
+	"api-service/internal/domain"
+	"api-service/internal/store"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Stats represent cache usage statistics
+type Stats struct {
+	PostHits   uint64
+	PostMisses uint64
+	ListHits   uint64
+	ListMisses uint64
+	Entries    int
+}
+
+type listKey struct {
+	title string // filter is case insensitive, so it is stored in lower case
+	page  int
+	limit int
+}
+
+// PostStore is a post store decorator caching single posts and query results,
+// concurrent loads of the same missing entry are merged into a single store request
+type PostStore struct {
+	next  store.PostStore
+	posts *LRU[int, domain.Post]
+	lists *LRU[listKey, []domain.Post]
+	group singleflight.Group
+
+	// generation is changed by every mutation, results loaded
+	// before a mutation are not cached as they could be stale
+	mu         sync.Mutex
+	generation uint64
+
+	postHits   atomic.Uint64
+	postMisses atomic.Uint64
+	listHits   atomic.Uint64
+	listMisses atomic.Uint64
+}
+
+// NewPostStore creates a caching decorator of post store, posts and query results
+// are cached separately with up to size entries each
+func NewPostStore(next store.PostStore, size int, ttl time.Duration) *PostStore {
+	return &PostStore{
+		next:  next,
+		posts: NewLRU[int, domain.Post](size, ttl),
+		lists: NewLRU[listKey, []domain.Post](size, ttl),
+	}
+}
+
+// Unwrap returns decorated store
+func (s *PostStore) Unwrap() store.PostStore {
+	return s.next
+}
+
+func (s *PostStore) Get(ctx context.Context, title string, page int, limit int) (*[]domain.Post, error) {
+	key := listKey{title: strings.ToLower(title), page: page, limit: limit}
+	if posts, ok := s.lists.Get(key); ok {
+		s.listHits.Add(1)
+		return copyPosts(posts), nil
+	}
+	s.listMisses.Add(1)
+
+	generation := s.currentGeneration()
+	value, err, _ := s.group.Do(fmt.Sprintf("list:%d:%d:%s", page, limit, key.title), func() (any, error) {
+		posts, err := s.next.Get(ctx, title, page, limit)
+		if err != nil {
+			return nil, err
+		}
+		s.fill(generation, func() { s.lists.Set(key, *posts) })
+		return *posts, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return copyPosts(value.([]domain.Post)), nil
+}
+
+func (s *PostStore) GetOne(ctx context.Context, id int) (*domain.Post, error) {
+	if post, ok := s.posts.Get(id); ok {
+		s.postHits.Add(1)
+		return &post, nil
+	}
+	s.postMisses.Add(1)
+
+	generation := s.currentGeneration()
+	value, err, _ := s.group.Do(fmt.Sprintf("post:%d", id), func() (any, error) {
+		post, err := s.next.GetOne(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		s.fill(generation, func() { s.posts.Set(id, *post) })
+		return *post, nil
+	})
+	if err != nil {
+		return &domain.Post{}, err
+	}
+	post := value.(domain.Post)
+	return &post, nil
+}
+
+func (s *PostStore) Insert(ctx context.Context, post domain.Post) (int, error) {
+	id, err := s.next.Insert(ctx, post)
+	if err != nil {
+		return id, err
+	}
+	s.invalidate(id, post.Title)
+	return id, nil
+}
+
+func (s *PostStore) Update(ctx context.Context, id int, post domain.Post) error {
+	// both old and new titles define query results affected by the update
+	titles := []string{post.Title}
+	if old, err := s.next.GetOne(ctx, id); err == nil {
+		titles = append(titles, old.Title)
+	}
+	err := s.next.Update(ctx, id, post)
+	if err != nil {
+		return err
+	}
+	s.invalidate(id, titles...)
+	return nil
+}
+
+func (s *PostStore) Delete(ctx context.Context, id int) error {
+	old, err := s.next.GetOne(ctx, id)
+	if err != nil {
+		return s.next.Delete(ctx, id)
+	}
+	err = s.next.Delete(ctx, id)
+	if err != nil {
+		return err
+	}
+	s.invalidate(id, old.Title)
+	return nil
+}
+
+func (s *PostStore) Put(ctx context.Context, post domain.Post) (bool, error) {
+	titles := []string{post.Title}
+	if old, err := s.next.GetOne(ctx, post.ID); err == nil {
+		titles = append(titles, old.Title)
+	}
+	created, err := s.next.Put(ctx, post)
+	if err != nil {
+		return created, err
+	}
+	s.invalidate(post.ID, titles...)
+	return created, nil
+}
+
+// Scan iterates posts of decorated store without caching, pages of iteration are rarely requested twice
+func (s *PostStore) Scan(ctx context.Context, afterID int, limit int) ([]domain.Post, error) {
+	return store.Scan(ctx, s.next, afterID, limit)
+}
+
+// WithTx runs transaction on decorated store without caching, cache is purged when transaction is committed
+func (s *PostStore) WithTx(ctx context.Context, fn func(tx store.PostStore) error) error {
+	err := s.next.WithTx(ctx, fn)
+	if err != nil {
+		return err
+	}
+	s.Purge()
+	return nil
+}
+
+// Capabilities reports capabilities of decorated store
+func (s *PostStore) Capabilities() store.Capabilities {
+	return s.next.Capabilities()
+}
+
+// Purge removes all cached entries, it is required when decorated store is modified directly
+func (s *PostStore) Purge() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.generation++
+	s.posts.Purge()
+	s.lists.Purge()
+}
+
+// Stats returns cache usage statistics
+func (s *PostStore) Stats() Stats {
+	return Stats{
+		PostHits:   s.postHits.Load(),
+		PostMisses: s.postMisses.Load(),
+		ListHits:   s.listHits.Load(),
+		ListMisses: s.listMisses.Load(),
+		Entries:    s.posts.Len() + s.lists.Len(),
+	}
+}
+
+func (s *PostStore) currentGeneration() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.generation
+}
+
+// fill caches loaded result unless a mutation happened since loading was started
+func (s *PostStore) fill(generation uint64, set func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.generation == generation {
+		set()
+	}
+}
+
+// invalidate removes cached post and query results whose filter matches any of post titles,
+// results of other queries neither contain the post nor shift because of it
+func (s *PostStore) invalidate(id int, titles ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.generation++
+	s.posts.Delete(id)
+	for i := range titles {
+		titles[i] = strings.ToLower(titles[i])
+	}
+	s.lists.DeleteFunc(func(key listKey, _ []domain.Post) bool {
+		for _, title := range titles {
+			if strings.Contains(title, key.title) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+func copyPosts(posts []domain.Post) *[]domain.Post {
+	result := make([]domain.Post, len(posts))
+	copy(result, posts)
+	return &result
+}
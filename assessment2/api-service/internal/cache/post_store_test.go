@@ -0,0 +1,130 @@
+package cache
+
+import (
+	"api-service/internal/domain"
+	"api-service/internal/store"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+)
+
+func newTestPostStore(t *testing.T) (*PostStore, *store.MockPostStore) {
+	t.Helper()
+
+	ctrl := gomock.NewController(t)
+	next := store.NewMockPostStore(ctrl)
+	return NewPostStore(next, 10, time.Minute), next
+}
+
+// TestPostStore_GetOne tests caching of single posts
+func TestPostStore_GetOne(t *testing.T) {
+	t.Parallel()
+	s, next := newTestPostStore(t)
+	ctx := context.Background()
+
+	post := domain.Post{ID: 1, Title: "Title 1"}
+	next.EXPECT().GetOne(gomock.Any(), 1).Return(&post, nil).Times(1)
+
+	for i := 0; i < 3; i++ {
+		cached, err := s.GetOne(ctx, 1)
+		if err != nil || cached.Title != "Title 1" {
+			t.Fatalf("unexpected post %+v, error %v", cached, err)
+		}
+		// callers could not modify cached entry
+		cached.Title = "Modified"
+	}
+
+	stats := s.Stats()
+	if stats.PostHits != 2 || stats.PostMisses != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+// TestPostStore_Invalidation tests that mutations invalidate only affected entries
+func TestPostStore_Invalidation(t *testing.T) {
+	t.Parallel()
+	s, next := newTestPostStore(t)
+	ctx := context.Background()
+
+	golang := []domain.Post{{ID: 1, Title: "Golang tips"}}
+	rust := []domain.Post{{ID: 2, Title: "Rust tips"}}
+	next.EXPECT().Get(gomock.Any(), "golang", 1, 5).Return(&golang, nil).Times(2)
+	next.EXPECT().Get(gomock.Any(), "rust", 1, 5).Return(&rust, nil).Times(1)
+	next.EXPECT().GetOne(gomock.Any(), 1).Return(&golang[0], nil).Times(2)
+	next.EXPECT().Update(gomock.Any(), 1, gomock.Any()).Return(nil)
+
+	load := func() {
+		_, _ = s.Get(ctx, "golang", 1, 5)
+		_, _ = s.Get(ctx, "rust", 1, 5)
+	}
+	load()
+	load()
+
+	// updating golang post keeps rust results cached
+	err := s.Update(ctx, 1, domain.Post{Title: "Golang tricks"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	load()
+	if _, err := s.GetOne(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestPostStore_SingleFlight tests merging of concurrent loads
+func TestPostStore_SingleFlight(t *testing.T) {
+	t.Parallel()
+	s, next := newTestPostStore(t)
+	ctx := context.Background()
+
+	release := make(chan struct{})
+	posts := []domain.Post{{ID: 1, Title: "Title 1"}}
+	next.EXPECT().Get(gomock.Any(), "", 1, 5).DoAndReturn(
+		func(ctx context.Context, title string, page int, limit int) (*[]domain.Post, error) {
+			<-release
+			return &posts, nil
+		}).Times(1)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := s.Get(ctx, "", 1, 5)
+			if err != nil || len(*result) != 1 {
+				t.Errorf("unexpected result %v, error %v", result, err)
+			}
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+}
+
+// TestLRU tests eviction and expiration of entries
+func TestLRU(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewLRU[int, string](2, time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.Set(1, "one")
+	c.Set(2, "two")
+	c.Get(1)
+	c.Set(3, "three")
+	if _, ok := c.Get(2); ok {
+		t.Errorf("expected least recently used entry to be evicted")
+	}
+	if _, ok := c.Get(1); !ok {
+		t.Errorf("expected recently used entry to be kept")
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok := c.Get(3); ok {
+		t.Errorf("expected expired entry")
+	}
+}
@@ -2,6 +2,7 @@ package domain
 
 import (
 	"errors"
+	"time"
 )
 
 // Post domain structure
@@ -10,7 +11,38 @@ type Post struct {
 	Title   string
 	Content string
 	Author  string
+	Owner   string
+	Version int    // incremented by every change of the post
+	Format  string // format of content, plain or markdown
+
+	// timestamps are zero when the store does not track them
+	Created time.Time
+	Updated time.Time
+}
+
+// Formats of post content
+const (
+	FormatPlain    = "plain"
+	FormatMarkdown = "markdown"
+)
+
+// ErrorInvalidFormat is returned when post is stored with unknown content format
+var ErrorInvalidFormat = errors.New("format must be one of plain or markdown")
+
+// NormalizeFormat validates content format, empty format is plain
+func NormalizeFormat(format string) (string, error) {
+	switch format {
+	case "", FormatPlain:
+		return FormatPlain, nil
+	case FormatMarkdown:
+		return FormatMarkdown, nil
+	default:
+		return "", ErrorInvalidFormat
+	}
 }
 
 // ErrorPostNotFound is returned by some functions when a post is not found
 var ErrorPostNotFound = errors.New("post not found")
+
+// ErrorInvalidPostID is returned when post is stored with non positive id
+var ErrorInvalidPostID = errors.New("post id must be positive")
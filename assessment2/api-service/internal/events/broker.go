@@ -0,0 +1,266 @@
+package events
+
+import (
+	"api-service/internal/domain"
+	"api-service/internal/store"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Type names kind of post change
+type Type string
+
+// Event types
+const (
+	TypeCreated Type = "created"
+	TypeUpdated Type = "updated"
+	TypeDeleted Type = "deleted"
+)
+
+// ErrorSlowConsumer is a reason of closed subscription whose buffer was overflown
+var ErrorSlowConsumer = errors.New("subscriber could not keep up with events")
+
+// ErrorBrokerClosed is a reason of subscriptions closed on shutdown
+var ErrorBrokerClosed = errors.New("event broker is closed")
+
+// ErrorEventID is returned when stream id of event could not be parsed
+var ErrorEventID = errors.New("event id must be an id of received event")
+
+// Event represent a change of a post, ids of events are increasing within epoch of the broker
+type Event struct {
+	ID      uint64       `json:"id"`
+	Epoch   uint64       `json:"epoch"` // epoch of broker which published the event, ids restart in every epoch
+	Type    Type         `json:"type"`
+	PostID  int          `json:"post_id"`
+	Version int          `json:"version"`
+	Post    *domain.Post `json:"post,omitempty"` // state of the post after change, empty for deleted posts
+	Time    time.Time    `json:"time"`
+	DedupID string       `json:"dedup_id,omitempty"` // id of outbox record, the same for redelivered events
+}
+
+// StreamID returns id of event in streams, it is prefixed by epoch, so that ids of other epochs are not resumed
+func (e Event) StreamID() string {
+	return fmt.Sprintf("%d-%d", e.Epoch, e.ID)
+}
+
+// ParseStreamID parses stream id of event, ids without epoch have zero epoch
+func ParseStreamID(streamID string) (epoch uint64, id uint64, err error) {
+	epochText, idText, found := strings.Cut(streamID, "-")
+	if !found {
+		epochText, idText = "0", streamID
+	}
+	if epoch, err = strconv.ParseUint(epochText, 10, 64); err != nil {
+		return 0, 0, ErrorEventID
+	}
+	if id, err = strconv.ParseUint(idText, 10, 64); err != nil {
+		return 0, 0, ErrorEventID
+	}
+	return epoch, id, nil
+}
+
+// Filter selects events delivered to subscriber
+type Filter func(event Event) bool
+
+// Broker delivers published events to subscribers and keeps bounded buffer of recent events,
+// so subscribers are able to resume after reconnection without missing events. Ids of events are restarted
+// with the process, so every broker has its own epoch and subscribers of other epochs are not resumed
+type Broker struct {
+	mu          sync.Mutex
+	epoch       uint64
+	sequence    uint64
+	replay      []Event // ring buffer of recent events
+	replayStart int
+	replaySize  int
+	bufferSize  int
+	subscribers map[*Subscription]struct{}
+	closed      bool
+	now         func() time.Time
+}
+
+// NewBroker creates a broker keeping replaySize recent events, every subscriber
+// could have up to bufferSize undelivered events before it is disconnected
+func NewBroker(replaySize int, bufferSize int) *Broker {
+	return &Broker{
+		epoch:       uint64(time.Now().UnixMilli()),
+		replaySize:  replaySize,
+		bufferSize:  bufferSize,
+		subscribers: make(map[*Subscription]struct{}),
+		now:         time.Now,
+	}
+}
+
+// Observer returns post store observer publishing changes of posts
+func (b *Broker) Observer() store.ChangeObserver {
+	return func(ctx context.Context, change store.Change) {
+		b.Publish(FromChange(change))
+	}
+}
+
+// FromChange converts post store change into event
+func FromChange(change store.Change) Event {
+	event := Event{
+		Type:    Type(change.Type),
+		PostID:  change.Post.ID,
+		Version: change.Post.Version,
+	}
+	if change.Type != store.ChangeDeleted {
+		post := change.Post
+		event.Post = &post
+	}
+	return event
+}
+
+// FromRecord converts outbox record into event, sequence of the record is used as event id
+func FromRecord(record store.OutboxRecord) Event {
+	event := FromChange(store.Change{Type: record.Type, Post: record.Post})
+	event.ID = record.Sequence
+	event.Time = record.Time
+	event.DedupID = record.ID
+	return event
+}
+
+// Publish assigns id and time to event, keeps it for replay and delivers it to subscribers,
+// it never blocks: subscribers whose buffer is full are disconnected
+func (b *Broker) Publish(event Event) Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.sequence++
+	event.ID, event.Epoch = b.sequence, b.epoch
+	if event.Time.IsZero() {
+		event.Time = b.now().UTC()
+	}
+	if b.closed {
+		return event
+	}
+
+	if b.replaySize > 0 {
+		if len(b.replay) < b.replaySize {
+			b.replay = append(b.replay, event)
+		} else {
+			b.replay[b.replayStart] = event
+			b.replayStart = (b.replayStart + 1) % b.replaySize
+		}
+	}
+
+	for sub := range b.subscribers {
+		if sub.filter != nil && !sub.filter(event) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			b.unsubscribe(sub, ErrorSlowConsumer)
+		}
+	}
+	return event
+}
+
+// Epoch returns epoch of events published by broker
+func (b *Broker) Epoch() uint64 {
+	return b.epoch
+}
+
+// Subscribe creates subscription to events matching filter, events published after lastID of epoch
+// are returned for replay, complete is false when some of them are no longer available
+func (b *Broker) Subscribe(epoch uint64, lastID uint64, filter Filter) (sub *Subscription, replay []Event, complete bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sub = &Subscription{
+		broker: b,
+		events: make(chan Event, b.bufferSize),
+		filter: filter,
+	}
+	if b.closed {
+		sub.err = ErrorBrokerClosed
+		close(sub.events)
+		return sub, nil, false
+	}
+	b.subscribers[sub] = struct{}{}
+
+	// events are replayed only to clients which already received some of them
+	complete = true
+	if lastID == 0 {
+		return sub, nil, complete
+	}
+	// ids are restarted with service, so id of other epoch or unknown id could not be resumed
+	if epoch != b.epoch || lastID > b.sequence {
+		return sub, nil, false
+	}
+	for i := 0; i < len(b.replay); i++ {
+		event := b.replay[(b.replayStart+i)%len(b.replay)]
+		if i == 0 && event.ID > lastID+1 {
+			complete = false
+		}
+		if event.ID > lastID && (filter == nil || filter(event)) {
+			replay = append(replay, event)
+		}
+	}
+	if len(b.replay) == 0 && b.sequence > lastID {
+		complete = false
+	}
+	return sub, replay, complete
+}
+
+// Close disconnects all subscribers, events published later are dropped
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for sub := range b.subscribers {
+		b.unsubscribe(sub, ErrorBrokerClosed)
+	}
+}
+
+// Subscribers returns number of active subscriptions
+func (b *Broker) Subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.subscribers)
+}
+
+func (b *Broker) unsubscribe(sub *Subscription, reason error) {
+	if _, ok := b.subscribers[sub]; !ok {
+		return
+	}
+	delete(b.subscribers, sub)
+	sub.err = reason
+	close(sub.events)
+}
+
+// Subscription receives events from broker until it is closed
+type Subscription struct {
+	broker *Broker
+	events chan Event
+	filter Filter
+	err    error
+}
+
+// Events returns channel of events, it is closed when subscription is finished
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Err returns reason of finished subscription, it is nil when subscription was closed by subscriber
+func (s *Subscription) Err() error {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+
+	return s.err
+}
+
+// Close finishes subscription
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+
+	s.broker.unsubscribe(s, nil)
+}
@@ -0,0 +1,184 @@
+package events
+
+import (
+	"api-service/internal/domain"
+	"api-service/internal/store"
+	"context"
+	"errors"
+	"testing"
+)
+
+// TestBroker_Publish tests delivery of store changes to filtered subscribers
+func TestBroker_Publish(t *testing.T) {
+	t.Parallel()
+
+	broker := NewBroker(10, 10)
+	memoryStore, _ := store.NewMemoryPostStore("")
+	memoryStore.Observe(broker.Observer())
+
+	all, _, _ := broker.Subscribe(0, 0, nil)
+	deleted, _, _ := broker.Subscribe(0, 0, func(event Event) bool { return event.Type == TypeDeleted })
+	defer all.Close()
+	defer deleted.Close()
+
+	ctx := context.Background()
+	id, _ := memoryStore.Insert(ctx, domain.Post{Title: "Title"})
+	_ = memoryStore.Update(ctx, id, domain.Post{Title: "Updated"})
+	_ = memoryStore.Delete(ctx, id)
+
+	expected := []struct {
+		id      uint64
+		typ     Type
+		version int
+		title   string
+	}{
+		{1, TypeCreated, 1, "Title"},
+		{2, TypeUpdated, 2, "Updated"},
+		{3, TypeDeleted, 3, ""},
+	}
+	for _, e := range expected {
+		event := <-all.Events()
+		title := ""
+		if event.Post != nil {
+			title = event.Post.Title
+		}
+		if event.ID != e.id || event.Type != e.typ || event.PostID != id || event.Version != e.version || title != e.title {
+			t.Errorf("unexpected event %+v", event)
+		}
+	}
+	if event := <-deleted.Events(); event.ID != 3 {
+		t.Errorf("expected only deleted event, but got %+v", event)
+	}
+}
+
+// TestBroker_Transaction tests that changes of transactions are published only when committed
+func TestBroker_Transaction(t *testing.T) {
+	t.Parallel()
+
+	broker := NewBroker(10, 10)
+	memoryStore, _ := store.NewMemoryPostStore("")
+	memoryStore.Observe(broker.Observer())
+	sub, _, _ := broker.Subscribe(0, 0, nil)
+	defer sub.Close()
+
+	ctx := context.Background()
+	_ = memoryStore.WithTx(ctx, func(tx store.PostStore) error {
+		_, _ = tx.Insert(ctx, domain.Post{Title: "Rolled back"})
+		return errors.New("failure")
+	})
+	_ = memoryStore.WithTx(ctx, func(tx store.PostStore) error {
+		_, err := tx.Insert(ctx, domain.Post{Title: "Committed"})
+		return err
+	})
+
+	event := <-sub.Events()
+	if event.Post == nil || event.Post.Title != "Committed" || len(sub.Events()) != 0 {
+		t.Errorf("expected only committed change, but got %+v", event)
+	}
+}
+
+// TestBroker_Subscribe tests replay of events missed by reconnected subscribers
+func TestBroker_Subscribe(t *testing.T) {
+	t.Parallel()
+
+	broker := NewBroker(3, 10)
+	for i := 1; i <= 5; i++ {
+		broker.Publish(Event{Type: TypeCreated, PostID: i})
+	}
+
+	epoch := broker.Epoch()
+	tests := []struct {
+		name             string
+		epoch            uint64
+		lastID           uint64
+		expected         []uint64
+		expectedComplete bool
+	}{
+		{"new subscriber", 0, 0, nil, true},
+		{"replayed events", epoch, 3, []uint64{4, 5}, true},
+		{"up to date", epoch, 5, nil, true},
+		{"evicted events", epoch, 1, []uint64{3, 4, 5}, false},
+		{"unknown id", epoch, 10, nil, false},
+		{"other epoch", epoch - 1, 3, nil, false},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			sub, replay, complete := broker.Subscribe(tt.epoch, tt.lastID, nil)
+			defer sub.Close()
+
+			var ids []uint64
+			for _, event := range replay {
+				ids = append(ids, event.ID)
+			}
+			if len(ids) != len(tt.expected) || complete != tt.expectedComplete {
+				t.Fatalf("expected %v (complete %v), but got %v (complete %v)", tt.expected, tt.expectedComplete, ids, complete)
+			}
+			for i := range ids {
+				if ids[i] != tt.expected[i] {
+					t.Errorf("expected %v, but got %v", tt.expected, ids)
+				}
+			}
+		})
+	}
+}
+
+// TestBroker_SlowConsumer tests that subscribers with full buffer are disconnected
+func TestBroker_SlowConsumer(t *testing.T) {
+	t.Parallel()
+
+	broker := NewBroker(10, 2)
+	slow, _, _ := broker.Subscribe(0, 0, nil)
+	for i := 0; i < 3; i++ {
+		broker.Publish(Event{Type: TypeCreated, PostID: i})
+	}
+
+	received := 0
+	for range slow.Events() {
+		received++
+	}
+	if received != 2 || !errors.Is(slow.Err(), ErrorSlowConsumer) {
+		t.Errorf("expected disconnection after buffered events, but got %d events and %v", received, slow.Err())
+	}
+	if broker.Subscribers() != 0 {
+		t.Errorf("expected no subscribers")
+	}
+
+	broker.Close()
+	closed, _, _ := broker.Subscribe(0, 0, nil)
+	if _, ok := <-closed.Events(); ok || !errors.Is(closed.Err(), ErrorBrokerClosed) {
+		t.Errorf("expected closed subscription")
+	}
+}
+
+// TestParseStreamID tests that stream ids of events are parsed back
+func TestParseStreamID(t *testing.T) {
+	t.Parallel()
+
+	event := NewBroker(1, 1).Publish(Event{Type: TypeCreated, PostID: 1})
+	epoch, id, err := ParseStreamID(event.StreamID())
+	if err != nil || epoch != event.Epoch || id != 1 {
+		t.Errorf("expected epoch %d and id 1, but got %d, %d, %v", event.Epoch, epoch, id, err)
+	}
+
+	tests := []struct {
+		streamID      string
+		expectedEpoch uint64
+		expectedID    uint64
+		expectedError error
+	}{
+		{"42", 0, 42, nil},
+		{"7-42", 7, 42, nil},
+		{"x-42", 0, 0, ErrorEventID},
+		{"7-", 0, 0, ErrorEventID},
+		{"-1", 0, 0, ErrorEventID},
+	}
+	for _, tt := range tests {
+		epoch, id, err := ParseStreamID(tt.streamID)
+		if epoch != tt.expectedEpoch || id != tt.expectedID || !errors.Is(err, tt.expectedError) {
+			t.Errorf("%s: expected %d, %d, %v, but got %d, %d, %v", tt.streamID, tt.expectedEpoch, tt.expectedID, tt.expectedError, epoch, id, err)
+		}
+	}
+}
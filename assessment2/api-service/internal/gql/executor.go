@@ -0,0 +1,182 @@
+package gql
+
+import (
+	"context"
+	"errors"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+// Error codes reported in extensions of errors
+const (
+	CodeParseFailed         = "GRAPHQL_PARSE_FAILED"
+	CodeValidationFailed    = "GRAPHQL_VALIDATION_FAILED"
+	CodeQueryTooComplex     = "QUERY_TOO_COMPLEX"
+	CodePersistedNotFound   = "PERSISTED_QUERY_NOT_FOUND"
+	CodePersistedNotAllowed = "PERSISTED_QUERY_NOT_SUPPORTED"
+	CodeBadRequest          = "BAD_REQUEST"
+	CodeMethodNotAllowed    = "METHOD_NOT_ALLOWED"
+)
+
+var (
+	ErrorQueryMissing         = errors.New("query is required")
+	ErrorOperationNotFound    = errors.New("operation is not found")
+	ErrorMutationNotAllowed   = errors.New("mutations are allowed with POST requests only")
+	ErrorSubscriptionDisabled = errors.New("subscriptions are not supported")
+)
+
+// Request represent GraphQL request sent as JSON body or URL query parameters
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+	Extensions    *RequestExtensions     `json:"extensions,omitempty"`
+}
+
+// RequestExtensions represent extensions of request, only persisted query extension is supported
+type RequestExtensions struct {
+	PersistedQuery *PersistedQuery `json:"persistedQuery,omitempty"`
+}
+
+// PersistedQuery identifies query by its sha256 hash
+type PersistedQuery struct {
+	Version    int    `json:"version"`
+	Sha256Hash string `json:"sha256Hash"`
+}
+
+// Error is an error of resolver with code reported in extensions of error
+type Error struct {
+	Err  error
+	Code string
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Extensions implements gqlerrors.ExtendedError
+func (e *Error) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": e.Code}
+}
+
+// Executor parses, validates, checks limits and executes requests against schema
+type Executor struct {
+	Schema    graphql.Schema
+	Limits    Limits
+	Persisted *PersistedQueries // persisted queries are not supported when nil
+}
+
+// Execute runs request, read only executors reject mutations, errors of request are reported in result
+func (e *Executor) Execute(ctx context.Context, req Request, readOnly bool) *graphql.Result {
+	// resolve query sent by hash
+	query := req.Query
+	var hash string
+	if req.Extensions != nil && req.Extensions.PersistedQuery != nil {
+		hash = req.Extensions.PersistedQuery.Sha256Hash
+	}
+	if e.Persisted != nil {
+		var err error
+		query, err = e.Persisted.Resolve(query, hash)
+		if err != nil {
+			return errorResult(err, persistedErrorCode(err))
+		}
+	} else if hash != "" && query == "" {
+		return errorResult(ErrorPersistedQueryDisabled, CodePersistedNotAllowed)
+	}
+	if query == "" {
+		return errorResult(ErrorQueryMissing, CodeBadRequest)
+	}
+
+	// parse and validate query
+	doc, err := parser.Parse(parser.ParseParams{
+		Source: source.NewSource(&source.Source{Body: []byte(query), Name: "GraphQL request"}),
+	})
+	if err != nil {
+		return errorResult(err, CodeParseFailed)
+	}
+	validation := graphql.ValidateDocument(&e.Schema, doc, nil)
+	if !validation.IsValid {
+		for i := range validation.Errors {
+			validation.Errors[i].Extensions = map[string]interface{}{"code": CodeValidationFailed}
+		}
+		return &graphql.Result{Errors: validation.Errors}
+	}
+
+	operation := findOperation(doc, req.OperationName)
+	if operation == nil {
+		return errorResult(ErrorOperationNotFound, CodeBadRequest)
+	}
+	switch {
+	case operation.Operation == ast.OperationTypeSubscription:
+		return errorResult(ErrorSubscriptionDisabled, CodeBadRequest)
+	case operation.Operation == ast.OperationTypeMutation && readOnly:
+		return errorResult(ErrorMutationNotAllowed, CodeMethodNotAllowed)
+	}
+
+	// reject expensive queries before execution
+	if err := e.Limits.Check(&e.Schema, doc, req.OperationName, req.Variables); err != nil {
+		return errorResult(err, CodeQueryTooComplex)
+	}
+
+	return graphql.Execute(graphql.ExecuteParams{
+		Schema:        e.Schema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       ctx,
+	})
+}
+
+// findOperation returns operation of document to execute, operation name is optional for single operation
+func findOperation(doc *ast.Document, operationName string) *ast.OperationDefinition {
+	var found *ast.OperationDefinition
+	for _, definition := range doc.Definitions {
+		operation, ok := definition.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if operationName == "" {
+			if found != nil {
+				return nil
+			}
+			found = operation
+		} else if operation.Name != nil && operation.Name.Value == operationName {
+			return operation
+		}
+	}
+	return found
+}
+
+// ResultCode returns code of the first error of result which failed before execution
+func ResultCode(result *graphql.Result) string {
+	if len(result.Errors) == 0 || result.Data != nil {
+		return ""
+	}
+	code, _ := result.Errors[0].Extensions["code"].(string)
+	return code
+}
+
+func persistedErrorCode(err error) string {
+	switch {
+	case errors.Is(err, ErrorPersistedQueryNotFound):
+		return CodePersistedNotFound
+	case errors.Is(err, ErrorPersistedQueryDisabled), errors.Is(err, ErrorPersistedQueryOnly):
+		return CodePersistedNotAllowed
+	default:
+		return CodeBadRequest
+	}
+}
+
+func errorResult(err error, code string) *graphql.Result {
+	formatted := gqlerrors.FormatError(err)
+	formatted.Extensions = map[string]interface{}{"code": code}
+	return &graphql.Result{Errors: []gqlerrors.FormattedError{formatted}}
+}
@@ -0,0 +1,199 @@
+package gql
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// DefaultListSize is an estimated size of list fields without limit argument
+const DefaultListSize = 10
+
+// Limits restrict cost of queries, zero values disable limits
+type Limits struct {
+	MaxDepth      int // levels of nested fields
+	MaxComplexity int // estimated number of resolved fields
+}
+
+// LimitError is returned when query exceeds limits
+type LimitError struct {
+	Limit string
+	Value int
+	Max   int
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("query %s %d exceeds maximum %d", e.Limit, e.Value, e.Max)
+}
+
+// Measure calculates depth and complexity of operation: every field costs one, fields nested in lists
+// are counted once per item, size of lists is taken from limit argument or its default value,
+// introspection fields are not counted
+func Measure(schema *graphql.Schema, doc *ast.Document, operationName string, variables map[string]interface{}) (depth int, complexity int) {
+	operation := findOperation(doc, operationName)
+	if operation == nil {
+		return 0, 0
+	}
+
+	fragments := make(map[string]*ast.FragmentDefinition)
+	for _, definition := range doc.Definitions {
+		if fragment, ok := definition.(*ast.FragmentDefinition); ok {
+			fragments[fragment.Name.Value] = fragment
+		}
+	}
+
+	root := schema.QueryType()
+	if operation.Operation == ast.OperationTypeMutation {
+		root = schema.MutationType()
+	}
+	m := measurer{schema: schema, fragments: fragments, variables: variables, visited: make(map[string]bool)}
+	return m.selectionSet(operation.SelectionSet, root)
+}
+
+// Check returns LimitError when operation exceeds limits
+func (l Limits) Check(schema *graphql.Schema, doc *ast.Document, operationName string, variables map[string]interface{}) error {
+	depth, complexity := Measure(schema, doc, operationName, variables)
+	if l.MaxDepth > 0 && depth > l.MaxDepth {
+		return &LimitError{Limit: "depth", Value: depth, Max: l.MaxDepth}
+	}
+	if l.MaxComplexity > 0 && complexity > l.MaxComplexity {
+		return &LimitError{Limit: "complexity", Value: complexity, Max: l.MaxComplexity}
+	}
+	return nil
+}
+
+type measurer struct {
+	schema    *graphql.Schema
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+	visited   map[string]bool // fragments being measured, validation rejects cycles anyway
+}
+
+// selectionSet returns depth and complexity of selections of the parent type
+func (m *measurer) selectionSet(set *ast.SelectionSet, parent graphql.Type) (int, int) {
+	if set == nil {
+		return 0, 0
+	}
+	depth, complexity := 0, 0
+	for _, selection := range set.Selections {
+		var d, c int
+		switch selection := selection.(type) {
+		case *ast.Field:
+			d, c = m.field(selection, parent)
+		case *ast.InlineFragment:
+			fragmentType := parent
+			if selection.TypeCondition != nil {
+				fragmentType = m.schema.Type(selection.TypeCondition.Name.Value)
+			}
+			d, c = m.selectionSet(selection.SelectionSet, fragmentType)
+		case *ast.FragmentSpread:
+			name := selection.Name.Value
+			fragment, ok := m.fragments[name]
+			if !ok || m.visited[name] {
+				continue
+			}
+			m.visited[name] = true
+			d, c = m.selectionSet(fragment.SelectionSet, m.schema.Type(fragment.TypeCondition.Name.Value))
+			delete(m.visited, name)
+		}
+		depth = max(depth, d)
+		complexity = saturatingAdd(complexity, c)
+	}
+	return depth, complexity
+}
+
+func (m *measurer) field(field *ast.Field, parent graphql.Type) (int, int) {
+	if strings.HasPrefix(field.Name.Value, "__") {
+		return 0, 0
+	}
+	object, ok := parent.(*graphql.Object)
+	if !ok {
+		return 1, 1
+	}
+	definition, ok := object.Fields()[field.Name.Value]
+	if !ok {
+		return 1, 1
+	}
+
+	// unwrap type of field to find out whether it is a list
+	fieldType, isList := definition.Type, false
+	for {
+		switch t := fieldType.(type) {
+		case *graphql.NonNull:
+			fieldType = t.OfType
+			continue
+		case *graphql.List:
+			fieldType, isList = t.OfType, true
+			continue
+		}
+		break
+	}
+
+	childDepth, childComplexity := m.selectionSet(field.SelectionSet, fieldType)
+	size := 1
+	if isList {
+		size = m.listSize(field, definition)
+	}
+	return childDepth + 1, saturatingAdd(1, saturatingMul(size, childComplexity))
+}
+
+// listSize returns value of limit argument specified literally, by variable or by default value,
+// non-positive limits are replaced by default value like resolvers do
+func (m *measurer) listSize(field *ast.Field, definition *graphql.FieldDefinition) int {
+	for _, argument := range field.Arguments {
+		if argument.Name.Value != "limit" {
+			continue
+		}
+		switch value := argument.Value.(type) {
+		case *ast.IntValue:
+			n, err := strconv.Atoi(value.Value)
+			if errors.Is(err, strconv.ErrRange) && !strings.HasPrefix(value.Value, "-") {
+				return math.MaxInt
+			}
+			if err == nil && n > 0 {
+				return n
+			}
+		case *ast.Variable:
+			switch n := m.variables[value.Name.Value].(type) {
+			case int:
+				if n > 0 {
+					return n
+				}
+			case float64:
+				if n >= math.MaxInt {
+					return math.MaxInt
+				}
+				if n >= 1 {
+					return int(n)
+				}
+			}
+		}
+	}
+	for _, argument := range definition.Args {
+		if n, ok := argument.DefaultValue.(int); ok && argument.Name() == "limit" && n > 0 {
+			return n
+		}
+	}
+	return DefaultListSize
+}
+
+// saturatingAdd returns sum of non-negative numbers or math.MaxInt when it overflows
+func saturatingAdd(a int, b int) int {
+	if a > math.MaxInt-b {
+		return math.MaxInt
+	}
+	return a + b
+}
+
+// saturatingMul returns product of non-negative numbers or math.MaxInt when it overflows
+func saturatingMul(a int, b int) int {
+	if a != 0 && b > math.MaxInt/a {
+		return math.MaxInt
+	}
+	return a * b
+}
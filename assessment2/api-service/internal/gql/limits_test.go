@@ -0,0 +1,109 @@
+package gql
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+// newTestSchema creates schema of items with nested lists of items
+func newTestSchema(t *testing.T) graphql.Schema {
+	t.Helper()
+
+	itemType := graphql.NewObject(graphql.ObjectConfig{
+		Name:   "Item",
+		Fields: graphql.Fields{"name": &graphql.Field{Type: graphql.String}},
+	})
+	itemType.AddFieldConfig("children", &graphql.Field{
+		Type: graphql.NewList(itemType),
+		Args: graphql.FieldConfigArgument{"limit": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 5}},
+	})
+	itemType.AddFieldConfig("parent", &graphql.Field{Type: itemType})
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Query",
+			Fields: graphql.Fields{
+				"item":  &graphql.Field{Type: itemType},
+				"items": &graphql.Field{Type: graphql.NewList(itemType)},
+			},
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return schema
+}
+
+// TestLimits_Measure tests depth and complexity estimation of queries
+func TestLimits_Measure(t *testing.T) {
+	t.Parallel()
+	schema := newTestSchema(t)
+
+	tests := []struct {
+		name               string
+		query              string
+		variables          map[string]interface{}
+		expectedDepth      int
+		expectedComplexity int
+	}{
+		{"single field", "{ item { name } }", nil, 2, 2},
+		{"default list size", "{ items { name } }", nil, 2, 1 + DefaultListSize},
+		{"default limit argument", "{ item { children { name } } }", nil, 3, 1 + 1 + 5},
+		{"literal limit", "{ item { children(limit: 2) { children(limit: 3) { name } } } }", nil, 4, 1 + 1 + 2*(1+3)},
+		{"variable limit", "query($n: Int) { item { children(limit: $n) { name } } }", map[string]interface{}{"n": float64(20)}, 3, 1 + 1 + 20},
+		{"negative literal limit", "{ item { children(limit: -1) { name } } }", nil, 3, 1 + 1 + 5},
+		{"negative variable limit", "query($n: Int) { item { children(limit: $n) { name } } }", map[string]interface{}{"n": float64(-3)}, 3, 1 + 1 + 5},
+		{"huge literal limit", "{ item { children(limit: 99999999999999999999) { children(limit: 1000000000) { name } } } }", nil, 4, math.MaxInt},
+		{"huge variable limit", "query($n: Int) { item { children(limit: $n) { children(limit: $n) { name } } } }", map[string]interface{}{"n": float64(1e300)}, 4, math.MaxInt},
+		{"fragments", "{ item { ...F ... on Item { parent { name } } } } fragment F on Item { parent { parent { name } } }", nil, 4, 1 + 3 + 2},
+		{"introspection", "{ __schema { types { name } } item { name } }", nil, 2, 2},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			doc, err := parser.Parse(parser.ParseParams{Source: tt.query})
+			if err != nil {
+				t.Fatal(err)
+			}
+			depth, complexity := Measure(&schema, doc, "", tt.variables)
+			if depth != tt.expectedDepth || complexity != tt.expectedComplexity {
+				t.Errorf("expected depth %d and complexity %d, but got %d and %d", tt.expectedDepth, tt.expectedComplexity, depth, complexity)
+			}
+		})
+	}
+}
+
+// TestLimits_Check tests rejection of queries exceeding limits
+func TestLimits_Check(t *testing.T) {
+	t.Parallel()
+	schema := newTestSchema(t)
+	doc, _ := parser.Parse(parser.ParseParams{Source: "{ item { parent { parent { children(limit: 50) { name } } } } }"})
+
+	tests := []struct {
+		name          string
+		limits        Limits
+		expectedLimit string
+	}{
+		{"within limits", Limits{MaxDepth: 5, MaxComplexity: 100}, ""},
+		{"disabled limits", Limits{}, ""},
+		{"too deep", Limits{MaxDepth: 4}, "depth"},
+		{"too complex", Limits{MaxComplexity: 50}, "complexity"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := tt.limits.Check(&schema, doc, "", nil)
+			var limitErr *LimitError
+			if errors.As(err, &limitErr) != (tt.expectedLimit != "") || (limitErr != nil && limitErr.Limit != tt.expectedLimit) {
+				t.Errorf("expected %q limit error, but got %v", tt.expectedLimit, err)
+			}
+		})
+	}
+}
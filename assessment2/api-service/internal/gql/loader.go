@@ -0,0 +1,89 @@
+package gql
+
+import (
+	"context"
+	"sync"
+)
+
+// BatchFunc loads values of keys at once, values and errors are aligned with keys
+type BatchFunc[K comparable, V any] func(ctx context.Context, keys []K) ([]V, []error)
+
+type loaded[V any] struct {
+	value V
+	err   error
+}
+
+// Loader collects keys requested while resolving one level of a query and loads them with a single batch,
+// loaded values are cached for the rest of the request, so a loader must not outlive its request
+type Loader[K comparable, V any] struct {
+	mu      sync.Mutex
+	ctx     context.Context
+	batch   BatchFunc[K, V]
+	pending []K
+	results map[K]loaded[V]
+}
+
+// NewLoader creates a loader of the request
+func NewLoader[K comparable, V any](ctx context.Context, batch BatchFunc[K, V]) *Loader[K, V] {
+	return &Loader[K, V]{
+		ctx:     ctx,
+		batch:   batch,
+		results: make(map[K]loaded[V]),
+	}
+}
+
+// Load registers key and returns a thunk resolving its value, the first called thunk loads all registered keys,
+// thunk signature is the one recognized by graphql executor
+func (l *Loader[K, V]) Load(key K) func() (interface{}, error) {
+	l.mu.Lock()
+	if _, ok := l.results[key]; !ok && !contains(l.pending, key) {
+		l.pending = append(l.pending, key)
+	}
+	l.mu.Unlock()
+
+	return func() (interface{}, error) {
+		value, err := l.Get(key)
+		return value, err
+	}
+}
+
+// Get returns value of key, loading it with all pending keys when it is not loaded yet
+func (l *Loader[K, V]) Get(key K) (V, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.results[key]; !ok {
+		if !contains(l.pending, key) {
+			l.pending = append(l.pending, key)
+		}
+		l.dispatch()
+	}
+	result := l.results[key]
+	return result.value, result.err
+}
+
+// dispatch loads pending keys
+func (l *Loader[K, V]) dispatch() {
+	keys := l.pending
+	l.pending = nil
+	values, errs := l.batch(l.ctx, keys)
+	for i, key := range keys {
+		var result loaded[V]
+		if i < len(values) {
+			result.value = values[i]
+		}
+		if i < len(errs) {
+			result.err = errs[i]
+		}
+		l.results[key] = result
+	}
+}
+
+func contains[K comparable](keys []K, key K) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
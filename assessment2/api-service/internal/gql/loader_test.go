@@ -0,0 +1,41 @@
+package gql
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// TestLoader_Batch tests that keys registered before the first thunk call are loaded with a single batch
+func TestLoader_Batch(t *testing.T) {
+	t.Parallel()
+	var batches [][]int
+	loader := NewLoader(context.Background(), func(ctx context.Context, keys []int) ([]string, []error) {
+		batches = append(batches, keys)
+		values := make([]string, len(keys))
+		errs := make([]error, len(keys))
+		for i, key := range keys {
+			if key < 0 {
+				errs[i] = errors.New("negative key")
+				continue
+			}
+			values[i] = string(rune('a' + key))
+		}
+		return values, errs
+	})
+
+	thunks := []func() (interface{}, error){loader.Load(1), loader.Load(2), loader.Load(1), loader.Load(-1)}
+	expected := []string{"b", "c", "b", ""}
+	for i, thunk := range thunks {
+		value, err := thunk()
+		if value != expected[i] || (err != nil) != (i == 3) {
+			t.Errorf("expected %q, but got %v, %v", expected[i], value, err)
+		}
+	}
+	// loaded keys are cached, new keys are loaded with another batch
+	_, _ = loader.Get(2)
+	_, _ = loader.Get(3)
+	if len(batches) != 2 || len(batches[0]) != 3 || len(batches[1]) != 1 {
+		t.Errorf("unexpected batches %v", batches)
+	}
+}
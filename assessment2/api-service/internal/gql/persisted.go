@@ -0,0 +1,110 @@
+package gql
+
+import (
+	"api-service/internal/cache"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"time"
+)
+
+// persistedTTL is a lifetime of automatically persisted queries which are not used
+const persistedTTL = 24 * time.Hour
+
+var (
+	ErrorPersistedQueryNotFound  = errors.New("PersistedQueryNotFound")
+	ErrorPersistedQueryMismatch  = errors.New("provided sha does not match query")
+	ErrorPersistedQueryOnly      = errors.New("only persisted queries are allowed")
+	ErrorPersistedQueryDisabled  = errors.New("PersistedQueryNotSupported")
+	ErrorPersistedQueryMalformed = errors.New("persisted query hash must be a sha256 hex digest")
+)
+
+// PersistedQueries resolves queries sent by their sha256 hash, unknown queries are registered
+// when clients send them with hash (automatic persisted queries), queries of allowlist are always known
+// and with allowlist only mode other queries are rejected
+type PersistedQueries struct {
+	automatic     *cache.LRU[string, string]
+	allowlist     map[string]string
+	allowlistOnly bool
+}
+
+// NewPersistedQueries creates persisted queries keeping up to capacity automatically registered queries,
+// zero capacity disables automatic registration
+func NewPersistedQueries(capacity int, allowlist map[string]string, allowlistOnly bool) *PersistedQueries {
+	p := &PersistedQueries{allowlist: allowlist, allowlistOnly: allowlistOnly}
+	if capacity > 0 && !allowlistOnly {
+		p.automatic = cache.NewLRU[string, string](capacity, persistedTTL)
+	}
+	return p
+}
+
+// LoadAllowlist reads list of queries from JSON file and keys them by their hashes
+func LoadAllowlist(path string) (map[string]string, error) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var queries []string
+	if err := json.Unmarshal(bytes, &queries); err != nil {
+		return nil, err
+	}
+	allowlist := make(map[string]string, len(queries))
+	for _, query := range queries {
+		allowlist[Hash(query)] = query
+	}
+	return allowlist, nil
+}
+
+// Hash returns hex sha256 digest of query
+func Hash(query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(sum[:])
+}
+
+// Resolve returns query to execute: query sent by hash is looked up, query sent with hash is verified
+// and registered, plain queries are allowed unless only allowlist is accepted
+func (p *PersistedQueries) Resolve(query string, hash string) (string, error) {
+	if hash == "" {
+		if p.allowlistOnly {
+			if _, ok := p.allowlist[Hash(query)]; !ok {
+				return "", ErrorPersistedQueryOnly
+			}
+		}
+		return query, nil
+	}
+	if len(hash) != sha256.Size*2 {
+		return "", ErrorPersistedQueryMalformed
+	}
+
+	if query == "" {
+		if known, ok := p.allowlist[hash]; ok {
+			return known, nil
+		}
+		if p.automatic == nil {
+			if p.allowlistOnly {
+				return "", ErrorPersistedQueryOnly
+			}
+			return "", ErrorPersistedQueryDisabled
+		}
+		if known, ok := p.automatic.Get(hash); ok {
+			return known, nil
+		}
+		return "", ErrorPersistedQueryNotFound
+	}
+
+	if Hash(query) != hash {
+		return "", ErrorPersistedQueryMismatch
+	}
+	if _, ok := p.allowlist[hash]; ok {
+		return query, nil
+	}
+	if p.allowlistOnly {
+		return "", ErrorPersistedQueryOnly
+	}
+	if p.automatic != nil {
+		p.automatic.Set(hash, query)
+	}
+	return query, nil
+}
@@ -0,0 +1,49 @@
+package gql
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestPersistedQueries_Resolve tests automatic persisted queries and allowlist
+func TestPersistedQueries_Resolve(t *testing.T) {
+	t.Parallel()
+	const query = "{ posts { id } }"
+	const allowed = "{ post(id: 1) { id } }"
+	allowlist := map[string]string{Hash(allowed): allowed}
+
+	tests := []struct {
+		name          string
+		persisted     *PersistedQueries
+		registered    string // query registered before resolving
+		query         string
+		hash          string
+		expectedQuery string
+		expectedError error
+	}{
+		{"plain query", NewPersistedQueries(10, nil, false), "", query, "", query, nil},
+		{"unknown hash", NewPersistedQueries(10, nil, false), "", "", Hash(query), "", ErrorPersistedQueryNotFound},
+		{"registered hash", NewPersistedQueries(10, nil, false), query, "", Hash(query), query, nil},
+		{"mismatched hash", NewPersistedQueries(10, nil, false), "", query, Hash(allowed), "", ErrorPersistedQueryMismatch},
+		{"malformed hash", NewPersistedQueries(10, nil, false), "", "", "abc", "", ErrorPersistedQueryMalformed},
+		{"disabled automatic queries", NewPersistedQueries(0, nil, false), "", "", Hash(query), "", ErrorPersistedQueryDisabled},
+		{"allowlisted hash", NewPersistedQueries(10, allowlist, true), "", "", Hash(allowed), allowed, nil},
+		{"allowlisted query", NewPersistedQueries(10, allowlist, true), "", allowed, "", allowed, nil},
+		{"query not in allowlist", NewPersistedQueries(10, allowlist, true), "", query, "", "", ErrorPersistedQueryOnly},
+		{"hash not in allowlist", NewPersistedQueries(10, allowlist, true), "", query, Hash(query), "", ErrorPersistedQueryOnly},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if tt.registered != "" {
+				_, _ = tt.persisted.Resolve(tt.registered, Hash(tt.registered))
+			}
+			resolved, err := tt.persisted.Resolve(tt.query, tt.hash)
+			if resolved != tt.expectedQuery || !errors.Is(err, tt.expectedError) {
+				t.Errorf("expected %q and %v, but got %q and %v", tt.expectedQuery, tt.expectedError, resolved, err)
+			}
+		})
+	}
+}
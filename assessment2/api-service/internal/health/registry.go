@@ -0,0 +1,189 @@
+package health
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Checker checks health of a service component
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc allows to use ordinary functions as checkers
+type CheckerFunc func(ctx context.Context) error
+
+// Check calls f(ctx)
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// State represent lifecycle state of the service
+type State string
+
+// Service lifecycle states, service is ready to process requests only in ready state
+const (
+	StateStarting State = "starting"
+	StateReady    State = "ready"
+	StateDraining State = "draining"
+)
+
+// Component status values
+const (
+	StatusOk   = "ok"
+	StatusFail = "fail"
+)
+
+// ComponentReport represent result of component check
+type ComponentReport struct {
+	Name    string    `json:"name"`
+	Status  string    `json:"status"`
+	Error   string    `json:"error,omitempty"`
+	Latency float64   `json:"latency_ms"`
+	Checked time.Time `json:"checked_at"`
+	Cached  bool      `json:"cached"`
+}
+
+// Report represent readiness report of the service
+type Report struct {
+	Ready      bool              `json:"ready"`
+	State      State             `json:"state"`
+	Components []ComponentReport `json:"components"`
+}
+
+type component struct {
+	name    string
+	checker Checker
+	last    *ComponentReport
+}
+
+// Registry holds service state and registered component checkers
+type Registry struct {
+	mu         sync.Mutex
+	state      State
+	components []*component
+	cacheTTL   time.Duration
+	timeout    time.Duration
+	now        func() time.Time
+}
+
+// NewRegistry creates a registry in starting state, check results are reused during
+// cacheTTL to avoid hammering backends (zero disables caching)
+func NewRegistry(cacheTTL time.Duration, timeout time.Duration) *Registry {
+	return &Registry{
+		state:    StateStarting,
+		cacheTTL: cacheTTL,
+		timeout:  timeout,
+		now:      time.Now,
+	}
+}
+
+// Register adds component checker
+func (r *Registry) Register(name string, checker Checker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.components = append(r.components, &component{name: name, checker: checker})
+	sort.Slice(r.components, func(i, j int) bool {
+		return r.components[i].name < r.components[j].name
+	})
+}
+
+// SetState changes service lifecycle state
+func (r *Registry) SetState(state State) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.state = state
+}
+
+// CompareAndSetState changes service lifecycle state only when it is in the old state,
+// it reports whether state was changed
+func (r *Registry) CompareAndSetState(old State, state State) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.state != old {
+		return false
+	}
+	r.state = state
+	return true
+}
+
+// State returns service lifecycle state
+func (r *Registry) State() State {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.state
+}
+
+// Check checks all registered components concurrently, service is ready
+// when it is in ready state and all components are healthy
+func (r *Registry) Check(ctx context.Context) Report {
+	r.mu.Lock()
+	state := r.state
+	components := append([]*component(nil), r.components...)
+	r.mu.Unlock()
+
+	reports := make([]ComponentReport, len(components))
+	var wg sync.WaitGroup
+	for i, c := range components {
+		wg.Add(1)
+		go func(i int, c *component) {
+			defer wg.Done()
+			reports[i] = r.checkComponent(ctx, c)
+		}(i, c)
+	}
+	wg.Wait()
+
+	report := Report{
+		Ready:      state == StateReady,
+		State:      state,
+		Components: reports,
+	}
+	for _, component := range reports {
+		if component.Status != StatusOk {
+			report.Ready = false
+		}
+	}
+	return report
+}
+
+func (r *Registry) checkComponent(ctx context.Context, c *component) ComponentReport {
+	// reuse cached result while it is fresh
+	r.mu.Lock()
+	if c.last != nil && r.now().Sub(c.last.Checked) < r.cacheTTL {
+		cached := *c.last
+		cached.Cached = true
+		r.mu.Unlock()
+		return cached
+	}
+	r.mu.Unlock()
+
+	if r.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+	}
+
+	start := r.now()
+	err := c.checker.Check(ctx)
+	report := ComponentReport{
+		Name:    c.name,
+		Status:  StatusOk,
+		Latency: float64(r.now().Sub(start).Microseconds()) / 1000,
+		Checked: start,
+	}
+	if err != nil {
+		report.Status = StatusFail
+		report.Error = err.Error()
+	}
+
+	r.mu.Lock()
+	c.last = &report
+	r.mu.Unlock()
+	return report
+}
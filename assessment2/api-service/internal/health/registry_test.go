@@ -0,0 +1,95 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestRegistry_Check tests readiness depending on state and components health
+func TestRegistry_Check(t *testing.T) {
+	t.Parallel()
+
+	var storeErr error
+	registry := NewRegistry(0, time.Second)
+	registry.Register("post_store", CheckerFunc(func(ctx context.Context) error {
+		return storeErr
+	}))
+	registry.Register("cache", CheckerFunc(func(ctx context.Context) error {
+		return nil
+	}))
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		state    State
+		storeErr error
+		ready    bool
+	}{
+		{"starting", StateStarting, nil, false},
+		{"ready", StateReady, nil, true},
+		{"failed component", StateReady, errors.New("store is down"), false},
+		{"draining", StateDraining, nil, false},
+	}
+
+	for _, tt := range tests {
+		registry.SetState(tt.state)
+		storeErr = tt.storeErr
+		report := registry.Check(ctx)
+		if report.Ready != tt.ready || report.State != tt.state {
+			t.Errorf("%s: expected ready %v, got %+v", tt.name, tt.ready, report)
+		}
+		if len(report.Components) != 2 || report.Components[0].Name != "cache" {
+			t.Fatalf("%s: expected sorted components, got %+v", tt.name, report.Components)
+		}
+		if tt.storeErr != nil && report.Components[1].Error != tt.storeErr.Error() {
+			t.Errorf("%s: expected component error, got %+v", tt.name, report.Components[1])
+		}
+	}
+}
+
+// TestRegistry_CheckCached tests reuse of fresh check results
+func TestRegistry_CheckCached(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	calls := 0
+	registry := NewRegistry(10*time.Second, time.Second)
+	registry.now = func() time.Time { return now }
+	registry.Register("post_store", CheckerFunc(func(ctx context.Context) error {
+		calls++
+		return nil
+	}))
+	registry.SetState(StateReady)
+	ctx := context.Background()
+
+	registry.Check(ctx)
+	report := registry.Check(ctx)
+	if calls != 1 || !report.Components[0].Cached {
+		t.Errorf("expected cached result, got %d calls and %+v", calls, report.Components[0])
+	}
+
+	now = now.Add(10 * time.Second)
+	report = registry.Check(ctx)
+	if calls != 2 || report.Components[0].Cached {
+		t.Errorf("expected fresh result, got %d calls and %+v", calls, report.Components[0])
+	}
+}
+
+// TestRegistry_CompareAndSetState tests that state is changed only from expected state
+func TestRegistry_CompareAndSetState(t *testing.T) {
+	t.Parallel()
+
+	registry := NewRegistry(0, time.Second)
+	registry.SetState(StateReady)
+	if !registry.CompareAndSetState(StateReady, StateStarting) || registry.State() != StateStarting {
+		t.Errorf("expected state changed to starting, got %s", registry.State())
+	}
+
+	// state changed meanwhile is kept
+	registry.SetState(StateDraining)
+	if registry.CompareAndSetState(StateStarting, StateReady) || registry.State() != StateDraining {
+		t.Errorf("expected draining state kept, got %s", registry.State())
+	}
+}
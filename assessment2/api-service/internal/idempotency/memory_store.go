@@ -0,0 +1,92 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval defines how often expired records are removed from memory
+const sweepInterval = time.Minute
+
+// MemoryStore is an in-memory storage of idempotency records
+type MemoryStore struct {
+	mu        sync.Mutex
+	records   map[string]Record
+	locks     map[string]chan struct{}
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryStore creates a new in-memory implementation of idempotency store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		records:   make(map[string]Record),
+		locks:     make(map[string]chan struct{}),
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+// Lock waits until the key is released by concurrent request and acquires it
+func (s *MemoryStore) Lock(ctx context.Context, key string) (func(), error) {
+	for {
+		s.mu.Lock()
+		held, ok := s.locks[key]
+		if !ok {
+			released := make(chan struct{})
+			s.locks[key] = released
+			s.mu.Unlock()
+			return func() {
+				s.mu.Lock()
+				delete(s.locks, key)
+				s.mu.Unlock()
+				close(released)
+			}, nil
+		}
+		s.mu.Unlock()
+
+		// wait for current holder
+		select {
+		case <-held:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Get returns record saved for the key
+func (s *MemoryStore) Get(ctx context.Context, key string) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.records[key]
+	if !ok || !s.now().Before(record.Expires) {
+		return nil, nil
+	}
+	return &record, nil
+}
+
+// Save saves record for the key
+func (s *MemoryStore) Save(ctx context.Context, key string, record Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep()
+	s.records[key] = record
+	return nil
+}
+
+// sweep removes expired records
+func (s *MemoryStore) sweep() {
+	now := s.now()
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, record := range s.records {
+		if !now.Before(record.Expires) {
+			delete(s.records, key)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestMemoryStore_Lock tests serialization of requests with the same key
+func TestMemoryStore_Lock(t *testing.T) {
+	t.Parallel()
+
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	active, maxActive := 0, 0
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unlock, err := store.Lock(ctx, "key")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			active++
+			if active > maxActive {
+				maxActive = active
+			}
+			mu.Unlock()
+			time.Sleep(time.Millisecond)
+			mu.Lock()
+			active--
+			mu.Unlock()
+			unlock()
+		}()
+	}
+	wg.Wait()
+
+	if maxActive != 1 {
+		t.Errorf("expected serialized key holders, got %d concurrent", maxActive)
+	}
+
+	// waiting is canceled with context
+	unlock, _ := store.Lock(ctx, "key")
+	defer unlock()
+	canceled, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := store.Lock(canceled, "key"); err == nil {
+		t.Errorf("expected context error while key is held")
+	}
+}
+
+// TestMemoryStore_Get tests expiration of records
+func TestMemoryStore_Get(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	_ = store.Save(ctx, "key", Record{Fingerprint: "abc", Expires: now.Add(time.Minute)})
+	if record, _ := store.Get(ctx, "key"); record == nil || record.Fingerprint != "abc" {
+		t.Fatalf("expected stored record, got %+v", record)
+	}
+
+	now = now.Add(time.Minute)
+	if record, _ := store.Get(ctx, "key"); record != nil {
+		t.Errorf("expected expired record, got %+v", record)
+	}
+}
@@ -0,0 +1,35 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// ErrorKeyReused is returned when idempotency key is reused with a different request
+var ErrorKeyReused = errors.New("idempotency key is already used for a different request")
+
+// Response represent stored response of the first request made with a key
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// Record represent stored idempotency key state
+type Record struct {
+	Fingerprint string
+	Response    Response
+	Expires     time.Time
+}
+
+// Store represent interface for idempotency records storage
+type Store interface {
+	// Lock serializes requests with the same key, returned function releases the key
+	Lock(ctx context.Context, key string) (func(), error)
+	// Get returns record saved for the key, if it is not expired yet
+	Get(ctx context.Context, key string) (*Record, error)
+	// Save saves record for the key
+	Save(ctx context.Context, key string, record Record) error
+}
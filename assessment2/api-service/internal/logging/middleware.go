@@ -0,0 +1,102 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// RequestIDHeader is a header used to pass request id between services
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength limits length of request ids accepted from clients
+const maxRequestIDLength = 128
+
+type requestIDKey struct{}
+
+// RequestIDFromContext returns id of the request stored in context
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// RequestID assigns an id to each request, honoring X-Request-ID header provided by client,
+// and stores request logger annotated with the id into request context
+func RequestID(logger *slog.Logger) func(http.Handler) http.Handler {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, id := WithRequestID(r.Context(), logger, r.Header.Get(RequestIDHeader))
+			w.Header().Set(RequestIDHeader, id)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// WithRequestID stores id of request and logger annotated with it into context,
+// a new id is assigned when provided one is not valid
+func WithRequestID(ctx context.Context, logger *slog.Logger, id string) (context.Context, string) {
+	if !validRequestID(id) {
+		id = newRequestID()
+	}
+	ctx = context.WithValue(ctx, requestIDKey{}, id)
+	return WithLogger(ctx, logger.With(slog.String("request_id", id))), id
+}
+
+// AccessLog writes a log record for each processed request
+func AccessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		// route pattern is known only after request is routed
+		route := ""
+		if rctx := chi.RouteContext(r.Context()); rctx != nil {
+			route = rctx.RoutePattern()
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		level := slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		FromContext(r.Context()).LogAttrs(r.Context(), level, "request processed",
+			slog.String("method", r.Method),
+			slog.String("route", route),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", status),
+			slog.Int("bytes", ww.BytesWritten()),
+			slog.Duration("latency", time.Since(start)),
+		)
+	})
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
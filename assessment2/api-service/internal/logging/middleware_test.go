@@ -0,0 +1,96 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// TestMiddleware_RequestLogging tests request ids and access log records
+func TestMiddleware_RequestLogging(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	logger, err := New(&buf, "json", "info")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux := chi.NewRouter()
+	mux.Use(RequestID(logger))
+	mux.Use(AccessLog)
+	mux.Get("/posts/{id}", func(w http.ResponseWriter, r *http.Request) {
+		FromContext(r.Context()).Info("handled")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("missing"))
+	})
+
+	req, _ := http.NewRequest("GET", "/posts/42", nil)
+	req.Header.Set(RequestIDHeader, "request-123")
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	if rr.Header().Get(RequestIDHeader) != "request-123" {
+		t.Errorf("expected client request id, got %q", rr.Header().Get(RequestIDHeader))
+	}
+
+	// both handler and access log records are annotated with request id
+	var records []map[string]any
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var record map[string]any
+		if err := dec.Decode(&record); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, record)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 log records, got %d", len(records))
+	}
+	for _, record := range records {
+		if record["request_id"] != "request-123" {
+			t.Errorf("expected request id in record %v", record)
+		}
+	}
+	access := records[1]
+	if access["route"] != "/posts/{id}" || access["status"] != float64(404) || access["bytes"] != float64(7) {
+		t.Errorf("unexpected access log record %v", access)
+	}
+
+	// invalid request ids are replaced
+	req, _ = http.NewRequest("GET", "/posts/42", nil)
+	req.Header.Set(RequestIDHeader, "bad id")
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	if id := rr.Header().Get(RequestIDHeader); id == "bad id" || len(id) != 32 {
+		t.Errorf("expected generated request id, got %q", id)
+	}
+}
+
+// TestNew tests logger configuration
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	logger, err := New(&buf, "text", "warn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.Info("skipped")
+	if logger.Enabled(context.Background(), slog.LevelInfo) || buf.Len() != 0 {
+		t.Errorf("expected info records to be skipped")
+	}
+
+	if _, err := New(&buf, "xml", ""); err == nil {
+		t.Errorf("expected invalid format error")
+	}
+	if _, err := New(&buf, "json", "verbose"); err == nil {
+		t.Errorf("expected invalid level error")
+	}
+}
@@ -0,0 +1,202 @@
+package metrics
+
+import (
+	"api-service/internal/cache"
+	"api-service/internal/store"
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// namespace prefixes all service metrics
+const namespace = "api"
+
+// Metrics holds service metrics registry and collectors
+type Metrics struct {
+	Registry *prometheus.Registry
+
+	httpRequests  *prometheus.CounterVec
+	httpDuration  *prometheus.HistogramVec
+	httpInFlight  prometheus.Gauge
+	grpcRequests  *prometheus.CounterVec
+	grpcDuration  *prometheus.HistogramVec
+	storeDuration *prometheus.HistogramVec
+	storeErrors   *prometheus.CounterVec
+}
+
+// New creates service metrics registered together with Go runtime and process collectors
+func New() *Metrics {
+	m := &Metrics{
+		Registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Number of processed HTTP requests.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Latency of processed HTTP requests.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		httpInFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "http_requests_in_flight",
+			Help:      "Number of HTTP requests being processed.",
+		}),
+		grpcRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "grpc_requests_total",
+			Help:      "Number of processed gRPC calls.",
+		}, []string{"method", "code"}),
+		grpcDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "grpc_request_duration_seconds",
+			Help:      "Latency of processed gRPC calls, streaming calls last until they are closed.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "code"}),
+		storeDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "store_operation_duration_seconds",
+			Help:      "Latency of post store operations.",
+			Buckets:   []float64{.0001, .0005, .001, .005, .01, .05, .1, .5, 1},
+		}, []string{"operation"}),
+		storeErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "store_operation_errors_total",
+			Help:      "Number of failed post store operations.",
+		}, []string{"operation"}),
+	}
+
+	m.Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.httpInFlight,
+		m.grpcRequests,
+		m.grpcDuration,
+		m.storeDuration,
+		m.storeErrors,
+	)
+	return m
+}
+
+// Handler serves registered metrics in Prometheus text format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.Registry, promhttp.HandlerOpts{Registry: m.Registry})
+}
+
+// Middleware collects metrics of HTTP requests labeled with chi route pattern
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.httpInFlight.Inc()
+		defer m.httpInFlight.Dec()
+
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		// route pattern is known only after request is routed, unmatched requests share one label
+		route := ""
+		if rctx := chi.RouteContext(r.Context()); rctx != nil {
+			route = rctx.RoutePattern()
+		}
+		if route == "" {
+			route = "unmatched"
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		labels := prometheus.Labels{"method": methodLabel(r.Method), "route": route, "status": strconv.Itoa(status)}
+		m.httpRequests.With(labels).Inc()
+		m.httpDuration.With(labels).Observe(time.Since(start).Seconds())
+	})
+}
+
+// methodLabel returns label of HTTP method, methods other than standard ones share one label,
+// so that clients could not create unlimited number of series
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	default:
+		return "other"
+	}
+}
+
+// UnaryServerInterceptor collects metrics of unary gRPC calls labeled with method and status code
+func (m *Metrics) UnaryServerInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	m.observeGRPC(info.FullMethod, start, err)
+	return resp, err
+}
+
+// StreamServerInterceptor collects metrics of streaming gRPC calls labeled with method and status code
+func (m *Metrics) StreamServerInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, ss)
+	m.observeGRPC(info.FullMethod, start, err)
+	return err
+}
+
+func (m *Metrics) observeGRPC(method string, start time.Time, err error) {
+	labels := prometheus.Labels{"method": method, "code": status.Code(err).String()}
+	m.grpcRequests.With(labels).Inc()
+	m.grpcDuration.With(labels).Observe(time.Since(start).Seconds())
+}
+
+// RegisterStoreStats exposes statistics of a post store as gauges
+func (m *Metrics) RegisterStoreStats(provider store.StatsProvider) {
+	m.Registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "store_posts",
+		Help:      "Number of posts in the store.",
+	}, func() float64 {
+		stats, err := provider.Stats(context.Background())
+		if err != nil {
+			return 0
+		}
+		return float64(stats.Posts)
+	}))
+}
+
+// RegisterCacheStats exposes cache hits and misses as counters
+func (m *Metrics) RegisterCacheStats(stats func() cache.Stats) {
+	counter := func(cacheName string, result string, value func(cache.Stats) uint64) prometheus.Collector {
+		return prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace:   namespace,
+			Name:        "cache_requests_total",
+			Help:        "Number of post store cache lookups.",
+			ConstLabels: prometheus.Labels{"cache": cacheName, "result": result},
+		}, func() float64 {
+			return float64(value(stats()))
+		})
+	}
+	m.Registry.MustRegister(
+		counter("post", "hit", func(s cache.Stats) uint64 { return s.PostHits }),
+		counter("post", "miss", func(s cache.Stats) uint64 { return s.PostMisses }),
+		counter("list", "hit", func(s cache.Stats) uint64 { return s.ListHits }),
+		counter("list", "miss", func(s cache.Stats) uint64 { return s.ListMisses }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "cache_entries",
+			Help:      "Number of entries in post store cache.",
+		}, func() float64 {
+			return float64(stats().Entries)
+		}),
+	)
+}
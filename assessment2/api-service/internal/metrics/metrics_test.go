@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	"api-service/internal/domain"
+	"api-service/internal/store"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// TestMetrics_Middleware tests HTTP metrics labeled by route pattern
+func TestMetrics_Middleware(t *testing.T) {
+	t.Parallel()
+
+	m := New()
+	mux := chi.NewRouter()
+	mux.Use(m.Middleware)
+	mux.Get("/v1/posts/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.Handle("/metrics", m.Handler())
+
+	for _, path := range []string{"/v1/posts/1", "/v1/posts/2", "/unknown"} {
+		req, _ := http.NewRequest("GET", path, nil)
+		mux.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	if count := testutil.ToFloat64(m.httpRequests.WithLabelValues("GET", "/v1/posts/{id}", "404")); count != 2 {
+		t.Errorf("expected 2 requests for route pattern, got %v", count)
+	}
+	if count := testutil.ToFloat64(m.httpRequests.WithLabelValues("GET", "unmatched", "404")); count != 1 {
+		t.Errorf("expected 1 unmatched request, got %v", count)
+	}
+
+	// arbitrary methods share one label
+	for _, method := range []string{"FOO", "BAR", "get"} {
+		req, _ := http.NewRequest(method, "/v1/posts/1", nil)
+		mux.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	if count := testutil.ToFloat64(m.httpRequests.WithLabelValues("other", "unmatched", "405")); count != 3 {
+		t.Errorf("expected 3 requests of other methods, got %v", count)
+	}
+
+	// runtime metrics are exposed in text format
+	req, _ := http.NewRequest("GET", "/metrics", nil)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	for _, name := range []string{"api_http_requests_total", "api_http_request_duration_seconds_bucket", "go_goroutines"} {
+		if !strings.Contains(rr.Body.String(), name) {
+			t.Errorf("expected %s metric in output", name)
+		}
+	}
+}
+
+// TestMetrics_PostStore tests instrumenting post store decorator
+func TestMetrics_PostStore(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	next := store.NewMockPostStore(ctrl)
+	m := New()
+	s := NewPostStore(next, m)
+	ctx := context.Background()
+
+	next.EXPECT().GetOne(gomock.Any(), 1).Return(&domain.Post{}, domain.ErrorPostNotFound)
+	next.EXPECT().Delete(gomock.Any(), 2).Return(errors.New("storage failure"))
+
+	_, _ = s.GetOne(ctx, 1)
+	_ = s.Delete(ctx, 2)
+
+	if count := testutil.ToFloat64(m.storeErrors.WithLabelValues("get_one")); count != 0 {
+		t.Errorf("expected missing post not counted as error, got %v", count)
+	}
+	if count := testutil.ToFloat64(m.storeErrors.WithLabelValues("delete")); count != 1 {
+		t.Errorf("expected 1 delete error, got %v", count)
+	}
+	if count := testutil.CollectAndCount(m.storeDuration); count != 2 {
+		t.Errorf("expected latencies of 2 operations, got %d", count)
+	}
+}
@@ -0,0 +1,101 @@
+package metrics
+
+import (
+	"api-service/internal/domain"
+	"api-service/internal/store"
+	"context"
+	"errors"
+	"time"
+)
+
+// PostStore is a post store decorator collecting operation metrics
+type PostStore struct {
+	next    store.PostStore
+	metrics *Metrics
+}
+
+// NewPostStore creates an instrumenting decorator of post store
+func NewPostStore(next store.PostStore, metrics *Metrics) *PostStore {
+	return &PostStore{
+		next:    next,
+		metrics: metrics,
+	}
+}
+
+// Unwrap returns decorated store
+func (s *PostStore) Unwrap() store.PostStore {
+	return s.next
+}
+
+func (s *PostStore) Get(ctx context.Context, title string, page int, limit int) (*[]domain.Post, error) {
+	start := time.Now()
+	posts, err := s.next.Get(ctx, title, page, limit)
+	s.record("get", start, err)
+	return posts, err
+}
+
+func (s *PostStore) GetOne(ctx context.Context, id int) (*domain.Post, error) {
+	start := time.Now()
+	post, err := s.next.GetOne(ctx, id)
+	s.record("get_one", start, err)
+	return post, err
+}
+
+func (s *PostStore) Insert(ctx context.Context, post domain.Post) (int, error) {
+	start := time.Now()
+	id, err := s.next.Insert(ctx, post)
+	s.record("insert", start, err)
+	return id, err
+}
+
+func (s *PostStore) Update(ctx context.Context, id int, post domain.Post) error {
+	start := time.Now()
+	err := s.next.Update(ctx, id, post)
+	s.record("update", start, err)
+	return err
+}
+
+func (s *PostStore) Delete(ctx context.Context, id int) error {
+	start := time.Now()
+	err := s.next.Delete(ctx, id)
+	s.record("delete", start, err)
+	return err
+}
+
+func (s *PostStore) Put(ctx context.Context, post domain.Post) (bool, error) {
+	start := time.Now()
+	created, err := s.next.Put(ctx, post)
+	s.record("put", start, err)
+	return created, err
+}
+
+func (s *PostStore) Scan(ctx context.Context, afterID int, limit int) ([]domain.Post, error) {
+	start := time.Now()
+	posts, err := store.Scan(ctx, s.next, afterID, limit)
+	s.record("scan", start, err)
+	return posts, err
+}
+
+// WithTx observes transaction latency, operations of the transaction are observed as well
+func (s *PostStore) WithTx(ctx context.Context, fn func(tx store.PostStore) error) error {
+	start := time.Now()
+	err := s.next.WithTx(ctx, func(tx store.PostStore) error {
+		return fn(&PostStore{next: tx, metrics: s.metrics})
+	})
+	s.record("tx", start, err)
+	return err
+}
+
+// Capabilities reports capabilities of decorated store
+func (s *PostStore) Capabilities() store.Capabilities {
+	return s.next.Capabilities()
+}
+
+// record observes operation latency and counts failed operations,
+// missing posts are expected results rather than failures
+func (s *PostStore) record(operation string, start time.Time, err error) {
+	s.metrics.storeDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	if err != nil && !errors.Is(err, domain.ErrorPostNotFound) {
+		s.metrics.storeErrors.WithLabelValues(operation).Inc()
+	}
+}
@@ -0,0 +1,26 @@
+package openapi
+
+import (
+	_ "embed"
+	"html/template"
+	"net/http"
+)
+
+//go:embed docs.html
+var docsPage string
+
+// docsTemplate renders documentation page loading the document from URL
+var docsTemplate = template.Must(template.New("docs").Parse(docsPage))
+
+// DocsHandler serves interactive documentation of the document served at specURL,
+// the page is embedded into the binary and does not load external resources
+func DocsHandler(title string, specURL string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Content-Security-Policy", "default-src 'self'; script-src 'unsafe-inline'; style-src 'unsafe-inline'")
+		_ = docsTemplate.Execute(w, struct {
+			Title   string
+			SpecURL string
+		}{title, specURL})
+	})
+}
@@ -0,0 +1,148 @@
+// Package openapi describes HTTP API with OpenAPI 3.1 documents
+package openapi
+
+import (
+	"sort"
+	"strings"
+)
+
+// Version is a version of OpenAPI specification used by documents
+const Version = "3.1.0"
+
+// Document is a root object of OpenAPI description
+type Document struct {
+	OpenAPI    string                `json:"openapi"`
+	Info       Info                  `json:"info"`
+	Servers    []Server              `json:"servers,omitempty"`
+	Paths      map[string]PathItem   `json:"paths"`
+	Components *Components           `json:"components,omitempty"`
+	Security   []SecurityRequirement `json:"security,omitempty"`
+	Tags       []Tag                 `json:"tags,omitempty"`
+}
+
+// Info provides metadata about the API
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// Server is a base URL of the API
+type Server struct {
+	URL         string `json:"url"`
+	Description string `json:"description,omitempty"`
+}
+
+// Tag groups operations in documentation
+type Tag struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem maps lower case HTTP methods to operations of a path
+type PathItem map[string]*Operation
+
+// Operation describes a single API operation on a path
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []*Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []SecurityRequirement `json:"security,omitempty"`
+	Servers     []Server              `json:"servers,omitempty"`
+	Deprecated  bool                  `json:"deprecated,omitempty"`
+	Streaming   bool                  `json:"x-streaming,omitempty"` // response is streamed or connection is upgraded
+}
+
+// Parameter describes a single operation parameter
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"` // query, header or path
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody describes a request body of operation
+type RequestBody struct {
+	Description string               `json:"description,omitempty"`
+	Required    bool                 `json:"required,omitempty"`
+	Content     map[string]MediaType `json:"content"`
+}
+
+// Response describes a single response of operation
+type Response struct {
+	Ref         string               `json:"$ref,omitempty"`
+	Description string               `json:"description,omitempty"`
+	Headers     map[string]*Header   `json:"headers,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// Header describes a response header
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// MediaType provides schema of content of a media type
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+// SecurityScheme defines a security scheme used by operations
+type SecurityScheme struct {
+	Type        string `json:"type"` // http or apiKey
+	Scheme      string `json:"scheme,omitempty"`
+	Name        string `json:"name,omitempty"`
+	In          string `json:"in,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// SecurityRequirement lists schemes required by operation, empty requirement allows anonymous access
+type SecurityRequirement map[string][]string
+
+// NewDocument creates a document without operations
+func NewDocument(info Info) *Document {
+	return &Document{
+		OpenAPI:    Version,
+		Info:       info,
+		Paths:      make(map[string]PathItem),
+		Components: NewComponents(),
+	}
+}
+
+// Add adds operation of the method to the path
+func (d *Document) Add(method string, path string, operation *Operation) {
+	item, ok := d.Paths[path]
+	if !ok {
+		item = make(PathItem)
+		d.Paths[path] = item
+	}
+	item[strings.ToLower(method)] = operation
+}
+
+// Operation returns operation of the method on the path
+func (d *Document) Operation(method string, path string) (*Operation, bool) {
+	operation, ok := d.Paths[path][strings.ToLower(method)]
+	return operation, ok
+}
+
+// Routes returns sorted list of described routes as "METHOD path" strings
+func (d *Document) Routes() []string {
+	var routes []string
+	for path, item := range d.Paths {
+		for method := range item {
+			routes = append(routes, strings.ToUpper(method)+" "+path)
+		}
+	}
+	sort.Strings(routes)
+	return routes
+}
+
+// JSONContent returns content of JSON media type with the schema
+func JSONContent(schema *Schema) map[string]MediaType {
+	return map[string]MediaType{"application/json": {Schema: schema}}
+}
@@ -0,0 +1,195 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Schema is a JSON Schema of a value, only keywords used by the API are supported
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Nullable             bool               `json:"-"` // null is allowed in addition to the type
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+	Default              any                `json:"default,omitempty"`
+}
+
+// MarshalJSON encodes type of nullable schema as list of the type and null
+func (s Schema) MarshalJSON() ([]byte, error) {
+	type schema Schema
+	if !s.Nullable || s.Type == "" {
+		return json.Marshal(schema(s))
+	}
+	return json.Marshal(struct {
+		schema
+		Type []string `json:"type"`
+	}{schema(s), []string{s.Type, "null"}})
+}
+
+// Components holds reusable objects of document
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas,omitempty"`
+	Responses       map[string]*Response       `json:"responses,omitempty"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// NewComponents creates empty components
+func NewComponents() *Components {
+	return &Components{
+		Schemas:         make(map[string]*Schema),
+		Responses:       make(map[string]*Response),
+		SecuritySchemes: make(map[string]*SecurityScheme),
+	}
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	rawType       = reflect.TypeOf(json.RawMessage{})
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// SchemaOf returns schema of JSON encoding of the value, named structures are added to components
+// and referenced, fields without omitempty option are required
+func (c *Components) SchemaOf(v any) *Schema {
+	return c.schemaOf(reflect.TypeOf(v))
+}
+
+func (c *Components) schemaOf(t reflect.Type) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == rawType, t.Implements(marshalerType):
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: c.schemaOf(t.Elem()), Nullable: t.Kind() == reflect.Slice}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: c.schemaOf(t.Elem()), Nullable: true}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return c.structSchema(t)
+		}
+		if _, ok := c.Schemas[t.Name()]; !ok {
+			// reserve the name before fields are described, so recursive types are referenced
+			c.Schemas[t.Name()] = &Schema{}
+			*c.Schemas[t.Name()] = *c.structSchema(t)
+		}
+		return Ref(t.Name())
+	default:
+		return &Schema{}
+	}
+}
+
+// structSchema describes fields of structure as encoded by encoding/json
+func (c *Components) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" || (!field.IsExported() && !field.Anonymous) {
+			continue
+		}
+
+		// fields of embedded structures are promoted
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				promoted := c.structSchema(embedded)
+				for property, propertySchema := range promoted.Properties {
+					schema.Properties[property] = propertySchema
+				}
+				schema.Required = append(schema.Required, promoted.Required...)
+				continue
+			}
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		property := c.schemaOf(field.Type)
+		if !strings.Contains(options, "omitempty") {
+			schema.Required = append(schema.Required, name)
+			// nil pointers are encoded as null
+			if field.Type.Kind() == reflect.Pointer {
+				property = nullable(property)
+			}
+		}
+		schema.Properties[name] = property
+	}
+	return schema
+}
+
+// nullable returns schema allowing null in addition to values of the schema
+func nullable(schema *Schema) *Schema {
+	switch {
+	case schema.Ref != "":
+		return &Schema{AnyOf: []*Schema{schema, {Type: "null"}}}
+	case schema.Type != "":
+		schema.Nullable = true
+	}
+	return schema
+}
+
+// Ref returns reference to schema of components
+func Ref(name string) *Schema {
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// Resolve returns schema referenced by the schema, other schemas are returned as they are
+func (c *Components) Resolve(schema *Schema) *Schema {
+	for schema != nil && schema.Ref != "" {
+		schema = c.Schemas[strings.TrimPrefix(schema.Ref, "#/components/schemas/")]
+	}
+	return schema
+}
+
+// Optional returns copy of object schema without required properties
+func Optional(schema *Schema) *Schema {
+	optional := *schema
+	optional.Required = nil
+	return &optional
+}
+
+// Float returns pointer to number used by minimum and maximum keywords
+func Float(v float64) *float64 {
+	return &v
+}
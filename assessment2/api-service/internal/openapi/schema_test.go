@@ -0,0 +1,65 @@
+package openapi
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+type testBase struct {
+	ID int `json:"id"`
+}
+
+type testNode struct {
+	testBase
+	Name     string            `json:"name,omitempty"`
+	Created  time.Time         `json:"created"`
+	Children []*testNode       `json:"children"`
+	Parent   *testNode         `json:"parent"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Raw      json.RawMessage   `json:"raw,omitempty"`
+	Skipped  string            `json:"-"`
+	Plain    bool
+	hidden   bool
+}
+
+// TestComponents_SchemaOf tests schemas generated from types
+func TestComponents_SchemaOf(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name              string
+		value             any
+		expectedSchema    string
+		expectedComponent string
+	}{
+		{"integer", 0, `{"type":"integer","format":"int32"}`, ""},
+		{"list of strings", []string{}, `{"items":{"type":"string"},"type":["array","null"]}`, ""},
+		{"map", map[string]any{}, `{"additionalProperties":{},"type":["object","null"]}`, ""},
+		{"time", time.Time{}, `{"type":"string","format":"date-time"}`, ""},
+		{
+			"recursive structure",
+			&testNode{},
+			`{"$ref":"#/components/schemas/testNode"}`,
+			`{"type":"object","properties":{"Plain":{"type":"boolean"},"children":{"items":{"$ref":"#/components/schemas/testNode"},"type":["array","null"]},"created":{"type":"string","format":"date-time"},"id":{"type":"integer","format":"int32"},"labels":{"additionalProperties":{"type":"string"},"type":["object","null"]},"name":{"type":"string"},"parent":{"anyOf":[{"$ref":"#/components/schemas/testNode"},{"type":"null"}]},"raw":{}},"required":["id","created","children","parent","Plain"]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			components := NewComponents()
+			schema, _ := json.Marshal(components.SchemaOf(tt.value))
+			if string(schema) != tt.expectedSchema {
+				t.Errorf("expected %s, but got %s", tt.expectedSchema, schema)
+			}
+			if tt.expectedComponent != "" {
+				component, _ := json.Marshal(components.Schemas["testNode"])
+				if string(component) != tt.expectedComponent {
+					t.Errorf("expected %s, but got %s", tt.expectedComponent, component)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,355 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ValidationError describes a value which does not match its description
+type ValidationError struct {
+	Location string // request or response part, for example "query parameter page" or "request body"
+	Pointer  string // JSON pointer of invalid value within a body
+	Reason   string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Pointer != "" {
+		return e.Location + " " + e.Pointer + " " + e.Reason
+	}
+	return e.Location + " " + e.Reason
+}
+
+// route is a compiled path template of document
+type route struct {
+	path     string
+	segments []string
+	literals int // number of literal segments, routes with more literals are preferred
+}
+
+// Validator validates requests and responses against operations of document
+type Validator struct {
+	doc    *Document
+	routes []route
+}
+
+// NewValidator creates validator of document, the document must not be changed afterwards
+func NewValidator(doc *Document) *Validator {
+	v := &Validator{doc: doc}
+	for path := range doc.Paths {
+		r := route{path: path, segments: strings.Split(strings.Trim(path, "/"), "/")}
+		for _, segment := range r.segments {
+			if !strings.HasPrefix(segment, "{") {
+				r.literals++
+			}
+		}
+		v.routes = append(v.routes, r)
+	}
+	sort.Slice(v.routes, func(i, j int) bool {
+		if v.routes[i].literals != v.routes[j].literals {
+			return v.routes[i].literals > v.routes[j].literals
+		}
+		return v.routes[i].path < v.routes[j].path
+	})
+	return v
+}
+
+// Find returns operation of request method and path together with values of path parameters
+func (v *Validator) Find(method string, path string) (*Operation, map[string]string, bool) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for _, r := range v.routes {
+		operation, ok := v.doc.Operation(method, r.path)
+		if !ok || len(r.segments) != len(segments) {
+			continue
+		}
+		params := make(map[string]string)
+		matched := true
+		for i, segment := range r.segments {
+			if name, ok := strings.CutPrefix(segment, "{"); ok {
+				value, err := url.PathUnescape(segments[i])
+				if err != nil {
+					matched = false
+					break
+				}
+				params[strings.TrimSuffix(name, "}")] = value
+				continue
+			}
+			if segment != segments[i] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return operation, params, true
+		}
+	}
+	return nil, nil, false
+}
+
+// ValidateParameters validates path, query and header parameters of request
+func (v *Validator) ValidateParameters(operation *Operation, r *http.Request, pathParams map[string]string) error {
+	query := r.URL.Query()
+	for _, parameter := range operation.Parameters {
+		var value string
+		var present bool
+		switch parameter.In {
+		case "path":
+			value, present = pathParams[parameter.Name]
+		case "query":
+			present = query.Has(parameter.Name)
+			value = query.Get(parameter.Name)
+		case "header":
+			value = r.Header.Get(parameter.Name)
+			present = value != ""
+		}
+
+		location := parameter.In + " parameter " + parameter.Name
+		if !present {
+			if parameter.Required {
+				return &ValidationError{Location: location, Reason: "is required"}
+			}
+			continue
+		}
+		parsed, err := parseParameter(v.doc.Components.Resolve(parameter.Schema), value)
+		if err != nil {
+			return &ValidationError{Location: location, Reason: err.Error()}
+		}
+		if err := v.validate(parameter.Schema, parsed, ""); err != nil {
+			err.Location = location
+			return err
+		}
+	}
+	return nil
+}
+
+// BodySchema returns schema of request body of the content type, only JSON bodies described
+// by schema are validated
+func (v *Validator) BodySchema(operation *Operation, contentType string) *Schema {
+	if operation.RequestBody == nil {
+		return nil
+	}
+	// bodies without Content-Type are decoded as JSON
+	mediaType := "application/json"
+	if strings.TrimSpace(contentType) != "" {
+		mediaType, _, _ = mime.ParseMediaType(contentType)
+	}
+	if mediaType != "application/json" {
+		return nil
+	}
+	return operation.RequestBody.Content[mediaType].Schema
+}
+
+// ValidateRequestBody validates JSON request body of the content type
+func (v *Validator) ValidateRequestBody(operation *Operation, contentType string, body []byte) error {
+	schema := v.BodySchema(operation, contentType)
+	if schema == nil {
+		return nil
+	}
+	if len(body) == 0 {
+		if operation.RequestBody.Required {
+			return &ValidationError{Location: "request body", Reason: "is required"}
+		}
+		return nil
+	}
+	value, err := decodeJSON(body)
+	if err != nil {
+		return &ValidationError{Location: "request body", Reason: "is not valid JSON"}
+	}
+	if err := v.validate(schema, value, ""); err != nil {
+		err.Location = "request body"
+		return err
+	}
+	return nil
+}
+
+// ValidateResponse validates status, media type and JSON body of response
+func (v *Validator) ValidateResponse(operation *Operation, status int, header http.Header, body []byte) error {
+	response, ok := operation.Responses[strconv.Itoa(status)]
+	if !ok {
+		response, ok = operation.Responses[strconv.Itoa(status/100)+"XX"]
+	}
+	if !ok {
+		response, ok = operation.Responses["default"]
+	}
+	if !ok {
+		return &ValidationError{Location: "response status", Reason: strconv.Itoa(status) + " is not described"}
+	}
+	if response.Ref != "" {
+		response = v.doc.Components.Responses[strings.TrimPrefix(response.Ref, "#/components/responses/")]
+	}
+	if len(response.Content) == 0 || len(body) == 0 {
+		return nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	content, ok := response.Content[mediaType]
+	if !ok {
+		return &ValidationError{Location: "response content type", Reason: fmt.Sprintf("%q is not described for status %d", mediaType, status)}
+	}
+	if mediaType != "application/json" || content.Schema == nil {
+		return nil
+	}
+	value, err := decodeJSON(body)
+	if err != nil {
+		return &ValidationError{Location: "response body", Reason: "is not valid JSON"}
+	}
+	if err := v.validate(content.Schema, value, ""); err != nil {
+		err.Location = "response body"
+		return err
+	}
+	return nil
+}
+
+// decodeJSON decodes JSON value keeping numbers, so integers could be told from other numbers
+func decodeJSON(body []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// parseParameter converts parameter value to value of schema type
+func parseParameter(schema *Schema, value string) (any, error) {
+	switch schema.Type {
+	case "integer", "number":
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return nil, fmt.Errorf("must be %s", schema.Type)
+		}
+		return json.Number(value), nil
+	case "boolean":
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("must be boolean")
+		}
+		return parsed, nil
+	default:
+		return value, nil
+	}
+}
+
+// validate validates value decoded from JSON against schema
+func (v *Validator) validate(schema *Schema, value any, pointer string) *ValidationError {
+	schema = v.doc.Components.Resolve(schema)
+	if schema == nil {
+		return nil
+	}
+	invalid := func(format string, args ...any) *ValidationError {
+		return &ValidationError{Pointer: pointer, Reason: fmt.Sprintf(format, args...)}
+	}
+
+	for _, s := range schema.AllOf {
+		if err := v.validate(s, value, pointer); err != nil {
+			return err
+		}
+	}
+	if len(schema.AnyOf) > 0 {
+		var err *ValidationError
+		for _, s := range schema.AnyOf {
+			if err = v.validate(s, value, pointer); err == nil {
+				break
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if value == nil {
+		if schema.Type == "" || schema.Type == "null" || schema.Nullable {
+			return nil
+		}
+		return invalid("must be %s", schema.Type)
+	}
+
+	switch schema.Type {
+	case "null":
+		return invalid("must be null")
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return invalid("must be boolean")
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			return invalid("must be string")
+		}
+	case "integer", "number":
+		number, ok := value.(json.Number)
+		if !ok {
+			return invalid("must be %s", schema.Type)
+		}
+		parsed, err := number.Float64()
+		if err != nil || (schema.Type == "integer" && parsed != math.Trunc(parsed)) {
+			return invalid("must be %s", schema.Type)
+		}
+		if schema.Minimum != nil && parsed < *schema.Minimum {
+			return invalid("must be at least %v", *schema.Minimum)
+		}
+		if schema.Maximum != nil && parsed > *schema.Maximum {
+			return invalid("must be at most %v", *schema.Maximum)
+		}
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			return invalid("must be array")
+		}
+		if schema.MinItems != nil && len(items) < *schema.MinItems {
+			return invalid("must have at least %d items", *schema.MinItems)
+		}
+		if schema.MaxItems != nil && len(items) > *schema.MaxItems {
+			return invalid("must have at most %d items", *schema.MaxItems)
+		}
+		for i, item := range items {
+			if err := v.validate(schema.Items, item, pointer+"/"+strconv.Itoa(i)); err != nil {
+				return err
+			}
+		}
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return invalid("must be object")
+		}
+		for _, name := range schema.Required {
+			if _, ok := object[name]; !ok {
+				return &ValidationError{Pointer: pointer + "/" + name, Reason: "is required"}
+			}
+		}
+		names := make([]string, 0, len(object))
+		for name := range object {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			property, ok := schema.Properties[name]
+			if !ok {
+				property = schema.AdditionalProperties
+			}
+			if err := v.validate(property, object[name], pointer+"/"+name); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(schema.Enum) > 0 {
+		for _, allowed := range schema.Enum {
+			if fmt.Sprint(allowed) == fmt.Sprint(value) {
+				return nil
+			}
+		}
+		values := make([]string, len(schema.Enum))
+		for i, allowed := range schema.Enum {
+			values[i] = fmt.Sprint(allowed)
+		}
+		return invalid("must be one of %s", strings.Join(values, ", "))
+	}
+	return nil
+}
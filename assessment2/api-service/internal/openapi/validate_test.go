@@ -0,0 +1,93 @@
+package openapi
+
+import (
+	"net/http"
+	"testing"
+)
+
+// newTestDocument creates document of items with literal and parametrized paths
+func newTestDocument() *Document {
+	doc := NewDocument(Info{Title: "Test", Version: "1"})
+	type item struct {
+		Name  string   `json:"name"`
+		Count int      `json:"count,omitempty"`
+		Tags  []string `json:"tags,omitempty"`
+	}
+	body := &RequestBody{Required: true, Content: JSONContent(doc.Components.SchemaOf(item{}))}
+	doc.Add(http.MethodGet, "/items/{id}", &Operation{OperationID: "getItem"})
+	doc.Add(http.MethodGet, "/items/search", &Operation{OperationID: "searchItems"})
+	doc.Add(http.MethodPost, "/items", &Operation{OperationID: "addItem", RequestBody: body})
+	return doc
+}
+
+// TestValidator_Find tests matching of request paths to operations
+func TestValidator_Find(t *testing.T) {
+	t.Parallel()
+	v := NewValidator(newTestDocument())
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		expectedID string
+		expectedP  string
+	}{
+		{"parametrized path", http.MethodGet, "/items/12", "getItem", "12"},
+		{"literal path is preferred", http.MethodGet, "/items/search", "searchItems", ""},
+		{"trailing slash", http.MethodPost, "/items/", "addItem", ""},
+		{"unknown method", http.MethodDelete, "/items/12", "", ""},
+		{"unknown path", http.MethodGet, "/items/12/parts", "", ""},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			operation, params, ok := v.Find(tt.method, tt.path)
+			if !ok {
+				if tt.expectedID != "" {
+					t.Errorf("expected %s operation, but got none", tt.expectedID)
+				}
+				return
+			}
+			if operation.OperationID != tt.expectedID || params["id"] != tt.expectedP {
+				t.Errorf("expected %s with id %q, but got %s with %v", tt.expectedID, tt.expectedP, operation.OperationID, params)
+			}
+		})
+	}
+}
+
+// TestValidator_ValidateRequestBody tests validation of JSON bodies against schemas
+func TestValidator_ValidateRequestBody(t *testing.T) {
+	t.Parallel()
+	v := NewValidator(newTestDocument())
+	operation, _, _ := v.Find(http.MethodPost, "/items")
+
+	tests := []struct {
+		name          string
+		contentType   string
+		body          string
+		expectedError string
+	}{
+		{"valid body", "application/json", `{"name": "a", "count": 2, "tags": ["x"]}`, ""},
+		{"body without content type", "", `{"name": "a"}`, ""},
+		{"null list", "application/json", `{"name": "a", "tags": null}`, ""},
+		{"other media type", "text/csv", `name`, ""},
+		{"missing body", "application/json", ``, "request body is required"},
+		{"missing property", "application/json", `{"count": 2}`, "request body /name is required"},
+		{"fractional integer", "application/json", `{"name": "a", "count": 2.5}`, "request body /count must be integer"},
+		{"invalid item", "application/json", `{"name": "a", "tags": ["x", 1]}`, "request body /tags/1 must be string"},
+		{"not an object", "application/json", `[]`, "request body must be object"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := v.ValidateRequestBody(operation, tt.contentType, []byte(tt.body))
+			if (err == nil && tt.expectedError != "") || (err != nil && err.Error() != tt.expectedError) {
+				t.Errorf("expected %q error, but got %v", tt.expectedError, err)
+			}
+		})
+	}
+}
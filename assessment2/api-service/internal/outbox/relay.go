@@ -0,0 +1,172 @@
+package outbox
+
+import (
+	"api-service/internal/events"
+	"api-service/internal/logging"
+	"api-service/internal/store"
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Default relay configuration
+const (
+	DefaultBatchSize    = 100
+	DefaultPollInterval = time.Second
+	DefaultMaxBackoff   = time.Minute
+)
+
+// Config defines relay rules, zero values are replaced by defaults
+type Config struct {
+	BatchSize    int           // records read from outbox at once
+	PollInterval time.Duration // interval of checking for records missed by notifications, delay after the first failure
+	MaxBackoff   time.Duration // delay between retries is doubled after every failure up to this value
+}
+
+// Relay publishes outbox records to sinks and acknowledges them when every sink succeeds,
+// so events are delivered at least once: sinks which failed get events again, succeeded ones do not
+// until the service is restarted
+type Relay struct {
+	source store.Outbox
+	sinks  []Sink
+	config Config
+	wake   chan struct{}
+
+	mu        sync.Mutex
+	published map[string]map[string]bool // sinks which already got record
+}
+
+// NewRelay creates relay of records from source outbox to sinks
+func NewRelay(source store.Outbox, sinks []Sink, config Config) *Relay {
+	if config.BatchSize <= 0 {
+		config.BatchSize = DefaultBatchSize
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = DefaultPollInterval
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = DefaultMaxBackoff
+	}
+	return &Relay{
+		source:    source,
+		sinks:     sinks,
+		config:    config,
+		wake:      make(chan struct{}, 1),
+		published: make(map[string]map[string]bool),
+	}
+}
+
+// Observer returns post store observer waking up relay after changes
+func (r *Relay) Observer() store.ChangeObserver {
+	return func(ctx context.Context, change store.Change) {
+		r.Notify()
+	}
+}
+
+// Notify wakes up relay, it never blocks
+func (r *Relay) Notify() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run relays records until context is cancelled, failed publishing is retried with exponential backoff
+func (r *Relay) Run(ctx context.Context) {
+	logger := logging.FromContext(ctx)
+	ticker := time.NewTicker(r.config.PollInterval)
+	defer ticker.Stop()
+
+	delay := time.Duration(0)
+	for {
+		if err := r.Flush(ctx); err != nil {
+			delay = min(max(2*delay, r.config.PollInterval), r.config.MaxBackoff)
+			logger.Warn("failed to relay outbox records", slog.Duration("retry", delay), slog.Any("error", err))
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+			continue
+		}
+		delay = 0
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// Drain publishes pending records until outbox is empty, failed publishing is retried with backoff until context is done;
+// it is used on shutdown, when changes are not written anymore
+func (r *Relay) Drain(ctx context.Context) error {
+	delay := time.Duration(0)
+	for {
+		err := r.Flush(ctx)
+		if err == nil {
+			return nil
+		}
+		delay = min(max(2*delay, r.config.PollInterval), r.config.MaxBackoff)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
+// Flush publishes all pending records and acknowledges them, it stops at the first failed sink
+func (r *Relay) Flush(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for {
+		records, err := r.source.Pending(ctx, r.config.BatchSize)
+		if err != nil {
+			return err
+		}
+		if len(records) == 0 {
+			return nil
+		}
+
+		for _, sink := range r.sinks {
+			var batch []events.Event
+			for _, record := range records {
+				if !r.published[record.ID][sink.Name()] {
+					batch = append(batch, events.FromRecord(record))
+				}
+			}
+			if len(batch) == 0 {
+				continue
+			}
+			if err := sink.Publish(ctx, batch); err != nil {
+				return fmt.Errorf("%s sink: %w", sink.Name(), err)
+			}
+			for _, record := range records {
+				if r.published[record.ID] == nil {
+					r.published[record.ID] = make(map[string]bool)
+				}
+				r.published[record.ID][sink.Name()] = true
+			}
+		}
+
+		ids := make([]string, 0, len(records))
+		for _, record := range records {
+			ids = append(ids, record.ID)
+		}
+		if err := r.source.Ack(ctx, ids); err != nil {
+			return err
+		}
+		for _, id := range ids {
+			delete(r.published, id)
+		}
+		if len(records) < r.config.BatchSize {
+			return nil
+		}
+	}
+}
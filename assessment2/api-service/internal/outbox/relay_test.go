@@ -0,0 +1,198 @@
+package outbox
+
+import (
+	"api-service/internal/domain"
+	"api-service/internal/events"
+	"api-service/internal/store"
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testSink records published events, it fails the first publishing attempts
+type testSink struct {
+	name     string
+	failures int
+	events   []events.Event
+}
+
+func (s *testSink) Name() string {
+	return s.name
+}
+
+func (s *testSink) Publish(ctx context.Context, batch []events.Event) error {
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("sink is not available")
+	}
+	s.events = append(s.events, batch...)
+	return nil
+}
+
+// newTestOutboxStore creates memory store writing outbox records
+func newTestOutboxStore(t *testing.T) *store.MemoryPostStore {
+	t.Helper()
+
+	memoryStore, _ := store.NewMemoryPostStore("")
+	memoryStore.EnableOutbox()
+	return memoryStore
+}
+
+// TestRelay_Transaction tests that outbox records are written only with committed changes
+func TestRelay_Transaction(t *testing.T) {
+	t.Parallel()
+	memoryStore := newTestOutboxStore(t)
+	ctx := context.Background()
+
+	id, _ := memoryStore.Insert(ctx, domain.Post{Title: "Title"})
+	_ = memoryStore.WithTx(ctx, func(tx store.PostStore) error {
+		_ = tx.Update(ctx, id, domain.Post{Title: "Rolled back"})
+		return errors.New("failure")
+	})
+	_ = memoryStore.WithTx(ctx, func(tx store.PostStore) error {
+		_ = tx.Update(ctx, id, domain.Post{Title: "Committed"})
+		return tx.Delete(ctx, id)
+	})
+
+	sink := &testSink{name: "test"}
+	if err := NewRelay(memoryStore, []Sink{sink}, Config{BatchSize: 2}).Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	expected := []struct {
+		id      uint64
+		typ     events.Type
+		version int
+	}{
+		{1, events.TypeCreated, 1},
+		{2, events.TypeUpdated, 2},
+		{3, events.TypeDeleted, 3},
+	}
+	if len(sink.events) != len(expected) {
+		t.Fatalf("expected %d events, but got %+v", len(expected), sink.events)
+	}
+	for i, e := range expected {
+		event := sink.events[i]
+		if event.ID != e.id || event.Type != e.typ || event.Version != e.version || event.DedupID == "" {
+			t.Errorf("expected %+v, but got %+v", e, event)
+		}
+	}
+	if pending, _ := memoryStore.Pending(ctx, 10); len(pending) != 0 {
+		t.Errorf("expected acknowledged records, but got %+v", pending)
+	}
+}
+
+// TestRelay_FailingSink tests that events are redelivered only to failed sinks with the same deduplication ids
+func TestRelay_FailingSink(t *testing.T) {
+	t.Parallel()
+	memoryStore := newTestOutboxStore(t)
+	ctx := context.Background()
+	_, _ = memoryStore.Insert(ctx, domain.Post{Title: "Title"})
+
+	healthy := &testSink{name: "healthy"}
+	failing := &testSink{name: "failing", failures: 2}
+	relay := NewRelay(memoryStore, []Sink{healthy, failing}, Config{})
+
+	for i := 0; i < 2; i++ {
+		if err := relay.Flush(ctx); err == nil {
+			t.Fatal("expected failure of sink")
+		}
+	}
+	// records changed after failure are delivered to both sinks
+	_, _ = memoryStore.Insert(ctx, domain.Post{Title: "Another"})
+	if err := relay.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(healthy.events) != 2 || len(failing.events) != 2 {
+		t.Fatalf("expected every event delivered once, but got %+v and %+v", healthy.events, failing.events)
+	}
+	for i := range healthy.events {
+		if healthy.events[i].DedupID != failing.events[i].DedupID {
+			t.Errorf("expected the same deduplication ids, but got %s and %s", healthy.events[i].DedupID, failing.events[i].DedupID)
+		}
+	}
+}
+
+// TestRelay_Drain tests that pending records are published on shutdown, records are kept when sinks keep failing
+func TestRelay_Drain(t *testing.T) {
+	t.Parallel()
+	memoryStore := newTestOutboxStore(t)
+	ctx := context.Background()
+	_, _ = memoryStore.Insert(ctx, domain.Post{Title: "Title"})
+	_, _ = memoryStore.Insert(ctx, domain.Post{Title: "Another"})
+
+	failing := &testSink{name: "failing", failures: 1000}
+	timeoutCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if err := NewRelay(memoryStore, []Sink{failing}, Config{PollInterval: time.Millisecond}).Drain(timeoutCtx); err == nil {
+		t.Fatal("expected failure of sink")
+	}
+	if pending, _ := memoryStore.Pending(ctx, 10); len(pending) != 2 {
+		t.Fatalf("expected unpublished records to be kept, but got %+v", pending)
+	}
+
+	recovering := &testSink{name: "recovering", failures: 2}
+	if err := NewRelay(memoryStore, []Sink{recovering}, Config{PollInterval: time.Millisecond, BatchSize: 1}).Drain(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if pending, _ := memoryStore.Pending(ctx, 10); len(pending) != 0 || len(recovering.events) != 2 {
+		t.Errorf("expected published records, but got %+v pending and %+v published", pending, recovering.events)
+	}
+}
+
+// TestRelay_Snapshot tests that unpublished records are saved with snapshot and restored with it
+func TestRelay_Snapshot(t *testing.T) {
+	t.Parallel()
+	memoryStore := newTestOutboxStore(t)
+	ctx := context.Background()
+	_, _ = memoryStore.Insert(ctx, domain.Post{Title: "Title"})
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	if err := memoryStore.Snapshot(ctx, path); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := newTestOutboxStore(t)
+	if err := restored.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	_, _ = restored.Insert(ctx, domain.Post{Title: "Another"})
+	pending, _ := restored.Pending(ctx, 10)
+	original, _ := memoryStore.Pending(ctx, 10)
+	if len(pending) != 2 || pending[0].ID != original[0].ID || pending[1].Sequence != 2 {
+		t.Errorf("expected restored and new records, but got %+v", pending)
+	}
+}
+
+// TestFileSink_Publish tests that events are appended to file as NDJSON
+func TestFileSink_Publish(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "events.ndjson")
+	sink := NewFileSink(path)
+	ctx := context.Background()
+
+	_ = sink.Publish(ctx, []events.Event{{ID: 1, DedupID: "a"}, {ID: 2, DedupID: "b"}})
+	_ = sink.Publish(ctx, []events.Event{{ID: 3, DedupID: "c"}})
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	var ids []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event events.Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, event.DedupID)
+	}
+	if len(ids) != 3 || ids[0] != "a" || ids[2] != "c" {
+		t.Errorf("unexpected events %v", ids)
+	}
+}
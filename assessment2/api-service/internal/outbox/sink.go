@@ -0,0 +1,131 @@
+package outbox
+
+import (
+	"api-service/internal/events"
+	"api-service/internal/webhooks"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"sync"
+)
+
+// Sink publishes events relayed from outbox, publishing must be idempotent for events with the same
+// deduplication id, because events are redelivered when any of sinks fails
+type Sink interface {
+	// Name identifies sink in logs and configuration
+	Name() string
+	// Publish publishes events in the order of changes
+	Publish(ctx context.Context, batch []events.Event) error
+}
+
+// LogSink writes events into log
+type LogSink struct {
+	logger *slog.Logger
+}
+
+// NewLogSink creates sink writing events into logger
+func NewLogSink(logger *slog.Logger) *LogSink {
+	return &LogSink{logger: logger}
+}
+
+// Name identifies log sink
+func (s *LogSink) Name() string {
+	return "log"
+}
+
+// Publish writes every event as a log entry
+func (s *LogSink) Publish(ctx context.Context, batch []events.Event) error {
+	for _, event := range batch {
+		s.logger.Info("post changed",
+			slog.String("dedup_id", event.DedupID),
+			slog.String("type", string(event.Type)),
+			slog.Int("post_id", event.PostID),
+			slog.Int("version", event.Version))
+	}
+	return nil
+}
+
+// FileSink appends events to a file as NDJSON
+type FileSink struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileSink creates sink appending events to the file
+func NewFileSink(path string) *FileSink {
+	return &FileSink{path: path}
+}
+
+// Name identifies file sink
+func (s *FileSink) Name() string {
+	return "file"
+}
+
+// Publish appends events to the file, events are synced to disk before they are acknowledged
+func (s *FileSink) Publish(ctx context.Context, batch []events.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(file)
+	for _, event := range batch {
+		if err = encoder.Encode(event); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// BusSink publishes events to in-memory broker of change streams
+type BusSink struct {
+	broker *events.Broker
+}
+
+// NewBusSink creates sink publishing events to the broker
+func NewBusSink(broker *events.Broker) *BusSink {
+	return &BusSink{broker: broker}
+}
+
+// Name identifies bus sink
+func (s *BusSink) Name() string {
+	return "bus"
+}
+
+// Publish publishes events to the broker, it never fails
+func (s *BusSink) Publish(ctx context.Context, batch []events.Event) error {
+	for _, event := range batch {
+		s.broker.Publish(event)
+	}
+	return nil
+}
+
+// WebhookSink queues deliveries of events for webhook subscriptions,
+// deliveries are identified by deduplication ids, so redelivered events are queued once
+type WebhookSink struct {
+	dispatcher *webhooks.Dispatcher
+}
+
+// NewWebhookSink creates sink queueing events in the dispatcher
+func NewWebhookSink(dispatcher *webhooks.Dispatcher) *WebhookSink {
+	return &WebhookSink{dispatcher: dispatcher}
+}
+
+// Name identifies webhook sink
+func (s *WebhookSink) Name() string {
+	return "webhooks"
+}
+
+// Publish queues deliveries of events
+func (s *WebhookSink) Publish(ctx context.Context, batch []events.Event) error {
+	return s.dispatcher.Publish(ctx, batch)
+}
@@ -0,0 +1,38 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrorRateLimited is returned when client exceeds its rate limit
+var ErrorRateLimited = errors.New("rate limit exceeded")
+
+// Limit defines token bucket parameters: bucket capacity and refill rate per second
+type Limit struct {
+	Burst int
+	Rate  float64
+}
+
+// PerMinute creates a limit allowing specified number of requests per minute
+func PerMinute(requests int) Limit {
+	return Limit{
+		Burst: requests,
+		Rate:  float64(requests) / 60,
+	}
+}
+
+// Result represent limiter decision for a single request
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration // time until bucket is full again
+	RetryAfter time.Duration // time until next request is allowed, zero when allowed
+}
+
+// Limiter represent interface for rate limiter backends
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
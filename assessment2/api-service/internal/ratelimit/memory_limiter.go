@@ -0,0 +1,95 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval defines how often idle buckets are removed from memory
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	limit   Limit
+}
+
+// MemoryLimiter is an in-memory token bucket limiter
+type MemoryLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryLimiter creates a new in-memory implementation of limiter
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+// Allow takes a token from bucket identified by key
+func (l *MemoryLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	// find or create bucket, buckets start full
+	b, ok := l.buckets[key]
+	if !ok || b.limit != limit {
+		b = &bucket{tokens: float64(limit.Burst), updated: now, limit: limit}
+		l.buckets[key] = b
+	}
+
+	// refill tokens for elapsed time
+	b.refill(now)
+
+	result := Result{Limit: limit.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = b.timeFor(1 - b.tokens)
+	}
+	result.Remaining = int(math.Floor(b.tokens))
+	result.Reset = b.timeFor(float64(limit.Burst) - b.tokens)
+	return result, nil
+}
+
+func (b *bucket) refill(now time.Time) {
+	elapsed := now.Sub(b.updated).Seconds()
+	b.tokens = math.Min(float64(b.limit.Burst), b.tokens+elapsed*b.limit.Rate)
+	b.updated = now
+}
+
+// timeFor returns time required to refill specified amount of tokens
+func (b *bucket) timeFor(tokens float64) time.Duration {
+	if tokens <= 0 {
+		return 0
+	}
+	if b.limit.Rate <= 0 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(tokens / b.limit.Rate * float64(time.Second))
+}
+
+// sweep removes buckets which are full again, they are equal to newly created ones
+func (l *MemoryLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		b.refill(now)
+		if b.tokens >= float64(b.limit.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestMemoryLimiter_Allow tests token bucket consumption and refill
+func TestMemoryLimiter_Allow(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := NewMemoryLimiter()
+	limiter.now = func() time.Time { return now }
+	limit := Limit{Burst: 2, Rate: 1}
+	ctx := context.Background()
+
+	// bucket starts full
+	for i := 1; i >= 0; i-- {
+		result, _ := limiter.Allow(ctx, "client", limit)
+		if !result.Allowed || result.Remaining != i {
+			t.Fatalf("expected allowed request with %d remaining, got %+v", i, result)
+		}
+	}
+
+	// bucket is empty
+	result, _ := limiter.Allow(ctx, "client", limit)
+	if result.Allowed {
+		t.Fatalf("expected rejected request, got %+v", result)
+	}
+	if result.RetryAfter != time.Second || result.Reset != 2*time.Second {
+		t.Errorf("unexpected retry after %s and reset %s", result.RetryAfter, result.Reset)
+	}
+
+	// other clients have their own buckets
+	result, _ = limiter.Allow(ctx, "other", limit)
+	if !result.Allowed {
+		t.Errorf("expected allowed request for other client, got %+v", result)
+	}
+
+	// tokens are refilled over time
+	now = now.Add(time.Second)
+	result, _ = limiter.Allow(ctx, "client", limit)
+	if !result.Allowed || result.Remaining != 0 {
+		t.Errorf("expected allowed request after refill, got %+v", result)
+	}
+}
@@ -0,0 +1,72 @@
+package render
+
+import (
+	"api-service/internal/cache"
+	"api-service/internal/domain"
+	"hash/fnv"
+	"sync/atomic"
+	"time"
+)
+
+// Stats represent render cache usage statistics
+type Stats struct {
+	Hits    uint64
+	Misses  uint64
+	Entries int
+}
+
+type cacheKey struct {
+	id      int
+	version int
+}
+
+type cacheEntry struct {
+	sum    uint64 // hash of format and content
+	result Result
+}
+
+// Cache keeps rendered content per post version, so that posts are rendered once per change;
+// entries are checked against hash of content, as ids and versions of deleted posts could be reused
+type Cache struct {
+	entries *cache.LRU[cacheKey, cacheEntry]
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+// NewCache creates a cache of up to size rendered posts, entries expire after TTL
+func NewCache(size int, ttl time.Duration) *Cache {
+	return &Cache{entries: cache.NewLRU[cacheKey, cacheEntry](size, ttl)}
+}
+
+// Render returns rendered content of post, it is rendered when the version is not cached
+func (c *Cache) Render(post *domain.Post) Result {
+	key := cacheKey{id: post.ID, version: post.Version}
+	sum := contentSum(post)
+	if entry, ok := c.entries.Get(key); ok && entry.sum == sum {
+		c.hits.Add(1)
+		return entry.result
+	}
+	c.misses.Add(1)
+	result := Render(post.Format, post.Content)
+	c.entries.Set(key, cacheEntry{sum: sum, result: result})
+	return result
+}
+
+// Stats returns cache usage statistics
+func (c *Cache) Stats() Stats {
+	return Stats{
+		Hits:    c.hits.Load(),
+		Misses:  c.misses.Load(),
+		Entries: c.entries.Len(),
+	}
+}
+
+// contentSum returns hash of rendered fields of post
+func contentSum(post *domain.Post) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(post.Format))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(post.Content))
+	return h.Sum64()
+}
@@ -0,0 +1,109 @@
+// Package render converts content of posts to sanitized HTML, excerpt and reading time are derived from its text
+package render
+
+import (
+	"api-service/internal/domain"
+	"slices"
+	"strings"
+
+	"github.com/russross/blackfriday/v2"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// ExcerptLength is a maximum length of excerpt in characters, excerpts are cut at word boundary
+const ExcerptLength = 200
+
+// WordsPerMinute is an average reading speed used to estimate reading time
+const WordsPerMinute = 200
+
+// Result is rendered content of a post
+type Result struct {
+	HTML        string
+	Excerpt     string
+	Words       int
+	ReadingTime int // minutes, zero for empty content
+}
+
+// Render renders content of the format as sanitized HTML, unknown formats are rendered as plain text
+func Render(format string, content string) Result {
+	var fragment string
+	switch format {
+	case domain.FormatMarkdown:
+		// raw HTML of Markdown is passed to sanitizer, so that allowed elements are kept
+		fragment = Sanitize(string(blackfriday.Run([]byte(content))))
+	default:
+		fragment = plainHTML(content)
+	}
+	text := Text(fragment)
+	words := len(strings.Fields(text))
+	return Result{
+		HTML:        fragment,
+		Excerpt:     Excerpt(text, ExcerptLength),
+		Words:       words,
+		ReadingTime: ReadingTime(words),
+	}
+}
+
+// plainHTML renders plain text as paragraphs separated by blank lines, line breaks are kept
+func plainHTML(content string) string {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	var out strings.Builder
+	for _, paragraph := range strings.Split(content, "\n\n") {
+		paragraph = strings.Trim(paragraph, "\n")
+		if strings.TrimSpace(paragraph) == "" {
+			continue
+		}
+		lines := strings.Split(paragraph, "\n")
+		for i, line := range lines {
+			lines[i] = html.EscapeString(line)
+		}
+		out.WriteString("<p>" + strings.Join(lines, "<br>\n") + "</p>\n")
+	}
+	return out.String()
+}
+
+// blockElements separate words of their text from text around them
+var blockElements = []atom.Atom{
+	atom.P, atom.Br, atom.Hr, atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6,
+	atom.Blockquote, atom.Pre, atom.Ul, atom.Ol, atom.Li, atom.Dl, atom.Dt, atom.Dd,
+	atom.Table, atom.Tr, atom.Th, atom.Td, atom.Img,
+}
+
+// Text returns text of HTML fragment with whitespace collapsed
+func Text(fragment string) string {
+	var out strings.Builder
+	tokenizer := html.NewTokenizer(strings.NewReader(fragment))
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			break
+		}
+		token := tokenizer.Token()
+		switch {
+		case tokenType == html.TextToken:
+			out.WriteString(token.Data)
+		case slices.Contains(blockElements, token.DataAtom):
+			out.WriteString(" ")
+		}
+	}
+	return strings.Join(strings.Fields(out.String()), " ")
+}
+
+// Excerpt returns text shortened to length characters at word boundary, shortened text ends with ellipsis
+func Excerpt(text string, length int) string {
+	runes := []rune(text)
+	if len(runes) <= length {
+		return text
+	}
+	cut := string(runes[:length])
+	if i := strings.LastIndex(cut, " "); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " .,;:!?-") + "…"
+}
+
+// ReadingTime estimates reading time of words in minutes, rounded up
+func ReadingTime(words int) int {
+	return (words + WordsPerMinute - 1) / WordsPerMinute
+}
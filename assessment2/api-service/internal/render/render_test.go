@@ -0,0 +1,120 @@
+package render
+
+import (
+	"api-service/internal/domain"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestRender tests rendering of content formats
+func TestRender(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		format   string
+		content  string
+		expected Result
+	}{
+		{
+			"markdown", domain.FormatMarkdown, "# Title\n\nSome *emphasis* and [link](https://example.com).",
+			Result{
+				HTML:        "<h1>Title</h1>\n\n<p>Some <em>emphasis</em> and <a href=\"https://example.com\" rel=\"nofollow noopener noreferrer\">link</a>.</p>\n",
+				Excerpt:     "Title Some emphasis and link.",
+				Words:       5,
+				ReadingTime: 1,
+			},
+		},
+		{
+			"markdown with script", domain.FormatMarkdown, "[x](javascript:void) <img src=x onerror=alert(1)>",
+			Result{HTML: "<p><a rel=\"nofollow noopener noreferrer\">x</a> <img src=\"x\"></p>\n", Excerpt: "x", Words: 1, ReadingTime: 1},
+		},
+		{
+			"plain", domain.FormatPlain, "Line <1>\nLine 2\n\nParagraph & more",
+			Result{HTML: "<p>Line &lt;1&gt;<br>\nLine 2</p>\n<p>Paragraph &amp; more</p>\n", Excerpt: "Line <1> Line 2 Paragraph & more", Words: 7, ReadingTime: 1},
+		},
+		{
+			"plain markdown", domain.FormatPlain, "*not emphasis*",
+			Result{HTML: "<p>*not emphasis*</p>\n", Excerpt: "*not emphasis*", Words: 2, ReadingTime: 1},
+		},
+		{"empty", domain.FormatPlain, "", Result{}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if actual := Render(tt.format, tt.content); actual != tt.expected {
+				t.Errorf("expected %#v, but got %#v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+// TestExcerpt tests shortening of text at word boundary
+func TestExcerpt(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		text     string
+		length   int
+		expected string
+	}{
+		{"short text", 20, "short text"},
+		{"one two three four", 10, "one two…"},
+		{"one, two, three", 9, "one…"},
+		{"longword", 4, "long…"},
+		{"żółw żółw żółw", 9, "żółw…"},
+	}
+
+	for _, tt := range tests {
+		if actual := Excerpt(tt.text, tt.length); actual != tt.expected {
+			t.Errorf("excerpt of %q: expected %q, but got %q", tt.text, tt.expected, actual)
+		}
+	}
+}
+
+// TestReadingTime tests that reading time is rounded up to minutes
+func TestReadingTime(t *testing.T) {
+	t.Parallel()
+
+	for words, expected := range map[int]int{0: 0, 1: 1, WordsPerMinute: 1, WordsPerMinute + 1: 2} {
+		if actual := ReadingTime(words); actual != expected {
+			t.Errorf("reading time of %d words: expected %d, but got %d", words, expected, actual)
+		}
+	}
+	long := Render(domain.FormatPlain, strings.Repeat("word ", 3*WordsPerMinute))
+	if long.Words != 3*WordsPerMinute || long.ReadingTime != 3 {
+		t.Errorf("unexpected result %d words, %d minutes", long.Words, long.ReadingTime)
+	}
+}
+
+// TestCache tests that posts are rendered once per version
+func TestCache(t *testing.T) {
+	t.Parallel()
+	c := NewCache(10, time.Minute)
+
+	post := domain.Post{ID: 1, Version: 1, Format: domain.FormatMarkdown, Content: "*one*"}
+	for i := 0; i < 3; i++ {
+		if result := c.Render(&post); result.HTML != "<p><em>one</em></p>\n" {
+			t.Fatalf("unexpected HTML %q", result.HTML)
+		}
+	}
+
+	// new version is rendered again
+	post.Version, post.Content = 2, "*two*"
+	if result := c.Render(&post); result.HTML != "<p><em>two</em></p>\n" {
+		t.Errorf("unexpected HTML %q of new version", result.HTML)
+	}
+
+	// content of reused version is not served from cache
+	post.Format = domain.FormatPlain
+	if result := c.Render(&post); result.HTML != "<p>*two*</p>\n" {
+		t.Errorf("unexpected HTML %q of changed content", result.HTML)
+	}
+
+	if stats := c.Stats(); stats.Hits != 2 || stats.Misses != 3 || stats.Entries != 2 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
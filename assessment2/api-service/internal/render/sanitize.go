@@ -0,0 +1,146 @@
+package render
+
+import (
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// allowedElements lists elements kept by sanitizer with their allowed attributes,
+// other elements are removed keeping their text
+var allowedElements = map[atom.Atom][]string{
+	atom.P: nil, atom.Br: nil, atom.Hr: nil,
+	atom.H1: nil, atom.H2: nil, atom.H3: nil, atom.H4: nil, atom.H5: nil, atom.H6: nil,
+	atom.Blockquote: nil, atom.Pre: nil, atom.Code: {"class"},
+	atom.Em: nil, atom.Strong: nil, atom.Del: nil, atom.S: nil, atom.Sup: nil, atom.Sub: nil,
+	atom.Ul: nil, atom.Ol: {"start"}, atom.Li: nil, atom.Dl: nil, atom.Dt: nil, atom.Dd: nil,
+	atom.A: {"href", "title"}, atom.Img: {"src", "alt", "title"},
+	atom.Table: nil, atom.Thead: nil, atom.Tbody: nil, atom.Tr: nil, atom.Th: {"align"}, atom.Td: {"align"},
+}
+
+// droppedElements are removed together with their content
+var droppedElements = []atom.Atom{
+	atom.Script, atom.Style, atom.Iframe, atom.Object, atom.Embed, atom.Template,
+	atom.Textarea, atom.Title, atom.Noscript, atom.Svg, atom.Math, atom.Select,
+}
+
+// voidElements have no content and no end tag
+var voidElements = []atom.Atom{atom.Br, atom.Hr, atom.Img}
+
+// attribute values are validated by patterns, URLs are validated by their schemes
+var (
+	codeClassPattern = regexp.MustCompile(`^language-[A-Za-z0-9_+-]+$`)
+	alignPattern     = regexp.MustCompile(`^(left|right|center)$`)
+	numberPattern    = regexp.MustCompile(`^[0-9]{1,9}$`)
+	linkSchemes      = []string{"http", "https", "mailto"}
+	imageSchemes     = []string{"http", "https"}
+)
+
+// Sanitize removes elements and attributes which are not allowed from HTML fragment, so that it could be
+// embedded into pages without script execution; unclosed elements are closed, links get nofollow relation
+func Sanitize(fragment string) string {
+	var out strings.Builder
+	var open []atom.Atom // allowed elements which are not closed yet
+	dropped := 0         // depth of dropped elements
+	tokenizer := html.NewTokenizer(strings.NewReader(fragment))
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			break
+		}
+		token := tokenizer.Token()
+		switch tokenType {
+		case html.TextToken:
+			if dropped == 0 {
+				out.WriteString(html.EscapeString(token.Data))
+			}
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			if slices.Contains(droppedElements, token.DataAtom) {
+				if tokenType == html.StartTagToken {
+					dropped++
+				}
+				continue
+			}
+			attributes, ok := allowedElements[token.DataAtom]
+			if dropped > 0 || !ok {
+				continue
+			}
+			out.WriteString("<" + token.Data)
+			for _, attr := range token.Attr {
+				if attr.Namespace == "" && slices.Contains(attributes, attr.Key) && allowedValue(attr.Key, attr.Val) {
+					out.WriteString(" " + attr.Key + `="` + html.EscapeString(attr.Val) + `"`)
+				}
+			}
+			if token.DataAtom == atom.A {
+				out.WriteString(` rel="nofollow noopener noreferrer"`)
+			}
+			out.WriteString(">")
+			if !slices.Contains(voidElements, token.DataAtom) {
+				open = append(open, token.DataAtom)
+			}
+
+		case html.EndTagToken:
+			if slices.Contains(droppedElements, token.DataAtom) {
+				dropped = max(dropped-1, 0)
+				continue
+			}
+			if dropped > 0 {
+				continue
+			}
+			// elements opened after the closed one are closed too, end tags without start tags are removed
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i] != token.DataAtom {
+					continue
+				}
+				for j := len(open) - 1; j >= i; j-- {
+					out.WriteString("</" + open[j].String() + ">")
+				}
+				open = open[:i]
+				break
+			}
+		}
+	}
+	for j := len(open) - 1; j >= 0; j-- {
+		out.WriteString("</" + open[j].String() + ">")
+	}
+	return out.String()
+}
+
+// allowedValue validates value of allowed attribute
+func allowedValue(key string, value string) bool {
+	switch key {
+	case "href":
+		return safeURL(value, linkSchemes)
+	case "src":
+		return safeURL(value, imageSchemes)
+	case "class":
+		return codeClassPattern.MatchString(value)
+	case "align":
+		return alignPattern.MatchString(value)
+	case "start":
+		return numberPattern.MatchString(value)
+	default:
+		return true
+	}
+}
+
+// safeURL reports whether URL is relative to the site or has one of schemes. URLs with control characters,
+// whitespace or backslashes are rejected as browsers drop or normalize them, so they could hide a scheme,
+// and protocol-relative URLs starting with // are rejected as they address other hosts
+func safeURL(value string, schemes []string) bool {
+	if strings.ContainsFunc(value, func(r rune) bool { return unicode.IsControl(r) || unicode.IsSpace(r) || r == '\\' }) ||
+		strings.HasPrefix(value, "//") {
+		return false
+	}
+	u, err := url.Parse(value)
+	if err != nil {
+		return false
+	}
+	return u.Scheme == "" || slices.Contains(schemes, strings.ToLower(u.Scheme))
+}
@@ -0,0 +1,44 @@
+package render
+
+import "testing"
+
+// TestSanitize tests that scripts, handlers and unsafe URLs are removed from HTML
+func TestSanitize(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		fragment string
+		expected string
+	}{
+		{"allowed elements", "<p><em>a</em> <strong>b</strong></p>", "<p><em>a</em> <strong>b</strong></p>"},
+		{"script", "Hello <script>alert(1)</script>world", "Hello world"},
+		{"style", "<style>p{}</style>text", "text"},
+		{"svg content", `<svg><a href="/">x</a></svg>after`, "after"},
+		{"event handler", "<img src=x onerror=alert(1)>", `<img src="x">`},
+		{"unknown element keeps text", `<div onclick="x"><em>kept</em></div>`, "<em>kept</em>"},
+		{"javascript link", `<a href="javascript:alert(1)">x</a>`, `<a rel="nofollow noopener noreferrer">x</a>`},
+		{"obfuscated scheme", "<a href=\"java\tscript:alert(1)\">x</a>", `<a rel="nofollow noopener noreferrer">x</a>`},
+		{"encoded control character", `<a href="java&#x0A;script:alert(1)">x</a>`, `<a rel="nofollow noopener noreferrer">x</a>`},
+		{"leading whitespace", "<a href=\" javascript:alert(1)\">x</a>", `<a rel="nofollow noopener noreferrer">x</a>`},
+		{"protocol-relative link", `<a href="//evil.example/">x</a>`, `<a rel="nofollow noopener noreferrer">x</a>`},
+		{"backslash link", `<a href="/\evil.example/">x</a>`, `<a rel="nofollow noopener noreferrer">x</a>`},
+		{"relative link", `<a href="/posts/1?a=b#c">x</a>`, `<a href="/posts/1?a=b#c" rel="nofollow noopener noreferrer">x</a>`},
+		{"data image", `<IMG SRC="data:image/png;base64,AAA">`, "<img>"},
+		{"mailto link", `<a href="mailto:a@example.com">mail</a>`, `<a href="mailto:a@example.com" rel="nofollow noopener noreferrer">mail</a>`},
+		{"escaped attribute", `<a href="/" title='"><script>'>x</a>`, `<a href="/" title="&#34;&gt;&lt;script&gt;" rel="nofollow noopener noreferrer">x</a>`},
+		{"code language", `<code class="language-go">x</code><code class="x onload">y</code>`, `<code class="language-go">x</code><code>y</code>`},
+		{"unclosed elements", "<p><em>unclosed", "<p><em>unclosed</em></p>"},
+		{"stray end tag", "</em>stray", "stray"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if actual := Sanitize(tt.fragment); actual != tt.expected {
+				t.Errorf("expected %q, but got %q", tt.expected, actual)
+			}
+		})
+	}
+}
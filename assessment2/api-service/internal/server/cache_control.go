@@ -0,0 +1,64 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// CacheControl allows clients and shared caches to reuse successful responses for maxAge,
+// responses of requests with credentials are reused only by clients, other responses are marked as not cacheable
+func CacheControl(maxAge time.Duration) func(http.Handler) http.Handler {
+	public := fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds()))
+	private := fmt.Sprintf("private, max-age=%d", int(maxAge.Seconds()))
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// responses could depend on user, so shared caches must not serve them to other users
+			cacheable := public
+			if r.Header.Get("Authorization") != "" {
+				cacheable = private
+			}
+			w.Header().Add("Vary", "Authorization")
+			next.ServeHTTP(&cacheControlWriter{ResponseWriter: w, cacheable: cacheable}, r)
+		})
+	}
+}
+
+// NoStore marks responses as not cacheable
+func NoStore(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+		next.ServeHTTP(w, r)
+	})
+}
+
+// cacheControlWriter sets Cache-Control header according to response status
+type cacheControlWriter struct {
+	http.ResponseWriter
+	cacheable   string
+	wroteHeader bool
+}
+
+func (w *cacheControlWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if status == http.StatusOK {
+			w.Header().Set("Cache-Control", w.cacheable)
+		} else {
+			w.Header().Set("Cache-Control", "no-store")
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *cacheControlWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap allows http.ResponseController to access underlying writer
+func (w *cacheControlWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
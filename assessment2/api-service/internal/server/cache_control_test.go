@@ -0,0 +1,48 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestCacheControl tests that only successful responses are cacheable and responses of users are private
+func TestCacheControl(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		authorization string
+		status        int
+		expected      string
+	}{
+		{"anonymous", "", http.StatusOK, "public, max-age=10"},
+		{"authenticated", "Bearer token", http.StatusOK, "private, max-age=10"},
+		{"failed", "", http.StatusNotFound, "no-store"},
+		{"failed authenticated", "Bearer token", http.StatusUnauthorized, "no-store"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			handler := CacheControl(10 * time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/posts", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if cacheControl := rr.Header().Get("Cache-Control"); cacheControl != tt.expected {
+				t.Errorf("expected Cache-Control %q, but got %q", tt.expected, cacheControl)
+			}
+			if vary := rr.Header().Get("Vary"); vary != "Authorization" {
+				t.Errorf("expected Vary header, but got %q", vary)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+)
+
+// TestTrustedProxies_ClientIP tests client address resolution behind proxies
+func TestTrustedProxies_ClientIP(t *testing.T) {
+	t.Parallel()
+
+	proxies, err := ParseTrustedProxies("10.0.0.0/8, 192.168.1.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		realIP     string
+		expected   string
+	}{
+		{"direct client", "203.0.113.5:1234", "", "", "203.0.113.5"},
+		{"untrusted proxy headers ignored", "203.0.113.5:1234", "198.51.100.7", "", "203.0.113.5"},
+		{"trusted proxy", "10.1.2.3:1234", "198.51.100.7", "", "198.51.100.7"},
+		{"trusted proxy chain", "192.168.1.1:1234", "198.51.100.7, 203.0.113.9, 10.0.0.2", "", "203.0.113.9"},
+		{"trusted proxy real ip", "10.1.2.3:1234", "", "198.51.100.8", "198.51.100.8"},
+		{"trusted proxy without headers", "10.1.2.3:1234", "", "", "10.1.2.3"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			req, _ := http.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			if tt.realIP != "" {
+				req.Header.Set("X-Real-IP", tt.realIP)
+			}
+			if ip := proxies.ClientIP(req); ip != tt.expected {
+				t.Errorf("expected %s, but got %s", tt.expected, ip)
+			}
+		})
+	}
+}
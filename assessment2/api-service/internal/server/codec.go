@@ -0,0 +1,245 @@
+package server
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"mime"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// ErrorNotAcceptable is returned when none of registered formats is accepted by client
+var ErrorNotAcceptable = errors.New("response format is not acceptable")
+
+// ErrorUnsupportedMediaType is returned when request body format is not supported
+var ErrorUnsupportedMediaType = errors.New("unsupported media type")
+
+// Codec encodes responses and decodes request bodies of a format
+type Codec interface {
+	// ContentType is a value of Content-Type header of encoded responses
+	ContentType() string
+	Encode(w io.Writer, v any) error
+	Decode(r io.Reader, v any) error
+}
+
+// Codecs is a registry of formats negotiated by Accept and Content-Type headers,
+// the first registered format is used by default
+type Codecs struct {
+	mu     sync.RWMutex
+	codecs []mediaCodec
+}
+
+type mediaCodec struct {
+	mediaType string
+	codec     Codec
+}
+
+// NewCodecs creates a registry of JSON, XML, CSV and MessagePack formats
+func NewCodecs() *Codecs {
+	c := &Codecs{}
+	c.Register(JSONCodec{}, "application/json")
+	c.Register(XMLCodec{}, "application/xml", "text/xml")
+	c.Register(CSVCodec{}, "text/csv")
+	c.Register(MsgpackCodec{}, "application/msgpack", "application/x-msgpack", "application/vnd.msgpack")
+	return c
+}
+
+// defaultCodecs are used by webservers created without registry
+var defaultCodecs = NewCodecs()
+
+// Register adds a format served for given media types, registration order defines server preference
+func (c *Codecs) Register(codec Codec, mediaTypes ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, mediaType := range mediaTypes {
+		c.codecs = append(c.codecs, mediaCodec{mediaType: strings.ToLower(mediaType), codec: codec})
+	}
+}
+
+// Negotiate selects a format by Accept header, quality values and specificity of media ranges are respected
+func (c *Codecs) Negotiate(accept string) (Codec, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if len(c.codecs) == 0 {
+		return nil, ErrorNotAcceptable
+	}
+	if strings.TrimSpace(accept) == "" {
+		return c.codecs[0].codec, nil
+	}
+
+	ranges := parseAccept(accept)
+	var selected Codec
+	best := 0.0
+	for _, mc := range c.codecs {
+		if q := acceptQuality(ranges, mc.mediaType); q > best {
+			selected, best = mc.codec, q
+		}
+	}
+	if selected == nil {
+		return nil, ErrorNotAcceptable
+	}
+	return selected, nil
+}
+
+// ForContentType selects a format of request body, body without Content-Type uses default format
+func (c *Codecs) ForContentType(contentType string) (Codec, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if len(c.codecs) == 0 {
+		return nil, ErrorUnsupportedMediaType
+	}
+	if strings.TrimSpace(contentType) == "" {
+		return c.codecs[0].codec, nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, ErrorUnsupportedMediaType
+	}
+	for _, mc := range c.codecs {
+		if mc.mediaType == mediaType {
+			return mc.codec, nil
+		}
+	}
+	return nil, ErrorUnsupportedMediaType
+}
+
+// mediaRange is a parsed entry of Accept header
+type mediaRange struct {
+	mediaType string
+	quality   float64
+}
+
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		ranges = append(ranges, mediaRange{mediaType: mediaType, quality: q})
+	}
+	return ranges
+}
+
+// acceptQuality returns quality of media type from the most specific matching range
+func acceptQuality(ranges []mediaRange, mediaType string) float64 {
+	typ, _, _ := strings.Cut(mediaType, "/")
+	quality, specificity := 0.0, -1
+	for _, mr := range ranges {
+		var s int
+		switch mr.mediaType {
+		case mediaType:
+			s = 2
+		case typ + "/*":
+			s = 1
+		case "*/*":
+			s = 0
+		default:
+			continue
+		}
+		if s > specificity {
+			quality, specificity = mr.quality, s
+		}
+	}
+	return quality
+}
+
+// JSONCodec encodes data in JSON format
+type JSONCodec struct{}
+
+func (JSONCodec) ContentType() string {
+	return "application/json"
+}
+
+func (JSONCodec) Encode(w io.Writer, v any) error {
+	out, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+func (JSONCodec) Decode(r io.Reader, v any) error {
+	return json.NewDecoder(r).Decode(v)
+}
+
+// XMLCodec encodes data in XML format, list data is encoded as item elements
+type XMLCodec struct{}
+
+// xmlResponse is an XML representation of JsonResponse
+type xmlResponse struct {
+	XMLName xml.Name `xml:"response"`
+	Error   bool     `xml:"error"`
+	Message string   `xml:"message"`
+	Data    any      `xml:"data,omitempty"`
+	TraceID string   `xml:"trace_id,omitempty"`
+}
+
+// xmlList wraps list elements of response data
+type xmlList struct {
+	Items any `xml:"item"`
+}
+
+func (XMLCodec) ContentType() string {
+	return "application/xml; charset=utf-8"
+}
+
+func (XMLCodec) Encode(w io.Writer, v any) error {
+	if response, ok := v.(JsonResponse); ok {
+		data := response.Data
+		if value := reflect.Indirect(reflect.ValueOf(data)); value.Kind() == reflect.Slice {
+			data = xmlList{Items: value.Interface()}
+		}
+		v = xmlResponse{
+			Error:   response.Error,
+			Message: response.Message,
+			Data:    data,
+			TraceID: response.TraceID,
+		}
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	return xml.NewEncoder(w).Encode(v)
+}
+
+func (XMLCodec) Decode(r io.Reader, v any) error {
+	return xml.NewDecoder(r).Decode(v)
+}
+
+// MsgpackCodec encodes data in MessagePack format, keys are named as in JSON
+type MsgpackCodec struct{}
+
+func (MsgpackCodec) ContentType() string {
+	return "application/msgpack"
+}
+
+func (MsgpackCodec) Encode(w io.Writer, v any) error {
+	enc := msgpack.NewEncoder(w)
+	enc.SetCustomStructTag("json")
+	return enc.Encode(v)
+}
+
+func (MsgpackCodec) Decode(r io.Reader, v any) error {
+	dec := msgpack.NewDecoder(r)
+	dec.SetCustomStructTag("json")
+	return dec.Decode(v)
+}
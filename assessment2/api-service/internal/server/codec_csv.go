@@ -0,0 +1,197 @@
+package server
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ErrorCSVTarget is returned when CSV data could not be decoded into the target value
+var ErrorCSVTarget = errors.New("csv could be decoded only into struct or slice of structs")
+
+// CSVCodec encodes list data as CSV table with a header row, columns are named as JSON fields
+// and fields tagged with csv:"-" are omitted, responses without struct data are encoded as a single row of error, message and data columns
+type CSVCodec struct{}
+
+func (CSVCodec) ContentType() string {
+	return "text/csv; charset=utf-8"
+}
+
+func (CSVCodec) Encode(w io.Writer, v any) error {
+	if response, ok := v.(JsonResponse); ok {
+		if value := reflect.Indirect(reflect.ValueOf(response.Data)); isTable(value) {
+			v = value.Interface()
+		} else {
+			data := ""
+			if value.IsValid() {
+				data = fmt.Sprint(value.Interface())
+			}
+			v = []struct {
+				Error   bool   `json:"error"`
+				Message string `json:"message"`
+				Data    string `json:"data"`
+			}{{response.Error, response.Message, data}}
+		}
+	}
+
+	value := reflect.Indirect(reflect.ValueOf(v))
+	if !isTable(value) {
+		return ErrorCSVTarget
+	}
+	rows := value
+	if value.Kind() == reflect.Struct {
+		rows = reflect.Append(reflect.MakeSlice(reflect.SliceOf(value.Type()), 0, 1), value)
+	}
+
+	cw := csv.NewWriter(w)
+	columns := csvColumns(rows.Type().Elem())
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = column.name
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	record := make([]string, len(columns))
+	for i := 0; i < rows.Len(); i++ {
+		row := rows.Index(i)
+		for j, column := range columns {
+			record[j] = fmt.Sprint(row.Field(column.index).Interface())
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// Decode reads CSV table with a header row into a struct (the first row) or a slice of structs
+func (CSVCodec) Decode(r io.Reader, v any) error {
+	target := reflect.ValueOf(v)
+	if target.Kind() != reflect.Pointer || target.IsNil() {
+		return ErrorCSVTarget
+	}
+	target = target.Elem()
+	rowType := target.Type()
+	if target.Kind() == reflect.Slice {
+		rowType = rowType.Elem()
+	}
+	if rowType.Kind() != reflect.Struct {
+		return ErrorCSVTarget
+	}
+
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err != nil {
+		return err
+	}
+	fields := make([]int, len(header))
+	for i, name := range header {
+		fields[i] = -1
+		for _, column := range csvColumns(rowType) {
+			if strings.EqualFold(column.name, strings.TrimSpace(name)) {
+				fields[i] = column.index
+			}
+		}
+	}
+
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		row := reflect.New(rowType).Elem()
+		for i, value := range record {
+			if i >= len(fields) || fields[i] < 0 {
+				continue
+			}
+			if err := setCSVField(row.Field(fields[i]), value); err != nil {
+				return fmt.Errorf("column %s: %w", header[i], err)
+			}
+		}
+		if target.Kind() == reflect.Struct {
+			target.Set(row)
+			return nil
+		}
+		target.Set(reflect.Append(target, row))
+	}
+}
+
+// csvColumn is an exported struct field encoded as CSV column
+type csvColumn struct {
+	name  string
+	index int
+}
+
+func csvColumns(t reflect.Type) []csvColumn {
+	var columns []csvColumn
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" || field.Tag.Get("csv") == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		columns = append(columns, csvColumn{name: name, index: i})
+	}
+	return columns
+}
+
+// isTable reports whether value is a struct or a slice of structs
+func isTable(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Struct:
+		return true
+	case reflect.Slice:
+		return value.Type().Elem().Kind() == reflect.Struct
+	default:
+		return false
+	}
+}
+
+func setCSVField(field reflect.Value, value string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type testPost struct {
+	ID    int
+	Title string `json:"title"`
+	Draft bool   `json:"draft"`
+}
+
+// TestCodecs_Negotiate tests selection of response format from Accept header
+func TestCodecs_Negotiate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		accept   string
+		expected string
+	}{
+		{"no header", "", "application/json"},
+		{"any format", "*/*", "application/json"},
+		{"xml", "application/xml", "application/xml; charset=utf-8"},
+		{"xml alias", "text/xml", "application/xml; charset=utf-8"},
+		{"csv", "text/csv", "text/csv; charset=utf-8"},
+		{"msgpack alias", "application/x-msgpack", "application/msgpack"},
+		{"quality values", "application/json;q=0.5, text/csv;q=0.9", "text/csv; charset=utf-8"},
+		{"specific range wins", "text/*;q=0.8, text/csv;q=0.1, */*;q=0.2", "application/xml; charset=utf-8"},
+		{"not acceptable", "image/png", ""},
+		{"rejected format", "application/json;q=0", ""},
+	}
+
+	codecs := NewCodecs()
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			codec, err := codecs.Negotiate(tt.accept)
+			if tt.expected == "" {
+				if !errors.Is(err, ErrorNotAcceptable) {
+					t.Errorf("expected not acceptable error, but got %v", err)
+				}
+				return
+			}
+			if err != nil || codec.ContentType() != tt.expected {
+				t.Errorf("expected %s, but got %v, error %v", tt.expected, codec, err)
+			}
+		})
+	}
+}
+
+// TestCodecs_ForContentType tests selection of request body format
+func TestCodecs_ForContentType(t *testing.T) {
+	t.Parallel()
+
+	codecs := NewCodecs()
+	if codec, err := codecs.ForContentType(""); err != nil || codec.ContentType() != "application/json" {
+		t.Errorf("expected default JSON format, but got %v, error %v", codec, err)
+	}
+	if codec, err := codecs.ForContentType("application/XML; charset=utf-8"); err != nil || codec.ContentType() != "application/xml; charset=utf-8" {
+		t.Errorf("expected XML format, but got %v, error %v", codec, err)
+	}
+	if _, err := codecs.ForContentType("text/plain"); !errors.Is(err, ErrorUnsupportedMediaType) {
+		t.Errorf("expected unsupported media type error, but got %v", err)
+	}
+}
+
+// TestCodecs_RoundTrip tests that decoders read data written by encoders
+func TestCodecs_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	posts := []testPost{{ID: 1, Title: "Title, with comma", Draft: true}, {ID: 2, Title: "Title 2"}}
+	for _, codec := range []Codec{JSONCodec{}, XMLCodec{}, CSVCodec{}, MsgpackCodec{}} {
+		codec := codec
+		t.Run(codec.ContentType(), func(t *testing.T) {
+			t.Parallel()
+			var buf bytes.Buffer
+			if err := codec.Encode(&buf, posts[0]); err != nil {
+				t.Fatal(err)
+			}
+			var decoded testPost
+			if err := codec.Decode(&buf, &decoded); err != nil {
+				t.Fatal(err)
+			}
+			if decoded != posts[0] {
+				t.Errorf("expected %+v, but got %+v", posts[0], decoded)
+			}
+		})
+	}
+}
+
+// TestCSVCodec_Encode tests encoding of responses as CSV tables
+func TestCSVCodec_Encode(t *testing.T) {
+	t.Parallel()
+
+	posts := []testPost{{ID: 1, Title: "Title, with comma", Draft: true}, {ID: 2, Title: "Title 2"}}
+	tests := []struct {
+		name     string
+		response JsonResponse
+		expected string
+	}{
+		{"list", JsonResponse{Data: &posts}, "ID,title,draft\n1,\"Title, with comma\",true\n2,Title 2,false\n"},
+		{"single post", JsonResponse{Data: posts[1]}, "ID,title,draft\n2,Title 2,false\n"},
+		{"omitted column", JsonResponse{Data: []struct {
+			ID     int
+			Format string `csv:"-"`
+		}{{1, "markdown"}}}, "ID\n1\n"},
+		{"message", JsonResponse{Message: "post added", Data: 3}, "error,message,data\nfalse,post added,3\n"},
+		{"error", JsonResponse{Error: true, Message: "post not found"}, "error,message,data\ntrue,post not found,\n"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var buf strings.Builder
+			if err := (CSVCodec{}).Encode(&buf, tt.response); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("expected %q, but got %q", tt.expected, buf.String())
+			}
+		})
+	}
+}
+
+// TestXMLCodec_Encode tests encoding of list responses as XML items
+func TestXMLCodec_Encode(t *testing.T) {
+	t.Parallel()
+
+	posts := []testPost{{ID: 1, Title: "Title 1"}}
+	var buf strings.Builder
+	if err := (XMLCodec{}).Encode(&buf, JsonResponse{Data: &posts}); err != nil {
+		t.Fatal(err)
+	}
+	expected := `<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+		`<response><error>false</error><message></message><data><item><ID>1</ID><Title>Title 1</Title><Draft>false</Draft></item></data></response>`
+	if buf.String() != expected {
+		t.Errorf("unexpected xml %s", buf.String())
+	}
+}
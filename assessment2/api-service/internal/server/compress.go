@@ -0,0 +1,265 @@
+package server
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// ErrorUnsupportedEncoding is returned when request body uses unsupported content encoding
+var ErrorUnsupportedEncoding = errors.New("unsupported content encoding")
+
+// ErrorBodyTooLarge is returned when decompressed request body exceeds the limit
+var ErrorBodyTooLarge = errors.New("request body too large")
+
+// encoder compresses response stream
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// encoding describes supported content coding
+type encoding struct {
+	name string
+	pool sync.Pool
+}
+
+// encodings are listed in order of server preference
+var encodings = []*encoding{
+	{name: "br", pool: sync.Pool{New: func() any {
+		return brotli.NewWriterLevel(io.Discard, brotli.DefaultCompression)
+	}}},
+	{name: "zstd", pool: sync.Pool{New: func() any {
+		w, _ := zstd.NewWriter(io.Discard, zstd.WithEncoderLevel(zstd.SpeedDefault), zstd.WithEncoderConcurrency(1))
+		return w
+	}}},
+	{name: "gzip", pool: sync.Pool{New: func() any {
+		return gzip.NewWriter(io.Discard)
+	}}},
+}
+
+// Compress compresses responses with encoding negotiated by Accept-Encoding header,
+// responses smaller than minSize are sent as is
+func Compress(minSize int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+			enc := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+			if enc == nil || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{ResponseWriter: w, encoding: enc, minSize: minSize}
+			defer cw.Close()
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// negotiateEncoding selects supported encoding with the highest quality value,
+// server preference order is used for encodings with equal quality
+func negotiateEncoding(header string) *encoding {
+	qualities := make(map[string]float64)
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		qualities[name] = q
+	}
+
+	var selected *encoding
+	best := 0.0
+	for _, enc := range encodings {
+		q, ok := qualities[enc.name]
+		if !ok {
+			q, ok = qualities["*"]
+		}
+		if ok && q > best {
+			selected, best = enc, q
+		}
+	}
+	return selected
+}
+
+// compressWriter buffers response until it reaches minimal size and then starts compression
+type compressWriter struct {
+	http.ResponseWriter
+	encoding    *encoding
+	minSize     int
+	status      int
+	buf         []byte
+	encoder     encoder
+	decided     bool // compression is either started or skipped
+	wroteHeader bool
+}
+
+func (w *compressWriter) WriteHeader(status int) {
+	if w.status != 0 {
+		return
+	}
+	w.status = status
+	// responses without body or already encoded are not compressed
+	if status < http.StatusOK || status == http.StatusNoContent || status == http.StatusNotModified ||
+		w.Header().Get("Content-Encoding") != "" {
+		w.decide(false)
+	}
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.decided {
+		w.buf = append(w.buf, b...)
+		if len(w.buf) < w.minSize {
+			return len(b), nil
+		}
+		w.decide(true)
+		return len(b), nil
+	}
+	if w.encoder != nil {
+		return w.encoder.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush starts streaming of buffered data, compressed when encoder is used
+func (w *compressWriter) Flush() {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.decided {
+		w.decide(true)
+	}
+	if w.encoder != nil {
+		_ = w.encoder.Flush()
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Close writes remaining data and returns encoder to the pool
+func (w *compressWriter) Close() {
+	if !w.decided {
+		// response was smaller than minimal size
+		if w.status == 0 && len(w.buf) == 0 {
+			return
+		}
+		w.decide(false)
+	}
+	if w.encoder != nil {
+		_ = w.encoder.Close()
+		w.encoder.Reset(io.Discard)
+		w.encoding.pool.Put(w.encoder)
+		w.encoder = nil
+	}
+}
+
+// Hijack allows protocol upgrades through compressing writer
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	w.decided = true
+	return hijacker.Hijack()
+}
+
+// Unwrap allows http.ResponseController to access underlying writer
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// decide writes response headers and buffered data, compressed or not
+func (w *compressWriter) decide(compress bool) {
+	w.decided = true
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if compress {
+		w.encoder = w.encoding.pool.Get().(encoder)
+		w.encoder.Reset(w.ResponseWriter)
+		w.Header().Set("Content-Encoding", w.encoding.name)
+		w.Header().Del("Content-Length")
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	if len(w.buf) == 0 {
+		return
+	}
+	if w.encoder != nil {
+		_, _ = w.encoder.Write(w.buf)
+	} else {
+		_, _ = w.ResponseWriter.Write(w.buf)
+	}
+	w.buf = nil
+}
+
+// decodeBody returns reader of decompressed request body limited by maxBytes
+func decodeBody(r *http.Request, maxBytes int64) (io.Reader, error) {
+	var reader io.Reader
+	switch strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding"))) {
+	case "", "identity":
+		return r.Body, nil
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		reader = gz
+	case "br":
+		reader = brotli.NewReader(r.Body)
+	case "zstd":
+		zr, err := zstd.NewReader(r.Body, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxMemory(uint64(maxBytes)))
+		if err != nil {
+			return nil, err
+		}
+		reader = zr.IOReadCloser()
+	default:
+		return nil, ErrorUnsupportedEncoding
+	}
+	return &limitedReader{reader: reader, remaining: maxBytes}, nil
+}
+
+// limitedReader fails when data exceeds the limit, protecting from decompression bombs
+type limitedReader struct {
+	reader    io.Reader
+	remaining int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.remaining < 0 {
+		return 0, ErrorBodyTooLarge
+	}
+	// read one byte more than allowed to detect exceeding data
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.reader.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n, ErrorBodyTooLarge
+	}
+	return n, err
+}
@@ -0,0 +1,167 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// TestNegotiateEncoding tests selection of response encoding from Accept-Encoding header
+func TestNegotiateEncoding(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		header   string
+		expected string
+	}{
+		{"no header", "", ""},
+		{"gzip only", "gzip", "gzip"},
+		{"server preference", "gzip, zstd, br", "br"},
+		{"quality values", "br;q=0.5, gzip;q=0.8, zstd;q=0.1", "gzip"},
+		{"rejected encoding", "br;q=0, gzip", "gzip"},
+		{"wildcard", "*", "br"},
+		{"wildcard with exclusion", "*;q=0.5, br;q=0", "zstd"},
+		{"unsupported", "deflate, compress", ""},
+		{"identity only", "identity", ""},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			name := ""
+			if enc := negotiateEncoding(tt.header); enc != nil {
+				name = enc.name
+			}
+			if name != tt.expected {
+				t.Errorf("expected %q, but got %q", tt.expected, name)
+			}
+		})
+	}
+}
+
+// TestCompress tests compression of responses depending on their size
+func TestCompress(t *testing.T) {
+	t.Parallel()
+
+	large := strings.Repeat("compressible response ", 100)
+	tests := []struct {
+		name     string
+		encoding string
+		body     string
+		expected string
+	}{
+		{"small response", "gzip", "small", ""},
+		{"gzip response", "gzip", large, "gzip"},
+		{"brotli response", "br", large, "br"},
+		{"zstd response", "zstd", large, "zstd"},
+		{"not accepted", "", large, ""},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			handler := Compress(1024)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/plain")
+				_, _ = io.WriteString(w, tt.body)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.encoding != "" {
+				req.Header.Set("Accept-Encoding", tt.encoding)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if encoding := rr.Header().Get("Content-Encoding"); encoding != tt.expected {
+				t.Fatalf("expected encoding %q, but got %q", tt.expected, encoding)
+			}
+			if vary := rr.Header().Get("Vary"); vary != "Accept-Encoding" {
+				t.Errorf("expected Vary header, but got %q", vary)
+			}
+
+			var reader io.Reader = rr.Body
+			switch tt.expected {
+			case "gzip":
+				reader, _ = gzip.NewReader(rr.Body)
+			case "br":
+				reader = brotli.NewReader(rr.Body)
+			case "zstd":
+				decoder, _ := zstd.NewReader(rr.Body)
+				defer decoder.Close()
+				reader = decoder
+			}
+			body, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(body) != tt.body {
+				t.Errorf("unexpected response body of %d bytes", len(body))
+			}
+		})
+	}
+}
+
+// TestWebServer_Read_Compressed tests decompression of request bodies
+func TestWebServer_Read_Compressed(t *testing.T) {
+	t.Parallel()
+
+	gzipBody := func(data []byte) *bytes.Buffer {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		_, _ = gz.Write(data)
+		_ = gz.Close()
+		return &buf
+	}
+	bomb := append([]byte(`{"title":"`), bytes.Repeat([]byte("a"), 2<<20)...)
+
+	tests := []struct {
+		name     string
+		encoding string
+		body     io.Reader
+		expected int
+	}{
+		{"plain body", "", strings.NewReader(`{"title":"Title"}`), 0},
+		{"gzip body", "gzip", gzipBody([]byte(`{"title":"Title"}`)), 0},
+		{"unsupported encoding", "deflate", strings.NewReader(`{"title":"Title"}`), http.StatusUnsupportedMediaType},
+		{"decompressed body too large", "gzip", gzipBody(bomb), http.StatusRequestEntityTooLarge},
+	}
+
+	srv := NewWebServer("0")
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			req := httptest.NewRequest(http.MethodPost, "/", tt.body)
+			if tt.encoding != "" {
+				req.Header.Set("Content-Encoding", tt.encoding)
+			}
+
+			var payload struct {
+				Title string `json:"title"`
+			}
+			err := srv.Read(httptest.NewRecorder(), req, &payload)
+			if tt.expected == 0 {
+				if err != nil || payload.Title != "Title" {
+					t.Errorf("unexpected payload %+v, error %v", payload, err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if status := ReadErrorStatus(err); status != tt.expected {
+				t.Errorf("expected status %d, but got %d: %v", tt.expected, status, err)
+			}
+		})
+	}
+}
@@ -1,53 +1,192 @@
 package server
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"time"
 )
 
-// WebServer allows to serve data in JSON format
+// WebServer allows to serve data in JSON and other negotiated formats
 type WebServer struct {
 	Port    string
 	Timeout time.Duration
+	Codecs  *Codecs      // formats of requests and responses, JSON, XML, CSV and MessagePack by default
+	server  *http.Server // created with webserver, so that it could be shut down before it is served
 }
 
+// TraceIDHeader is a response header carrying id of request trace
+const TraceIDHeader = "X-Trace-ID"
+
+// ErrorEncode is returned when response data could not be encoded in negotiated format
+var ErrorEncode = errors.New("failed to encode response")
+
 // JsonResponse represent typical webserver response
 type JsonResponse struct {
 	Error   bool   `json:"error"`
 	Message string `json:"message"`
 	Data    any    `json:"data,omitempty"`
+	TraceID string `json:"trace_id,omitempty"`
+}
+
+// ErrorEnvelope builds body of error response, API versions with own envelopes provide it with request context
+type ErrorEnvelope func(err error, status int, traceID string) any
+
+type errorEnvelopeKey struct{}
+
+// WithErrorEnvelope returns context whose error responses are built by envelope
+func WithErrorEnvelope(ctx context.Context, envelope ErrorEnvelope) context.Context {
+	return context.WithValue(ctx, errorEnvelopeKey{}, envelope)
+}
+
+// errorBody builds body of error response with envelope of request context, standard
+// response is used by default
+func errorBody(r *http.Request, err error, status int, traceID string) any {
+	if envelope, ok := r.Context().Value(errorEnvelopeKey{}).(ErrorEnvelope); ok {
+		return envelope(err, status, traceID)
+	}
+	return JsonResponse{Error: true, Message: err.Error(), TraceID: traceID}
 }
 
 // NewWebServer creates a new webserver
 func NewWebServer(port string) WebServer {
 	return WebServer{
-		Port: port,
+		Port:   port,
+		Codecs: NewCodecs(),
+		server: &http.Server{Addr: fmt.Sprintf(":%s", port)},
+	}
+}
+
+// ReadErrorStatus returns HTTP status code of request body reading error
+func ReadErrorStatus(err error) int {
+	var maxBytesError *http.MaxBytesError
+	switch {
+	case errors.Is(err, ErrorUnsupportedEncoding), errors.Is(err, ErrorUnsupportedMediaType):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, ErrorBodyTooLarge), errors.As(err, &maxBytesError):
+		return http.StatusRequestEntityTooLarge
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// codecs returns registry of formats, default formats are used when registry is not provided
+func (srv *WebServer) codecs() *Codecs {
+	if srv.Codecs != nil {
+		return srv.Codecs
 	}
+	return defaultCodecs
 }
 
-// ReadJSON reads JSON data from request body
-func (srv *WebServer) ReadJSON(w http.ResponseWriter, r *http.Request, data any) error {
+// Read reads request body in format defined by Content-Type header, compressed bodies
+// are decompressed and both compressed and decompressed sizes are limited
+func (srv *WebServer) Read(w http.ResponseWriter, r *http.Request, data any) error {
+	codec, err := srv.codecs().ForContentType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return err
+	}
+
 	// decode request body
 	maxBytes := 1048576 // one Mb
-	r.Body = http.MaxBytesReader(w, r.Body, int64(maxBytes))
-	dec := json.NewDecoder(r.Body)
-	err := dec.Decode(data)
+	body, err := srv.Body(w, r, int64(maxBytes))
 	if err != nil {
 		return err
 	}
+	return codec.Decode(body, data)
+}
 
-	return nil
+// Body returns reader of request body decompressed according to Content-Encoding header,
+// both compressed and decompressed sizes are limited by maxBytes
+func (srv *WebServer) Body(w http.ResponseWriter, r *http.Request, maxBytes int64) (io.Reader, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+	return decodeBody(r, maxBytes)
 }
 
-// WriteJSON writes JSON data as a response
-func (srv *WebServer) WriteJSON(w http.ResponseWriter, status int, data any, headers ...http.Header) error {
-	// encode JSON data
-	out, err := json.Marshal(data)
+// Write writes data as a response in format negotiated by Accept header,
+// Not Acceptable error is sent when none of formats is accepted
+func (srv *WebServer) Write(w http.ResponseWriter, r *http.Request, status int, data any, headers ...http.Header) error {
+	codec, err := srv.codecs().Negotiate(r.Header.Get("Accept"))
 	if err != nil {
+		srv.ErrorJSON(w, r, err, http.StatusNotAcceptable)
 		return err
 	}
+	return srv.write(w, r, codec, status, data, headers...)
+}
+
+// Error writes error data as a response in negotiated format, JSON is used when none of formats is accepted
+func (srv *WebServer) Error(w http.ResponseWriter, r *http.Request, err error, status ...int) {
+	codec, negotiateErr := srv.codecs().Negotiate(r.Header.Get("Accept"))
+	if negotiateErr != nil {
+		codec = JSONCodec{}
+	}
+
+	// process status code, by default BadRequest
+	statusCode := http.StatusBadRequest
+	if len(status) > 0 {
+		statusCode = status[0]
+	}
+
+	// prepare and send error response, trace id helps to find failed request
+	payload := errorBody(r, err, statusCode, w.Header().Get(TraceIDHeader))
+	_ = srv.write(w, r, codec, statusCode, payload)
+}
+
+// Negotiate rejects requests with unsupported body format or not acceptable response format
+// before they are processed, so that mutations are not applied without a response
+func (srv *WebServer) Negotiate(next http.Handler) http.Handler {
+	return srv.negotiate(next, srv.codecs, srv.Error)
+}
+
+// NegotiateJSON rejects requests of routes served in JSON only, when request body or accepted response
+// formats are other than JSON
+func (srv *WebServer) NegotiateJSON(next http.Handler) http.Handler {
+	return srv.negotiate(next, func() *Codecs { return jsonCodecs }, srv.ErrorJSON)
+}
+
+// jsonCodecs is a registry of routes served in JSON only
+var jsonCodecs = func() *Codecs {
+	c := &Codecs{}
+	c.Register(JSONCodec{}, "application/json")
+	return c
+}()
+
+// negotiate checks formats of request against registry, unsupported body formats are reported by writeError
+func (srv *WebServer) negotiate(next http.Handler, codecs func() *Codecs, writeError func(http.ResponseWriter, *http.Request, error, ...int)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept")
+		if _, err := codecs().Negotiate(r.Header.Get("Accept")); err != nil {
+			srv.ErrorJSON(w, r, err, http.StatusNotAcceptable)
+			return
+		}
+		if r.ContentLength != 0 {
+			if _, err := codecs().ForContentType(r.Header.Get("Content-Type")); err != nil {
+				writeError(w, r, err, http.StatusUnsupportedMediaType)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// write encodes data before headers are sent, data which could not be encoded is replaced with
+// Internal Server Error in JSON, in envelope of request when it is given
+func (srv *WebServer) write(w http.ResponseWriter, r *http.Request, codec Codec, status int, data any, headers ...http.Header) error {
+	var out bytes.Buffer
+	if err := codec.Encode(&out, data); err != nil {
+		var payload any = JsonResponse{Error: true, Message: ErrorEncode.Error(), TraceID: w.Header().Get(TraceIDHeader)}
+		if r != nil {
+			payload = errorBody(r, ErrorEncode, http.StatusInternalServerError, w.Header().Get(TraceIDHeader))
+		}
+		body, _ := json.Marshal(payload)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write(body)
+		return fmt.Errorf("%w: %w", ErrorEncode, err)
+	}
 
 	// provide headers
 	if len(headers) > 0 {
@@ -57,39 +196,49 @@ func (srv *WebServer) WriteJSON(w http.ResponseWriter, status int, data any, hea
 	}
 
 	// write response
-	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Type", codec.ContentType())
 	w.WriteHeader(status)
-	_, err = w.Write(out)
-	if err != nil {
-		return err
-	}
-	return nil
+	_, err := w.Write(out.Bytes())
+	return err
 }
 
-// ErrorJSON writes JSON error data as a response
-func (srv *WebServer) ErrorJSON(w http.ResponseWriter, err error, status ...int) {
+// WriteJSON writes JSON data as a response, data which could not be encoded is replaced with
+// Internal Server Error
+func (srv *WebServer) WriteJSON(w http.ResponseWriter, status int, data any, headers ...http.Header) error {
+	return srv.write(w, nil, JSONCodec{}, status, data, headers...)
+}
+
+// ErrorJSON writes JSON error data as a response in envelope of request
+func (srv *WebServer) ErrorJSON(w http.ResponseWriter, r *http.Request, err error, status ...int) {
 	// process status code, by default BadRequest
 	statusCode := http.StatusBadRequest
 	if len(status) > 0 {
 		statusCode = status[0]
 	}
 
-	// prepare and send error response
-	var payload JsonResponse
-	payload.Error = true
-	payload.Message = err.Error()
-	_ = srv.WriteJSON(w, statusCode, payload)
+	// prepare and send error response, trace id helps to find failed request
+	payload := errorBody(r, err, statusCode, w.Header().Get(TraceIDHeader))
+	_ = srv.write(w, r, JSONCodec{}, statusCode, payload)
 }
 
-// Serve listens and serves data for webserver
+// Serve listens and serves data for webserver until it is shut down,
+// webserver which is already shut down is not served
 func (srv *WebServer) Serve(routes http.Handler) error {
-	server := &http.Server{
-		Addr:    fmt.Sprintf(":%s", srv.Port),
-		Handler: routes,
+	if srv.server == nil {
+		srv.server = &http.Server{Addr: fmt.Sprintf(":%s", srv.Port)}
 	}
-	err := server.ListenAndServe()
-	if err != nil {
+	srv.server.Handler = routes
+	err := srv.server.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		return err
 	}
 	return nil
 }
+
+// Shutdown gracefully stops webserver waiting for active requests
+func (srv *WebServer) Shutdown(ctx context.Context) error {
+	if srv.server == nil {
+		return nil
+	}
+	return srv.server.Shutdown(ctx)
+}
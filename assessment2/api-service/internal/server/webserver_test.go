@@ -0,0 +1,94 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestWebServer_WriteEncodeError tests that data which could not be encoded is replaced with Internal Server Error
+func TestWebServer_WriteEncodeError(t *testing.T) {
+	t.Parallel()
+	srv := NewWebServer("8080")
+	envelope := ErrorEnvelope(func(err error, status int, traceID string) any {
+		return map[string]any{"error": map[string]any{"status": status, "message": err.Error()}}
+	})
+
+	tests := []struct {
+		name     string
+		accept   string
+		envelope bool
+		write    func(w http.ResponseWriter, r *http.Request) error
+		expected string
+	}{
+		{"json", "application/json", false, func(w http.ResponseWriter, r *http.Request) error {
+			return srv.Write(w, r, http.StatusOK, JsonResponse{Data: make(chan int)})
+		}, `{"error":true,"message":"failed to encode response"}`},
+		{"xml", "application/xml", false, func(w http.ResponseWriter, r *http.Request) error {
+			return srv.Write(w, r, http.StatusOK, JsonResponse{Data: make(chan int)})
+		}, `{"error":true,"message":"failed to encode response"}`},
+		{"envelope", "application/json", true, func(w http.ResponseWriter, r *http.Request) error {
+			return srv.Write(w, r, http.StatusOK, JsonResponse{Data: make(chan int)})
+		}, `{"error":{"message":"failed to encode response","status":500}}`},
+		{"write json", "", false, func(w http.ResponseWriter, r *http.Request) error {
+			return srv.WriteJSON(w, http.StatusCreated, JsonResponse{Data: make(chan int)}, http.Header{"Location": {"/posts/1"}})
+		}, `{"error":true,"message":"failed to encode response"}`},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			req := httptest.NewRequest(http.MethodGet, "/posts", nil)
+			req.Header.Set("Accept", tt.accept)
+			if tt.envelope {
+				req = req.WithContext(WithErrorEnvelope(req.Context(), envelope))
+			}
+			rr := httptest.NewRecorder()
+
+			if err := tt.write(rr, req); !errors.Is(err, ErrorEncode) {
+				t.Errorf("expected ErrorEncode, but got %v", err)
+			}
+			if rr.Code != http.StatusInternalServerError {
+				t.Errorf("expected http.StatusInternalServerError, but got %d", rr.Code)
+			}
+			if contentType := rr.Header().Get("Content-Type"); contentType != "application/json" {
+				t.Errorf("expected application/json, but got %s", contentType)
+			}
+			if location := rr.Header().Get("Location"); location != "" {
+				t.Errorf("expected no Location header, but got %s", location)
+			}
+			if body := strings.TrimSpace(rr.Body.String()); body != tt.expected {
+				t.Errorf("expected %s, but got %s", tt.expected, body)
+			}
+		})
+	}
+}
+
+// TestWebServer_Shutdown tests that webserver shut down right after it is started stops serving
+func TestWebServer_Shutdown(t *testing.T) {
+	t.Parallel()
+	srv := NewWebServer("0")
+	errs := make(chan error, 1)
+	go func() {
+		errs <- srv.Serve(http.NotFoundHandler())
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-errs:
+		if err != nil {
+			t.Errorf("expected no error, but got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("expected webserver to stop serving")
+	}
+}
@@ -2,40 +2,70 @@ package store
 
 import (
 	"api-service/internal/domain"
+	"api-service/internal/logging"
 	"context"
 	"encoding/json"
+	"errors"
 	"io"
+	"log/slog"
+	"maps"
 	"os"
+	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
+	"time"
 )
 
 type FileData struct {
-	Posts []PostEntry `json:"posts"`
+	Posts  []PostEntry    `json:"posts"`
+	Outbox []OutboxRecord `json:"outbox,omitempty"` // unpublished changes saved with snapshot
 }
 
 // MemoryPostStore allows to store and retrieve posts
 type MemoryPostStore struct {
+	mu            sync.RWMutex
 	collection    map[int]PostEntry
 	autoincrement int
+	observers     []ChangeObserver
+
+	outboxEnabled  bool
+	outbox         []OutboxRecord
+	outboxSequence uint64
 }
 
-// NewMemoryPostStore creates a new implementation of posts store
+// NewMemoryPostStore creates a new implementation of posts store, initialized from file when it is specified
 func NewMemoryPostStore(initFile string) (*MemoryPostStore, error) {
-	file, err := os.Open(initFile)
+	s := &MemoryPostStore{
+		collection: make(map[int]PostEntry),
+	}
+	if initFile == "" {
+		return s, nil
+	}
+	err := s.Load(initFile)
 	if err != nil {
 		return nil, err
 	}
+	return s, nil
+}
+
+// Load replaces content of the store with posts from file, posts missing in the file are notified as deleted,
+// observers are not notified of loaded posts; ids of dropped posts are not reused by inserts
+func (s *MemoryPostStore) Load(initFile string) error {
+	file, err := os.Open(initFile)
+	if err != nil {
+		return err
+	}
 	defer file.Close()
 
 	bytes, err := io.ReadAll(file)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	var data FileData
 	err = json.Unmarshal(bytes, &data)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	var collection = make(map[int]PostEntry)
@@ -44,16 +74,51 @@ func NewMemoryPostStore(initFile string) (*MemoryPostStore, error) {
 		if post.ID > maxID {
 			maxID = post.ID
 		}
+		// seed data could have no versions
+		if post.Version < 1 {
+			post.Version = 1
+		}
 		collection[post.ID] = post
 	}
-	return &MemoryPostStore{
-		collection:    collection,
-		autoincrement: maxID,
-	}, nil
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dropped := make([]int, 0)
+	for id := range s.collection {
+		if _, ok := collection[id]; !ok {
+			dropped = append(dropped, id)
+		}
+	}
+	sort.Ints(dropped)
+	for _, id := range dropped {
+		doc := s.collection[id]
+		doc.Version++
+		s.notify(context.Background(), ChangeDeleted, doc)
+	}
+	s.collection = collection
+	s.autoincrement = max(s.autoincrement, maxID)
+	// unpublished changes of snapshot are added to pending ones
+	pending := make(map[string]bool, len(s.outbox))
+	for _, record := range s.outbox {
+		pending[record.ID] = true
+	}
+	for _, record := range data.Outbox {
+		if !pending[record.ID] {
+			s.outbox = append(s.outbox, record)
+		}
+		s.outboxSequence = max(s.outboxSequence, record.Sequence)
+	}
+	sort.SliceStable(s.outbox, func(i, j int) bool {
+		return s.outbox[i].Sequence < s.outbox[j].Sequence
+	})
+	return nil
 }
 
 // Get fetch the list of posts according specified criteria (inc pagination)
 func (s *MemoryPostStore) Get(ctx context.Context, title string, page int, limit int) (*[]domain.Post, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
 	// filter data
 	titleLower := strings.ToLower(title)
 	arr := make([]domain.Post, 0, len(s.collection))
@@ -76,11 +141,15 @@ func (s *MemoryPostStore) Get(ctx context.Context, title string, page int, limit
 		end = len(arr)
 	}
 	paginated := arr[start:end]
+	logging.FromContext(ctx).Debug("posts fetched", slog.Int("count", len(paginated)))
 	return &paginated, nil
 }
 
 // GetOne fetch the one post according to specified id
 func (s *MemoryPostStore) GetOne(ctx context.Context, id int) (*domain.Post, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
 	doc, ok := s.collection[id]
 	if !ok {
 		return &domain.Post{}, domain.ErrorPostNotFound
@@ -89,42 +158,340 @@ func (s *MemoryPostStore) GetOne(ctx context.Context, id int) (*domain.Post, err
 	return &post, nil
 }
 
+// Scan returns up to limit posts with ids greater than afterID in the order of ids, ids are probed one by one
+// while they are dense, otherwise ids after cursor are collected and sorted
+func (s *MemoryPostStore) Scan(ctx context.Context, afterID int, limit int) ([]domain.Post, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	afterID = max(afterID, 0)
+	posts := make([]domain.Post, 0, min(limit, len(s.collection)))
+	if s.autoincrement-afterID <= 2*len(s.collection) {
+		for id := afterID + 1; id <= s.autoincrement && len(posts) < limit; id++ {
+			if doc, ok := s.collection[id]; ok {
+				posts = append(posts, doc.toDomain())
+			}
+		}
+		return posts, nil
+	}
+
+	ids := make([]int, 0, len(s.collection))
+	for id := range s.collection {
+		if id > afterID {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	for _, id := range ids[:min(limit, len(ids))] {
+		doc := s.collection[id]
+		posts = append(posts, doc.toDomain())
+	}
+	return posts, nil
+}
+
 func (s *MemoryPostStore) Insert(ctx context.Context, post domain.Post) (int, error) {
+	format, err := domain.NormalizeFormat(post.Format)
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	// increase ID counter
 	s.autoincrement++
 	// construct document structure
+	now := time.Now().UTC()
 	doc := PostEntry{
 		ID:      s.autoincrement,
 		Title:   post.Title,
 		Content: post.Content,
 		Author:  post.Author,
+		Owner:   post.Owner,
+		Version: 1,
+		Format:  format,
+		Created: now,
+		Updated: now,
 	}
 	// insert document into storage
 	s.collection[doc.ID] = doc
+	s.notify(ctx, ChangeCreated, doc)
+	logging.FromContext(ctx).Debug("post inserted", slog.Int("id", doc.ID))
 	return doc.ID, nil
 }
 
 func (s *MemoryPostStore) Update(ctx context.Context, id int, post domain.Post) error {
+	// empty format keeps format of the post
+	format := post.Format
+	if format != "" {
+		var err error
+		if format, err = domain.NormalizeFormat(format); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	// check id exists
 	doc, ok := s.collection[id]
 	if !ok {
 		return domain.ErrorPostNotFound
 	}
-	// update document
+	// update document, ownership is preserved
 	doc.Title = post.Title
 	doc.Content = post.Content
 	doc.Author = post.Author
+	if format != "" {
+		doc.Format = format
+	}
+	doc.Version++
+	doc.Updated = time.Now().UTC()
 	s.collection[id] = doc
+	s.notify(ctx, ChangeUpdated, doc)
+	logging.FromContext(ctx).Debug("post updated", slog.Int("id", id))
 	return nil
 }
 
 func (s *MemoryPostStore) Delete(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	// check id exists
-	_, ok := s.collection[id]
+	doc, ok := s.collection[id]
 	if !ok {
 		return domain.ErrorPostNotFound
 	}
 	// delete document
 	delete(s.collection, id)
+	doc.Version++
+	s.notify(ctx, ChangeDeleted, doc)
+	logging.FromContext(ctx).Debug("post deleted", slog.Int("id", id))
+	return nil
+}
+
+// Put inserts post with its id or replaces existing post, ownership is preserved unless post has an owner
+func (s *MemoryPostStore) Put(ctx context.Context, post domain.Post) (bool, error) {
+	if post.ID <= 0 {
+		return false, domain.ErrorInvalidPostID
+	}
+	format, err := domain.NormalizeFormat(post.Format)
+	if err != nil {
+		return false, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	doc, exists := s.collection[post.ID]
+	owner := post.Owner
+	if owner == "" {
+		owner = doc.Owner
+	}
+	// creation time of replaced post is preserved
+	now := time.Now().UTC()
+	created := doc.Created
+	if !exists {
+		created = now
+	}
+	doc = PostEntry{
+		ID:      post.ID,
+		Title:   post.Title,
+		Content: post.Content,
+		Author:  post.Author,
+		Owner:   owner,
+		Version: doc.Version + 1,
+		Format:  format,
+		Created: created,
+		Updated: now,
+	}
+	s.collection[post.ID] = doc
+	if exists {
+		s.notify(ctx, ChangeUpdated, doc)
+	} else {
+		s.notify(ctx, ChangeCreated, doc)
+	}
+	// generated ids must not collide with stored ones
+	if post.ID > s.autoincrement {
+		s.autoincrement = post.ID
+	}
+	logging.FromContext(ctx).Debug("post stored", slog.Int("id", post.ID), slog.Bool("created", !exists))
+	return !exists, nil
+}
+
+// WithTx runs fn with a copy of the store which replaces store content when fn succeeds,
+// other operations wait until transaction is finished, so fn must use only the provided store
+func (s *MemoryPostStore) WithTx(ctx context.Context, fn func(tx PostStore) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// changes of transaction are notified only when it is committed
+	type txChange struct {
+		ctx    context.Context
+		change Change
+	}
+	var changes []txChange
+	tx := &MemoryPostStore{
+		collection:     maps.Clone(s.collection),
+		autoincrement:  s.autoincrement,
+		outboxEnabled:  s.outboxEnabled,
+		outboxSequence: s.outboxSequence,
+		observers: []ChangeObserver{func(ctx context.Context, change Change) {
+			changes = append(changes, txChange{ctx: ctx, change: change})
+		}},
+	}
+	err := fn(tx)
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		return err
+	}
+	// outbox records are committed together with changes
+	s.collection = tx.collection
+	s.autoincrement = tx.autoincrement
+	s.outbox = append(s.outbox, tx.outbox...)
+	s.outboxSequence = tx.outboxSequence
+	for _, c := range changes {
+		for _, observer := range s.observers {
+			observer(c.ctx, c.change)
+		}
+	}
+	return nil
+}
+
+// Observe registers observer of post changes, observers are called while the store is locked,
+// so they receive changes in the order they are applied
+func (s *MemoryPostStore) Observe(observer ChangeObserver) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.observers = append(s.observers, observer)
+}
+
+// notify writes outbox record of a change and passes the change to observers
+func (s *MemoryPostStore) notify(ctx context.Context, changeType ChangeType, doc PostEntry) {
+	change := Change{Type: changeType, Post: doc.toDomain()}
+	if s.outboxEnabled {
+		s.outboxSequence++
+		s.outbox = append(s.outbox, newOutboxRecord(s.outboxSequence, change))
+	}
+	for _, observer := range s.observers {
+		observer(ctx, change)
+	}
+}
+
+// EnableOutbox starts writing outbox records of changes, records are kept until they are acknowledged,
+// so outbox should be enabled only when a relay publishes them
+func (s *MemoryPostStore) EnableOutbox() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.outboxEnabled = true
+}
+
+// Pending returns up to limit unacknowledged outbox records in the order of changes
+func (s *MemoryPostStore) Pending(ctx context.Context, limit int) ([]OutboxRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	records := s.outbox[:min(limit, len(s.outbox))]
+	return append([]OutboxRecord(nil), records...), nil
+}
+
+// Ack removes published outbox records
+func (s *MemoryPostStore) Ack(ctx context.Context, ids []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	acked := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		acked[id] = true
+	}
+	outbox := s.outbox[:0]
+	for _, record := range s.outbox {
+		if !acked[record.ID] {
+			outbox = append(outbox, record)
+		}
+	}
+	clear(s.outbox[len(outbox):])
+	s.outbox = outbox
+	return nil
+}
+
+// Capabilities reports that changes made with WithTx are applied atomically
+func (s *MemoryPostStore) Capabilities() Capabilities {
+	return Capabilities{Transactions: true}
+}
+
+// Stats returns statistics of the store
+func (s *MemoryPostStore) Stats(ctx context.Context) (Stats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return Stats{
+		Posts:         len(s.collection),
+		Autoincrement: s.autoincrement,
+		Outbox:        len(s.outbox),
+	}, nil
+}
+
+// Check checks that the store is able to serve requests
+func (s *MemoryPostStore) Check(ctx context.Context) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.collection == nil {
+		return errors.New("store is not initialized")
+	}
+	return ctx.Err()
+}
+
+// Snapshot saves content of the store into a file in the same format as init file,
+// file is replaced atomically so readers never see partially written snapshot
+func (s *MemoryPostStore) Snapshot(ctx context.Context, path string) error {
+	s.mu.RLock()
+	data := FileData{Posts: make([]PostEntry, 0, len(s.collection))}
+	for _, doc := range s.collection {
+		data.Posts = append(data.Posts, doc)
+	}
+	data.Outbox = append(data.Outbox, s.outbox...)
+	s.mu.RUnlock()
+	sort.Slice(data.Posts, func(i, j int) bool {
+		return data.Posts[i].ID < data.Posts[j].ID
+	})
+
+	bytes, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(bytes)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Compact rebuilds collection, maps do not release memory of deleted entries
+func (s *MemoryPostStore) Compact(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	collection := make(map[int]PostEntry, len(s.collection))
+	for id, doc := range s.collection {
+		collection[id] = doc
+	}
+	s.collection = collection
 	return nil
 }
@@ -0,0 +1,328 @@
+package store
+
+import (
+	"api-service/internal/domain"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// TestMemoryPostStore_Outbox tests that changes are notified to observers and written to outbox until acknowledged
+func TestMemoryPostStore_Outbox(t *testing.T) {
+	t.Parallel()
+	s, _ := NewMemoryPostStore("")
+	ctx := context.Background()
+
+	var changes []Change
+	s.Observe(func(ctx context.Context, change Change) {
+		changes = append(changes, change)
+	})
+
+	// records are not written until outbox is enabled
+	id, _ := s.Insert(ctx, domain.Post{Title: "Title"})
+	if pending, _ := s.Pending(ctx, 10); len(pending) != 0 {
+		t.Fatalf("expected no records, but got %+v", pending)
+	}
+
+	s.EnableOutbox()
+	_ = s.Update(ctx, id, domain.Post{Title: "Updated"})
+	_ = s.Delete(ctx, id)
+	_, _ = s.Put(ctx, domain.Post{ID: id, Title: "Restored"})
+
+	expected := []struct {
+		typ     ChangeType
+		title   string
+		version int
+	}{
+		{ChangeCreated, "Title", 1},
+		{ChangeUpdated, "Updated", 2},
+		{ChangeDeleted, "Updated", 3},
+		{ChangeCreated, "Restored", 1},
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("expected %d changes, but got %+v", len(expected), changes)
+	}
+	for i, e := range expected {
+		if changes[i].Type != e.typ || changes[i].Post.Title != e.title || changes[i].Post.Version != e.version {
+			t.Errorf("expected change %+v, but got %+v", e, changes[i])
+		}
+	}
+
+	// records are written in the order of changes with increasing sequences
+	pending, _ := s.Pending(ctx, 10)
+	if len(pending) != 3 {
+		t.Fatalf("expected 3 records, but got %+v", pending)
+	}
+	for i, record := range pending {
+		if record.Sequence != uint64(i+1) || record.Type != expected[i+1].typ || record.ID == "" {
+			t.Errorf("unexpected record %+v", record)
+		}
+	}
+	if limited, _ := s.Pending(ctx, 2); len(limited) != 2 || limited[0].ID != pending[0].ID {
+		t.Errorf("expected the first 2 records, but got %+v", limited)
+	}
+
+	// acknowledged records are removed, unknown ids are ignored
+	_ = s.Ack(ctx, []string{pending[0].ID, pending[2].ID, "unknown"})
+	if left, _ := s.Pending(ctx, 10); len(left) != 1 || left[0].ID != pending[1].ID {
+		t.Errorf("expected the second record, but got %+v", left)
+	}
+	if stats, _ := s.Stats(ctx); stats.Outbox != 1 {
+		t.Errorf("expected 1 unpublished record, but got %d", stats.Outbox)
+	}
+}
+
+// TestMemoryPostStore_Scan tests iteration of posts by id cursor with dense and sparse ids
+func TestMemoryPostStore_Scan(t *testing.T) {
+	t.Parallel()
+	s, _ := NewMemoryPostStore("")
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		_, _ = s.Insert(ctx, domain.Post{Title: "Title"})
+	}
+	_ = s.Delete(ctx, 2)
+	_, _ = s.Put(ctx, domain.Post{ID: 1000, Title: "Sparse"})
+
+	tests := []struct {
+		afterID  int
+		limit    int
+		expected []int
+	}{
+		{0, 2, []int{1, 3}},
+		{3, 2, []int{4, 5}},
+		{5, 10, []int{1000}},
+		{-1, 10, []int{1, 3, 4, 5, 1000}},
+		{1000, 10, nil},
+		{0, 0, nil},
+	}
+	for _, tt := range tests {
+		posts, err := s.Scan(ctx, tt.afterID, tt.limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []int
+		for _, post := range posts {
+			ids = append(ids, post.ID)
+		}
+		if !slices.Equal(ids, tt.expected) {
+			t.Errorf("scan after %d limited to %d: expected %v, but got %v", tt.afterID, tt.limit, tt.expected, ids)
+		}
+	}
+}
+
+// TestMemoryPostStore_WithTx tests that changes of failed transactions are rolled back
+// and changes of committed transactions are notified after commit
+func TestMemoryPostStore_WithTx(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		cancelled bool
+		err       error
+		committed bool
+	}{
+		{"committed", false, nil, true},
+		{"failed", false, errors.New("failure"), false},
+		{"cancelled", true, nil, false},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			s, _ := NewMemoryPostStore("")
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			_, _ = s.Insert(ctx, domain.Post{Title: "Title"})
+			s.EnableOutbox()
+			var changes []Change
+			s.Observe(func(ctx context.Context, change Change) {
+				changes = append(changes, change)
+			})
+
+			err := s.WithTx(ctx, func(tx PostStore) error {
+				if _, err := tx.Insert(ctx, domain.Post{Title: "Inserted"}); err != nil {
+					return err
+				}
+				if err := tx.Update(ctx, 1, domain.Post{Title: "Updated"}); err != nil {
+					return err
+				}
+				// changes are not visible outside of transaction until it is committed
+				if len(changes) != 0 {
+					t.Errorf("expected no changes before commit, but got %+v", changes)
+				}
+				if tt.cancelled {
+					cancel()
+				}
+				return tt.err
+			})
+			if (err == nil) != tt.committed {
+				t.Fatalf("expected committed %v, but got error %v", tt.committed, err)
+			}
+
+			post, _ := s.GetOne(context.Background(), 1)
+			pending, _ := s.Pending(context.Background(), 10)
+			stats, _ := s.Stats(context.Background())
+			if tt.committed {
+				if post.Title != "Updated" || post.Version != 2 || len(changes) != 2 || len(pending) != 2 || stats.Autoincrement != 2 {
+					t.Errorf("expected committed changes, but got %+v, changes %+v, records %+v, stats %+v", post, changes, pending, stats)
+				}
+				return
+			}
+			// content, autoincrement and outbox are restored
+			if post.Title != "Title" || post.Version != 1 || len(changes) != 0 || len(pending) != 0 || stats.Autoincrement != 1 || stats.Posts != 1 {
+				t.Errorf("expected rolled back changes, but got %+v, changes %+v, records %+v, stats %+v", post, changes, pending, stats)
+			}
+			if id, _ := s.Insert(context.Background(), domain.Post{Title: "Next"}); id != 2 {
+				t.Errorf("expected id 2 after rollback, but got %d", id)
+			}
+			if pending, _ := s.Pending(context.Background(), 10); len(pending) != 1 || pending[0].Sequence != 1 {
+				t.Errorf("expected the first outbox record after rollback, but got %+v", pending)
+			}
+		})
+	}
+}
+
+// TestMemoryPostStore_Put tests that put creates posts with their ids and bumps versions of replaced posts
+func TestMemoryPostStore_Put(t *testing.T) {
+	t.Parallel()
+	s, _ := NewMemoryPostStore("")
+	ctx := context.Background()
+
+	if _, err := s.Put(ctx, domain.Post{ID: 0, Title: "Title"}); !errors.Is(err, domain.ErrorInvalidPostID) {
+		t.Errorf("expected invalid id error, but got %v", err)
+	}
+
+	created, err := s.Put(ctx, domain.Post{ID: 5, Title: "Title", Owner: "author-1"})
+	if err != nil || !created {
+		t.Fatalf("expected created post, but got %v, %v", created, err)
+	}
+	first, _ := s.GetOne(ctx, 5)
+
+	// replaced post keeps owner and creation time, its version is bumped
+	created, err = s.Put(ctx, domain.Post{ID: 5, Title: "Replaced", Format: domain.FormatMarkdown})
+	if err != nil || created {
+		t.Fatalf("expected replaced post, but got %v, %v", created, err)
+	}
+	post, _ := s.GetOne(ctx, 5)
+	if post.Title != "Replaced" || post.Version != first.Version+1 || post.Owner != "author-1" || !post.Created.Equal(first.Created) || post.Format != domain.FormatMarkdown {
+		t.Errorf("expected replaced post of version %d, but got %+v", first.Version+1, post)
+	}
+
+	// generated ids follow ids of put posts
+	if id, _ := s.Insert(ctx, domain.Post{Title: "Inserted"}); id != 6 {
+		t.Errorf("expected id 6, but got %d", id)
+	}
+	_, _ = s.Put(ctx, domain.Post{ID: 3, Title: "Lower"})
+	if id, _ := s.Insert(ctx, domain.Post{Title: "Inserted"}); id != 7 {
+		t.Errorf("expected id 7, but got %d", id)
+	}
+}
+
+// TestMemoryPostStore_SnapshotLoad tests that snapshot restores posts, autoincrement and unpublished outbox records
+func TestMemoryPostStore_SnapshotLoad(t *testing.T) {
+	t.Parallel()
+	s, _ := NewMemoryPostStore("")
+	s.EnableOutbox()
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		_, _ = s.Insert(ctx, domain.Post{Title: "Title", Owner: "author-1"})
+	}
+	_ = s.Update(ctx, 2, domain.Post{Title: "Updated", Format: domain.FormatMarkdown})
+	pending, _ := s.Pending(ctx, 10)
+	_ = s.Ack(ctx, []string{pending[0].ID})
+	pending = pending[1:]
+
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	if err := s.Snapshot(ctx, path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := NewMemoryPostStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	posts, _ := s.Scan(ctx, 0, 10)
+	loadedPosts, _ := loaded.Scan(ctx, 0, 10)
+	if len(loadedPosts) != len(posts) {
+		t.Fatalf("expected %d posts, but got %+v", len(posts), loadedPosts)
+	}
+	for i := range posts {
+		// timestamps are compared as instants, they lose monotonic clock in file
+		expected, actual := posts[i], loadedPosts[i]
+		sameTimes := actual.Created.Equal(expected.Created) && actual.Updated.Equal(expected.Updated)
+		actual.Created, actual.Updated = expected.Created, expected.Updated
+		if !sameTimes || actual != expected {
+			t.Errorf("expected post %+v, but got %+v", posts[i], loadedPosts[i])
+		}
+	}
+	if stats, _ := loaded.Stats(ctx); stats.Autoincrement != 3 || stats.Outbox != len(pending) {
+		t.Errorf("expected autoincrement 3 and %d records, but got %+v", len(pending), stats)
+	}
+
+	// unpublished records are kept with their ids, loading snapshot again does not duplicate them
+	_ = loaded.Load(path)
+	loadedPending, _ := loaded.Pending(ctx, 10)
+	if len(loadedPending) != len(pending) {
+		t.Fatalf("expected %d records, but got %+v", len(pending), loadedPending)
+	}
+	for i := range pending {
+		if loadedPending[i].ID != pending[i].ID || loadedPending[i].Sequence != pending[i].Sequence || loadedPending[i].Post.Title != pending[i].Post.Title {
+			t.Errorf("expected record %+v, but got %+v", pending[i], loadedPending[i])
+		}
+	}
+
+	// sequence of new records continues after loaded ones
+	loaded.EnableOutbox()
+	if id, _ := loaded.Insert(ctx, domain.Post{Title: "Next"}); id != 4 {
+		t.Errorf("expected id 4, but got %d", id)
+	}
+	loadedPending, _ = loaded.Pending(ctx, 10)
+	if last := loadedPending[len(loadedPending)-1]; last.Sequence != pending[len(pending)-1].Sequence+1 {
+		t.Errorf("expected sequence %d, but got %d", pending[len(pending)-1].Sequence+1, last.Sequence)
+	}
+}
+
+// TestMemoryPostStore_Reload tests that reload notifies dropped posts as deleted and does not reuse their ids
+func TestMemoryPostStore_Reload(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "seed.json")
+	if err := os.WriteFile(path, []byte(`{"posts": [{"id": 1, "title": "Seed 1"}, {"id": 2, "title": "Seed 2"}]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewMemoryPostStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	_, _ = s.Insert(ctx, domain.Post{Title: "Inserted"})
+	_, _ = s.Insert(ctx, domain.Post{Title: "Inserted"})
+	_ = s.Update(ctx, 1, domain.Post{Title: "Updated"})
+
+	var changes []Change
+	s.Observe(func(ctx context.Context, change Change) {
+		changes = append(changes, change)
+	})
+	if err := s.Load(path); err != nil {
+		t.Fatal(err)
+	}
+
+	// seed posts are replaced without changes, inserted posts are deleted
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 changes, but got %+v", changes)
+	}
+	for i, id := range []int{3, 4} {
+		if changes[i].Type != ChangeDeleted || changes[i].Post.ID != id || changes[i].Post.Version != 2 {
+			t.Errorf("expected deletion of post %d with version 2, but got %+v", id, changes[i])
+		}
+	}
+	if post, _ := s.GetOne(ctx, 1); post.Title != "Seed 1" {
+		t.Errorf("expected seed post, but got %+v", post)
+	}
+	if id, _ := s.Insert(ctx, domain.Post{Title: "Next"}); id != 5 {
+		t.Errorf("expected id 5, but got %d", id)
+	}
+}
@@ -93,3 +93,40 @@ func (mr *MockPostStoreMockRecorder) Delete(ctx interface{}, id int) *gomock.Cal
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockPostStore)(nil).Delete), ctx, id)
 }
+
+func (m *MockPostStore) Put(ctx context.Context, post domain.Post) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Put", ctx, post)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *MockPostStoreMockRecorder) Put(ctx, post interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Put", reflect.TypeOf((*MockPostStore)(nil).Put), ctx, post)
+}
+
+func (m *MockPostStore) WithTx(ctx context.Context, fn func(tx PostStore) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WithTx", ctx, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (mr *MockPostStoreMockRecorder) WithTx(ctx, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithTx", reflect.TypeOf((*MockPostStore)(nil).WithTx), ctx, fn)
+}
+
+func (m *MockPostStore) Capabilities() Capabilities {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Capabilities")
+	ret0, _ := ret[0].(Capabilities)
+	return ret0
+}
+
+func (mr *MockPostStoreMockRecorder) Capabilities() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Capabilities", reflect.TypeOf((*MockPostStore)(nil).Capabilities))
+}
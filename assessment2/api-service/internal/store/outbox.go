@@ -0,0 +1,41 @@
+package store
+
+import (
+	"api-service/internal/domain"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// OutboxRecord represent event of a post change written atomically with the change,
+// id is unique across restarts so consumers could drop duplicates, sequence orders records of the store
+type OutboxRecord struct {
+	ID       string      `json:"id"`
+	Sequence uint64      `json:"sequence"`
+	Type     ChangeType  `json:"type"`
+	Post     domain.Post `json:"post"`
+	Time     time.Time   `json:"time"`
+}
+
+// Outbox is implemented by stores writing record of every change in the same transaction as the change,
+// records are kept until they are acknowledged, so a crash between change and publishing does not lose events
+type Outbox interface {
+	// Pending returns up to limit unacknowledged records in the order of changes
+	Pending(ctx context.Context, limit int) ([]OutboxRecord, error)
+	// Ack removes published records
+	Ack(ctx context.Context, ids []string) error
+}
+
+// newOutboxRecord creates record of a change with random id
+func newOutboxRecord(sequence uint64, change Change) OutboxRecord {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return OutboxRecord{
+		ID:       hex.EncodeToString(b),
+		Sequence: sequence,
+		Type:     change.Type,
+		Post:     change.Post,
+		Time:     time.Now().UTC(),
+	}
+}
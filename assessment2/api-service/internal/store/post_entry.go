@@ -2,6 +2,7 @@ package store
 
 import (
 	"api-service/internal/domain"
+	"time"
 )
 
 // PostEntry represent database document structure
@@ -10,14 +11,29 @@ type PostEntry struct {
 	Title   string `json:"title"`
 	Content string `json:"content"`
 	Author  string `json:"author"`
+	Owner   string `json:"owner,omitempty"`
+	Version int    `json:"version,omitempty"`
+	Format  string `json:"format,omitempty"` // seed data without format is plain
+
+	Created time.Time `json:"created,omitzero"`
+	Updated time.Time `json:"updated,omitzero"`
 }
 
 // convert entry to domain structure
 func (p *PostEntry) toDomain() domain.Post {
+	format := p.Format
+	if format == "" {
+		format = domain.FormatPlain
+	}
 	return domain.Post{
 		ID:      p.ID,
 		Title:   p.Title,
 		Content: p.Content,
 		Author:  p.Author,
+		Owner:   p.Owner,
+		Version: p.Version,
+		Format:  format,
+		Created: p.Created,
+		Updated: p.Updated,
 	}
 }
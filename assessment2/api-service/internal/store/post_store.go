@@ -3,8 +3,12 @@ package store
 import (
 	"api-service/internal/domain"
 	"context"
+	"errors"
 )
 
+// ErrorScanNotSupported is returned when posts of a store could not be iterated by id
+var ErrorScanNotSupported = errors.New("store does not support scanning of posts")
+
 // PostStore represent interface for blog storage
 type PostStore interface {
 	Get(ctx context.Context, title string, page int, limit int) (*[]domain.Post, error)
@@ -12,4 +16,86 @@ type PostStore interface {
 	Insert(ctx context.Context, post domain.Post) (int, error)
 	Update(ctx context.Context, id int, post domain.Post) error
 	Delete(ctx context.Context, id int) error
+	// Put inserts post with its id or replaces existing post, it reports whether post was created
+	Put(ctx context.Context, post domain.Post) (bool, error)
+	// WithTx runs fn with a transactional view of the store, changes are applied only when fn succeeds,
+	// stores without transactions support run fn directly, so changes made before a failure are kept
+	WithTx(ctx context.Context, fn func(tx PostStore) error) error
+	// Capabilities reports optional features supported by the store
+	Capabilities() Capabilities
+}
+
+// Capabilities describe optional features of a post store
+type Capabilities struct {
+	Transactions bool `json:"transactions"` // WithTx applies changes atomically
+}
+
+// ChangeType names kind of post change
+type ChangeType string
+
+// Post change types
+const (
+	ChangeCreated ChangeType = "created"
+	ChangeUpdated ChangeType = "updated"
+	ChangeDeleted ChangeType = "deleted"
+)
+
+// Change represent applied change of a post, deleted post has version following its last version
+type Change struct {
+	Type ChangeType
+	Post domain.Post
+}
+
+// ChangeObserver is notified about applied changes in the order they are applied, it must not block
+type ChangeObserver func(ctx context.Context, change Change)
+
+// Observable is implemented by stores notifying observers about changes of posts,
+// changes made in transactions are notified when transaction is committed
+type Observable interface {
+	Observe(observer ChangeObserver)
+}
+
+// Stats represent post store statistics
+type Stats struct {
+	Posts         int `json:"posts"`
+	Autoincrement int `json:"autoincrement"`
+	Outbox        int `json:"outbox,omitempty"` // unpublished outbox records
+}
+
+// StatsProvider is implemented by stores able to report their statistics
+type StatsProvider interface {
+	Stats(ctx context.Context) (Stats, error)
+}
+
+// Snapshotter is implemented by stores able to save their content into a file
+type Snapshotter interface {
+	Snapshot(ctx context.Context, path string) error
+}
+
+// Loader is implemented by stores able to replace their content with data from a file,
+// loaded posts are not notified to observers and are not written to outbox, posts missing in the file are
+// notified as deleted and ids are not reused after reload
+type Loader interface {
+	Load(initFile string) error
+}
+
+// Scanner is implemented by stores able to iterate posts by id cursor, iteration is not shifted
+// by posts added or deleted meanwhile
+type Scanner interface {
+	// Scan returns up to limit posts with ids greater than afterID in the order of ids
+	Scan(ctx context.Context, afterID int, limit int) ([]domain.Post, error)
+}
+
+// Scan iterates posts of store implementing Scanner, decorators implement it when decorated store does
+func Scan(ctx context.Context, st PostStore, afterID int, limit int) ([]domain.Post, error) {
+	scanner, ok := st.(Scanner)
+	if !ok {
+		return nil, ErrorScanNotSupported
+	}
+	return scanner.Scan(ctx, afterID, limit)
+}
+
+// Compactor is implemented by stores able to release storage left by deleted posts
+type Compactor interface {
+	Compact(ctx context.Context) error
 }
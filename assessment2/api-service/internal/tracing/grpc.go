@@ -0,0 +1,115 @@
+package tracing
+
+import (
+	"api-service/internal/logging"
+	"api-service/internal/server"
+	"context"
+	"log/slog"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	grpccodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor starts server span for each unary call continuing trace propagated in metadata,
+// trace id is returned in header metadata and added to call logger
+func UnaryServerInterceptor(provider trace.TracerProvider) grpc.UnaryServerInterceptor {
+	tracer := provider.Tracer(instrumentation)
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, span := startRPC(ctx, tracer, info.FullMethod)
+		defer span.End()
+		resp, err := handler(ctx, req)
+		endRPC(span, err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor starts server span for each streaming call continuing trace propagated in metadata,
+// trace id is returned in header metadata and added to call logger
+func StreamServerInterceptor(provider trace.TracerProvider) grpc.StreamServerInterceptor {
+	tracer := provider.Tracer(instrumentation)
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, span := startRPC(ss.Context(), tracer, info.FullMethod)
+		defer span.End()
+		err := handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+		endRPC(span, err)
+		return err
+	}
+}
+
+// startRPC starts span named by full method of the call
+func startRPC(ctx context.Context, tracer trace.Tracer, method string) (context.Context, trace.Span) {
+	// continue trace propagated in traceparent metadata
+	md, _ := metadata.FromIncomingContext(ctx)
+	ctx = otel.GetTextMapPropagator().Extract(ctx, metadataCarrier(md))
+	service, name, _ := strings.Cut(strings.TrimPrefix(method, "/"), "/")
+	ctx, span := tracer.Start(ctx, strings.TrimPrefix(method, "/"),
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			semconv.RPCSystemGRPC,
+			semconv.RPCService(service),
+			semconv.RPCMethod(name),
+			attribute.String("request_id", logging.RequestIDFromContext(ctx)),
+		),
+	)
+
+	// expose trace id to clients and logs
+	spanContext := span.SpanContext()
+	if spanContext.HasTraceID() {
+		_ = grpc.SetHeader(ctx, metadata.Pairs(server.TraceIDHeader, spanContext.TraceID().String()))
+		ctx = logging.WithLogger(ctx, logging.FromContext(ctx).With(
+			slog.String("trace_id", spanContext.TraceID().String()),
+			slog.String("span_id", spanContext.SpanID().String()),
+		))
+	}
+	return ctx, span
+}
+
+// endRPC records status code of the call, server failures mark span as failed
+func endRPC(span trace.Span, err error) {
+	code := status.Code(err)
+	span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int(int(code)))
+	switch code {
+	case grpccodes.Internal, grpccodes.Unknown, grpccodes.DataLoss, grpccodes.Unavailable:
+		span.SetStatus(codes.Error, code.String())
+	}
+}
+
+// metadataCarrier adapts gRPC metadata to propagation of trace context
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	if values := metadata.MD(c).Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func (c metadataCarrier) Set(key string, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// serverStream replaces context of stream with traced one
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
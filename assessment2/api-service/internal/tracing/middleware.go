@@ -0,0 +1,68 @@
+package tracing
+
+import (
+	"api-service/internal/logging"
+	"api-service/internal/server"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentation identifies tracer of the service
+const instrumentation = "api-service/internal/tracing"
+
+// Middleware starts server span for each request continuing trace propagated by client,
+// trace id is returned in response headers and added to request logger
+func Middleware(provider trace.TracerProvider) func(http.Handler) http.Handler {
+	tracer := provider.Tracer(instrumentation)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// continue trace propagated in traceparent header
+			ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			ctx, span := tracer.Start(ctx, r.Method,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.HTTPRequestMethodKey.String(r.Method),
+					semconv.URLPath(r.URL.Path),
+					attribute.String("request_id", logging.RequestIDFromContext(r.Context())),
+				),
+			)
+			defer span.End()
+
+			// expose trace id to clients and logs
+			spanContext := span.SpanContext()
+			if spanContext.HasTraceID() {
+				w.Header().Set(server.TraceIDHeader, spanContext.TraceID().String())
+				ctx = logging.WithLogger(ctx, logging.FromContext(ctx).With(
+					slog.String("trace_id", spanContext.TraceID().String()),
+					slog.String("span_id", spanContext.SpanID().String()),
+				))
+			}
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r.WithContext(ctx))
+
+			// span is named by route pattern known only after request is routed
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+				span.SetName(r.Method + " " + rctx.RoutePattern())
+				span.SetAttributes(semconv.HTTPRoute(rctx.RoutePattern()))
+			}
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+		})
+	}
+}
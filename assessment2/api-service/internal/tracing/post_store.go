@@ -0,0 +1,123 @@
+package tracing
+
+import (
+	"api-service/internal/domain"
+	"api-service/internal/store"
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// PostStore is a post store decorator creating a child span for each operation
+type PostStore struct {
+	next   store.PostStore
+	tracer trace.Tracer
+}
+
+// NewPostStore creates a tracing decorator of post store
+func NewPostStore(next store.PostStore, provider trace.TracerProvider) *PostStore {
+	return &PostStore{
+		next:   next,
+		tracer: provider.Tracer(instrumentation),
+	}
+}
+
+// Unwrap returns decorated store
+func (s *PostStore) Unwrap() store.PostStore {
+	return s.next
+}
+
+func (s *PostStore) Get(ctx context.Context, title string, page int, limit int) (*[]domain.Post, error) {
+	ctx, span := s.start(ctx, "PostStore.Get",
+		attribute.String("post.title_filter", title),
+		attribute.Int("page", page),
+		attribute.Int("limit", limit),
+	)
+	posts, err := s.next.Get(ctx, title, page, limit)
+	if err == nil {
+		span.SetAttributes(attribute.Int("posts.count", len(*posts)))
+	}
+	end(span, err)
+	return posts, err
+}
+
+func (s *PostStore) GetOne(ctx context.Context, id int) (*domain.Post, error) {
+	ctx, span := s.start(ctx, "PostStore.GetOne", attribute.Int("post.id", id))
+	post, err := s.next.GetOne(ctx, id)
+	end(span, err)
+	return post, err
+}
+
+func (s *PostStore) Insert(ctx context.Context, post domain.Post) (int, error) {
+	ctx, span := s.start(ctx, "PostStore.Insert")
+	id, err := s.next.Insert(ctx, post)
+	span.SetAttributes(attribute.Int("post.id", id))
+	end(span, err)
+	return id, err
+}
+
+func (s *PostStore) Update(ctx context.Context, id int, post domain.Post) error {
+	ctx, span := s.start(ctx, "PostStore.Update", attribute.Int("post.id", id))
+	err := s.next.Update(ctx, id, post)
+	end(span, err)
+	return err
+}
+
+func (s *PostStore) Delete(ctx context.Context, id int) error {
+	ctx, span := s.start(ctx, "PostStore.Delete", attribute.Int("post.id", id))
+	err := s.next.Delete(ctx, id)
+	end(span, err)
+	return err
+}
+
+func (s *PostStore) Put(ctx context.Context, post domain.Post) (bool, error) {
+	ctx, span := s.start(ctx, "PostStore.Put", attribute.Int("post.id", post.ID))
+	created, err := s.next.Put(ctx, post)
+	span.SetAttributes(attribute.Bool("post.created", created))
+	end(span, err)
+	return created, err
+}
+
+func (s *PostStore) Scan(ctx context.Context, afterID int, limit int) ([]domain.Post, error) {
+	ctx, span := s.start(ctx, "PostStore.Scan",
+		attribute.Int("after_id", afterID),
+		attribute.Int("limit", limit),
+	)
+	posts, err := store.Scan(ctx, s.next, afterID, limit)
+	if err == nil {
+		span.SetAttributes(attribute.Int("posts.count", len(posts)))
+	}
+	end(span, err)
+	return posts, err
+}
+
+// WithTx records transaction span, operations of the transaction are traced as well
+func (s *PostStore) WithTx(ctx context.Context, fn func(tx store.PostStore) error) error {
+	ctx, span := s.start(ctx, "PostStore.WithTx")
+	err := s.next.WithTx(ctx, func(tx store.PostStore) error {
+		return fn(&PostStore{next: tx, tracer: s.tracer})
+	})
+	end(span, err)
+	return err
+}
+
+// Capabilities reports capabilities of decorated store
+func (s *PostStore) Capabilities() store.Capabilities {
+	return s.next.Capabilities()
+}
+
+func (s *PostStore) start(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return s.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindInternal), trace.WithAttributes(attributes...))
+}
+
+// end finishes span recording operation error, missing posts are expected results rather than failures
+func end(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, domain.ErrorPostNotFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,91 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// ServiceName identifies service in traces
+const ServiceName = "api-service"
+
+// Config defines spans exporter: otlp, stdout, file or none
+type Config struct {
+	Exporter string
+	File     string // destination of file exporter
+}
+
+// Provider holds tracer provider and releases its resources on shutdown
+type Provider struct {
+	trace.TracerProvider
+	shutdown func(ctx context.Context) error
+}
+
+// Shutdown flushes pending spans and releases exporter resources
+func (p *Provider) Shutdown(ctx context.Context) error {
+	return p.shutdown(ctx)
+}
+
+// NewProvider creates tracer provider with configured exporter and registers it globally together
+// with W3C trace context propagator, OTLP exporter is configured by standard OTEL_EXPORTER_OTLP_* variables
+func NewProvider(ctx context.Context, config Config) (*Provider, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var closer io.Closer
+	var err error
+	switch strings.ToLower(config.Exporter) {
+	case "", "none":
+		provider := noop.NewTracerProvider()
+		otel.SetTracerProvider(provider)
+		return &Provider{
+			TracerProvider: provider,
+			shutdown:       func(ctx context.Context) error { return nil },
+		}, nil
+	case "otlp":
+		exporter, err = otlptracehttp.New(ctx)
+	case "stdout":
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case "file":
+		var file *os.File
+		file, err = os.OpenFile(config.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		closer = file
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(file))
+	default:
+		return nil, fmt.Errorf("invalid tracing exporter %q", config.Exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(ServiceName))),
+	)
+	otel.SetTracerProvider(provider)
+	return &Provider{
+		TracerProvider: provider,
+		shutdown: func(ctx context.Context) error {
+			err := provider.Shutdown(ctx)
+			if closer != nil {
+				_ = closer.Close()
+			}
+			return err
+		},
+	}, nil
+}
@@ -0,0 +1,70 @@
+package tracing
+
+import (
+	"api-service/internal/domain"
+	"api-service/internal/server"
+	"api-service/internal/store"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/golang/mock/gomock"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const testTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+
+// TestTracing_Request tests server and store spans of a traced request
+func TestTracing_Request(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	ctrl := gomock.NewController(t)
+	next := store.NewMockPostStore(ctrl)
+	next.EXPECT().GetOne(gomock.Any(), 42).Return(&domain.Post{}, domain.ErrorPostNotFound)
+	postStore := NewPostStore(next, provider)
+
+	srv := server.NewWebServer("")
+	mux := chi.NewRouter()
+	mux.Use(Middleware(provider))
+	mux.Get("/v1/posts/{id}", func(w http.ResponseWriter, r *http.Request) {
+		_, err := postStore.GetOne(r.Context(), 42)
+		srv.ErrorJSON(w, r, err, http.StatusNotFound)
+	})
+
+	req, _ := http.NewRequest("GET", "/v1/posts/42", nil)
+	req.Header.Set("traceparent", "00-"+testTraceID+"-00f067aa0ba902b7-01")
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	// trace id is returned to client
+	expectedBody := "{\"error\":true,\"message\":\"post not found\",\"trace_id\":\"" + testTraceID + "\"}"
+	if rr.Header().Get(server.TraceIDHeader) != testTraceID {
+		t.Errorf("expected trace id header, got %q", rr.Header().Get(server.TraceIDHeader))
+	}
+	if rr.Body.String() != expectedBody {
+		t.Errorf("incorrect response body, got %s", rr.Body.String())
+	}
+
+	// store span is a child of server span continuing client trace
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	storeSpan, serverSpan := spans[0], spans[1]
+	if serverSpan.Name() != "GET /v1/posts/{id}" || serverSpan.SpanKind() != trace.SpanKindServer {
+		t.Errorf("unexpected server span %s", serverSpan.Name())
+	}
+	if serverSpan.SpanContext().TraceID().String() != testTraceID {
+		t.Errorf("expected propagated trace id, got %s", serverSpan.SpanContext().TraceID())
+	}
+	if storeSpan.Name() != "PostStore.GetOne" || storeSpan.Parent().SpanID() != serverSpan.SpanContext().SpanID() {
+		t.Errorf("unexpected store span %s", storeSpan.Name())
+	}
+}
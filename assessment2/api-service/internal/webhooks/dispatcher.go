@@ -0,0 +1,314 @@
+package webhooks
+
+import (
+	"api-service/internal/events"
+	"api-service/internal/logging"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// userAgent identifies delivery requests
+const userAgent = "api-service-webhooks/1.0"
+
+// Default dispatcher configuration
+const (
+	DefaultWorkers      = 4
+	DefaultTimeout      = 10 * time.Second
+	DefaultMaxAttempts  = 8
+	DefaultBaseBackoff  = 5 * time.Second
+	DefaultMaxBackoff   = time.Hour
+	DefaultPollInterval = time.Second
+)
+
+// Config defines delivery rules, zero values are replaced by defaults
+type Config struct {
+	Workers      int           // concurrent deliveries
+	Timeout      time.Duration // timeout of a delivery request, claimed deliveries are leased for twice as long
+	MaxAttempts  int           // deliveries are dead-lettered after so many failed tries
+	BaseBackoff  time.Duration // delay after the first failure, doubled after every next one
+	MaxBackoff   time.Duration
+	PollInterval time.Duration // interval of checking for due retries
+	Addresses    AddressPolicy // receivers allowed by default client, only public addresses when empty
+}
+
+// Dispatcher queues post events for matching subscriptions and delivers them with retries
+type Dispatcher struct {
+	store  Store
+	client *http.Client
+	config Config
+	wake   chan struct{}
+	now    func() time.Time
+}
+
+// NewDispatcher creates dispatcher of deliveries queued in the store, when client is nil deliveries are sent
+// by a client connecting only to addresses allowed by configuration
+func NewDispatcher(store Store, client *http.Client, config Config) *Dispatcher {
+	if client == nil {
+		client = NewClient(config.Addresses)
+	}
+	if config.Workers <= 0 {
+		config.Workers = DefaultWorkers
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultTimeout
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = DefaultMaxAttempts
+	}
+	if config.BaseBackoff <= 0 {
+		config.BaseBackoff = DefaultBaseBackoff
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = DefaultMaxBackoff
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = DefaultPollInterval
+	}
+	return &Dispatcher{
+		store:  store,
+		client: client,
+		config: config,
+		wake:   make(chan struct{}, 1),
+		now:    time.Now,
+	}
+}
+
+// Store returns storage of subscriptions and deliveries
+func (d *Dispatcher) Store() Store {
+	return d.store
+}
+
+// CheckURL rejects receiver URLs with addresses which are not allowed by configuration
+func (d *Dispatcher) CheckURL(target *url.URL) error {
+	return d.config.Addresses.CheckURL(target)
+}
+
+// Enqueue creates deliveries of the event for active subscriptions matching its type,
+// redelivered events with deduplication id do not create deliveries again
+func (d *Dispatcher) Enqueue(ctx context.Context, event events.Event) error {
+	return d.Publish(ctx, []events.Event{event})
+}
+
+// Publish enqueues deliveries of events at once, so dispatcher could be used as a sink of outbox relay
+func (d *Dispatcher) Publish(ctx context.Context, batch []events.Event) error {
+	subs, err := d.store.Subscriptions(ctx)
+	if err != nil {
+		return err
+	}
+	now := d.now().UTC()
+	var deliveries []Delivery
+	for _, event := range batch {
+		for _, sub := range subs {
+			if !sub.Matches(event.Type) {
+				continue
+			}
+			id := NewID()
+			if event.DedupID != "" {
+				id = deliveryID(event.DedupID, sub.ID)
+			}
+			deliveries = append(deliveries, Delivery{
+				ID:             id,
+				SubscriptionID: sub.ID,
+				Event:          event,
+				Status:         StatusPending,
+				NextAttempt:    now,
+				Attempts:       []Attempt{},
+				CreatedAt:      now,
+			})
+		}
+	}
+	if err := d.store.Enqueue(ctx, deliveries...); err != nil {
+		return err
+	}
+	if len(deliveries) > 0 {
+		d.notify()
+	}
+	return nil
+}
+
+// deliveryID derives id of delivery from deduplication id of event
+func deliveryID(dedupID string, subscriptionID string) string {
+	sum := sha256.Sum256([]byte(dedupID + ":" + subscriptionID))
+	return hex.EncodeToString(sum[:16])
+}
+
+// Replay queues delivery again with a new set of tries, succeeded and dead-lettered deliveries could be replayed
+func (d *Dispatcher) Replay(ctx context.Context, subscriptionID string, id string) (*Delivery, error) {
+	delivery, err := d.store.Delivery(ctx, subscriptionID, id)
+	if err != nil {
+		return nil, err
+	}
+	delivery.Status = StatusPending
+	delivery.Tries = 0
+	delivery.NextAttempt = d.now().UTC()
+	if err := d.store.SaveDelivery(ctx, *delivery); err != nil {
+		return nil, err
+	}
+	d.notify()
+	return delivery, nil
+}
+
+// ReplayDead queues again all dead-lettered deliveries of the subscription and returns their number
+func (d *Dispatcher) ReplayDead(ctx context.Context, subscriptionID string) (int, error) {
+	dead, err := d.store.Deliveries(ctx, subscriptionID, StatusDead)
+	if err != nil {
+		return 0, err
+	}
+	for _, delivery := range dead {
+		if _, err := d.Replay(ctx, subscriptionID, delivery.ID); err != nil {
+			return 0, err
+		}
+	}
+	return len(dead), nil
+}
+
+// Run delivers due deliveries until context is cancelled, started deliveries are finished before return
+func (d *Dispatcher) Run(ctx context.Context) {
+	logger := logging.FromContext(ctx)
+	ticker := time.NewTicker(d.config.PollInterval)
+	defer ticker.Stop()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	slots := make(chan struct{}, d.config.Workers)
+	for {
+		// claim as many deliveries as there are free workers
+		free := d.config.Workers - len(slots)
+		if free > 0 {
+			due, err := d.store.Claim(ctx, d.now().UTC(), 2*d.config.Timeout, free)
+			if err != nil {
+				logger.Error("failed to claim webhook deliveries", slog.Any("error", err))
+			}
+			for _, delivery := range due {
+				slots <- struct{}{}
+				wg.Add(1)
+				go func(delivery Delivery) {
+					defer wg.Done()
+					defer func() { <-slots }()
+					// deliveries are finished even when dispatcher is stopped
+					d.deliver(context.WithoutCancel(ctx), delivery)
+					d.notify()
+				}(delivery)
+			}
+			if len(due) == free {
+				continue
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// notify wakes up dispatcher loop
+func (d *Dispatcher) notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// deliver makes an attempt of delivery and schedules retry or dead-letters it on failure
+func (d *Dispatcher) deliver(ctx context.Context, delivery Delivery) {
+	logger := logging.FromContext(ctx).With(slog.String("delivery_id", delivery.ID), slog.String("subscription_id", delivery.SubscriptionID))
+	sub, err := d.store.Subscription(ctx, delivery.SubscriptionID)
+	if err != nil {
+		// deliveries of deleted subscriptions are removed with them
+		return
+	}
+	if !sub.Active {
+		// paused subscriptions keep their deliveries without spending tries
+		delivery.NextAttempt = d.now().UTC().Add(d.config.MaxBackoff)
+		_ = d.store.SaveDelivery(ctx, delivery)
+		return
+	}
+
+	start := d.now()
+	statusCode, retryAfter, err := d.send(ctx, sub, delivery)
+	attempt := Attempt{Time: start.UTC(), Duration: d.now().Sub(start).Milliseconds(), StatusCode: statusCode}
+	if err != nil {
+		attempt.Error = err.Error()
+	}
+	delivery.Attempts = append(delivery.Attempts, attempt)
+	delivery.Tries++
+
+	switch {
+	case err == nil:
+		delivery.Status = StatusSucceeded
+		delivery.NextAttempt = time.Time{}
+	case delivery.Tries >= d.config.MaxAttempts:
+		delivery.Status = StatusDead
+		delivery.NextAttempt = time.Time{}
+		logger.Warn("webhook delivery is dead-lettered", slog.Int("tries", delivery.Tries), slog.Any("error", err))
+	default:
+		delivery.NextAttempt = d.now().UTC().Add(max(d.backoff(delivery.Tries), retryAfter))
+		logger.Info("webhook delivery failed", slog.Int("tries", delivery.Tries), slog.Any("error", err))
+	}
+	if err := d.store.SaveDelivery(ctx, delivery); err != nil {
+		logger.Error("failed to save webhook delivery", slog.Any("error", err))
+	}
+}
+
+// backoff returns delay before the next try, it is doubled after every failure
+func (d *Dispatcher) backoff(tries int) time.Duration {
+	delay := d.config.BaseBackoff
+	for i := 1; i < tries && delay < d.config.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, d.config.MaxBackoff)
+}
+
+// send posts signed payload to subscription URL, any non 2xx response is a failure,
+// delay requested by Retry-After header of the receiver is returned
+func (d *Dispatcher) send(ctx context.Context, sub *Subscription, delivery Delivery) (int, time.Duration, error) {
+	body, err := json.Marshal(Payload{ID: delivery.ID, SubscriptionID: sub.ID, Event: delivery.Event})
+	if err != nil {
+		return 0, 0, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, d.config.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, 0, err
+	}
+	timestamp := d.now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set(HeaderID, delivery.ID)
+	req.Header.Set(HeaderEvent, string(delivery.Event.Type))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(sub.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer resp.Body.Close()
+	// drain response, so connection could be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp.StatusCode, 0, nil
+	}
+	var retryAfter time.Duration
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		retryAfter = min(time.Duration(seconds)*time.Second, d.config.MaxBackoff)
+	}
+	return resp.StatusCode, retryAfter, fmt.Errorf("receiver responded with status %d", resp.StatusCode)
+}
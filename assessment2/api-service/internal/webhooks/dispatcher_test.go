@@ -0,0 +1,211 @@
+package webhooks
+
+import (
+	"api-service/internal/domain"
+	"api-service/internal/events"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testReceiver is a webhook receiver verifying signatures, it fails the first requests
+type testReceiver struct {
+	*httptest.Server
+	failures atomic.Int32
+	mu       sync.Mutex
+	payloads []Payload
+	errors   []error
+}
+
+func newTestReceiver(t *testing.T, secret string, failures int) *testReceiver {
+	t.Helper()
+
+	receiver := &testReceiver{}
+	receiver.failures.Store(int32(failures))
+	receiver.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		receiver.mu.Lock()
+		defer receiver.mu.Unlock()
+
+		var payload Payload
+		err := Verify(secret, r.Header.Get(HeaderSignature), r.Header.Get(HeaderTimestamp), body, time.Minute)
+		if err == nil {
+			err = json.Unmarshal(body, &payload)
+		}
+		if err == nil && payload.ID != r.Header.Get(HeaderID) {
+			err = ErrorInvalidSignature
+		}
+		if err != nil {
+			receiver.errors = append(receiver.errors, err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if receiver.failures.Add(-1) >= 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		receiver.payloads = append(receiver.payloads, payload)
+	}))
+	t.Cleanup(receiver.Close)
+	return receiver
+}
+
+// startTestDispatcher creates dispatcher with a subscription of receiver and runs it until test is finished
+func startTestDispatcher(t *testing.T, receiver *testReceiver, secret string, maxAttempts int) (*Dispatcher, Subscription) {
+	t.Helper()
+
+	store, _ := NewFileStore("", 0)
+	sub := Subscription{ID: NewID(), URL: receiver.URL, Secret: secret, Active: true, Events: []events.Type{events.TypeCreated, events.TypeUpdated}}
+	_ = store.CreateSubscription(context.Background(), sub)
+	dispatcher := NewDispatcher(store, receiver.Client(), Config{
+		MaxAttempts:  maxAttempts,
+		BaseBackoff:  5 * time.Millisecond,
+		PollInterval: 5 * time.Millisecond,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		dispatcher.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return dispatcher, sub
+}
+
+// waitForDelivery waits until delivery gets the status
+func waitForDelivery(t *testing.T, store Store, subscriptionID string, status Status) Delivery {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		deliveries, _ := store.Deliveries(context.Background(), subscriptionID, status)
+		if len(deliveries) > 0 {
+			return deliveries[0]
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("delivery with status %s is not found", status)
+	return Delivery{}
+}
+
+// TestDispatcher_Deliver tests signed deliveries retried with backoff until receiver accepts them
+func TestDispatcher_Deliver(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name             string
+		failures         int
+		expectedAttempts int
+	}{
+		{"accepted delivery", 0, 1},
+		{"retried delivery", 2, 3},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			receiver := newTestReceiver(t, "secret", tt.failures)
+			dispatcher, sub := startTestDispatcher(t, receiver, "secret", 5)
+
+			event := events.Event{ID: 7, Type: events.TypeUpdated, PostID: 1, Version: 2, Post: &domain.Post{ID: 1, Title: "Title"}}
+			_ = dispatcher.Enqueue(context.Background(), event)
+			// deleted events are not subscribed
+			_ = dispatcher.Enqueue(context.Background(), events.Event{ID: 8, Type: events.TypeDeleted, PostID: 1})
+
+			delivery := waitForDelivery(t, dispatcher.Store(), sub.ID, StatusSucceeded)
+			if len(delivery.Attempts) != tt.expectedAttempts || delivery.Attempts[len(delivery.Attempts)-1].StatusCode != http.StatusOK {
+				t.Errorf("expected %d attempts, but got %+v", tt.expectedAttempts, delivery.Attempts)
+			}
+			receiver.mu.Lock()
+			defer receiver.mu.Unlock()
+			if len(receiver.errors) != 0 || len(receiver.payloads) != 1 {
+				t.Fatalf("expected single verified payload, but got %v and errors %v", receiver.payloads, receiver.errors)
+			}
+			if payload := receiver.payloads[0]; payload.ID != delivery.ID || payload.Event.ID != 7 || payload.Event.Post.Title != "Title" {
+				t.Errorf("unexpected payload %+v", payload)
+			}
+		})
+	}
+}
+
+// TestDispatcher_DeadLetter tests dead-lettering of failing deliveries and their replay
+func TestDispatcher_DeadLetter(t *testing.T) {
+	t.Parallel()
+	receiver := newTestReceiver(t, "secret", 4)
+	dispatcher, sub := startTestDispatcher(t, receiver, "secret", 3)
+
+	_ = dispatcher.Enqueue(context.Background(), events.Event{ID: 1, Type: events.TypeCreated, PostID: 1})
+	dead := waitForDelivery(t, dispatcher.Store(), sub.ID, StatusDead)
+	if dead.Tries != 3 || len(dead.Attempts) != 3 || dead.Attempts[0].StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected 3 failed attempts, but got %+v", dead)
+	}
+
+	// the first replayed try still fails, the second one is accepted
+	replayed, err := dispatcher.ReplayDead(context.Background(), sub.ID)
+	if err != nil || replayed != 1 {
+		t.Fatalf("expected replayed delivery, but got %d, %v", replayed, err)
+	}
+	delivery := waitForDelivery(t, dispatcher.Store(), sub.ID, StatusSucceeded)
+	if delivery.ID != dead.ID || delivery.Tries != 2 || len(delivery.Attempts) != 5 {
+		t.Errorf("unexpected replayed delivery %+v", delivery)
+	}
+}
+
+// TestDispatcher_Signature tests that receivers reject deliveries signed with another secret
+func TestDispatcher_Signature(t *testing.T) {
+	t.Parallel()
+	receiver := newTestReceiver(t, "secret", 0)
+	dispatcher, sub := startTestDispatcher(t, receiver, "another secret", 1)
+
+	_ = dispatcher.Enqueue(context.Background(), events.Event{ID: 1, Type: events.TypeCreated, PostID: 1})
+	dead := waitForDelivery(t, dispatcher.Store(), sub.ID, StatusDead)
+	if dead.Attempts[0].StatusCode != http.StatusBadRequest {
+		t.Errorf("expected rejected delivery, but got %+v", dead.Attempts)
+	}
+
+	if err := Verify("secret", Sign("secret", 1, []byte("body")), "1", []byte("body"), time.Minute); err != ErrorExpiredTimestamp {
+		t.Errorf("expected expired timestamp, but got %v", err)
+	}
+}
+
+// TestDispatcher_Backoff tests exponential growth of retry delays
+func TestDispatcher_Backoff(t *testing.T) {
+	t.Parallel()
+	store, _ := NewFileStore("", 0)
+	dispatcher := NewDispatcher(store, nil, Config{BaseBackoff: time.Second, MaxBackoff: 10 * time.Second})
+
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second}
+	for i, e := range expected {
+		if delay := dispatcher.backoff(i + 1); delay != e {
+			t.Errorf("expected %v after %d tries, but got %v", e, i+1, delay)
+		}
+	}
+}
+
+// TestDispatcher_Deduplication tests that redelivered events with the same deduplication id are queued once
+func TestDispatcher_Deduplication(t *testing.T) {
+	t.Parallel()
+	store, _ := NewFileStore("", 0)
+	_ = store.CreateSubscription(context.Background(), Subscription{ID: "sub", Active: true})
+	dispatcher := NewDispatcher(store, nil, Config{})
+
+	event := events.Event{ID: 1, Type: events.TypeCreated, PostID: 1, DedupID: "record"}
+	_ = dispatcher.Publish(context.Background(), []events.Event{event, event})
+	_ = dispatcher.Enqueue(context.Background(), event)
+
+	deliveries, _ := store.Deliveries(context.Background(), "sub", "")
+	if len(deliveries) != 1 {
+		t.Errorf("expected single delivery, but got %+v", deliveries)
+	}
+}
@@ -0,0 +1,314 @@
+package webhooks
+
+import (
+	"api-service/internal/logging"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultRetention is a number of succeeded and of dead-lettered deliveries kept per subscription for delivery logs
+const DefaultRetention = 100
+
+// DefaultFlushInterval is an interval of saving delivery attempts into the store file
+const DefaultFlushInterval = time.Second
+
+// fileData represent content of the store file
+type fileData struct {
+	Subscriptions []Subscription `json:"subscriptions"`
+	Deliveries    []Delivery     `json:"deliveries"`
+}
+
+// FileStore keeps subscriptions and deliveries in memory and saves them into a file, so queued deliveries
+// survive restarts; subscriptions and new deliveries are saved immediately, while claims and attempts are saved
+// in batches by Run and Flush, as losing them only makes deliveries retried; the store is not persisted when path is empty
+type FileStore struct {
+	mu            sync.Mutex
+	path          string
+	retention     int
+	dirty         bool // changes not saved yet
+	subscriptions map[string]Subscription
+	deliveries    map[string]Delivery
+}
+
+// NewFileStore creates a store persisted in the file, existing file is loaded,
+// up to retention succeeded and up to retention dead-lettered deliveries are kept per subscription
+func NewFileStore(path string, retention int) (*FileStore, error) {
+	s := &FileStore{
+		path:          path,
+		retention:     retention,
+		subscriptions: make(map[string]Subscription),
+		deliveries:    make(map[string]Delivery),
+	}
+	if s.retention <= 0 {
+		s.retention = DefaultRetention
+	}
+	if path == "" {
+		return s, nil
+	}
+
+	bytes, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var data fileData
+	if err := json.Unmarshal(bytes, &data); err != nil {
+		return nil, err
+	}
+	for _, sub := range data.Subscriptions {
+		s.subscriptions[sub.ID] = sub
+	}
+	for _, delivery := range data.Deliveries {
+		s.deliveries[delivery.ID] = delivery
+	}
+	return s, nil
+}
+
+// CreateSubscription saves a new subscription
+func (s *FileStore) CreateSubscription(ctx context.Context, sub Subscription) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.subscriptions[sub.ID] = sub
+	return s.save()
+}
+
+// UpdateSubscription replaces existing subscription
+func (s *FileStore) UpdateSubscription(ctx context.Context, sub Subscription) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.subscriptions[sub.ID]; !ok {
+		return ErrorSubscriptionNotFound
+	}
+	s.subscriptions[sub.ID] = sub
+	return s.save()
+}
+
+// DeleteSubscription removes subscription with its deliveries
+func (s *FileStore) DeleteSubscription(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.subscriptions[id]; !ok {
+		return ErrorSubscriptionNotFound
+	}
+	delete(s.subscriptions, id)
+	for deliveryID, delivery := range s.deliveries {
+		if delivery.SubscriptionID == id {
+			delete(s.deliveries, deliveryID)
+		}
+	}
+	return s.save()
+}
+
+// Subscription returns subscription by id
+func (s *FileStore) Subscription(ctx context.Context, id string) (*Subscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sub, ok := s.subscriptions[id]
+	if !ok {
+		return nil, ErrorSubscriptionNotFound
+	}
+	return &sub, nil
+}
+
+// Subscriptions returns all subscriptions ordered by creation time
+func (s *FileStore) Subscriptions(ctx context.Context) ([]Subscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.sortedSubscriptions(), nil
+}
+
+// Enqueue saves new deliveries, deliveries with already existing ids are ignored
+func (s *FileStore) Enqueue(ctx context.Context, deliveries ...Delivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, delivery := range deliveries {
+		if _, ok := s.deliveries[delivery.ID]; !ok {
+			s.deliveries[delivery.ID] = delivery
+		}
+	}
+	return s.save()
+}
+
+// Claim returns up to limit pending deliveries due at now, the earliest go first
+func (s *FileStore) Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []Delivery
+	for _, delivery := range s.deliveries {
+		if delivery.Status == StatusPending && !delivery.NextAttempt.After(now) {
+			due = append(due, delivery)
+		}
+	}
+	if len(due) == 0 {
+		return nil, nil
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if !due[i].NextAttempt.Equal(due[j].NextAttempt) {
+			return due[i].NextAttempt.Before(due[j].NextAttempt)
+		}
+		return due[i].Event.ID < due[j].Event.ID
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	for i := range due {
+		due[i].NextAttempt = now.Add(lease)
+		s.deliveries[due[i].ID] = due[i]
+	}
+	s.dirty = true
+	return due, nil
+}
+
+// SaveDelivery replaces existing delivery, the oldest succeeded and dead-lettered deliveries above retention are removed
+func (s *FileStore) SaveDelivery(ctx context.Context, delivery Delivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.deliveries[delivery.ID]; !ok {
+		return ErrorDeliveryNotFound
+	}
+	s.deliveries[delivery.ID] = delivery
+	if delivery.Status == StatusSucceeded || delivery.Status == StatusDead {
+		finished := s.sortedDeliveries(delivery.SubscriptionID, delivery.Status)
+		for _, old := range finished[min(len(finished), s.retention):] {
+			delete(s.deliveries, old.ID)
+		}
+	}
+	s.dirty = true
+	return nil
+}
+
+// Delivery returns delivery of the subscription by id
+func (s *FileStore) Delivery(ctx context.Context, subscriptionID string, id string) (*Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delivery, ok := s.deliveries[id]
+	if !ok || delivery.SubscriptionID != subscriptionID {
+		return nil, ErrorDeliveryNotFound
+	}
+	return &delivery, nil
+}
+
+// Deliveries returns deliveries of the subscription with the status, the latest go first
+func (s *FileStore) Deliveries(ctx context.Context, subscriptionID string, status Status) ([]Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.subscriptions[subscriptionID]; !ok {
+		return nil, ErrorSubscriptionNotFound
+	}
+	return s.sortedDeliveries(subscriptionID, status), nil
+}
+
+func (s *FileStore) sortedSubscriptions() []Subscription {
+	subs := make([]Subscription, 0, len(s.subscriptions))
+	for _, sub := range s.subscriptions {
+		subs = append(subs, sub)
+	}
+	sort.Slice(subs, func(i, j int) bool {
+		if !subs[i].CreatedAt.Equal(subs[j].CreatedAt) {
+			return subs[i].CreatedAt.Before(subs[j].CreatedAt)
+		}
+		return subs[i].ID < subs[j].ID
+	})
+	return subs
+}
+
+func (s *FileStore) sortedDeliveries(subscriptionID string, status Status) []Delivery {
+	deliveries := make([]Delivery, 0)
+	for _, delivery := range s.deliveries {
+		if delivery.SubscriptionID == subscriptionID && (status == "" || delivery.Status == status) {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	sort.Slice(deliveries, func(i, j int) bool {
+		if !deliveries[i].CreatedAt.Equal(deliveries[j].CreatedAt) {
+			return deliveries[i].CreatedAt.After(deliveries[j].CreatedAt)
+		}
+		return deliveries[i].Event.ID > deliveries[j].Event.ID
+	})
+	return deliveries
+}
+
+// Run saves changes every interval until context is cancelled
+func (s *FileStore) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Flush(); err != nil {
+				logging.FromContext(ctx).Error("failed to save webhook deliveries", slog.Any("error", err))
+			}
+		}
+	}
+}
+
+// Flush saves changes which are not saved yet
+func (s *FileStore) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.dirty {
+		return nil
+	}
+	return s.save()
+}
+
+// save replaces the store file atomically, so restarted service never reads partially written file
+func (s *FileStore) save() error {
+	if s.path == "" {
+		return nil
+	}
+	data := fileData{Subscriptions: s.sortedSubscriptions(), Deliveries: make([]Delivery, 0, len(s.deliveries))}
+	for _, sub := range data.Subscriptions {
+		data.Deliveries = append(data.Deliveries, s.sortedDeliveries(sub.ID, "")...)
+	}
+
+	bytes, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(bytes)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return err
+	}
+	s.dirty = false
+	return nil
+}
@@ -0,0 +1,108 @@
+package webhooks
+
+import (
+	"api-service/internal/events"
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestFileStore_Persistence tests that queued deliveries are restored after restart
+func TestFileStore_Persistence(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "webhooks.json")
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	store, err := NewFileStore(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = store.CreateSubscription(ctx, Subscription{ID: "sub", URL: "http://localhost", Active: true, CreatedAt: now})
+	_ = store.Enqueue(ctx,
+		Delivery{ID: "due", SubscriptionID: "sub", Event: events.Event{ID: 1}, Status: StatusPending, NextAttempt: now, CreatedAt: now},
+		Delivery{ID: "later", SubscriptionID: "sub", Event: events.Event{ID: 2}, Status: StatusPending, NextAttempt: now.Add(time.Hour), CreatedAt: now},
+	)
+
+	restored, err := NewFileStore(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claimed, _ := restored.Claim(ctx, now, time.Minute, 10)
+	if len(claimed) != 1 || claimed[0].ID != "due" {
+		t.Fatalf("expected due delivery, but got %+v", claimed)
+	}
+	// claimed deliveries are leased
+	if again, _ := restored.Claim(ctx, now.Add(time.Second), time.Minute, 10); len(again) != 0 {
+		t.Errorf("expected leased delivery, but got %+v", again)
+	}
+	if expired, _ := restored.Claim(ctx, now.Add(2*time.Minute), time.Minute, 10); len(expired) != 1 {
+		t.Errorf("expected delivery with expired lease, but got %+v", expired)
+	}
+
+	// attempts are saved by flush
+	delivery := claimed[0]
+	delivery.Status = StatusSucceeded
+	_ = restored.SaveDelivery(ctx, delivery)
+	if unsaved, _ := NewFileStore(path, 0); len(mustDeliveries(t, unsaved, StatusSucceeded)) != 0 {
+		t.Error("expected attempt saved only by flush")
+	}
+	if err := restored.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if saved, _ := NewFileStore(path, 0); len(mustDeliveries(t, saved, StatusSucceeded)) != 1 {
+		t.Error("expected attempt saved by flush")
+	}
+
+	_ = restored.DeleteSubscription(ctx, "sub")
+	if _, err := restored.Delivery(ctx, "sub", "later"); err != ErrorDeliveryNotFound {
+		t.Errorf("expected deliveries removed with subscription, but got %v", err)
+	}
+}
+
+// TestFileStore_Retention tests that only recent succeeded and dead-lettered deliveries are kept
+func TestFileStore_Retention(t *testing.T) {
+	t.Parallel()
+	store, _ := NewFileStore("", 2)
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	_ = store.CreateSubscription(ctx, Subscription{ID: "sub", Active: true})
+	for i := 1; i <= 7; i++ {
+		delivery := Delivery{ID: string(rune('a' + i)), SubscriptionID: "sub", Event: events.Event{ID: uint64(i)}, Status: StatusPending, CreatedAt: now.Add(time.Duration(i) * time.Second)}
+		_ = store.Enqueue(ctx, delivery)
+		switch {
+		case i <= 3:
+			delivery.Status = StatusSucceeded
+			_ = store.SaveDelivery(ctx, delivery)
+		case i <= 6:
+			delivery.Status = StatusDead
+			_ = store.SaveDelivery(ctx, delivery)
+		}
+	}
+
+	succeeded := mustDeliveries(t, store, StatusSucceeded)
+	dead := mustDeliveries(t, store, StatusDead)
+	all := mustDeliveries(t, store, "")
+	if len(succeeded) != 2 || succeeded[0].Event.ID != 3 || succeeded[1].Event.ID != 2 {
+		t.Errorf("expected 2 latest succeeded deliveries, but got %+v", succeeded)
+	}
+	if len(dead) != 2 || dead[0].Event.ID != 6 || dead[1].Event.ID != 5 {
+		t.Errorf("expected 2 latest dead deliveries, but got %+v", dead)
+	}
+	if len(all) != 5 {
+		t.Errorf("expected finished and pending deliveries, but got %+v", all)
+	}
+}
+
+// mustDeliveries returns deliveries of test subscription with the status
+func mustDeliveries(t *testing.T, store *FileStore, status Status) []Delivery {
+	t.Helper()
+
+	deliveries, err := store.Deliveries(context.Background(), "sub", status)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return deliveries
+}
@@ -0,0 +1,106 @@
+package webhooks
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrorAddressNotAllowed is returned for receivers in loopback, private, link-local and other non-public networks
+var ErrorAddressNotAllowed = errors.New("webhook receiver address is not allowed")
+
+// sharedAddressSpace is a carrier-grade NAT network (RFC 6598), it is not reachable from the internet
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// AddressPolicy decides which addresses receive webhooks: public addresses and addresses of allowed networks,
+// so subscriptions could not reach internal services or cloud metadata endpoints
+type AddressPolicy struct {
+	Allowed []netip.Prefix // networks allowed in addition to public addresses
+}
+
+// ParseNetworks parses comma separated list of networks in CIDR notation or single addresses
+func ParseNetworks(value string) ([]netip.Prefix, error) {
+	var networks []netip.Prefix
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if !strings.Contains(item, "/") {
+			addr, err := netip.ParseAddr(item)
+			if err != nil {
+				return nil, fmt.Errorf("invalid network %q: %w", item, err)
+			}
+			networks = append(networks, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		network, err := netip.ParsePrefix(item)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network %q: %w", item, err)
+		}
+		networks = append(networks, network.Masked())
+	}
+	return networks, nil
+}
+
+// Allows reports whether address could receive webhooks
+func (p AddressPolicy) Allows(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, network := range p.Allowed {
+		if network.Contains(addr) {
+			return true
+		}
+	}
+	return addr.IsValid() && !addr.IsLoopback() && !addr.IsPrivate() && !addr.IsUnspecified() &&
+		!addr.IsLinkLocalUnicast() && !addr.IsLinkLocalMulticast() && !addr.IsInterfaceLocalMulticast() &&
+		!addr.IsMulticast() && !sharedAddressSpace.Contains(addr)
+}
+
+// CheckURL rejects URLs with addresses or localhost names which are not allowed,
+// host names are checked when they are resolved for delivery
+func (p AddressPolicy) CheckURL(target *url.URL) error {
+	host := strings.TrimSuffix(strings.ToLower(target.Hostname()), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		host = "127.0.0.1"
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return nil
+	}
+	if !p.Allows(addr) {
+		return ErrorAddressNotAllowed
+	}
+	return nil
+}
+
+// NewClient creates HTTP client connecting only to addresses allowed by policy, addresses are checked after
+// host names are resolved, so names resolving to internal addresses are rejected as well;
+// redirects are not followed and proxies from environment are not used, as both would bypass the check
+func NewClient(policy AddressPolicy) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(network string, address string, conn syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil || !policy.Allows(addrPort.Addr()) {
+				return fmt.Errorf("%w: %s", ErrorAddressNotAllowed, address)
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
@@ -0,0 +1,119 @@
+package webhooks
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"testing"
+)
+
+// TestAddressPolicy_Allows tests that only public addresses and addresses of allowed networks receive webhooks
+func TestAddressPolicy_Allows(t *testing.T) {
+	t.Parallel()
+
+	allowed, err := ParseNetworks(" 10.1.0.0/16, fd00::1,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseNetworks("10.1.0.0/33"); err == nil {
+		t.Error("expected error of invalid network")
+	}
+	policy := AddressPolicy{Allowed: allowed}
+
+	tests := []struct {
+		addr     string
+		expected bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1::1", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"::ffff:127.0.0.1", false},
+		{"0.0.0.0", false},
+		{"10.0.0.1", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.64.0.1", false},
+		{"224.0.0.1", false},
+		{"fc00::1", false},
+		{"fe80::1", false},
+		{"10.1.2.3", true},
+		{"fd00::1", true},
+		{"fd00::2", false},
+	}
+	for _, tt := range tests {
+		if actual := policy.Allows(netip.MustParseAddr(tt.addr)); actual != tt.expected {
+			t.Errorf("address %s: expected %v, but got %v", tt.addr, tt.expected, actual)
+		}
+	}
+
+	urls := []struct {
+		url      string
+		expected error
+	}{
+		{"https://example.com/hook", nil},
+		{"http://localhost:8080/", ErrorAddressNotAllowed},
+		{"http://api.localhost./", ErrorAddressNotAllowed},
+		{"http://[::1]/", ErrorAddressNotAllowed},
+		{"http://10.1.0.5/", nil},
+	}
+	for _, tt := range urls {
+		target, _ := url.Parse(tt.url)
+		if err := policy.CheckURL(target); !errors.Is(err, tt.expected) {
+			t.Errorf("url %s: expected %v, but got %v", tt.url, tt.expected, err)
+		}
+	}
+}
+
+// TestNewClient tests that client checks resolved addresses and does not follow redirects
+func TestNewClient(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "/target", http.StatusFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	// loopback receivers are refused by default, also when addressed by name
+	resp, err := NewClient(AddressPolicy{}).Get(server.URL)
+	if err == nil {
+		resp.Body.Close()
+	}
+	if !errors.Is(err, ErrorAddressNotAllowed) {
+		t.Errorf("expected ErrorAddressNotAllowed, but got %v", err)
+	}
+	target, _ := url.Parse(server.URL)
+	resp, err = NewClient(AddressPolicy{}).Get("http://localhost:" + target.Port())
+	if err == nil {
+		resp.Body.Close()
+	}
+	if !errors.Is(err, ErrorAddressNotAllowed) {
+		t.Errorf("expected ErrorAddressNotAllowed for localhost, but got %v", err)
+	}
+
+	client := NewClient(AddressPolicy{Allowed: []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}})
+	resp, err = client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("expected http.StatusNoContent, but got %d", resp.StatusCode)
+	}
+
+	// redirects are returned as responses
+	resp, err = client.Get(server.URL + "/redirect")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Errorf("expected http.StatusFound, but got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,56 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers of delivery requests
+const (
+	HeaderID        = "X-Webhook-ID"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// signaturePrefix names signature algorithm
+const signaturePrefix = "sha256="
+
+var (
+	ErrorInvalidSignature = errors.New("invalid webhook signature")
+	ErrorExpiredTimestamp = errors.New("webhook timestamp is out of tolerance")
+)
+
+// Sign calculates HMAC-SHA256 signature of timestamp and body, timestamp is signed
+// to prevent replaying of captured requests
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks signature and timestamp headers of received delivery,
+// requests older than tolerance are rejected, zero tolerance disables the check
+func Verify(secret string, signature string, timestamp string, body []byte, tolerance time.Duration) error {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || !strings.HasPrefix(signature, signaturePrefix) {
+		return ErrorInvalidSignature
+	}
+	if tolerance > 0 {
+		age := time.Since(time.Unix(ts, 0))
+		if age > tolerance || age < -tolerance {
+			return ErrorExpiredTimestamp
+		}
+	}
+	if !hmac.Equal([]byte(signature), []byte(Sign(secret, ts, body))) {
+		return ErrorInvalidSignature
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package webhooks
+
+import (
+	"api-service/internal/events"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+// Status names state of a delivery
+type Status string
+
+// Delivery statuses
+const (
+	StatusPending   Status = "pending"
+	StatusSucceeded Status = "succeeded"
+	StatusDead      Status = "dead" // delivery failed too many times and waits for replay
+)
+
+var (
+	ErrorSubscriptionNotFound = errors.New("webhook subscription not found")
+	ErrorDeliveryNotFound     = errors.New("webhook delivery not found")
+)
+
+// Subscription represent receiver of post events, events are delivered to URL and signed with secret,
+// an empty list of events means all of them
+type Subscription struct {
+	ID        string        `json:"id"`
+	URL       string        `json:"url"`
+	Secret    string        `json:"secret,omitempty"`
+	Events    []events.Type `json:"events,omitempty"`
+	Active    bool          `json:"active"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+// Matches checks that subscription receives events of the type
+func (s Subscription) Matches(eventType events.Type) bool {
+	if !s.Active {
+		return false
+	}
+	if len(s.Events) == 0 {
+		return true
+	}
+	for _, t := range s.Events {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Attempt represent a single try of delivery
+type Attempt struct {
+	Time       time.Time `json:"time"`
+	Duration   int64     `json:"duration_ms"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Delivery represent an event queued for a subscription with log of its attempts,
+// tries are counted since delivery was created or replayed
+type Delivery struct {
+	ID             string       `json:"id"`
+	SubscriptionID string       `json:"subscription_id"`
+	Event          events.Event `json:"event"`
+	Status         Status       `json:"status"`
+	Tries          int          `json:"tries"`
+	NextAttempt    time.Time    `json:"next_attempt"`
+	Attempts       []Attempt    `json:"attempts"`
+	CreatedAt      time.Time    `json:"created_at"`
+}
+
+// Payload represent body of delivery request, id is the same for every attempt,
+// so receivers are able to drop duplicates
+type Payload struct {
+	ID             string       `json:"id"`
+	SubscriptionID string       `json:"subscription_id"`
+	Event          events.Event `json:"event"`
+}
+
+// Store represent interface for persistent storage of subscriptions and delivery queue
+type Store interface {
+	// CreateSubscription saves a new subscription
+	CreateSubscription(ctx context.Context, sub Subscription) error
+	// UpdateSubscription replaces existing subscription
+	UpdateSubscription(ctx context.Context, sub Subscription) error
+	// DeleteSubscription removes subscription with its deliveries
+	DeleteSubscription(ctx context.Context, id string) error
+	// Subscription returns subscription by id
+	Subscription(ctx context.Context, id string) (*Subscription, error)
+	// Subscriptions returns all subscriptions ordered by creation time
+	Subscriptions(ctx context.Context) ([]Subscription, error)
+
+	// Enqueue saves new deliveries, deliveries with already existing ids are ignored
+	Enqueue(ctx context.Context, deliveries ...Delivery) error
+	// Claim returns up to limit pending deliveries due at now and postpones them by lease,
+	// so deliveries of crashed workers are retried after lease is expired
+	Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]Delivery, error)
+	// SaveDelivery replaces existing delivery
+	SaveDelivery(ctx context.Context, delivery Delivery) error
+	// Delivery returns delivery of the subscription by id
+	Delivery(ctx context.Context, subscriptionID string, id string) (*Delivery, error)
+	// Deliveries returns deliveries of the subscription with the status, or all of them
+	// when status is empty, the latest deliveries go first
+	Deliveries(ctx context.Context, subscriptionID string, status Status) ([]Delivery, error)
+}
+
+// NewID generates random identifier of subscriptions and deliveries
+func NewID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// NewSecret generates random secret of a subscription
+func NewSecret() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return "whsec_" + hex.EncodeToString(b)
+}